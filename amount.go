@@ -0,0 +1,265 @@
+package tapsilat
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// AmountFormat describes how a locale writes currency amounts.
+type AmountFormat struct {
+	Decimal     string
+	Group       string
+	SymbolFirst bool
+	SymbolSpace bool
+}
+
+// amountFormats is keyed by the language part of a locale.
+var amountFormats = map[string]AmountFormat{
+	"en": {Decimal: ".", Group: ",", SymbolFirst: true},
+	"tr": {Decimal: ",", Group: ".", SymbolFirst: true},
+	"de": {Decimal: ",", Group: ".", SymbolSpace: true},
+	"es": {Decimal: ",", Group: ".", SymbolSpace: true},
+	"it": {Decimal: ",", Group: ".", SymbolSpace: true},
+	"az": {Decimal: ",", Group: ".", SymbolSpace: true},
+	"nl": {Decimal: ",", Group: ".", SymbolFirst: true, SymbolSpace: true},
+	"pt": {Decimal: ",", Group: ".", SymbolFirst: true, SymbolSpace: true},
+	"fr": {Decimal: ",", Group: "\u202f", SymbolSpace: true},
+	"ru": {Decimal: ",", Group: "\u00a0", SymbolSpace: true},
+}
+
+// LocaleAmountFormat returns the amount format for a locale such as "tr" or
+// "en-US", falling back to English.
+func LocaleAmountFormat(locale string) AmountFormat {
+	language := strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(language, "-_"); i >= 0 {
+		language = language[:i]
+	}
+	if format, ok := amountFormats[language]; ok {
+		return format
+	}
+	return amountFormats["en"]
+}
+
+// AmountToMinor converts an amount to minor units of the currency, rounding
+// half away from zero (100.456 TRY -> 10046). The amount is rounded as the
+// shortest decimal that represents it, so 1.005 TRY is 101 rather than the
+// 100 that multiplying the binary float by 100 gives.
+func AmountToMinor(amount float64, currency string) int64 {
+	units := CurrencyMinorUnits(currency)
+	if minor, ok := decimalToMinor(strconv.FormatFloat(amount, 'f', -1, 64), units); ok {
+		return minor
+	}
+	return int64(math.Round(amount * math.Pow10(units)))
+}
+
+// decimalToMinor converts a decimal string such as "-1.005" to minor units,
+// rounding half away from zero on the decimal digits. It reports false for
+// anything but an optional "-", digits and at most one ".", and on overflow.
+func decimalToMinor(text string, units int) (int64, bool) {
+	negative := strings.HasPrefix(text, "-")
+	text = strings.TrimPrefix(text, "-")
+	whole, fraction, _ := strings.Cut(text, ".")
+	if whole == "" && fraction == "" {
+		return 0, false
+	}
+	for _, r := range whole + fraction {
+		if r < '0' || r > '9' {
+			return 0, false
+		}
+	}
+
+	if len(fraction) <= units {
+		fraction += strings.Repeat("0", units-len(fraction)+1)
+	}
+	digits := strings.TrimLeft(whole+fraction[:units], "0")
+	if digits == "" {
+		digits = "0"
+	}
+	minor, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	if fraction[units] >= '5' {
+		if minor == math.MaxInt64 {
+			return 0, false
+		}
+		minor++
+	}
+	if negative {
+		minor = -minor
+	}
+	return minor, true
+}
+
+// MinorToAmount converts minor units of the currency back to an amount.
+func MinorToAmount(minor int64, currency string) float64 {
+	return float64(minor) / math.Pow10(CurrencyMinorUnits(currency))
+}
+
+// FormatAmount formats amount for display in the given locale, using the
+// currency symbol and minor units: FormatAmount(1234.5, "TRY", "tr") returns
+// "₺1.234,50".
+func FormatAmount(amount float64, currency, locale string) string {
+	return FormatMinorAmount(AmountToMinor(amount, currency), currency, locale)
+}
+
+// FormatMinorAmount is FormatAmount for an amount already in minor units.
+func FormatMinorAmount(minor int64, currency, locale string) string {
+	format := LocaleAmountFormat(locale)
+	units := CurrencyMinorUnits(currency)
+	symbol := strings.ToUpper(currency)
+	if info, ok := LookupCurrency(currency); ok {
+		symbol = info.Symbol
+	}
+
+	negative := minor < 0
+	if negative {
+		minor = -minor
+	}
+	digits := strconv.FormatInt(minor, 10)
+	if len(digits) <= units {
+		digits = strings.Repeat("0", units-len(digits)+1) + digits
+	}
+	whole := digits[:len(digits)-units]
+	fraction := digits[len(digits)-units:]
+
+	var b strings.Builder
+	for i, r := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteString(format.Group)
+		}
+		b.WriteRune(r)
+	}
+	number := b.String()
+	if units > 0 {
+		number += format.Decimal + fraction
+	}
+
+	space := ""
+	if format.SymbolSpace {
+		space = "\u00a0"
+	}
+	sign := ""
+	if negative {
+		sign = "-"
+	}
+	if format.SymbolFirst {
+		return sign + symbol + space + number
+	}
+	return sign + number + space + symbol
+}
+
+// ParseAmount parses an amount written for the given locale, ignoring the
+// currency symbol or code and grouping separators. Grouping must be in
+// threes; text that is not grouped for the locale but is in the API's
+// "100.00" form is read as such, so "100.00" is 100 for "tr" too. With an
+// empty locale it accepts the API's form as well as "1,234.56" and
+// "1.234,56", treating the last separator as the decimal point. The result
+// is rounded half away from zero to the currency's minor units.
+func ParseAmount(value, currency, locale string) (float64, error) {
+	text := strings.TrimSpace(value)
+	if info, ok := LookupCurrency(currency); ok {
+		text = strings.ReplaceAll(text, info.Symbol, "")
+		text = strings.ReplaceAll(text, info.Code, "")
+	}
+	text = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '\u202f' {
+			return -1
+		}
+		return r
+	}, text)
+
+	negative := strings.HasPrefix(text, "-")
+	text = strings.TrimPrefix(text, "-")
+	if text == "" {
+		return 0, invalidAmountError(value)
+	}
+
+	var normalized string
+	if locale != "" {
+		var ok bool
+		if normalized, ok = normalizeLocaleAmount(text, LocaleAmountFormat(locale)); !ok {
+			return 0, invalidAmountError(value)
+		}
+	} else {
+		normalized = normalizeAmountSeparators(text)
+	}
+
+	minor, ok := decimalToMinor(normalized, CurrencyMinorUnits(currency))
+	if !ok {
+		return 0, invalidAmountError(value)
+	}
+	if negative {
+		minor = -minor
+	}
+	return MinorToAmount(minor, currency), nil
+}
+
+// normalizeLocaleAmount rewrites an amount written in format to use "." as
+// the decimal point and no grouping. Text whose grouping does not fit the
+// format is accepted in the API's "100.00" form.
+func normalizeLocaleAmount(text string, format AmountFormat) (string, bool) {
+	whole, fraction, hasFraction := strings.Cut(text, format.Decimal)
+	if validAmountGrouping(whole, format.Group) && !strings.Contains(fraction, format.Group) && !strings.Contains(fraction, format.Decimal) {
+		normalized := strings.ReplaceAll(whole, format.Group, "")
+		if hasFraction {
+			normalized += "." + fraction
+		}
+		return normalized, true
+	}
+	if !strings.Contains(text, ",") && strings.Count(text, ".") <= 1 {
+		return text, true
+	}
+	return "", false
+}
+
+// validAmountGrouping reports whether whole is grouped in threes by group,
+// with a first group of one to three digits. Ungrouped text is valid.
+func validAmountGrouping(whole, group string) bool {
+	if group == "" || !strings.Contains(whole, group) {
+		return true
+	}
+	for i, part := range strings.Split(whole, group) {
+		if len(part) == 0 || len(part) > 3 || (i > 0 && len(part) != 3) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeAmountSeparators rewrites an amount with unknown separators to
+// use "." as the decimal point and no grouping.
+func normalizeAmountSeparators(text string) string {
+	lastDot := strings.LastIndex(text, ".")
+	lastComma := strings.LastIndex(text, ",")
+
+	switch {
+	case lastDot >= 0 && lastComma >= 0:
+		if lastComma > lastDot {
+			text = strings.ReplaceAll(text, ".", "")
+			return strings.Replace(text, ",", ".", 1)
+		}
+		return strings.ReplaceAll(text, ",", "")
+	case lastComma >= 0:
+		// A single comma followed by other than three digits is a decimal comma.
+		if strings.Count(text, ",") == 1 && len(text)-lastComma-1 != 3 {
+			return strings.Replace(text, ",", ".", 1)
+		}
+		return strings.ReplaceAll(text, ",", "")
+	case strings.Count(text, ".") > 1:
+		return strings.ReplaceAll(text, ".", "")
+	default:
+		return text
+	}
+}
+
+func invalidAmountError(value string) error {
+	return &ValidationError{
+		StatusCode: 400,
+		Code:       0,
+		Message:    fmt.Sprintf("Invalid amount: %s", value),
+	}
+}
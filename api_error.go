@@ -11,6 +11,8 @@ type APIError struct {
 	Code       string
 	Message    string
 	RawBody    string
+	// RequestID is the request ID reported by the server, if any.
+	RequestID string
 }
 
 func (e *APIError) Error() string {
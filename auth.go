@@ -0,0 +1,221 @@
+package tapsilat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenSource supplies the bearer token of each request.
+type TokenSource interface {
+	Token(ctx context.Context) (Secret, error)
+}
+
+// RefreshableTokenSource is a TokenSource that can replace a token the API
+// rejected. After a 401, the request is retried once with the token returned
+// by the next Token call.
+type RefreshableTokenSource interface {
+	TokenSource
+	// Refresh is called with the rejected token. It should drop the token if
+	// it is still current; a concurrent refresh may already have replaced it.
+	Refresh(ctx context.Context, rejected Secret) error
+}
+
+// TokenSourceFunc adapts a function to a TokenSource.
+type TokenSourceFunc func(ctx context.Context) (Secret, error)
+
+// Token calls f.
+func (f TokenSourceFunc) Token(ctx context.Context) (Secret, error) {
+	return f(ctx)
+}
+
+// StaticTokenSource returns a TokenSource that always returns token.
+func StaticTokenSource(token string) TokenSource {
+	return TokenSourceFunc(func(context.Context) (Secret, error) {
+		return Secret(token), nil
+	})
+}
+
+// EnvTokenSource reads the token from the named environment variable on
+// every request, so a rotated value is picked up without a restart.
+type EnvTokenSource string
+
+// Token returns the variable's value, trimmed. An unset or empty variable is
+// an error.
+func (e EnvTokenSource) Token(context.Context) (Secret, error) {
+	token := strings.TrimSpace(os.Getenv(string(e)))
+	if token == "" {
+		return "", fmt.Errorf("tapsilat: environment variable %s is empty", string(e))
+	}
+	return Secret(token), nil
+}
+
+// Refresh does nothing; the variable is read on every request anyway.
+func (e EnvTokenSource) Refresh(context.Context, Secret) error {
+	return nil
+}
+
+// FileTokenSource reads the token from a file, such as a mounted secret, and
+// reads it again whenever the file's modification time or size changes.
+type FileTokenSource struct {
+	Path string
+
+	mu      sync.Mutex
+	token   Secret
+	modTime time.Time
+	size    int64
+}
+
+// NewFileTokenSource creates a source for the file at path.
+func NewFileTokenSource(path string) *FileTokenSource {
+	return &FileTokenSource{Path: path}
+}
+
+// Token returns the file's content, trimmed. An empty file is an error.
+func (f *FileTokenSource) Token(context.Context) (Secret, error) {
+	info, err := os.Stat(f.Path)
+	if err != nil {
+		return "", fmt.Errorf("tapsilat: read token file: %w", err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.token != "" && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return f.token, nil
+	}
+	data, err := os.ReadFile(f.Path)
+	if err != nil {
+		return "", fmt.Errorf("tapsilat: read token file: %w", err)
+	}
+	token := Secret(strings.TrimSpace(string(data)))
+	if token == "" {
+		return "", fmt.Errorf("tapsilat: token file %s is empty", f.Path)
+	}
+	f.token, f.modTime, f.size = token, info.ModTime(), info.Size()
+	return token, nil
+}
+
+// Refresh forces the next Token call to read the file again.
+func (f *FileTokenSource) Refresh(_ context.Context, rejected Secret) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.token == rejected {
+		f.token = ""
+	}
+	return nil
+}
+
+// RefreshingTokenSource caches a token obtained from Fetch, such as an
+// OAuth-style token endpoint, and fetches a new one RefreshBefore it
+// expires or after the API rejects it. Fetches never run concurrently.
+type RefreshingTokenSource struct {
+	// Fetch returns a new token and its expiry. A zero expiry means the
+	// token is used until the API rejects it.
+	Fetch func(ctx context.Context) (token string, expiresAt time.Time, err error)
+	// RefreshBefore is how long before expiry a new token is fetched.
+	RefreshBefore time.Duration
+	Now           func() time.Time
+
+	mu        sync.Mutex
+	fetching  chan struct{}
+	token     Secret
+	expiresAt time.Time
+}
+
+// Token returns the cached token, fetching a new one when there is none or
+// it is about to expire.
+func (r *RefreshingTokenSource) Token(ctx context.Context) (Secret, error) {
+	if token, ok := r.cached(); ok {
+		return token, nil
+	}
+
+	r.mu.Lock()
+	if r.fetching == nil {
+		r.fetching = make(chan struct{}, 1)
+	}
+	fetching := r.fetching
+	r.mu.Unlock()
+	select {
+	case fetching <- struct{}{}:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	defer func() { <-fetching }()
+
+	// Another caller may have fetched while this one waited.
+	if token, ok := r.cached(); ok {
+		return token, nil
+	}
+	if r.Fetch == nil {
+		return "", errors.New("tapsilat: RefreshingTokenSource has no Fetch")
+	}
+	token, expiresAt, err := r.Fetch(ctx)
+	if err != nil {
+		return "", fmt.Errorf("tapsilat: fetch token: %w", err)
+	}
+	if token == "" {
+		return "", errors.New("tapsilat: fetch token: empty token")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.token, r.expiresAt = Secret(token), expiresAt
+	return r.token, nil
+}
+
+// Refresh drops the cached token if it is the rejected one.
+func (r *RefreshingTokenSource) Refresh(_ context.Context, rejected Secret) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.token == rejected {
+		r.token = ""
+	}
+	return nil
+}
+
+func (r *RefreshingTokenSource) cached() (Secret, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.token == "" {
+		return "", false
+	}
+	if r.expiresAt.IsZero() {
+		return r.token, true
+	}
+	now := time.Now()
+	if r.Now != nil {
+		now = r.Now()
+	}
+	return r.token, now.Before(r.expiresAt.Add(-r.RefreshBefore))
+}
+
+type tokenSourceKey struct{}
+
+// ContextWithTokenSource returns a context whose requests are authenticated
+// by source instead of the client's own token.
+func ContextWithTokenSource(ctx context.Context, source TokenSource) context.Context {
+	return context.WithValue(ctx, tokenSourceKey{}, source)
+}
+
+// ContextWithToken returns a context whose requests are sent with token
+// instead of the client's own token.
+func ContextWithToken(ctx context.Context, token string) context.Context {
+	return ContextWithTokenSource(ctx, StaticTokenSource(token))
+}
+
+// tokenSource returns the source of a request's token: the context's, then
+// the client's TokenSource, then its Token field.
+func (t *API) tokenSource(ctx context.Context) TokenSource {
+	if source, ok := ctx.Value(tokenSourceKey{}).(TokenSource); ok && source != nil {
+		return source
+	}
+	if t.TokenSource != nil {
+		return t.TokenSource
+	}
+	return TokenSourceFunc(func(context.Context) (Secret, error) {
+		return t.bearerToken(), nil
+	})
+}
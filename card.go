@@ -0,0 +1,357 @@
+package tapsilat
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// CardBrand is the card network detected from the leading digits (BIN) of a
+// card number.
+type CardBrand string
+
+const (
+	CardBrandUnknown    CardBrand = ""
+	CardBrandVisa       CardBrand = "visa"
+	CardBrandMastercard CardBrand = "mastercard"
+	CardBrandAmex       CardBrand = "amex"
+	CardBrandTroy       CardBrand = "troy"
+	CardBrandDiscover   CardBrand = "discover"
+	CardBrandJCB        CardBrand = "jcb"
+	CardBrandUnionPay   CardBrand = "unionpay"
+	CardBrandDiners     CardBrand = "diners"
+	CardBrandMaestro    CardBrand = "maestro"
+)
+
+// cardBrandRule matches card numbers whose prefix of the length of from/to
+// falls in [from, to].
+type cardBrandRule struct {
+	brand   CardBrand
+	from    int
+	to      int
+	lengths []int
+}
+
+// cardBrandRules is ordered so that narrower ranges win over broader ones.
+var cardBrandRules = []cardBrandRule{
+	{CardBrandTroy, 9792, 9792, []int{16}},
+	{CardBrandAmex, 34, 34, []int{15}},
+	{CardBrandAmex, 37, 37, []int{15}},
+	{CardBrandDiners, 300, 305, []int{14, 16, 17, 18, 19}},
+	{CardBrandDiners, 36, 36, []int{14, 16, 17, 18, 19}},
+	{CardBrandDiners, 38, 39, []int{16, 17, 18, 19}},
+	{CardBrandJCB, 3528, 3589, []int{16, 17, 18, 19}},
+	{CardBrandDiscover, 6011, 6011, []int{16, 17, 18, 19}},
+	{CardBrandDiscover, 644, 649, []int{16, 17, 18, 19}},
+	{CardBrandDiscover, 65, 65, []int{16, 17, 18, 19}},
+	{CardBrandUnionPay, 62, 62, []int{16, 17, 18, 19}},
+	{CardBrandMastercard, 51, 55, []int{16}},
+	{CardBrandMastercard, 2221, 2720, []int{16}},
+	{CardBrandMaestro, 50, 50, []int{12, 13, 14, 15, 16, 17, 18, 19}},
+	{CardBrandMaestro, 56, 58, []int{12, 13, 14, 15, 16, 17, 18, 19}},
+	{CardBrandMaestro, 6304, 6304, []int{12, 13, 14, 15, 16, 17, 18, 19}},
+	{CardBrandMaestro, 639, 639, []int{12, 13, 14, 15, 16, 17, 18, 19}},
+	{CardBrandMaestro, 67, 67, []int{12, 13, 14, 15, 16, 17, 18, 19}},
+	{CardBrandVisa, 4, 4, []int{13, 16, 19}},
+}
+
+// cardBrandNames lists the card scheme names each brand is known under, used
+// to match the schemes returned by ListCardSchemes.
+var cardBrandNames = map[CardBrand][]string{
+	CardBrandVisa:       {"visa"},
+	CardBrandMastercard: {"mastercard", "master card", "master"},
+	CardBrandAmex:       {"amex", "american express", "americanexpress"},
+	CardBrandTroy:       {"troy"},
+	CardBrandDiscover:   {"discover"},
+	CardBrandJCB:        {"jcb"},
+	CardBrandUnionPay:   {"unionpay", "union pay", "china unionpay"},
+	CardBrandDiners:     {"diners", "diners club", "dinersclub"},
+	CardBrandMaestro:    {"maestro"},
+}
+
+// MatchesScheme reports whether a card scheme name, such as "MasterCard" or
+// "American Express", refers to this brand.
+func (b CardBrand) MatchesScheme(name string) bool {
+	name = strings.ToLower(strings.Join(strings.Fields(name), " "))
+	for _, alias := range cardBrandNames[b] {
+		if name == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// CVVLength returns the number of security code digits used by the brand.
+func (b CardBrand) CVVLength() int {
+	if b == CardBrandAmex {
+		return 4
+	}
+	return 3
+}
+
+// NormalizeCardNumber removes spaces and dashes from a card number.
+func NormalizeCardNumber(number string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, number)
+}
+
+// DetectCardBrand returns the brand of a card number from its BIN, or
+// CardBrandUnknown.
+func DetectCardBrand(number string) CardBrand {
+	rule, _ := matchCardBrandRule(NormalizeCardNumber(number))
+	return rule.brand
+}
+
+func matchCardBrandRule(number string) (cardBrandRule, bool) {
+	if !isDigits(number) {
+		return cardBrandRule{}, false
+	}
+	for _, rule := range cardBrandRules {
+		width := len(strconv.Itoa(rule.from))
+		if len(number) < width {
+			continue
+		}
+		prefix, _ := strconv.Atoi(number[:width])
+		if prefix >= rule.from && prefix <= rule.to {
+			return rule, true
+		}
+	}
+	return cardBrandRule{}, false
+}
+
+// LuhnValid reports whether a card number passes the Luhn checksum.
+func LuhnValid(number string) bool {
+	number = NormalizeCardNumber(number)
+	if len(number) < 2 || !isDigits(number) {
+		return false
+	}
+
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		digit := int(number[i] - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// ValidateCardNumber validates card number format, length and Luhn checksum.
+// Returns the cleaned card number and its brand if valid.
+func ValidateCardNumber(number string) (string, CardBrand, error) {
+	cleanNumber := NormalizeCardNumber(strings.TrimSpace(number))
+	if !isDigits(cleanNumber) || len(cleanNumber) < 12 || len(cleanNumber) > 19 {
+		return "", CardBrandUnknown, cardValidationError("Card number must be 12 to 19 digits")
+	}
+	if !LuhnValid(cleanNumber) {
+		return "", CardBrandUnknown, cardValidationError("Card number is invalid")
+	}
+
+	rule, ok := matchCardBrandRule(cleanNumber)
+	if ok && !slices.Contains(rule.lengths, len(cleanNumber)) {
+		return "", rule.brand, cardValidationError(fmt.Sprintf("Card number length %d is invalid for %s cards", len(cleanNumber), rule.brand))
+	}
+	return cleanNumber, rule.brand, nil
+}
+
+// ValidateCardExpiry validates an expiry month and a two or four digit year,
+// rejecting cards that expired before now. A card is valid until the end of
+// its expiry month. Returns the two digit month and the year as given.
+func ValidateCardExpiry(month, year string, now time.Time) (string, string, error) {
+	month = strings.TrimSpace(month)
+	year = strings.TrimSpace(year)
+
+	m, err := strconv.Atoi(month)
+	if err != nil || !isDigits(month) || m < 1 || m > 12 {
+		return "", "", cardValidationError("Expiry month must be between 01 and 12")
+	}
+	y, ok := expiryYear(year, now)
+	if !ok {
+		return "", "", cardValidationError("Expiry year must be two or four digits")
+	}
+
+	expiresAt := time.Date(y, time.Month(m)+1, 1, 0, 0, 0, 0, now.Location())
+	if !now.Before(expiresAt) {
+		return "", "", cardValidationError(fmt.Sprintf("Card expired in %02d/%d", m, y))
+	}
+	return fmt.Sprintf("%02d", m), year, nil
+}
+
+// expiryYear parses a two or four digit expiry year. Two digit years are in
+// the century of now.
+func expiryYear(year string, now time.Time) (int, bool) {
+	y, err := strconv.Atoi(year)
+	if err != nil || !isDigits(year) || (len(year) != 2 && len(year) != 4) {
+		return 0, false
+	}
+	if len(year) == 2 {
+		y += now.Year() / 100 * 100
+	}
+	return y, true
+}
+
+// ValidateCVV validates the security code length for the card brand.
+func ValidateCVV(cvv string, brand CardBrand) (string, error) {
+	cvv = strings.TrimSpace(cvv)
+	if !isDigits(cvv) || len(cvv) != brand.CVVLength() {
+		return "", cardValidationError(fmt.Sprintf("CVV must be %d digits", brand.CVVLength()))
+	}
+	return cvv, nil
+}
+
+// NormalizeHolderName trims a card holder name, collapses inner whitespace
+// and drops control characters.
+func NormalizeHolderName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, name)
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// ValidateHolderName normalizes a card holder name and checks it only
+// contains letters, spaces, dots, apostrophes and hyphens. Returns the
+// normalized name if valid.
+func ValidateHolderName(name string) (string, error) {
+	name = NormalizeHolderName(name)
+	if name == "" {
+		return "", cardValidationError("Holder name is required")
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsMark(r) && !strings.ContainsRune(" .'-", r) {
+			return "", cardValidationError("Holder name may only contain letters, spaces, dots, apostrophes and hyphens")
+		}
+	}
+	return name, nil
+}
+
+// Validate checks the card data of the request and returns a copy with the
+// card number, expiry month, CVV and holder name normalized.
+func (r CardTokenizeRequest) Validate() (CardTokenizeRequest, error) {
+	return r.validateAt(time.Now())
+}
+
+func (r CardTokenizeRequest) validateAt(now time.Time) (CardTokenizeRequest, error) {
+	number, brand, err := ValidateCardNumber(r.CardNumber)
+	if err != nil {
+		return r, err
+	}
+	month, year, err := ValidateCardExpiry(r.ExpiryMonth, r.ExpiryYear, now)
+	if err != nil {
+		return r, err
+	}
+	cvv, err := ValidateCVV(r.CVV, brand)
+	if err != nil {
+		return r, err
+	}
+	holderName, err := ValidateHolderName(r.HolderName)
+	if err != nil {
+		return r, err
+	}
+
+	r.CardNumber = number
+	r.ExpiryMonth = month
+	r.ExpiryYear = year
+	r.CVV = cvv
+	r.HolderName = holderName
+	return r, nil
+}
+
+// Brand returns the brand detected from the request card number.
+func (r CardTokenizeRequest) Brand() CardBrand {
+	return DetectCardBrand(r.CardNumber)
+}
+
+// ResolveCardScheme returns the scheme from ListCardSchemes matching the
+// brand of a card number.
+func (t *API) ResolveCardScheme(ctx context.Context, number string) (CardScheme, error) {
+	brand := DetectCardBrand(number)
+	if brand == CardBrandUnknown {
+		return CardScheme{}, cardValidationError("Card brand could not be detected")
+	}
+
+	schemes, err := t.CachedCardSchemes(ctx)
+	if err != nil {
+		return CardScheme{}, err
+	}
+	for _, scheme := range schemes {
+		if brand.MatchesScheme(scheme.Name) {
+			return scheme, nil
+		}
+	}
+	return CardScheme{}, cardValidationError(fmt.Sprintf("Card scheme %s is not supported", brand))
+}
+
+// MaskCardNumber keeps the BIN and last four digits of a card number and
+// masks the rest, e.g. "552608******0006".
+func MaskCardNumber(number string) string {
+	number = NormalizeCardNumber(number)
+	if len(number) <= 10 {
+		return strings.Repeat("*", len(number))
+	}
+	return number[:6] + strings.Repeat("*", len(number)-10) + number[len(number)-4:]
+}
+
+// String returns the request with the card number masked and the CVV
+// hidden, so it is safe to log.
+func (r CardTokenizeRequest) String() string {
+	return fmt.Sprintf("CardTokenizeRequest{CardNumber:%s HolderName:%s ExpiryMonth:%s ExpiryYear:%s CVV:%s Name:%s Default:%t Mode:%d UserId:%s UserEmail:%s RedirectSuccessUrl:%s RedirectFailureUrl:%s}",
+		MaskCardNumber(r.CardNumber), r.HolderName, r.ExpiryMonth, r.ExpiryYear, redactedCVV(r.CVV),
+		r.Name, r.Default, r.Mode, r.UserId, r.UserEmail, r.RedirectSuccessUrl, r.RedirectFailureUrl)
+}
+
+// GoString is String for the %#v verb.
+func (r CardTokenizeRequest) GoString() string {
+	return r.String()
+}
+
+// LogValue implements slog.LogValuer with the card number masked and the
+// CVV hidden.
+func (r CardTokenizeRequest) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("card_number", MaskCardNumber(r.CardNumber)),
+		slog.String("holder_name", r.HolderName),
+		slog.String("expiry_month", r.ExpiryMonth),
+		slog.String("expiry_year", r.ExpiryYear),
+		slog.String("cvv", redactedCVV(r.CVV)),
+		slog.String("name", r.Name),
+		slog.Bool("default", r.Default),
+		slog.Int("mode", r.Mode),
+		slog.String("user_id", r.UserId),
+		slog.String("user_email", r.UserEmail),
+		slog.String("redirect_success_url", r.RedirectSuccessUrl),
+		slog.String("redirect_failure_url", r.RedirectFailureUrl),
+	)
+}
+
+func redactedCVV(cvv string) string {
+	if cvv == "" {
+		return ""
+	}
+	return "***"
+}
+
+func cardValidationError(message string) error {
+	return &ValidationError{
+		StatusCode: 400,
+		Code:       0,
+		Message:    message,
+	}
+}
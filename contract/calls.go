@@ -0,0 +1,254 @@
+package contract
+
+import (
+	"context"
+
+	"github.com/tapsilat/tapsilat-go"
+)
+
+// currencyID is a currency UUID, which the client sends without looking it
+// up in the organization currency list.
+const currencyID = "9b2f6c3e-4a1d-4c8e-9f7a-2b5d8e1c6a34"
+
+// Calls returns a Call for every method of tapsilat.API that sends a request.
+func Calls() []Call {
+	currencies := []string{currencyID}
+
+	return []Call{
+		call("CreateOrder", func(ctx context.Context, api *tapsilat.API) (tapsilat.OrderResponse, error) {
+			return api.CreateOrder(ctx, tapsilat.Order{
+				Locale:         "tr",
+				Amount:         100,
+				Currency:       "TRY",
+				ConversationID: "conv_1",
+				Buyer: tapsilat.OrderBuyer{
+					Name:      "Ayse",
+					Surname:   "Yilmaz",
+					Email:     "ayse@example.com",
+					GsmNumber: "+905551234567",
+				},
+				BasketItems: []tapsilat.OrderBasketItem{{Id: "item_1", Name: "Kalem", Price: 100, ItemType: "PHYSICAL"}},
+			})
+		}),
+		call("GetOrder", func(ctx context.Context, api *tapsilat.API) (tapsilat.OrderDetail, error) {
+			return api.GetOrder(ctx, "ref_1")
+		}),
+		call("GetOrderByConversationID", func(ctx context.Context, api *tapsilat.API) (tapsilat.OrderDetail, error) {
+			return api.GetOrderByConversationID(ctx, "conv_1")
+		}),
+		call("GetOrders", func(ctx context.Context, api *tapsilat.API) (tapsilat.PaginatedData, error) {
+			return api.GetOrders(ctx, "1", "10", "buyer_1")
+		}),
+		call("GetOrderList", func(ctx context.Context, api *tapsilat.API) (tapsilat.PaginatedData, error) {
+			return api.GetOrderList(ctx, 1, 10, "2024-01-01", "2024-01-31", "org_1", "rel_1")
+		}),
+		call("GetOrderSubmerchants", func(ctx context.Context, api *tapsilat.API) (tapsilat.PaginatedData, error) {
+			return api.GetOrderSubmerchants(ctx, 1, 10)
+		}),
+		call("GetCheckoutURL", func(ctx context.Context, api *tapsilat.API) (string, error) {
+			return api.GetCheckoutURL(ctx, "ref_1")
+		}),
+		call("GetOrderStatus", func(ctx context.Context, api *tapsilat.API) (tapsilat.OrderStatus, error) {
+			return api.GetOrderStatus(ctx, "ref_1")
+		}),
+		call("GetOrderPaymentDetails", func(ctx context.Context, api *tapsilat.API) (map[string]any, error) {
+			return api.GetOrderPaymentDetails(ctx, "ref_1")
+		}),
+		call("GetOrderTransactions", func(ctx context.Context, api *tapsilat.API) (map[string]any, error) {
+			return api.GetOrderTransactions(ctx, "ref_1")
+		}),
+		call("GetOrderPayments", func(ctx context.Context, api *tapsilat.API) (tapsilat.GetOrderPaymentsResponse, error) {
+			return api.GetOrderPayments(ctx, tapsilat.GetOrderPaymentsRequest{OrderReferenceID: "ref_1"})
+		}),
+		call("CancelOrder", func(ctx context.Context, api *tapsilat.API) (tapsilat.RefundCancelOrderResponse, error) {
+			return api.CancelOrder(ctx, tapsilat.CancelOrder{ReferenceID: "ref_1"})
+		}),
+		call("RefundOrder", func(ctx context.Context, api *tapsilat.API) (tapsilat.RefundCancelOrderResponse, error) {
+			return api.RefundOrder(ctx, tapsilat.RefundOrder{ReferenceID: "ref_1", Amount: 25.5})
+		}),
+		call("RefundAllOrder", func(ctx context.Context, api *tapsilat.API) (tapsilat.RefundCancelOrderResponse, error) {
+			return api.RefundAllOrder(ctx, "ref_1")
+		}),
+		call("GetOrderTerm", func(ctx context.Context, api *tapsilat.API) (map[string]any, error) {
+			return api.GetOrderTerm(ctx, "term_1")
+		}),
+		call("CreateOrderTerm", func(ctx context.Context, api *tapsilat.API) (map[string]any, error) {
+			return api.CreateOrderTerm(ctx, tapsilat.OrderPaymentTermCreateDTO{OrderReferenceID: "ref_1", Amount: 50, DueDate: "2024-02-01", Required: true})
+		}),
+		call("DeleteOrderTerm", func(ctx context.Context, api *tapsilat.API) (map[string]any, error) {
+			return api.DeleteOrderTerm(ctx, "order_1", "term_1")
+		}),
+		call("UpdateOrderTerm", func(ctx context.Context, api *tapsilat.API) (map[string]any, error) {
+			return api.UpdateOrderTerm(ctx, tapsilat.OrderPaymentTermUpdateDTO{TermReferenceID: "term_1", Amount: tapsilat.Ptr(25.5)})
+		}),
+		call("RefundOrderTerm", func(ctx context.Context, api *tapsilat.API) (map[string]any, error) {
+			return api.RefundOrderTerm(ctx, tapsilat.OrderTermRefundRequest{TermReferenceID: "term_1", Amount: tapsilat.Ptr(25.5)})
+		}),
+		call("OrderTerminate", func(ctx context.Context, api *tapsilat.API) (map[string]any, error) {
+			return api.OrderTerminate(ctx, "ref_1")
+		}),
+		call("OrderManualCallback", func(ctx context.Context, api *tapsilat.API) (map[string]any, error) {
+			return api.OrderManualCallback(ctx, "ref_1", "conv_1")
+		}),
+		call("OrderRelatedUpdate", func(ctx context.Context, api *tapsilat.API) (map[string]any, error) {
+			return api.OrderRelatedUpdate(ctx, "ref_1", "rel_1")
+		}),
+		call("GetOrganizationSettings", func(ctx context.Context, api *tapsilat.API) (tapsilat.OrganizationSettings, error) {
+			return api.GetOrganizationSettings(ctx)
+		}),
+		call("GetOrganizationCurrencies", func(ctx context.Context, api *tapsilat.API) (tapsilat.OrganizationCurrenciesResponse, error) {
+			return api.GetOrganizationCurrencies(ctx)
+		}),
+		call("ListOrganizationCurrencyPresets", func(ctx context.Context, api *tapsilat.API) (tapsilat.OrganizationCurrencyPresetsResponse, error) {
+			return api.ListOrganizationCurrencyPresets(ctx)
+		}),
+		call("CreateOrganizationCurrency", func(ctx context.Context, api *tapsilat.API) (tapsilat.CreateOrganizationCurrencyResponse, error) {
+			return api.CreateOrganizationCurrency(ctx, "usd")
+		}),
+		call("CreateOrganizationUser", func(ctx context.Context, api *tapsilat.API) (tapsilat.OrgCreateUserResponse, error) {
+			return api.CreateOrganizationUser(ctx, tapsilat.OrgCreateUserRequest{Email: "user@example.com", FirstName: "Ayse", LastName: "Yilmaz"})
+		}),
+		call("CreateOrganizationUserToken", func(ctx context.Context, api *tapsilat.API) (tapsilat.OrgUserTokenCreateResponse, error) {
+			return api.CreateOrganizationUserToken(ctx, tapsilat.OrgUserTokenCreateRequest{Email: "user@example.com", Expire: 60})
+		}),
+		call("CreateSubmerchant", func(ctx context.Context, api *tapsilat.API) (tapsilat.SubmerchantMutationResponse, error) {
+			return api.CreateSubmerchant(ctx, tapsilat.SubmerchantCreateRequest{Name: "Tenant A", Email: "tenant@example.com", CurrencyID: currencyID})
+		}),
+		call("GetSubmerchant", func(ctx context.Context, api *tapsilat.API) (tapsilat.Submerchant, error) {
+			return api.GetSubmerchant(ctx, "sub_1")
+		}),
+		call("ListSubmerchants", func(ctx context.Context, api *tapsilat.API) (tapsilat.SubmerchantListResponse, error) {
+			return api.ListSubmerchants(ctx, 1, 10)
+		}),
+		call("UpdateSubmerchant", func(ctx context.Context, api *tapsilat.API) (tapsilat.SubmerchantMutationResponse, error) {
+			return api.UpdateSubmerchant(ctx, "sub_1", tapsilat.SubmerchantUpdateRequest{Name: "Tenant B", CurrencyID: currencyID})
+		}),
+		call("PatchSubmerchant", func(ctx context.Context, api *tapsilat.API) (tapsilat.SubmerchantMutationResponse, error) {
+			return api.PatchSubmerchant(ctx, "sub_1", tapsilat.SubmerchantPatch{Name: tapsilat.Ptr("Tenant B"), Address: tapsilat.Ptr("")})
+		}),
+		call("DeleteSubmerchant", func(ctx context.Context, api *tapsilat.API) (tapsilat.SubmerchantMutationResponse, error) {
+			return api.DeleteSubmerchant(ctx, "sub_1")
+		}),
+		call("GetSuborganizations", func(ctx context.Context, api *tapsilat.API) (tapsilat.SuborganizationListResponse, error) {
+			return api.GetSuborganizations(ctx, 1, 10)
+		}),
+		call("GetSuborganization", func(ctx context.Context, api *tapsilat.API) (tapsilat.SuborganizationListItem, error) {
+			return api.GetSuborganization(ctx, "suborg_1")
+		}),
+		call("GetSuborganizationDetail", func(ctx context.Context, api *tapsilat.API) (tapsilat.SuborganizationDetail, error) {
+			return api.GetSuborganizationDetail(ctx, "suborg_1")
+		}),
+		call("ListVpos", func(ctx context.Context, api *tapsilat.API) (tapsilat.VposListResponse, error) {
+			return api.ListVpos(ctx, 1, 10)
+		}),
+		call("ListVposWithFilter", func(ctx context.Context, api *tapsilat.API) (tapsilat.VposListResponse, error) {
+			return api.ListVposWithFilter(ctx, 1, 10, tapsilat.VposListFilter{SuborganizationID: "suborg_1"})
+		}),
+		call("CreateVpos", func(ctx context.Context, api *tapsilat.API) (tapsilat.VposMutationResponse, error) {
+			return api.CreateVpos(ctx, tapsilat.VposCreateRequest{Name: "Akbank POS", BankName: "Akbank", MerchantKey: "key", Currencies: currencies})
+		}),
+		call("GetVpos", func(ctx context.Context, api *tapsilat.API) (tapsilat.Vpos, error) {
+			return api.GetVpos(ctx, "v_1")
+		}),
+		call("UpdateVpos", func(ctx context.Context, api *tapsilat.API) (tapsilat.VposMutationResponse, error) {
+			return api.UpdateVpos(ctx, "v_1", tapsilat.VposUpdateRequest{Name: "Akbank POS", Currencies: currencies})
+		}),
+		call("PatchVpos", func(ctx context.Context, api *tapsilat.API) (tapsilat.VposMutationResponse, error) {
+			return api.PatchVpos(ctx, "v_1", tapsilat.VposPatch{Priority: tapsilat.Ptr(int64(0)), Currencies: &currencies})
+		}),
+		call("DeleteVpos", func(ctx context.Context, api *tapsilat.API) (tapsilat.VposMutationResponse, error) {
+			return api.DeleteVpos(ctx, "v_1")
+		}),
+		call("ListVposAcquirers", func(ctx context.Context, api *tapsilat.API) (tapsilat.VposAcquirerListResponse, error) {
+			return api.ListVposAcquirers(ctx)
+		}),
+		call("ListCardSchemes", func(ctx context.Context, api *tapsilat.API) (tapsilat.CardSchemeListResponse, error) {
+			return api.ListCardSchemes(ctx)
+		}),
+		call("ListVposAcquirerTemplates", func(ctx context.Context, api *tapsilat.API) (tapsilat.VposAcquirerTemplateListResponse, error) {
+			return api.ListVposAcquirerTemplates(ctx)
+		}),
+		call("ListVposSubmerchants", func(ctx context.Context, api *tapsilat.API) (tapsilat.VposSubmerchantListResponse, error) {
+			return api.ListVposSubmerchants(ctx, 1, 10, "v_1", "ext_1")
+		}),
+		call("CreateVposSubmerchant", func(ctx context.Context, api *tapsilat.API) (tapsilat.VposSubmerchantMutationResponse, error) {
+			return api.CreateVposSubmerchant(ctx, tapsilat.VposSubmerchantCreateRequest{ExternalReferenceID: "ext_1", SubmerchantID: "sub_1", VposID: "v_1"})
+		}),
+		call("GetVposSubmerchant", func(ctx context.Context, api *tapsilat.API) (tapsilat.VposSubmerchant, error) {
+			return api.GetVposSubmerchant(ctx, "vs_1")
+		}),
+		call("UpdateVposSubmerchant", func(ctx context.Context, api *tapsilat.API) (tapsilat.VposSubmerchantMutationResponse, error) {
+			return api.UpdateVposSubmerchant(ctx, "vs_1", tapsilat.VposSubmerchantUpdateRequest{Title: "Tenant A"})
+		}),
+		call("PatchVposSubmerchant", func(ctx context.Context, api *tapsilat.API) (tapsilat.VposSubmerchantMutationResponse, error) {
+			return api.PatchVposSubmerchant(ctx, "vs_1", tapsilat.VposSubmerchantPatch{Title: tapsilat.Ptr("")})
+		}),
+		call("DeleteVposSubmerchant", func(ctx context.Context, api *tapsilat.API) (tapsilat.VposSubmerchantMutationResponse, error) {
+			return api.DeleteVposSubmerchant(ctx, "vs_1")
+		}),
+		call("GetSuborganizationBySubmerchant", func(ctx context.Context, api *tapsilat.API) (tapsilat.SubmerchantSuborganizationMapping, error) {
+			return api.GetSuborganizationBySubmerchant(ctx, "sub_1")
+		}),
+		call("GetSubmerchantBySuborganization", func(ctx context.Context, api *tapsilat.API) (tapsilat.SuborganizationSubmerchantMapping, error) {
+			return api.GetSubmerchantBySuborganization(ctx, "suborg_1")
+		}),
+		call("GetSubscription", func(ctx context.Context, api *tapsilat.API) (tapsilat.SubscriptionDetail, error) {
+			return api.GetSubscription(ctx, tapsilat.SubscriptionGetRequest{ReferenceID: "subs_1"})
+		}),
+		errCall("CancelSubscription", func(ctx context.Context, api *tapsilat.API) error {
+			return api.CancelSubscription(ctx, tapsilat.SubscriptionCancelRequest{ReferenceID: "subs_1"})
+		}),
+		call("CreateSubscription", func(ctx context.Context, api *tapsilat.API) (tapsilat.SubscriptionCreateResponse, error) {
+			return api.CreateSubscription(ctx, tapsilat.SubscriptionCreateRequest{
+				Amount:      99.9,
+				Currency:    "TRY",
+				Period:      tapsilat.SubscriptionPeriodMonthly,
+				PaymentDate: 15,
+				Cycle:       12,
+				Title:       "Pro plan",
+				User:        tapsilat.SubscriptionUser{Email: "user@example.com"},
+			})
+		}),
+		call("ListSubscriptions", func(ctx context.Context, api *tapsilat.API) (tapsilat.PaginatedData, error) {
+			return api.ListSubscriptions(ctx, 1, 10)
+		}),
+		call("RedirectSubscription", func(ctx context.Context, api *tapsilat.API) (tapsilat.SubscriptionRedirectResponse, error) {
+			return api.RedirectSubscription(ctx, tapsilat.SubscriptionRedirectRequest{SubscriptionID: "subs_1"})
+		}),
+		errCall("UpdateSubscription", func(ctx context.Context, api *tapsilat.API) error {
+			return api.UpdateSubscription(ctx, tapsilat.SubscriptionUpdateRequest{ReferenceID: "subs_1", Amount: 129.9})
+		}),
+		errCall("PauseSubscription", func(ctx context.Context, api *tapsilat.API) error {
+			return api.PauseSubscription(ctx, tapsilat.SubscriptionPauseRequest{ReferenceID: "subs_1"})
+		}),
+		errCall("ResumeSubscription", func(ctx context.Context, api *tapsilat.API) error {
+			return api.ResumeSubscription(ctx, tapsilat.SubscriptionPauseRequest{ReferenceID: "subs_1"})
+		}),
+		call("TokenizeCard", func(ctx context.Context, api *tapsilat.API) (tapsilat.CardTokenizeResponse, error) {
+			return api.TokenizeCard(ctx, tapsilat.CardTokenizeRequest{
+				CardNumber:  "5528 7900 0000 0008",
+				HolderName:  "Ayse Yilmaz",
+				ExpiryMonth: "12",
+				ExpiryYear:  "2099",
+				CVV:         "123",
+			})
+		}),
+		call("ListSavedCards", func(ctx context.Context, api *tapsilat.API) (tapsilat.ListSavedCardsResponse, error) {
+			return api.ListSavedCards(ctx, 1, 10)
+		}),
+		call("DeleteSavedCard", func(ctx context.Context, api *tapsilat.API) (tapsilat.DeleteSavedCardResponse, error) {
+			return api.DeleteSavedCard(ctx, "card_1")
+		}),
+	}
+}
+
+func call[T any](method string, invoke func(context.Context, *tapsilat.API) (T, error)) Call {
+	return Call{Method: method, Invoke: func(ctx context.Context, api *tapsilat.API) error {
+		_, err := invoke(ctx, api)
+		return err
+	}}
+}
+
+func errCall(method string, invoke func(context.Context, *tapsilat.API) error) Call {
+	return Call{Method: method, Invoke: invoke}
+}
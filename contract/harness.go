@@ -0,0 +1,253 @@
+package contract
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/tapsilat/tapsilat-go"
+)
+
+// Call invokes one method of tapsilat.API with a payload that passes its
+// client-side validation.
+type Call struct {
+	Method string
+	Invoke func(ctx context.Context, api *tapsilat.API) error
+}
+
+// Where tells which side of an exchange an Issue was found on.
+type Where string
+
+const (
+	// InRequest issues are in the path, query or body the client sent.
+	InRequest Where = "request"
+	// InResponse issues are between the response schema and the DTO the
+	// client decoded it into.
+	InResponse Where = "response"
+)
+
+// Issue is one disagreement between the client and the description.
+type Issue struct {
+	Method    string `json:"method"`
+	Operation string `json:"operation"`
+	In        Where  `json:"in"`
+	tapsilat.FieldIssue
+}
+
+func (i Issue) String() string {
+	field := i.Field
+	if field == "" {
+		field = "body"
+	}
+	return fmt.Sprintf("%s (%s) %s: %s %s", i.Method, i.Operation, i.In, field, i.Message)
+}
+
+// Result is the outcome of one Call. Operations lists the "METHOD /path"
+// operations it reached, in order. Err is set when the call failed for a
+// reason other than the issues, such as a client-side validation error.
+type Result struct {
+	Method     string   `json:"method"`
+	Operations []string `json:"operations"`
+	Issues     []Issue  `json:"issues,omitempty"`
+	Err        error    `json:"-"`
+}
+
+// Report holds the results of Check in the order of the calls.
+type Report struct {
+	Results []Result `json:"results"`
+}
+
+// Issues returns the issues of every result.
+func (r *Report) Issues() []Issue {
+	var issues []Issue
+	for _, result := range r.Results {
+		issues = append(issues, result.Issues...)
+	}
+	return issues
+}
+
+// Err joins the errors of the results that failed to run.
+func (r *Report) Err() error {
+	var errs []error
+	for _, result := range r.Results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", result.Method, result.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Covered returns the operations reached by at least one call, sorted.
+func (r *Report) Covered() []string {
+	seen := map[string]bool{}
+	var covered []string
+	for _, result := range r.Results {
+		for _, op := range result.Operations {
+			if !seen[op] {
+				seen[op] = true
+				covered = append(covered, op)
+			}
+		}
+	}
+	sort.Strings(covered)
+	return covered
+}
+
+const harnessEndpoint = "https://panel.contract.test"
+
+// Check runs calls against an in-process panel described by doc. Each
+// request is matched to an operation, its query parameters and body are
+// validated, and the reply is an Example of the response schema. The client
+// decodes replies with StrictDecoding, so DTO drift comes back as a
+// *tapsilat.FieldValidationError and is reported as InResponse issues.
+func Check(ctx context.Context, doc *Document, calls []Call) *Report {
+	report := &Report{Results: make([]Result, 0, len(calls))}
+	for _, call := range calls {
+		report.Results = append(report.Results, checkCall(ctx, doc, call))
+	}
+	return report
+}
+
+func checkCall(ctx context.Context, doc *Document, call Call) Result {
+	panel := &panel{doc: doc, method: call.Method}
+	api := tapsilat.NewCustomAPIWithClient(harnessEndpoint, "contract-token", &http.Client{Transport: panel})
+	api.StrictDecoding = true
+
+	err := call.Invoke(ctx, api)
+	result := Result{Method: call.Method, Operations: panel.operations, Issues: panel.issues}
+
+	var fieldErr *tapsilat.FieldValidationError
+	switch {
+	case err == nil:
+	case errors.As(err, &fieldErr) && len(panel.operations) > 0:
+		// Client-side validation fails before any request is sent, so a
+		// FieldValidationError after a request is the strict decoder's.
+		operation := panel.operations[len(panel.operations)-1]
+		for _, issue := range fieldErr.Issues {
+			result.Issues = append(result.Issues, Issue{Method: call.Method, Operation: operation, In: InResponse, FieldIssue: issue})
+		}
+	default:
+		var apiErr *tapsilat.APIError
+		if !errors.As(err, &apiErr) || len(panel.issues) == 0 {
+			result.Err = err
+		}
+	}
+	return result
+}
+
+// panel is the http.RoundTripper that plays the server.
+type panel struct {
+	doc    *Document
+	method string
+
+	mu         sync.Mutex
+	operations []string
+	issues     []Issue
+}
+
+func (p *panel) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	path := req.URL.Path
+	op, template, _ := p.doc.OperationFor(req.Method, path)
+	if op == nil {
+		key := req.Method + " " + path
+		p.record(key, Issue{Method: p.method, Operation: key, In: InRequest, FieldIssue: tapsilat.FieldIssue{
+			Reason: tapsilat.FieldUnknown, Message: "is not an operation of the description",
+		}})
+		return reply(req, http.StatusNotFound, []byte(`{"code":"404","error":"unknown operation"}`)), nil
+	}
+	key := req.Method + " " + template
+
+	var issues []Issue
+	add := func(found ...tapsilat.FieldIssue) {
+		for _, issue := range found {
+			issues = append(issues, Issue{Method: p.method, Operation: key, In: InRequest, FieldIssue: issue})
+		}
+	}
+	add(checkQuery(op, req.URL.Query())...)
+	switch schema := op.RequestSchema(); {
+	case schema == nil && len(bytes.TrimSpace(body)) > 0:
+		add(tapsilat.FieldIssue{Reason: tapsilat.FieldUnknown, Message: "is sent, but the operation takes no body"})
+	case schema != nil && len(bytes.TrimSpace(body)) == 0:
+		add(tapsilat.FieldIssue{Reason: tapsilat.FieldMissing, Message: "is missing"})
+	case schema != nil:
+		found, err := p.doc.Validate(schema, body)
+		if err != nil {
+			add(tapsilat.FieldIssue{Reason: tapsilat.FieldInvalid, Message: err.Error()})
+		}
+		add(found...)
+	}
+	p.record(key, issues...)
+	if len(issues) > 0 {
+		return reply(req, http.StatusBadRequest, []byte(`{"code":"400","error":"request does not match the description"}`)), nil
+	}
+
+	response, err := json.Marshal(p.doc.Example(op.ResponseSchema()))
+	if err != nil {
+		return nil, err
+	}
+	return reply(req, http.StatusOK, response), nil
+}
+
+func (p *panel) record(operation string, issues ...Issue) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.operations = append(p.operations, operation)
+	p.issues = append(p.issues, issues...)
+}
+
+func checkQuery(op *Operation, query map[string][]string) []tapsilat.FieldIssue {
+	declared := map[string]bool{}
+	for _, param := range op.Parameters {
+		if param.In == "query" {
+			declared[param.Name] = true
+		}
+	}
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var issues []tapsilat.FieldIssue
+	for _, name := range names {
+		if !declared[name] {
+			issues = append(issues, tapsilat.FieldIssue{Field: "?" + name, Reason: tapsilat.FieldUnknown, Message: "is not a query parameter of the operation"})
+		}
+	}
+	for _, param := range op.Parameters {
+		if param.In == "query" && param.Required && len(query[param.Name]) == 0 {
+			issues = append(issues, tapsilat.FieldIssue{Field: "?" + param.Name, Reason: tapsilat.FieldMissing, Message: "is missing"})
+		}
+	}
+	return issues
+}
+
+func reply(req *http.Request, status int, body []byte) *http.Response {
+	return &http.Response{
+		StatusCode:    status,
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
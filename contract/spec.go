@@ -0,0 +1,333 @@
+// Package contract checks the client against the OpenAPI description of
+// the panel endpoints it calls.
+//
+// The description is embedded as openapi.json. It is written by hand from
+// the SDK's request and response types, not published by the panel, so it
+// catches the SDK drifting from itself rather than from the panel; entries
+// marked x-unverified are not known to exist on the panel at all. Check runs the methods of
+// tapsilat.API against an in-process panel that validates every request
+// body against the description and replies with an example built from the
+// response schema, decoded with StrictDecoding, so a field renamed on either
+// side shows up as an Issue.
+package contract
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//go:embed openapi.json
+var openapiJSON []byte
+
+// Document is the subset of an OpenAPI 3 document the harness reads.
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem maps lower-case HTTP methods to operations.
+type PathItem map[string]*Operation
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Operation is one endpoint. SDKMethods lists the tapsilat.API methods that
+// call it, from the x-sdk-methods extension. Unverified, from x-unverified,
+// marks an endpoint the SDK calls that is not known to exist on the panel.
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary,omitempty"`
+	SDKMethods  []string            `json:"x-sdk-methods,omitempty"`
+	Unverified  bool                `json:"x-unverified,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema,omitempty"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is the subset of the OpenAPI schema object the validator supports.
+// Unverified, from x-unverified, marks a schema or property the SDK sends
+// or reads that is not known to exist on the panel.
+type Schema struct {
+	Ref                  string                `json:"$ref,omitempty"`
+	Type                 string                `json:"type,omitempty"`
+	Format               string                `json:"format,omitempty"`
+	Nullable             bool                  `json:"nullable,omitempty"`
+	Properties           map[string]*Schema    `json:"properties,omitempty"`
+	Required             []string              `json:"required,omitempty"`
+	AdditionalProperties *AdditionalProperties `json:"additionalProperties,omitempty"`
+	Items                *Schema               `json:"items,omitempty"`
+	AllOf                []*Schema             `json:"allOf,omitempty"`
+	Unverified           bool                  `json:"x-unverified,omitempty"`
+}
+
+// AdditionalProperties is either a boolean or a schema for the values of
+// the properties an object does not declare.
+type AdditionalProperties struct {
+	Allowed bool
+	Schema  *Schema
+}
+
+func (a *AdditionalProperties) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &a.Allowed); err == nil {
+		return nil
+	}
+	a.Allowed = true
+	return json.Unmarshal(data, &a.Schema)
+}
+
+func (a AdditionalProperties) MarshalJSON() ([]byte, error) {
+	if a.Schema != nil {
+		return json.Marshal(a.Schema)
+	}
+	return json.Marshal(a.Allowed)
+}
+
+// Load parses an OpenAPI document and checks that its references resolve.
+func Load(data []byte) (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("contract: parse document: %w", err)
+	}
+	for path, item := range doc.Paths {
+		for method, op := range item {
+			for _, schema := range op.schemas() {
+				if err := doc.checkRefs(schema, map[string]bool{}); err != nil {
+					return nil, fmt.Errorf("contract: %s %s: %w", strings.ToUpper(method), path, err)
+				}
+			}
+		}
+	}
+	return &doc, nil
+}
+
+var loadDefault = sync.OnceValues(func() (*Document, error) {
+	return Load(openapiJSON)
+})
+
+// Default returns the embedded description of the panel API.
+func Default() (*Document, error) {
+	return loadDefault()
+}
+
+// Spec returns the embedded openapi.json.
+func Spec() []byte {
+	return append([]byte(nil), openapiJSON...)
+}
+
+// OperationFor returns the operation that serves a request, its path
+// template and the values of its path parameters. Literal path segments win
+// over parameters, so /vpos/acquirers is not matched as /vpos/{id}.
+func (d *Document) OperationFor(method, path string) (*Operation, string, map[string]string) {
+	segments := splitPath(path)
+	var (
+		best     *Operation
+		template string
+		params   map[string]string
+		score    = -1
+	)
+	for candidate, item := range d.Paths {
+		op := item[strings.ToLower(method)]
+		if op == nil {
+			continue
+		}
+		parts := splitPath(candidate)
+		if len(parts) != len(segments) {
+			continue
+		}
+		literal, values := 0, map[string]string{}
+		matched := true
+		for i, part := range parts {
+			switch {
+			case strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}"):
+				values[part[1:len(part)-1]] = segments[i]
+			case part == segments[i]:
+				literal++
+			default:
+				matched = false
+			}
+			if !matched {
+				break
+			}
+		}
+		if matched && (literal > score || literal == score && candidate < template) {
+			best, template, params, score = op, candidate, values, literal
+		}
+	}
+	return best, template, params
+}
+
+// Operations returns every operation keyed by "METHOD /path", sorted.
+func (d *Document) Operations() []string {
+	var keys []string
+	for path, item := range d.Paths {
+		for method := range item {
+			keys = append(keys, strings.ToUpper(method)+" "+path)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Unverified returns the operations ("METHOD /path"), component schemas
+// and component properties ("Schema.property") marked x-unverified, sorted.
+func (d *Document) Unverified() []string {
+	var keys []string
+	for path, item := range d.Paths {
+		for method, op := range item {
+			if op.Unverified {
+				keys = append(keys, strings.ToUpper(method)+" "+path)
+			}
+		}
+	}
+	for name, schema := range d.Components.Schemas {
+		if schema.Unverified {
+			keys = append(keys, name)
+		}
+		for property, propertySchema := range schema.Properties {
+			if propertySchema.Unverified {
+				keys = append(keys, name+"."+property)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Resolve follows $ref and single-element allOf wrappers. The returned
+// schema is nullable when any schema on the way is.
+func (d *Document) Resolve(schema *Schema) *Schema {
+	nullable := false
+	for schema != nil {
+		nullable = nullable || schema.Nullable
+		switch {
+		case schema.Ref != "":
+			schema = d.Components.Schemas[schemaName(schema.Ref)]
+		case len(schema.AllOf) == 1 && schema.Type == "" && schema.Properties == nil:
+			schema = schema.AllOf[0]
+		default:
+			if nullable && !schema.Nullable {
+				copied := *schema
+				copied.Nullable = true
+				return &copied
+			}
+			return schema
+		}
+	}
+	return nil
+}
+
+// Name returns the component name a schema refers to, or "".
+func (d *Document) Name(schema *Schema) string {
+	for schema != nil {
+		switch {
+		case schema.Ref != "":
+			return schemaName(schema.Ref)
+		case len(schema.AllOf) == 1:
+			schema = schema.AllOf[0]
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+func (d *Document) checkRefs(schema *Schema, seen map[string]bool) error {
+	if schema == nil {
+		return nil
+	}
+	if schema.Ref != "" {
+		name := schemaName(schema.Ref)
+		target, ok := d.Components.Schemas[name]
+		if !ok {
+			return fmt.Errorf("unresolved reference %q", schema.Ref)
+		}
+		if seen[name] {
+			return nil
+		}
+		seen[name] = true
+		return d.checkRefs(target, seen)
+	}
+	children := append([]*Schema{schema.Items}, schema.AllOf...)
+	for _, property := range schema.Properties {
+		children = append(children, property)
+	}
+	if schema.AdditionalProperties != nil {
+		children = append(children, schema.AdditionalProperties.Schema)
+	}
+	for _, child := range children {
+		if err := d.checkRefs(child, seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RequestSchema returns the JSON request body schema, or nil.
+func (op *Operation) RequestSchema() *Schema {
+	if op.RequestBody == nil {
+		return nil
+	}
+	return op.RequestBody.Content["application/json"].Schema
+}
+
+// ResponseSchema returns the JSON schema of the 200 or 201 response, or nil.
+func (op *Operation) ResponseSchema() *Schema {
+	for _, status := range []string{"200", "201"} {
+		if response, ok := op.Responses[status]; ok {
+			return response.Content["application/json"].Schema
+		}
+	}
+	return nil
+}
+
+func (op *Operation) schemas() []*Schema {
+	schemas := []*Schema{op.RequestSchema()}
+	for _, response := range op.Responses {
+		for _, media := range response.Content {
+			schemas = append(schemas, media.Schema)
+		}
+	}
+	return schemas
+}
+
+func schemaName(ref string) string {
+	return strings.TrimPrefix(ref, "#/components/schemas/")
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
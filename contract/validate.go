@@ -0,0 +1,223 @@
+package contract
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/tapsilat/tapsilat-go"
+)
+
+// Validate checks a JSON body against schema. Properties the schema does
+// not declare are FieldUnknown when additionalProperties is false, required
+// properties that are absent are FieldMissing, and values of the wrong type
+// are FieldInvalid. Field paths use the form of tapsilat.FieldIssue, such as
+// basket_items[0].name.
+func (d *Document) Validate(schema *Schema, body []byte) ([]tapsilat.FieldIssue, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("contract: decode body: %w", err)
+	}
+	v := &validator{doc: d}
+	v.value(schema, value, "")
+	return v.issues, nil
+}
+
+type validator struct {
+	doc    *Document
+	issues []tapsilat.FieldIssue
+}
+
+func (v *validator) value(schema *Schema, value any, path string) {
+	name := v.doc.Name(schema)
+	schema = v.doc.Resolve(schema)
+	if schema == nil {
+		return
+	}
+	if value == nil {
+		if !schema.Nullable && schema.Type != "" {
+			v.issue(path, tapsilat.FieldInvalid, "is null, expected "+schema.Type)
+		}
+		return
+	}
+
+	switch schema.Type {
+	case "":
+		return
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			v.mismatch(path, value, schema)
+			return
+		}
+		v.object(schema, name, object, path)
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			v.mismatch(path, value, schema)
+			return
+		}
+		for i, item := range items {
+			v.value(schema.Items, item, fmt.Sprintf("%s[%d]", path, i))
+		}
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			v.mismatch(path, value, schema)
+			return
+		}
+		if schema.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, text); err != nil {
+				v.issue(path, tapsilat.FieldInvalid, "is not an RFC 3339 date-time")
+			}
+		}
+	case "integer":
+		number, ok := value.(json.Number)
+		if !ok {
+			v.mismatch(path, value, schema)
+			return
+		}
+		if _, err := number.Int64(); err != nil {
+			v.issue(path, tapsilat.FieldInvalid, "is "+number.String()+", expected integer")
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			v.mismatch(path, value, schema)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			v.mismatch(path, value, schema)
+		}
+	}
+}
+
+func (v *validator) object(schema *Schema, name string, object map[string]any, path string) {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if property, ok := schema.Properties[key]; ok {
+			v.value(property, object[key], joinPath(path, key))
+			continue
+		}
+		additional := schema.AdditionalProperties
+		switch {
+		case additional == nil || additional.Allowed && additional.Schema == nil:
+		case additional.Schema != nil:
+			v.value(additional.Schema, object[key], joinPath(path, key))
+		default:
+			v.issue(joinPath(path, key), tapsilat.FieldUnknown, "is not a property of "+describe(name))
+		}
+	}
+	for _, key := range schema.Required {
+		if _, ok := object[key]; !ok {
+			v.issue(joinPath(path, key), tapsilat.FieldMissing, "is missing")
+		}
+	}
+}
+
+func (v *validator) mismatch(path string, value any, schema *Schema) {
+	v.issue(path, tapsilat.FieldInvalid, fmt.Sprintf("is %s, expected %s", jsonType(value), schema.Type))
+}
+
+func (v *validator) issue(path string, reason tapsilat.FieldIssueReason, message string) {
+	v.issues = append(v.issues, tapsilat.FieldIssue{Field: path, Reason: reason, Message: message})
+}
+
+// Example builds a value that sets every property of schema, so decoding it
+// strictly also catches fields a DTO spells differently.
+func (d *Document) Example(schema *Schema) any {
+	return d.example(schema, "", map[string]bool{})
+}
+
+func (d *Document) example(schema *Schema, key string, stack map[string]bool) any {
+	name := d.Name(schema)
+	if name != "" {
+		if stack[name] {
+			return nil
+		}
+		stack[name] = true
+		defer delete(stack, name)
+	}
+	schema = d.Resolve(schema)
+	if schema == nil {
+		return nil
+	}
+
+	switch schema.Type {
+	case "object":
+		object := map[string]any{}
+		for property, propertySchema := range schema.Properties {
+			if value := d.example(propertySchema, property, stack); value != nil {
+				object[property] = value
+			}
+		}
+		if len(schema.Properties) == 0 && schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil {
+			if value := d.example(schema.AdditionalProperties.Schema, "key", stack); value != nil {
+				object["key"] = value
+			}
+		}
+		return object
+	case "array":
+		items := []any{}
+		if item := d.example(schema.Items, key, stack); item != nil {
+			items = append(items, item)
+		}
+		return items
+	case "string":
+		switch schema.Format {
+		case "date-time":
+			return "2024-01-02T15:04:05Z"
+		case "password":
+			return "secret"
+		}
+		if key == "" {
+			return "example"
+		}
+		return "example_" + key
+	case "integer":
+		return 1
+	case "number":
+		return 1.5
+	case "boolean":
+		return true
+	}
+	return "example"
+}
+
+func describe(name string) string {
+	if name == "" {
+		return "the object"
+	}
+	return name
+}
+
+func jsonType(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "an object"
+	case []any:
+		return "an array"
+	case string:
+		return "a string"
+	case json.Number:
+		return "a number"
+	case bool:
+		return "a boolean"
+	}
+	return "null"
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
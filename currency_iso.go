@@ -0,0 +1,352 @@
+package tapsilat
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+)
+
+// CurrencyInfo describes a currency from ISO 4217.
+type CurrencyInfo struct {
+	Code       string `json:"code"`
+	Numeric    string `json:"numeric"`
+	MinorUnits int    `json:"minor_units"`
+	Symbol     string `json:"symbol"`
+	Name       string `json:"name"`
+}
+
+// iso4217 lists the active ISO 4217 currencies.
+var iso4217 = []CurrencyInfo{
+	{"AED", "784", 2, "د.إ", "UAE Dirham"},
+	{"AFN", "971", 2, "؋", "Afghani"},
+	{"ALL", "008", 2, "L", "Lek"},
+	{"AMD", "051", 2, "֏", "Armenian Dram"},
+	{"AOA", "973", 2, "Kz", "Kwanza"},
+	{"ARS", "032", 2, "$", "Argentine Peso"},
+	{"AUD", "036", 2, "A$", "Australian Dollar"},
+	{"AWG", "533", 2, "ƒ", "Aruban Florin"},
+	{"AZN", "944", 2, "₼", "Azerbaijan Manat"},
+	{"BAM", "977", 2, "KM", "Convertible Mark"},
+	{"BBD", "052", 2, "Bds$", "Barbados Dollar"},
+	{"BDT", "050", 2, "৳", "Taka"},
+	{"BGN", "975", 2, "лв", "Bulgarian Lev"},
+	{"BHD", "048", 3, "BD", "Bahraini Dinar"},
+	{"BIF", "108", 0, "FBu", "Burundi Franc"},
+	{"BMD", "060", 2, "$", "Bermudian Dollar"},
+	{"BND", "096", 2, "B$", "Brunei Dollar"},
+	{"BOB", "068", 2, "Bs", "Boliviano"},
+	{"BRL", "986", 2, "R$", "Brazilian Real"},
+	{"BSD", "044", 2, "$", "Bahamian Dollar"},
+	{"BTN", "064", 2, "Nu.", "Ngultrum"},
+	{"BWP", "072", 2, "P", "Pula"},
+	{"BYN", "933", 2, "Br", "Belarusian Ruble"},
+	{"BZD", "084", 2, "BZ$", "Belize Dollar"},
+	{"CAD", "124", 2, "CA$", "Canadian Dollar"},
+	{"CDF", "976", 2, "FC", "Congolese Franc"},
+	{"CHF", "756", 2, "CHF", "Swiss Franc"},
+	{"CLP", "152", 0, "$", "Chilean Peso"},
+	{"CNY", "156", 2, "¥", "Yuan Renminbi"},
+	{"COP", "170", 2, "$", "Colombian Peso"},
+	{"CRC", "188", 2, "₡", "Costa Rican Colon"},
+	{"CUP", "192", 2, "$", "Cuban Peso"},
+	{"CVE", "132", 2, "Esc", "Cabo Verde Escudo"},
+	{"CZK", "203", 2, "Kč", "Czech Koruna"},
+	{"DJF", "262", 0, "Fdj", "Djibouti Franc"},
+	{"DKK", "208", 2, "kr", "Danish Krone"},
+	{"DOP", "214", 2, "RD$", "Dominican Peso"},
+	{"DZD", "012", 2, "DA", "Algerian Dinar"},
+	{"EGP", "818", 2, "E£", "Egyptian Pound"},
+	{"ERN", "232", 2, "Nfk", "Nakfa"},
+	{"ETB", "230", 2, "Br", "Ethiopian Birr"},
+	{"EUR", "978", 2, "€", "Euro"},
+	{"FJD", "242", 2, "FJ$", "Fiji Dollar"},
+	{"FKP", "238", 2, "£", "Falkland Islands Pound"},
+	{"GBP", "826", 2, "£", "Pound Sterling"},
+	{"GEL", "981", 2, "₾", "Lari"},
+	{"GHS", "936", 2, "₵", "Ghana Cedi"},
+	{"GIP", "292", 2, "£", "Gibraltar Pound"},
+	{"GMD", "270", 2, "D", "Dalasi"},
+	{"GNF", "324", 0, "FG", "Guinean Franc"},
+	{"GTQ", "320", 2, "Q", "Quetzal"},
+	{"GYD", "328", 2, "G$", "Guyana Dollar"},
+	{"HKD", "344", 2, "HK$", "Hong Kong Dollar"},
+	{"HNL", "340", 2, "L", "Lempira"},
+	{"HTG", "332", 2, "G", "Gourde"},
+	{"HUF", "348", 2, "Ft", "Forint"},
+	{"IDR", "360", 2, "Rp", "Rupiah"},
+	{"ILS", "376", 2, "₪", "New Israeli Sheqel"},
+	{"INR", "356", 2, "₹", "Indian Rupee"},
+	{"IQD", "368", 3, "ع.د", "Iraqi Dinar"},
+	{"IRR", "364", 2, "﷼", "Iranian Rial"},
+	{"ISK", "352", 0, "kr", "Iceland Krona"},
+	{"JMD", "388", 2, "J$", "Jamaican Dollar"},
+	{"JOD", "400", 3, "JD", "Jordanian Dinar"},
+	{"JPY", "392", 0, "¥", "Yen"},
+	{"KES", "404", 2, "KSh", "Kenyan Shilling"},
+	{"KGS", "417", 2, "сом", "Som"},
+	{"KHR", "116", 2, "៛", "Riel"},
+	{"KMF", "174", 0, "CF", "Comorian Franc"},
+	{"KPW", "408", 2, "₩", "North Korean Won"},
+	{"KRW", "410", 0, "₩", "Won"},
+	{"KWD", "414", 3, "KD", "Kuwaiti Dinar"},
+	{"KYD", "136", 2, "CI$", "Cayman Islands Dollar"},
+	{"KZT", "398", 2, "₸", "Tenge"},
+	{"LAK", "418", 2, "₭", "Lao Kip"},
+	{"LBP", "422", 2, "L£", "Lebanese Pound"},
+	{"LKR", "144", 2, "Rs", "Sri Lanka Rupee"},
+	{"LRD", "430", 2, "L$", "Liberian Dollar"},
+	{"LSL", "426", 2, "L", "Loti"},
+	{"LYD", "434", 3, "LD", "Libyan Dinar"},
+	{"MAD", "504", 2, "DH", "Moroccan Dirham"},
+	{"MDL", "498", 2, "L", "Moldovan Leu"},
+	{"MGA", "969", 2, "Ar", "Malagasy Ariary"},
+	{"MKD", "807", 2, "ден", "Denar"},
+	{"MMK", "104", 2, "K", "Kyat"},
+	{"MNT", "496", 2, "₮", "Tugrik"},
+	{"MOP", "446", 2, "MOP$", "Pataca"},
+	{"MRU", "929", 2, "UM", "Ouguiya"},
+	{"MUR", "480", 2, "Rs", "Mauritius Rupee"},
+	{"MVR", "462", 2, "Rf", "Rufiyaa"},
+	{"MWK", "454", 2, "MK", "Malawi Kwacha"},
+	{"MXN", "484", 2, "MX$", "Mexican Peso"},
+	{"MYR", "458", 2, "RM", "Malaysian Ringgit"},
+	{"MZN", "943", 2, "MT", "Mozambique Metical"},
+	{"NAD", "516", 2, "N$", "Namibia Dollar"},
+	{"NGN", "566", 2, "₦", "Naira"},
+	{"NIO", "558", 2, "C$", "Cordoba Oro"},
+	{"NOK", "578", 2, "kr", "Norwegian Krone"},
+	{"NPR", "524", 2, "Rs", "Nepalese Rupee"},
+	{"NZD", "554", 2, "NZ$", "New Zealand Dollar"},
+	{"OMR", "512", 3, "ر.ع.", "Rial Omani"},
+	{"PAB", "590", 2, "B/.", "Balboa"},
+	{"PEN", "604", 2, "S/", "Sol"},
+	{"PGK", "598", 2, "K", "Kina"},
+	{"PHP", "608", 2, "₱", "Philippine Peso"},
+	{"PKR", "586", 2, "Rs", "Pakistan Rupee"},
+	{"PLN", "985", 2, "zł", "Zloty"},
+	{"PYG", "600", 0, "₲", "Guarani"},
+	{"QAR", "634", 2, "QR", "Qatari Rial"},
+	{"RON", "946", 2, "lei", "Romanian Leu"},
+	{"RSD", "941", 2, "дин", "Serbian Dinar"},
+	{"RUB", "643", 2, "₽", "Russian Ruble"},
+	{"RWF", "646", 0, "FRw", "Rwanda Franc"},
+	{"SAR", "682", 2, "SR", "Saudi Riyal"},
+	{"SBD", "090", 2, "SI$", "Solomon Islands Dollar"},
+	{"SCR", "690", 2, "SR", "Seychelles Rupee"},
+	{"SDG", "938", 2, "£SD", "Sudanese Pound"},
+	{"SEK", "752", 2, "kr", "Swedish Krona"},
+	{"SGD", "702", 2, "S$", "Singapore Dollar"},
+	{"SHP", "654", 2, "£", "Saint Helena Pound"},
+	{"SLE", "925", 2, "Le", "Leone"},
+	{"SOS", "706", 2, "Sh", "Somali Shilling"},
+	{"SRD", "968", 2, "$", "Surinam Dollar"},
+	{"SSP", "728", 2, "£", "South Sudanese Pound"},
+	{"STN", "930", 2, "Db", "Dobra"},
+	{"SVC", "222", 2, "₡", "El Salvador Colon"},
+	{"SYP", "760", 2, "£S", "Syrian Pound"},
+	{"SZL", "748", 2, "E", "Lilangeni"},
+	{"THB", "764", 2, "฿", "Baht"},
+	{"TJS", "972", 2, "SM", "Somoni"},
+	{"TMT", "934", 2, "m", "Turkmenistan New Manat"},
+	{"TND", "788", 3, "DT", "Tunisian Dinar"},
+	{"TOP", "776", 2, "T$", "Pa'anga"},
+	{"TRY", "949", 2, "₺", "Turkish Lira"},
+	{"TTD", "780", 2, "TT$", "Trinidad and Tobago Dollar"},
+	{"TWD", "901", 2, "NT$", "New Taiwan Dollar"},
+	{"TZS", "834", 2, "TSh", "Tanzanian Shilling"},
+	{"UAH", "980", 2, "₴", "Hryvnia"},
+	{"UGX", "800", 0, "USh", "Uganda Shilling"},
+	{"USD", "840", 2, "$", "US Dollar"},
+	{"UYU", "858", 2, "$U", "Peso Uruguayo"},
+	{"UZS", "860", 2, "сўм", "Uzbekistan Sum"},
+	{"VES", "928", 2, "Bs.", "Bolívar Soberano"},
+	{"VND", "704", 0, "₫", "Dong"},
+	{"VUV", "548", 0, "VT", "Vatu"},
+	{"WST", "882", 2, "WS$", "Tala"},
+	{"XAF", "950", 0, "FCFA", "CFA Franc BEAC"},
+	{"XCD", "951", 2, "EC$", "East Caribbean Dollar"},
+	{"XCG", "532", 2, "Cg", "Caribbean Guilder"},
+	{"XOF", "952", 0, "CFA", "CFA Franc BCEAO"},
+	{"XPF", "953", 0, "₣", "CFP Franc"},
+	{"YER", "886", 2, "﷼", "Yemeni Rial"},
+	{"ZAR", "710", 2, "R", "Rand"},
+	{"ZMW", "967", 2, "ZK", "Zambian Kwacha"},
+	{"ZWG", "924", 2, "ZiG", "Zimbabwe Gold"},
+}
+
+// CurrencyRegistry looks up currencies by alphabetic or numeric code.
+type CurrencyRegistry struct {
+	mu        sync.RWMutex
+	byCode    map[string]CurrencyInfo
+	byNumeric map[string]string
+}
+
+// DefaultCurrencyRegistry holds the built-in ISO 4217 table. It is used by
+// ValidateCurrencyCode and the amount helpers; register extra codes on it to
+// accept currencies outside ISO 4217.
+var DefaultCurrencyRegistry = NewCurrencyRegistry()
+
+// NewCurrencyRegistry creates a registry populated with ISO 4217 currencies.
+func NewCurrencyRegistry() *CurrencyRegistry {
+	registry := &CurrencyRegistry{
+		byCode:    make(map[string]CurrencyInfo, len(iso4217)),
+		byNumeric: make(map[string]string, len(iso4217)),
+	}
+	for _, info := range iso4217 {
+		registry.register(info)
+	}
+	return registry
+}
+
+// Lookup returns the currency for an alphabetic ("TRY") or numeric ("949") code.
+func (r *CurrencyRegistry) Lookup(code string) (CurrencyInfo, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if info, ok := r.byCode[code]; ok {
+		return info, true
+	}
+	if alpha, ok := r.byNumeric[code]; ok {
+		return r.byCode[alpha], true
+	}
+	return CurrencyInfo{}, false
+}
+
+// Register adds or replaces a currency.
+func (r *CurrencyRegistry) Register(info CurrencyInfo) {
+	info.Code = strings.ToUpper(strings.TrimSpace(info.Code))
+	if info.Symbol == "" {
+		info.Symbol = info.Code
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.register(info)
+}
+
+func (r *CurrencyRegistry) register(info CurrencyInfo) {
+	r.byCode[info.Code] = info
+	if info.Numeric != "" {
+		r.byNumeric[info.Numeric] = info.Code
+	}
+}
+
+// MergePresets adds organization currency presets. Presets for ISO currencies
+// update the name and, when set, the minor units; other presets are added.
+func (r *CurrencyRegistry) MergePresets(presets []OrganizationCurrencyPreset) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, preset := range presets {
+		code := strings.ToUpper(strings.TrimSpace(preset.CurrencyUnit))
+		numeric := ""
+		presetCode := strings.ToUpper(strings.TrimSpace(preset.CurrencyCode))
+		if isDigits(presetCode) {
+			numeric = presetCode
+		} else if code == "" {
+			code = presetCode
+		}
+		if code == "" {
+			continue
+		}
+
+		info, ok := r.byCode[code]
+		if !ok {
+			info = CurrencyInfo{Code: code, Symbol: code, MinorUnits: 2}
+		}
+		if numeric != "" {
+			info.Numeric = numeric
+		}
+		if preset.Name != "" {
+			info.Name = preset.Name
+		}
+		if preset.MinorUnit > 0 || !ok {
+			info.MinorUnits = preset.MinorUnit
+		}
+		r.register(info)
+	}
+}
+
+// Clone returns an independent copy of the registry.
+func (r *CurrencyRegistry) Clone() *CurrencyRegistry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return &CurrencyRegistry{
+		byCode:    maps.Clone(r.byCode),
+		byNumeric: maps.Clone(r.byNumeric),
+	}
+}
+
+// LookupCurrency looks up a currency in DefaultCurrencyRegistry.
+func LookupCurrency(code string) (CurrencyInfo, bool) {
+	return DefaultCurrencyRegistry.Lookup(code)
+}
+
+// CurrencyMinorUnits returns the number of decimals used by a currency,
+// defaulting to two for unknown codes.
+func CurrencyMinorUnits(code string) int {
+	if info, ok := LookupCurrency(code); ok {
+		return info.MinorUnits
+	}
+	return 2
+}
+
+// CurrencyRegistry returns DefaultCurrencyRegistry merged with the
+// organization currency presets.
+func (t *API) CurrencyRegistry(ctx context.Context) (*CurrencyRegistry, error) {
+	presets, err := t.references().currencyPresets.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	registry := DefaultCurrencyRegistry.Clone()
+	registry.MergePresets(presets.Items)
+	return registry, nil
+}
+
+// Validate validates an alphabetic or numeric currency code against the
+// registry. Returns the alphabetic code if valid.
+func (r *CurrencyRegistry) Validate(code string) (string, error) {
+	info, ok := r.Lookup(code)
+	if !ok {
+		return "", &ValidationError{
+			StatusCode: 400,
+			Code:       0,
+			Message:    fmt.Sprintf("Invalid currency code: %s", code),
+		}
+	}
+	return info.Code, nil
+}
+
+// ValidateCurrencyCode validates an alphabetic or numeric ISO 4217 code
+// against DefaultCurrencyRegistry. Returns the alphabetic code if valid.
+func ValidateCurrencyCode(code string) (string, error) {
+	return DefaultCurrencyRegistry.Validate(code)
+}
+
+// validateCurrency is ValidateCurrencyCode that also accepts the
+// organization currency presets. The presets are only loaded for codes
+// missing from DefaultCurrencyRegistry.
+func (t *API) validateCurrency(ctx context.Context, code string) (string, error) {
+	if info, ok := LookupCurrency(code); ok {
+		return info.Code, nil
+	}
+	registry, err := t.CurrencyRegistry(ctx)
+	if err != nil {
+		return "", err
+	}
+	return registry.Validate(code)
+}
+
+func isDigits(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -3,9 +3,9 @@ package tapsilat
 import (
 	"context"
 	"fmt"
-	"maps"
 	"regexp"
 	"strings"
+	"time"
 )
 
 var uuidRefRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[1-5][0-9a-fA-F]{3}-[89aAbB][0-9a-fA-F]{3}-[0-9a-fA-F]{12}$`)
@@ -28,12 +28,22 @@ func (t *API) normalizeCurrencyID(ctx context.Context, ref string) (string, erro
 		return trimmedRef, nil
 	}
 
+	unit := strings.ToUpper(trimmedRef)
 	currencyIDsByUnit, err := t.getOrganizationCurrencyIDsByUnit(ctx)
 	if err != nil {
 		return "", err
 	}
 
-	if currencyID, ok := currencyIDsByUnit[strings.ToUpper(trimmedRef)]; ok {
+	if currencyID, ok := currencyIDsByUnit[unit]; ok {
+		return currencyID, nil
+	}
+
+	// The unit may have been added from the panel after the list was cached.
+	currencyIDsByUnit, err = t.refreshCurrenciesOnMiss(ctx, unit)
+	if err != nil {
+		return "", err
+	}
+	if currencyID, ok := currencyIDsByUnit[unit]; ok {
 		return currencyID, nil
 	}
 
@@ -80,19 +90,70 @@ func (t *API) normalizeCurrencyIDs(ctx context.Context, refs []string) ([]string
 }
 
 func (t *API) getOrganizationCurrencyIDsByUnit(ctx context.Context) (map[string]string, error) {
-	t.currencyRefsMu.RLock()
-	if t.currencyCacheReady {
-		cached := cloneStringMap(t.currencyIDsByUnit)
-		t.currencyRefsMu.RUnlock()
-		return cached, nil
+	response, err := t.references().currencies.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return currencyIDsByUnit(response), nil
+}
+
+// maxCurrencyMisses bounds the units remembered as missing from the
+// currency list.
+const maxCurrencyMisses = 256
+
+// refreshCurrenciesOnMiss reloads the currency list once for a unit that is
+// not cached. A unit still missing after the reload is remembered for the
+// currency cache TTL, or DefaultReferenceCacheTTL when the list never
+// expires, and does not reload the list again meanwhile, whatever other
+// units are looked up or reloaded in between. At most maxCurrencyMisses
+// units are remembered; once that many are, unknown units no longer reload
+// the list until a remembered one expires, so lookups cannot drive more
+// than maxCurrencyMisses reloads per TTL.
+func (t *API) refreshCurrenciesOnMiss(ctx context.Context, unit string) (map[string]string, error) {
+	cache := t.references().currencies
+	ttl := cache.TTL
+	if ttl <= 0 {
+		ttl = DefaultReferenceCacheTTL
+	}
+	now := cache.now()
+
+	t.currencyMissMu.Lock()
+	for missed, at := range t.currencyMisses {
+		if now.Sub(at) > ttl {
+			delete(t.currencyMisses, missed)
+		}
+	}
+	_, missed := t.currencyMisses[unit]
+	full := len(t.currencyMisses) >= maxCurrencyMisses
+	t.currencyMissMu.Unlock()
+	if missed || full {
+		return t.getOrganizationCurrencyIDsByUnit(ctx)
 	}
-	t.currencyRefsMu.RUnlock()
 
-	response, err := t.GetOrganizationCurrencies(ctx)
+	response, err := cache.Refresh(ctx)
 	if err != nil {
 		return nil, err
 	}
+	resolved := currencyIDsByUnit(response)
+	if _, ok := resolved[unit]; !ok {
+		t.currencyMissMu.Lock()
+		if t.currencyMisses == nil {
+			t.currencyMisses = make(map[string]time.Time)
+		}
+		if len(t.currencyMisses) < maxCurrencyMisses {
+			t.currencyMisses[unit] = now
+		}
+		t.currencyMissMu.Unlock()
+	}
+	return resolved, nil
+}
+
+// CurrencyCacheStats returns the stats of the organization currency cache.
+func (t *API) CurrencyCacheStats() RefCacheStats {
+	return t.references().currencies.Stats()
+}
 
+func currencyIDsByUnit(response OrganizationCurrenciesResponse) map[string]string {
 	resolved := make(map[string]string, len(response.Currencies))
 	for _, currency := range response.Currencies {
 		unit := strings.ToUpper(strings.TrimSpace(currency.CurrencyUnit))
@@ -101,28 +162,18 @@ func (t *API) getOrganizationCurrencyIDsByUnit(ctx context.Context) (map[string]
 		}
 		resolved[unit] = currency.ID
 	}
-
-	t.currencyRefsMu.Lock()
-	t.currencyIDsByUnit = cloneStringMap(resolved)
-	t.currencyCacheReady = true
-	t.currencyRefsMu.Unlock()
-
-	return resolved, nil
+	return resolved
 }
 
-func cloneStringMap(values map[string]string) map[string]string {
-	if len(values) == 0 {
-		return map[string]string{}
-	}
-
-	cloned := make(map[string]string, len(values))
-	maps.Copy(cloned, values)
-	return cloned
+// invalidateCurrencyCache drops the currency list and the remembered
+// misses, since the organization currencies were just changed.
+func (t *API) invalidateCurrencyCache() {
+	t.references().currencies.Invalidate()
+	t.forgetCurrencyMisses()
 }
 
-func (t *API) invalidateCurrencyCache() {
-	t.currencyRefsMu.Lock()
-	t.currencyIDsByUnit = nil
-	t.currencyCacheReady = false
-	t.currencyRefsMu.Unlock()
+func (t *API) forgetCurrencyMisses() {
+	t.currencyMissMu.Lock()
+	t.currencyMisses = nil
+	t.currencyMissMu.Unlock()
 }
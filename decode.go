@@ -0,0 +1,362 @@
+package tapsilat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Unmarshal decodes a response body the way the client does. It differs
+// from json.Unmarshal in three ways:
+//
+//   - numbers sent as strings decode into numeric fields, and numbers decode
+//     into string fields as their literal text
+//   - fields the DTO does not model are kept in its Extra map, when it has
+//     one
+//   - numbers in interface values decode as json.Number
+func Unmarshal(data []byte, v any) error {
+	return decodeJSON(data, v, false)
+}
+
+// UnmarshalStrict decodes like Unmarshal but reports contract drift as a
+// *FieldValidationError: unknown fields as FieldUnknown, fields without
+// omitempty that are absent as FieldMissing, and numbers or strings of the
+// wrong JSON type as FieldInvalid. Use it in tests.
+func UnmarshalStrict(data []byte, v any) error {
+	return decodeJSON(data, v, true)
+}
+
+func decodeJSON(data []byte, v any, strict bool) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return &json.InvalidUnmarshalError{Type: reflect.TypeOf(v)}
+	}
+	if !json.Valid(data) {
+		// Let encoding/json report the syntax error, or decode the first of
+		// several values, as it always has.
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		return decoder.Decode(v)
+	}
+
+	d := &jsonDecoder{strict: strict}
+	d.value(bytes.TrimSpace(data), rv.Elem(), "")
+	if d.err != nil {
+		return d.err
+	}
+	if len(d.issues) > 0 {
+		return &FieldValidationError{Issues: d.issues}
+	}
+	return nil
+}
+
+type jsonDecoder struct {
+	strict bool
+	issues []FieldIssue
+	err    error
+}
+
+var (
+	unmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+	numberType      = reflect.TypeFor[json.Number]()
+)
+
+func (d *jsonDecoder) value(raw []byte, v reflect.Value, path string) {
+	if raw[0] == 'n' {
+		switch v.Kind() {
+		case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+			v.SetZero()
+		}
+		return
+	}
+	if v.Type() == numberType || v.Kind() != reflect.Pointer && reflect.PointerTo(v.Type()).Implements(unmarshalerType) {
+		d.standard(raw, v, path)
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		d.value(raw, v.Elem(), path)
+	case reflect.Struct:
+		d.object(raw, v, path)
+	case reflect.Slice:
+		if raw[0] != '[' || v.Type().Elem().Kind() == reflect.Uint8 {
+			d.standard(raw, v, path)
+			return
+		}
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			d.fail(err)
+			return
+		}
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			d.value(item, slice.Index(i), fmt.Sprintf("%s[%d]", path, i))
+		}
+		v.Set(slice)
+	case reflect.String:
+		if isJSONNumber(raw) {
+			d.coerced(path, "a number", v.Type())
+			v.SetString(string(raw))
+			return
+		}
+		d.standard(raw, v, path)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		switch {
+		case raw[0] == '"':
+			var text string
+			if err := json.Unmarshal(raw, &text); err != nil {
+				d.fail(err)
+				return
+			}
+			d.coerced(path, "a string", v.Type())
+			if text = strings.TrimSpace(text); text != "" {
+				d.number(text, v, path)
+			}
+		case isJSONNumber(raw):
+			d.number(string(raw), v, path)
+		default:
+			d.standard(raw, v, path)
+		}
+	default:
+		d.standard(raw, v, path)
+	}
+}
+
+// object decodes a JSON object field by field, so one mis-typed field is
+// coerced or reported on its own, and collects the keys v does not model.
+func (d *jsonDecoder) object(raw []byte, v reflect.Value, path string) {
+	if raw[0] != '{' {
+		d.standard(raw, v, path)
+		return
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &object); err != nil {
+		d.fail(err)
+		return
+	}
+
+	fields := cachedStructFields(v.Type())
+	seen := make(map[string]bool, len(object))
+	var extra map[string]json.RawMessage
+	for _, key := range slices.Sorted(maps.Keys(object)) {
+		field, ok := fields.lookup(key)
+		if !ok {
+			if fields.extra != nil {
+				if extra == nil {
+					extra = map[string]json.RawMessage{}
+				}
+				extra[key] = object[key]
+			}
+			if d.strict {
+				d.issue(joinFieldPath(path, key), FieldUnknown, "is not a field of "+v.Type().String())
+			}
+			continue
+		}
+		seen[field.name] = true
+		d.value(object[key], fieldByIndex(v, field.index), joinFieldPath(path, field.name))
+	}
+	if fields.extra != nil {
+		fieldByIndex(v, fields.extra).Set(reflect.ValueOf(extra))
+	}
+
+	if d.strict {
+		for _, field := range fields.list {
+			if field.required && !seen[field.name] {
+				d.issue(joinFieldPath(path, field.name), FieldMissing, "is missing")
+			}
+		}
+	}
+}
+
+func (d *jsonDecoder) number(text string, v reflect.Value, path string) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			f, ferr := strconv.ParseFloat(text, 64)
+			if ferr != nil || f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+				break
+			}
+			n = int64(f)
+		}
+		if v.OverflowInt(n) {
+			break
+		}
+		v.SetInt(n)
+		return
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(text, 10, 64)
+		if err != nil {
+			f, ferr := strconv.ParseFloat(text, 64)
+			if ferr != nil || f != math.Trunc(f) || f < 0 || f >= math.MaxUint64 {
+				break
+			}
+			n = uint64(f)
+		}
+		if v.OverflowUint(n) {
+			break
+		}
+		v.SetUint(n)
+		return
+	default:
+		f, err := strconv.ParseFloat(text, v.Type().Bits())
+		if err != nil {
+			break
+		}
+		v.SetFloat(f)
+		return
+	}
+	d.fail(&json.UnmarshalTypeError{Value: "number " + text, Type: v.Type(), Field: path})
+}
+
+// standard decodes raw with encoding/json, for values the decoder does not
+// treat specially and to report type errors in its usual form.
+func (d *jsonDecoder) standard(raw []byte, v reflect.Value, path string) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	err := decoder.Decode(v.Addr().Interface())
+	if typeErr, ok := err.(*json.UnmarshalTypeError); ok {
+		typeErr.Field = joinFieldPath(path, typeErr.Field)
+	}
+	if err != nil {
+		d.fail(err)
+	}
+}
+
+func (d *jsonDecoder) coerced(path, got string, want reflect.Type) {
+	if d.strict {
+		d.issue(path, FieldInvalid, fmt.Sprintf("is %s, expected %s", got, want))
+	}
+}
+
+func (d *jsonDecoder) issue(path string, reason FieldIssueReason, message string) {
+	d.issues = append(d.issues, FieldIssue{Field: path, Reason: reason, Message: message})
+}
+
+func (d *jsonDecoder) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+func isJSONNumber(raw []byte) bool {
+	return raw[0] == '-' || (raw[0] >= '0' && raw[0] <= '9')
+}
+
+func joinFieldPath(path, name string) string {
+	if path == "" || name == "" {
+		return path + name
+	}
+	return path + "." + name
+}
+
+// fieldByIndex is reflect.Value.FieldByIndex that allocates nil embedded
+// struct pointers on the way.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+type jsonField struct {
+	name     string
+	index    []int
+	required bool
+}
+
+type jsonStructFields struct {
+	list   []jsonField
+	byName map[string]int
+	// extra is the index of the Extra map that receives unknown keys, or nil.
+	extra []int
+}
+
+func (f *jsonStructFields) lookup(key string) (jsonField, bool) {
+	if i, ok := f.byName[key]; ok {
+		return f.list[i], true
+	}
+	// encoding/json also matches keys case-insensitively.
+	for _, field := range f.list {
+		if strings.EqualFold(field.name, key) {
+			return field, true
+		}
+	}
+	return jsonField{}, false
+}
+
+var structFieldsCache sync.Map // reflect.Type -> *jsonStructFields
+
+var extraType = reflect.TypeFor[map[string]json.RawMessage]()
+
+func cachedStructFields(t reflect.Type) *jsonStructFields {
+	if cached, ok := structFieldsCache.Load(t); ok {
+		return cached.(*jsonStructFields)
+	}
+	fields := &jsonStructFields{byName: map[string]int{}}
+	collectStructFields(fields, t, nil)
+	if field, ok := t.FieldByName("Extra"); ok && field.Type == extraType && field.Tag.Get("json") == "-" {
+		fields.extra = field.Index
+	}
+	cached, _ := structFieldsCache.LoadOrStore(t, fields)
+	return cached.(*jsonStructFields)
+}
+
+// collectStructFields adds the JSON fields of t. Fields of embedded structs
+// without a JSON name are promoted unless an outer field has the same name.
+func collectStructFields(fields *jsonStructFields, t reflect.Type, prefix []int) {
+	var embedded []reflect.StructField
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded = append(embedded, field)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if _, ok := fields.byName[name]; ok {
+			continue
+		}
+		required := !slices.Contains(strings.Split(options, ","), "omitempty") &&
+			!slices.Contains(strings.Split(options, ","), "omitzero")
+		fields.byName[name] = len(fields.list)
+		fields.list = append(fields.list, jsonField{name: name, index: append(slices.Clone(prefix), i), required: required})
+	}
+	for _, field := range embedded {
+		embeddedType := field.Type
+		if embeddedType.Kind() == reflect.Pointer {
+			embeddedType = embeddedType.Elem()
+		}
+		if embeddedType.Kind() == reflect.Struct {
+			collectStructFields(fields, embeddedType, append(slices.Clone(prefix), field.Index...))
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package tapsilat
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -47,9 +48,9 @@ const (
 	OrderTypeRemittance     uint64 = 10
 	OrderTypeCarLoan        uint64 = 11
 
-	SubscriptionStatusSuccess = "success"
-	SubscriptionStatusFailure = "failure"
-	SubscriptionStatusPending = "pending"
+	SubscriptionStatusSuccess SubscriptionPaymentStatus = "success"
+	SubscriptionStatusFailure SubscriptionPaymentStatus = "failure"
+	SubscriptionStatusPending SubscriptionPaymentStatus = "pending"
 )
 
 var OrderStatuesMap = []struct {
@@ -182,6 +183,9 @@ type OrderDetail struct {
 	PaymentOptions      []string               `json:"payment_options" example:"credit_card,bank_transfer,cash"`
 	ExternalReferenceID string                 `json:"external_reference_id" example:"ext-123456"`
 	MCC                 string                 `json:"mcc" example:"5411"`
+
+	// Extra holds the response fields this struct does not model.
+	Extra map[string]json.RawMessage `json:"-"`
 }
 type OrderPaymentTermDTO struct {
 	ID              string             `json:"id" example:"123456789"`
@@ -322,11 +326,17 @@ type OrderResponse struct {
 	ReferenceID string `json:"reference_id"`
 	CheckoutURL string `json:"checkout_url"`
 	Error       string `json:"error"`
+
+	// Extra holds the response fields this struct does not model.
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type OrderStatus struct {
 	Status string `json:"status"`
 	Error  string `json:"error"`
+
+	// Extra holds the response fields this struct does not model.
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type RefundOrder struct {
@@ -345,6 +355,9 @@ type RefundCancelOrderResponse struct {
 	Message   string `json:"message"`
 	IsSuccess bool   `json:"is_success"`
 	Error     string `json:"error"`
+
+	// Extra holds the response fields this struct does not model.
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type PaginatedData struct {
@@ -414,27 +427,55 @@ type SubmerchantCreateRequest struct {
 	ContactSurname        string `json:"contact_surname"`
 }
 
+// SubmerchantUpdateRequest has the same fields as SubmerchantCreateRequest,
+// so one converts to the other, but empty fields are left out of the request
+// and keep their stored value. Use SubmerchantPatch to clear a field.
 type SubmerchantUpdateRequest struct {
-	Locale                string `json:"locale"`
-	ConversationID        string `json:"conversation_id"`
-	Name                  string `json:"name"`
-	Email                 string `json:"email"`
-	GsmNumber             string `json:"gsm_number"`
-	Address               string `json:"address"`
-	Iban                  string `json:"iban"`
-	TaxOffice             string `json:"tax_office"`
-	LegalCompanyTitle     string `json:"legal_company_title"`
-	CurrencyID            string `json:"currency_id"`
-	SubmerchantExternalID string `json:"sub_merchant_external_id"`
-	IdentityNumber        string `json:"identity_number"`
-	SubmerchantType       string `json:"sub_merchant_type"`
-	TaxNumber             string `json:"tax_number"`
-	SubmerchantKey        string `json:"sub_merchant_key"`
-	OrganizationID        string `json:"organization_id"`
-	Status                string `json:"status"`
-	SystemTime            int64  `json:"system_time"`
-	ContactName           string `json:"contact_name"`
-	ContactSurname        string `json:"contact_surname"`
+	Locale                string `json:"locale,omitempty"`
+	ConversationID        string `json:"conversation_id,omitempty"`
+	Name                  string `json:"name,omitempty"`
+	Email                 string `json:"email,omitempty"`
+	GsmNumber             string `json:"gsm_number,omitempty"`
+	Address               string `json:"address,omitempty"`
+	Iban                  string `json:"iban,omitempty"`
+	TaxOffice             string `json:"tax_office,omitempty"`
+	LegalCompanyTitle     string `json:"legal_company_title,omitempty"`
+	CurrencyID            string `json:"currency_id,omitempty"`
+	SubmerchantExternalID string `json:"sub_merchant_external_id,omitempty"`
+	IdentityNumber        string `json:"identity_number,omitempty"`
+	SubmerchantType       string `json:"sub_merchant_type,omitempty"`
+	TaxNumber             string `json:"tax_number,omitempty"`
+	SubmerchantKey        string `json:"sub_merchant_key,omitempty"`
+	OrganizationID        string `json:"organization_id,omitempty"`
+	Status                string `json:"status,omitempty"`
+	SystemTime            int64  `json:"system_time,omitempty"`
+	ContactName           string `json:"contact_name,omitempty"`
+	ContactSurname        string `json:"contact_surname,omitempty"`
+}
+
+// SubmerchantPatch is a partial submerchant update: nil fields are left
+// unchanged, and non-nil fields are sent even when they hold a zero value.
+type SubmerchantPatch struct {
+	Locale                *string `json:"locale,omitempty"`
+	ConversationID        *string `json:"conversation_id,omitempty"`
+	Name                  *string `json:"name,omitempty"`
+	Email                 *string `json:"email,omitempty"`
+	GsmNumber             *string `json:"gsm_number,omitempty"`
+	Address               *string `json:"address,omitempty"`
+	Iban                  *string `json:"iban,omitempty"`
+	TaxOffice             *string `json:"tax_office,omitempty"`
+	LegalCompanyTitle     *string `json:"legal_company_title,omitempty"`
+	CurrencyID            *string `json:"currency_id,omitempty"`
+	SubmerchantExternalID *string `json:"sub_merchant_external_id,omitempty"`
+	IdentityNumber        *string `json:"identity_number,omitempty"`
+	SubmerchantType       *string `json:"sub_merchant_type,omitempty"`
+	TaxNumber             *string `json:"tax_number,omitempty"`
+	SubmerchantKey        *string `json:"sub_merchant_key,omitempty"`
+	OrganizationID        *string `json:"organization_id,omitempty"`
+	Status                *string `json:"status,omitempty"`
+	SystemTime            *int64  `json:"system_time,omitempty"`
+	ContactName           *string `json:"contact_name,omitempty"`
+	ContactSurname        *string `json:"contact_surname,omitempty"`
 }
 
 type Submerchant struct {
@@ -461,6 +502,9 @@ type Submerchant struct {
 	Acquirer              string `json:"acquirer,omitempty"`
 	ContactName           string `json:"contact_name,omitempty"`
 	ContactSurname        string `json:"contact_surname,omitempty"`
+
+	// Extra holds the response fields this struct does not model.
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type SubmerchantListItem struct {
@@ -505,6 +549,9 @@ type SuborganizationDetail struct {
 	AvailabilityStatus int64  `json:"availability_status,omitempty"`
 	CreatedAt          string `json:"created_at,omitempty"`
 	UpdatedAt          string `json:"updated_at,omitempty"`
+
+	// Extra holds the response fields this struct does not model.
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type SuborganizationListResponse struct {
@@ -531,15 +578,15 @@ type VposCreateRequest struct {
 	EnvMode      string   `json:"env_mode"`
 	Merchant     string   `json:"merchant"`
 	MerchantCode string   `json:"merchant_code"`
-	MerchantKey  string   `json:"merchant_key"`
-	AuthKey      string   `json:"auth_key"`
+	MerchantKey  Secret   `json:"merchant_key"`
+	AuthKey      Secret   `json:"auth_key"`
 	Terminal     string   `json:"terminal"`
 	Company      string   `json:"company"`
 	Username     string   `json:"username"`
-	Password     string   `json:"password"`
-	StoreKey     string   `json:"store_key"`
-	ApiKey       string   `json:"api_key"`
-	ApiSecret    string   `json:"api_secret"`
+	Password     Secret   `json:"password"`
+	StoreKey     Secret   `json:"store_key"`
+	ApiKey       Secret   `json:"api_key"`
+	ApiSecret    Secret   `json:"api_secret"`
 	GUID         string   `json:"guid"`
 	PID          string   `json:"pid"`
 	ClientID     string   `json:"client_id"`
@@ -558,37 +605,75 @@ type VposCreateRequest struct {
 	Currencies   []string `json:"currencies"`
 }
 
+// VposUpdateRequest leaves empty fields out of the request, so they keep
+// their stored value. Use VposPatch to clear a field or to set Priority to 0
+// or a flag to false.
 type VposUpdateRequest struct {
-	Name         string   `json:"name"`
-	BankName     string   `json:"bank_name"`
-	EnvMode      string   `json:"env_mode"`
-	Merchant     string   `json:"merchant"`
-	MerchantCode string   `json:"merchant_code"`
-	MerchantKey  string   `json:"merchant_key"`
-	AuthKey      string   `json:"auth_key"`
-	Terminal     string   `json:"terminal"`
-	Company      string   `json:"company"`
-	Username     string   `json:"username"`
-	Password     string   `json:"password"`
-	StoreKey     string   `json:"store_key"`
-	ApiKey       string   `json:"api_key"`
-	ApiSecret    string   `json:"api_secret"`
-	GUID         string   `json:"guid"`
-	PID          string   `json:"pid"`
-	ClientID     string   `json:"client_id"`
-	ClientCode   string   `json:"client_code"`
-	Type         string   `json:"type"`
-	BlockDate    uint64   `json:"block_date"`
-	Priority     int64    `json:"priority"`
-	Main         bool     `json:"main"`
-	ForceThreeD  bool     `json:"force_three_d"`
-	Marketplace  bool     `json:"marketplace"`
-	Pf           bool     `json:"pf"`
-	PaymentMode  string   `json:"payment_mode"`
-	Prefix       string   `json:"prefix"`
-	AcquirerID   string   `json:"acquirer_id"`
-	CardSchemes  []string `json:"card_schemes"`
-	Currencies   []string `json:"currencies"`
+	Name         string   `json:"name,omitempty"`
+	BankName     string   `json:"bank_name,omitempty"`
+	EnvMode      string   `json:"env_mode,omitempty"`
+	Merchant     string   `json:"merchant,omitempty"`
+	MerchantCode string   `json:"merchant_code,omitempty"`
+	MerchantKey  Secret   `json:"merchant_key,omitempty"`
+	AuthKey      Secret   `json:"auth_key,omitempty"`
+	Terminal     string   `json:"terminal,omitempty"`
+	Company      string   `json:"company,omitempty"`
+	Username     string   `json:"username,omitempty"`
+	Password     Secret   `json:"password,omitempty"`
+	StoreKey     Secret   `json:"store_key,omitempty"`
+	ApiKey       Secret   `json:"api_key,omitempty"`
+	ApiSecret    Secret   `json:"api_secret,omitempty"`
+	GUID         string   `json:"guid,omitempty"`
+	PID          string   `json:"pid,omitempty"`
+	ClientID     string   `json:"client_id,omitempty"`
+	ClientCode   string   `json:"client_code,omitempty"`
+	Type         string   `json:"type,omitempty"`
+	BlockDate    uint64   `json:"block_date,omitempty"`
+	Priority     int64    `json:"priority,omitempty"`
+	Main         bool     `json:"main,omitempty"`
+	ForceThreeD  bool     `json:"force_three_d,omitempty"`
+	Marketplace  bool     `json:"marketplace,omitempty"`
+	Pf           bool     `json:"pf,omitempty"`
+	PaymentMode  string   `json:"payment_mode,omitempty"`
+	Prefix       string   `json:"prefix,omitempty"`
+	AcquirerID   string   `json:"acquirer_id,omitempty"`
+	CardSchemes  []string `json:"card_schemes,omitempty"`
+	Currencies   []string `json:"currencies,omitempty"`
+}
+
+// VposPatch is a partial VPOS update: nil fields are left unchanged, and
+// non-nil fields are sent even when they hold a zero value.
+type VposPatch struct {
+	Name         *string   `json:"name,omitempty"`
+	BankName     *string   `json:"bank_name,omitempty"`
+	EnvMode      *string   `json:"env_mode,omitempty"`
+	Merchant     *string   `json:"merchant,omitempty"`
+	MerchantCode *string   `json:"merchant_code,omitempty"`
+	MerchantKey  *Secret   `json:"merchant_key,omitempty"`
+	AuthKey      *Secret   `json:"auth_key,omitempty"`
+	Terminal     *string   `json:"terminal,omitempty"`
+	Company      *string   `json:"company,omitempty"`
+	Username     *string   `json:"username,omitempty"`
+	Password     *Secret   `json:"password,omitempty"`
+	StoreKey     *Secret   `json:"store_key,omitempty"`
+	ApiKey       *Secret   `json:"api_key,omitempty"`
+	ApiSecret    *Secret   `json:"api_secret,omitempty"`
+	GUID         *string   `json:"guid,omitempty"`
+	PID          *string   `json:"pid,omitempty"`
+	ClientID     *string   `json:"client_id,omitempty"`
+	ClientCode   *string   `json:"client_code,omitempty"`
+	Type         *string   `json:"type,omitempty"`
+	BlockDate    *uint64   `json:"block_date,omitempty"`
+	Priority     *int64    `json:"priority,omitempty"`
+	Main         *bool     `json:"main,omitempty"`
+	ForceThreeD  *bool     `json:"force_three_d,omitempty"`
+	Marketplace  *bool     `json:"marketplace,omitempty"`
+	Pf           *bool     `json:"pf,omitempty"`
+	PaymentMode  *string   `json:"payment_mode,omitempty"`
+	Prefix       *string   `json:"prefix,omitempty"`
+	AcquirerID   *string   `json:"acquirer_id,omitempty"`
+	CardSchemes  *[]string `json:"card_schemes,omitempty"`
+	Currencies   *[]string `json:"currencies,omitempty"`
 }
 
 type Vpos struct {
@@ -600,15 +685,15 @@ type Vpos struct {
 	PaymentMode  string   `json:"payment_mode,omitempty"`
 	Merchant     string   `json:"merchant,omitempty"`
 	MerchantCode string   `json:"merchant_code,omitempty"`
-	MerchantKey  string   `json:"merchant_key,omitempty"`
-	AuthKey      string   `json:"auth_key,omitempty"`
+	MerchantKey  Secret   `json:"merchant_key,omitempty"`
+	AuthKey      Secret   `json:"auth_key,omitempty"`
 	Terminal     string   `json:"terminal,omitempty"`
 	Company      string   `json:"company,omitempty"`
 	Username     string   `json:"username,omitempty"`
-	Password     string   `json:"password,omitempty"`
-	StoreKey     string   `json:"store_key,omitempty"`
-	ApiKey       string   `json:"api_key,omitempty"`
-	ApiSecret    string   `json:"api_secret,omitempty"`
+	Password     Secret   `json:"password,omitempty"`
+	StoreKey     Secret   `json:"store_key,omitempty"`
+	ApiKey       Secret   `json:"api_key,omitempty"`
+	ApiSecret    Secret   `json:"api_secret,omitempty"`
 	GUID         string   `json:"guid,omitempty"`
 	PID          string   `json:"pid,omitempty"`
 	ClientID     string   `json:"client_id,omitempty"`
@@ -624,6 +709,9 @@ type Vpos struct {
 	AcquirerID   string   `json:"acquirer_id,omitempty"`
 	CardSchemes  []string `json:"card_schemes,omitempty"`
 	Currencies   []string `json:"currencies,omitempty"`
+
+	// Extra holds the response fields this struct does not model.
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type VposListItem struct {
@@ -690,15 +778,31 @@ type VposSubmerchantCreateRequest struct {
 	SubmerchantNIN      string `json:"submerchant_nin"`
 }
 
+// VposSubmerchantUpdateRequest leaves empty fields out of the request, so
+// they keep their stored value. Use VposSubmerchantPatch to clear a field.
 type VposSubmerchantUpdateRequest struct {
-	ExternalReferenceID string `json:"external_reference_id"`
-	SubmerchantID       string `json:"submerchant_id"`
-	TerminalNo          string `json:"terminal_no"`
-	MCC                 string `json:"mcc"`
-	TaxID               string `json:"tax_id"`
-	NationalID          string `json:"national_id"`
-	Title               string `json:"title"`
-	SwitchID            string `json:"switch_id"`
+	ExternalReferenceID string `json:"external_reference_id,omitempty"`
+	SubmerchantID       string `json:"submerchant_id,omitempty"`
+	TerminalNo          string `json:"terminal_no,omitempty"`
+	MCC                 string `json:"mcc,omitempty"`
+	TaxID               string `json:"tax_id,omitempty"`
+	NationalID          string `json:"national_id,omitempty"`
+	Title               string `json:"title,omitempty"`
+	SwitchID            string `json:"switch_id,omitempty"`
+}
+
+// VposSubmerchantPatch is a partial VPOS submerchant update: nil fields are
+// left unchanged, and non-nil fields are sent even when they hold a zero
+// value.
+type VposSubmerchantPatch struct {
+	ExternalReferenceID *string `json:"external_reference_id,omitempty"`
+	SubmerchantID       *string `json:"submerchant_id,omitempty"`
+	TerminalNo          *string `json:"terminal_no,omitempty"`
+	MCC                 *string `json:"mcc,omitempty"`
+	TaxID               *string `json:"tax_id,omitempty"`
+	NationalID          *string `json:"national_id,omitempty"`
+	Title               *string `json:"title,omitempty"`
+	SwitchID            *string `json:"switch_id,omitempty"`
 }
 
 type VposSubmerchant struct {
@@ -712,6 +816,9 @@ type VposSubmerchant struct {
 	NationalID          string `json:"national_id,omitempty"`
 	Title               string `json:"title,omitempty"`
 	SwitchID            string `json:"switch_id,omitempty"`
+
+	// Extra holds the response fields this struct does not model.
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type VposSubmerchantListItem struct {
@@ -759,6 +866,9 @@ type OrganizationSettings struct {
 	DomainAddress      string `json:"domain_address,omitempty"`
 	CheckoutDomain     string `json:"checkout_domain,omitempty"`
 	SubscriptionDomain string `json:"subscription_domain,omitempty"`
+
+	// Extra holds the response fields this struct does not model.
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type OrganizationCurrenciesResponse struct {
@@ -1009,14 +1119,15 @@ type SubscriptionCreateRequest struct {
 	Billing             SubscriptionBilling `json:"billing"`
 	CardID              string              `json:"card_id,omitempty"`
 	Currency            string              `json:"currency,omitempty"`
-	Cycle               int                 `json:"cycle,omitempty"`
+	Cycle               SubscriptionCycle   `json:"cycle,omitempty"`
 	ExternalReferenceID string              `json:"external_reference_id,omitempty"`
 	FailureURL          string              `json:"failure_url,omitempty"`
 	PaymentDate         int                 `json:"payment_date,omitempty"`
-	Period              int                 `json:"period,omitempty"`
+	Period              SubscriptionPeriod  `json:"period,omitempty"`
 	SuccessURL          string              `json:"success_url,omitempty"`
 	Title               string              `json:"title,omitempty"`
 	User                SubscriptionUser    `json:"user"`
+	Metadata            []OrderMetadata     `json:"metadata,omitempty"`
 }
 
 // SubscriptionRedirectRequest represents the request payload for redirecting a subscription
@@ -1026,26 +1137,30 @@ type SubscriptionRedirectRequest struct {
 
 // SubscriptionOrder represents an order within a subscription
 type SubscriptionOrder struct {
-	Amount      string `json:"amount,omitempty"`
-	Currency    string `json:"currency,omitempty"`
-	PaymentDate string `json:"payment_date,omitempty"`
-	PaymentURL  string `json:"payment_url,omitempty"`
-	ReferenceID string `json:"reference_id,omitempty"`
-	Status      string `json:"status,omitempty"`
+	Amount      string                    `json:"amount,omitempty"`
+	Currency    string                    `json:"currency,omitempty"`
+	PaymentDate string                    `json:"payment_date,omitempty"`
+	PaymentURL  string                    `json:"payment_url,omitempty"`
+	ReferenceID string                    `json:"reference_id,omitempty"`
+	Status      SubscriptionPaymentStatus `json:"status,omitempty"`
 }
 
 // SubscriptionDetail represents the detailed subscription information
 type SubscriptionDetail struct {
-	Amount              string              `json:"amount,omitempty"`
-	Currency            string              `json:"currency,omitempty"`
-	DueDate             string              `json:"due_date,omitempty"`
-	ExternalReferenceID string              `json:"external_reference_id,omitempty"`
-	IsActive            bool                `json:"is_active,omitempty"`
-	Orders              []SubscriptionOrder `json:"orders,omitempty"`
-	PaymentDate         int                 `json:"payment_date,omitempty"`
-	PaymentStatus       string              `json:"payment_status,omitempty"`
-	Period              int                 `json:"period,omitempty"`
-	Title               string              `json:"title,omitempty"`
+	Amount              string                    `json:"amount,omitempty"`
+	Currency            string                    `json:"currency,omitempty"`
+	DueDate             string                    `json:"due_date,omitempty"`
+	ExternalReferenceID string                    `json:"external_reference_id,omitempty"`
+	IsActive            bool                      `json:"is_active,omitempty"`
+	Orders              []SubscriptionOrder       `json:"orders,omitempty"`
+	PaymentDate         int                       `json:"payment_date,omitempty"`
+	PaymentStatus       SubscriptionPaymentStatus `json:"payment_status,omitempty"`
+	Period              SubscriptionPeriod        `json:"period,omitempty"`
+	Title               string                    `json:"title,omitempty"`
+	Metadata            []OrderMetadata           `json:"metadata,omitempty"`
+
+	// Extra holds the response fields this struct does not model.
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // SubscriptionCreateResponse represents the response from creating a subscription
@@ -1058,15 +1173,15 @@ type SubscriptionCreateResponse struct {
 
 // SubscriptionListItem represents a single subscription item in the list
 type SubscriptionListItem struct {
-	Amount              string `json:"amount,omitempty"`
-	Currency            string `json:"currency,omitempty"`
-	ExternalReferenceID string `json:"external_reference_id,omitempty"`
-	IsActive            bool   `json:"is_active,omitempty"`
-	PaymentDate         int    `json:"payment_date,omitempty"`
-	PaymentStatus       string `json:"payment_status,omitempty"`
-	Period              int    `json:"period,omitempty"`
-	ReferenceID         string `json:"reference_id,omitempty"`
-	Title               string `json:"title,omitempty"`
+	Amount              string                    `json:"amount,omitempty"`
+	Currency            string                    `json:"currency,omitempty"`
+	ExternalReferenceID string                    `json:"external_reference_id,omitempty"`
+	IsActive            bool                      `json:"is_active,omitempty"`
+	PaymentDate         int                       `json:"payment_date,omitempty"`
+	PaymentStatus       SubscriptionPaymentStatus `json:"payment_status,omitempty"`
+	Period              SubscriptionPeriod        `json:"period,omitempty"`
+	ReferenceID         string                    `json:"reference_id,omitempty"`
+	Title               string                    `json:"title,omitempty"`
 }
 
 // SubscriptionRedirectResponse represents the response from redirecting a subscription
@@ -1074,6 +1189,26 @@ type SubscriptionRedirectResponse struct {
 	URL string `json:"url,omitempty"`
 }
 
+// SubscriptionUpdateRequest represents the request payload for changing the
+// plan or card of a subscription. Zero fields are left unchanged.
+type SubscriptionUpdateRequest struct {
+	ExternalReferenceID string             `json:"external_reference_id,omitempty"`
+	ReferenceID         string             `json:"reference_id,omitempty"`
+	Amount              float64            `json:"amount,omitempty"`
+	CardID              string             `json:"card_id,omitempty"`
+	Cycle               SubscriptionCycle  `json:"cycle,omitempty"`
+	PaymentDate         int                `json:"payment_date,omitempty"`
+	Period              SubscriptionPeriod `json:"period,omitempty"`
+	Title               string             `json:"title,omitempty"`
+}
+
+// SubscriptionPauseRequest represents the request payload for pausing or
+// resuming a subscription
+type SubscriptionPauseRequest struct {
+	ExternalReferenceID string `json:"external_reference_id,omitempty"`
+	ReferenceID         string `json:"reference_id,omitempty"`
+}
+
 // CardTokenizeRequest represents the request to tokenize a card
 type CardTokenizeRequest struct {
 	CardNumber         string `json:"card_number"`
@@ -1112,6 +1247,11 @@ type SavedCard struct {
 	Brand        string `json:"brand,omitempty"`
 	Bin          string `json:"bin,omitempty"`
 	IsDefault    bool   `json:"is_default,omitempty"`
+	ExpiryMonth  string `json:"expiry_month,omitempty"`
+	ExpiryYear   string `json:"expiry_year,omitempty"`
+	UserID       string `json:"user_id,omitempty"`
+	UserEmail    string `json:"user_email,omitempty"`
+	CreatedAt    string `json:"created_at,omitempty"`
 }
 
 // ListSavedCardsResponse represents a paginated list of saved cards
@@ -0,0 +1,90 @@
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"slices"
+	"time"
+)
+
+// Checkpoint records how far an ExportFile run got.
+type Checkpoint struct {
+	Format  Format    `json:"format"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Columns []string  `json:"columns"`
+	Kinds   []RowKind `json:"kinds"`
+	// OrganizationID and PerPage decide which orders land on which page.
+	OrganizationID string          `json:"organization_id,omitempty"`
+	PerPage        int             `json:"per_page"`
+	NextPage       int             `json:"next_page"`
+	Offset         int64           `json:"offset"`
+	Orders         int             `json:"orders"`
+	Rows           int64           `json:"rows"`
+	Pages          int             `json:"pages"`
+	WriterState    json.RawMessage `json:"writer_state,omitempty"`
+	// PageOrders are the reference IDs on the last completed page. A resumed
+	// run lists that page again and starts over when they changed, since
+	// orders added or removed since then shifted the later pages.
+	PageOrders []string `json:"page_orders,omitempty"`
+}
+
+// matches reports whether the checkpoint belongs to the same export run.
+func (c *Checkpoint) matches(e *Exporter, start, end time.Time) bool {
+	return c.Format == e.Format &&
+		c.Start.Equal(start) &&
+		c.End.Equal(end) &&
+		slices.Equal(c.Columns, e.columnNames()) &&
+		slices.Equal(c.Kinds, e.kinds()) &&
+		c.OrganizationID == e.OrganizationID &&
+		c.PerPage == e.perPage() &&
+		c.NextPage > 1
+}
+
+// CheckpointStore persists export progress between runs.
+type CheckpointStore interface {
+	// Load returns the saved checkpoint, or nil when there is none.
+	Load() (*Checkpoint, error)
+	Save(checkpoint Checkpoint) error
+	Clear() error
+}
+
+// FileCheckpointStore stores the checkpoint as JSON in a file.
+type FileCheckpointStore string
+
+func (path FileCheckpointStore) Load() (*Checkpoint, error) {
+	data, err := os.ReadFile(string(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var checkpoint Checkpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+// Save writes the checkpoint atomically by renaming a temporary file.
+func (path FileCheckpointStore) Save(checkpoint Checkpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	tmp := string(path) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, string(path))
+}
+
+func (path FileCheckpointStore) Clear() error {
+	err := os.Remove(string(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
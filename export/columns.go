@@ -0,0 +1,74 @@
+package export
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Column is a named output column computed from a Row.
+type Column struct {
+	Name  string
+	Value func(Row) string
+}
+
+// AllColumns lists every column the exporter knows about.
+var AllColumns = []Column{
+	{Name: "kind", Value: func(r Row) string { return string(r.Kind) }},
+	{Name: "order_reference_id", Value: func(r Row) string { return r.OrderReferenceID }},
+	{Name: "conversation_id", Value: func(r Row) string { return r.ConversationID }},
+	{Name: "external_reference_id", Value: func(r Row) string { return r.ExternalReferenceID }},
+	{Name: "order_status", Value: func(r Row) string { return r.OrderStatus }},
+	{Name: "order_created_at", Value: func(r Row) string { return r.OrderCreatedAt }},
+	{Name: "id", Value: func(r Row) string { return r.ID }},
+	{Name: "date", Value: func(r Row) string { return r.Date }},
+	{Name: "amount", Value: func(r Row) string { return FormatAmount(r.Amount, r.MinorUnits) }},
+	{Name: "currency", Value: func(r Row) string { return r.Currency }},
+	{Name: "payment_mode", Value: func(r Row) string { return r.PaymentMode }},
+	{Name: "type", Value: func(r Row) string { return r.Type }},
+	{Name: "masked_card", Value: func(r Row) string { return r.MaskedCard }},
+	{Name: "card_holder_name", Value: func(r Row) string { return r.CardHolderName }},
+	{Name: "paid", Value: func(r Row) string { return strconv.FormatBool(r.Paid) }},
+	{Name: "acquirer_response", Value: func(r Row) string { return r.AcquirerResponse }},
+}
+
+// DefaultColumns is the column set used when Exporter.Columns is empty.
+var DefaultColumns = mustColumns(
+	"kind",
+	"order_reference_id",
+	"conversation_id",
+	"external_reference_id",
+	"id",
+	"date",
+	"amount",
+	"currency",
+	"payment_mode",
+	"type",
+	"paid",
+)
+
+// Columns returns the named columns from AllColumns in the given order.
+func Columns(names ...string) ([]Column, error) {
+	columns := make([]Column, 0, len(names))
+	for _, name := range names {
+		found := false
+		for _, column := range AllColumns {
+			if column.Name == name {
+				columns = append(columns, column)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("export: unknown column %q", name)
+		}
+	}
+	return columns, nil
+}
+
+func mustColumns(names ...string) []Column {
+	columns, err := Columns(names...)
+	if err != nil {
+		panic(err)
+	}
+	return columns
+}
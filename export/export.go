@@ -0,0 +1,562 @@
+// Package export streams Tapsilat orders, payments and refunds for a date
+// range into CSV, JSON Lines or Parquet files.
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tapsilat/tapsilat-go"
+	"github.com/tapsilat/tapsilat-go/internal/parallel"
+)
+
+// Client is the subset of *tapsilat.API used by the exporter.
+type Client interface {
+	GetOrderList(ctx context.Context, page, perPage int, startDate, endDate, organizationID, relatedReferenceID string) (tapsilat.PaginatedData, error)
+	GetOrderPayments(ctx context.Context, payload tapsilat.GetOrderPaymentsRequest) (tapsilat.GetOrderPaymentsResponse, error)
+	GetOrderTransactions(ctx context.Context, referenceID string) (map[string]any, error)
+}
+
+// RowKind identifies what an exported row describes.
+type RowKind string
+
+const (
+	KindOrder   RowKind = "order"
+	KindPayment RowKind = "payment"
+	KindRefund  RowKind = "refund"
+)
+
+// Row is a single exported record. Order fields are repeated on payment and
+// refund rows so every row is self-contained.
+type Row struct {
+	Kind                RowKind
+	OrderReferenceID    string
+	ConversationID      string
+	ExternalReferenceID string
+	OrderStatus         string
+	OrderCreatedAt      string
+	ID                  string
+	Date                string
+	Amount              float64
+	Currency            string
+	MinorUnits          int
+	PaymentMode         string
+	Type                string
+	MaskedCard          string
+	CardHolderName      string
+	Paid                bool
+	AcquirerResponse    string
+}
+
+// Stats summarizes an export run.
+type Stats struct {
+	Orders  int   `json:"orders"`
+	Rows    int64 `json:"rows"`
+	Pages   int   `json:"pages"`
+	Resumed bool  `json:"resumed"`
+}
+
+// Exporter fetches orders page by page and writes the selected row kinds.
+type Exporter struct {
+	Client Client
+	Format Format
+	// Columns selects and orders the exported columns. Defaults to DefaultColumns.
+	Columns []Column
+	// Kinds selects the row kinds to export. Defaults to payments and refunds.
+	Kinds []RowKind
+	// PerPage is the page size used for GetOrderList.
+	PerPage int
+	// Workers bounds concurrent GetOrderPayments/GetOrderTransactions calls.
+	Workers int
+	// OrganizationID optionally restricts GetOrderList to one organization.
+	OrganizationID string
+	// DateLayout formats the start_date/end_date query parameters.
+	DateLayout string
+	// MinorUnits overrides the number of decimals used per currency code.
+	MinorUnits map[string]int
+	// Checkpoints stores progress for ExportFile. Defaults to a JSON file next
+	// to the output named "<path>.checkpoint".
+	Checkpoints CheckpointStore
+}
+
+// New creates an Exporter with default settings.
+func New(client Client, format Format) *Exporter {
+	return &Exporter{
+		Client:     client,
+		Format:     format,
+		Kinds:      []RowKind{KindPayment, KindRefund},
+		PerPage:    100,
+		Workers:    4,
+		DateLayout: "2006-01-02",
+	}
+}
+
+// Export streams every row for the date range into w without checkpointing.
+func (e *Exporter) Export(ctx context.Context, w io.Writer, start, end time.Time) (Stats, error) {
+	var stats Stats
+	writer, err := newWriter(e.Format, w, e.columnNames(), nil)
+	if err != nil {
+		return stats, err
+	}
+
+	err = e.walk(ctx, start, end, 1, &stats, func(page int, orders []order, rows []Row) error {
+		if err := e.writeRows(writer, rows); err != nil {
+			return err
+		}
+		return writer.Flush()
+	})
+	if err != nil {
+		return stats, err
+	}
+	return stats, writer.Close()
+}
+
+// ExportFile writes the date range into the file at path. Progress is saved
+// after every page, so a run interrupted halfway resumes from the last
+// completed page instead of starting over. The export starts over instead
+// when the file is shorter than the checkpoint says, or when the last
+// completed page no longer lists the same orders.
+func (e *Exporter) ExportFile(ctx context.Context, path string, start, end time.Time) (Stats, error) {
+	var stats Stats
+	store := e.Checkpoints
+	if store == nil {
+		store = FileCheckpointStore(path + ".checkpoint")
+	}
+
+	checkpoint, err := store.Load()
+	if err != nil {
+		return stats, err
+	}
+	if checkpoint != nil && !checkpoint.matches(e, start, end) {
+		checkpoint = nil
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return stats, err
+	}
+	defer file.Close()
+
+	if checkpoint != nil {
+		info, err := file.Stat()
+		if err != nil {
+			return stats, err
+		}
+		if info.Size() < checkpoint.Offset {
+			checkpoint = nil
+		}
+	}
+	if checkpoint != nil {
+		unchanged, err := e.pageUnchanged(ctx, start, end, checkpoint)
+		if err != nil {
+			return stats, err
+		}
+		if !unchanged {
+			checkpoint = nil
+		}
+	}
+
+	page := 1
+	var offset int64
+	var state []byte
+	if checkpoint != nil {
+		page = checkpoint.NextPage
+		offset = checkpoint.Offset
+		state = checkpoint.WriterState
+		stats.Orders = checkpoint.Orders
+		stats.Rows = checkpoint.Rows
+		stats.Pages = checkpoint.Pages
+		stats.Resumed = true
+	}
+	if err := file.Truncate(offset); err != nil {
+		return stats, err
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return stats, err
+	}
+
+	writer, err := newWriter(e.Format, file, e.columnNames(), state)
+	if err != nil {
+		return stats, err
+	}
+
+	err = e.walk(ctx, start, end, page, &stats, func(page int, orders []order, rows []Row) error {
+		if err := e.writeRows(writer, rows); err != nil {
+			return err
+		}
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		offset, err := file.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		state, err := writer.State()
+		if err != nil {
+			return err
+		}
+		return store.Save(Checkpoint{
+			Format:         e.Format,
+			Start:          start,
+			End:            end,
+			Columns:        e.columnNames(),
+			Kinds:          e.kinds(),
+			OrganizationID: e.OrganizationID,
+			PerPage:        e.perPage(),
+			NextPage:       page + 1,
+			Offset:         offset,
+			Orders:         stats.Orders,
+			Rows:           stats.Rows,
+			Pages:          stats.Pages,
+			WriterState:    state,
+			PageOrders:     referenceIDs(orders),
+		})
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return stats, err
+	}
+	return stats, store.Clear()
+}
+
+// pageUnchanged lists the last page the checkpoint completed and reports
+// whether it still holds the same orders.
+func (e *Exporter) pageUnchanged(ctx context.Context, start, end time.Time, checkpoint *Checkpoint) (bool, error) {
+	_, orders, err := e.listOrders(ctx, start, end, checkpoint.NextPage-1)
+	if err != nil {
+		return false, err
+	}
+	return slices.Equal(referenceIDs(orders), checkpoint.PageOrders), nil
+}
+
+func (e *Exporter) listOrders(ctx context.Context, start, end time.Time, page int) (tapsilat.PaginatedData, []order, error) {
+	res, err := e.Client.GetOrderList(ctx, page, e.perPage(), start.Format(e.dateLayout()), end.Format(e.dateLayout()), e.OrganizationID, "")
+	if err != nil {
+		return res, nil, fmt.Errorf("export: list orders page %d: %w", page, err)
+	}
+	orders, err := decodeOrders(res.Rows)
+	if err != nil {
+		return res, nil, fmt.Errorf("export: decode orders page %d: %w", page, err)
+	}
+	return res, orders, nil
+}
+
+func (e *Exporter) walk(ctx context.Context, start, end time.Time, page int, stats *Stats, emit func(page int, orders []order, rows []Row) error) error {
+	for {
+		res, orders, err := e.listOrders(ctx, start, end, page)
+		if err != nil {
+			return err
+		}
+
+		rows, err := e.buildRows(ctx, orders)
+		if err != nil {
+			return err
+		}
+		stats.Orders += len(orders)
+		stats.Rows += int64(len(rows))
+		stats.Pages++
+		if err := emit(page, orders, rows); err != nil {
+			return err
+		}
+
+		if page >= res.TotalPages || len(orders) == 0 {
+			return nil
+		}
+		page++
+	}
+}
+
+// buildRows loads payments and transactions for each order using at most
+// Workers concurrent requests, keeping the original order of the page.
+func (e *Exporter) buildRows(ctx context.Context, orders []order) ([]Row, error) {
+	results := make([][]Row, len(orders))
+	err := parallel.Each(ctx, e.workers(), len(orders), func(ctx context.Context, i int) error {
+		rows, err := e.orderRows(ctx, orders[i])
+		results[i] = rows
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []Row
+	for _, r := range results {
+		rows = append(rows, r...)
+	}
+	return rows, nil
+}
+
+func (e *Exporter) orderRows(ctx context.Context, o order) ([]Row, error) {
+	base := Row{
+		OrderReferenceID:    o.ReferenceID,
+		ConversationID:      o.ConversationID,
+		ExternalReferenceID: o.ExternalReferenceID,
+		OrderStatus:         o.status(),
+		OrderCreatedAt:      o.CreatedAt,
+		Currency:            o.Currency,
+		MinorUnits:          e.minorUnits(o.Currency),
+	}
+
+	var rows []Row
+	if e.includes(KindOrder) {
+		row := base
+		row.Kind = KindOrder
+		row.ID = o.ReferenceID
+		row.Date = o.CreatedAt
+		row.Amount = float64(o.Amount)
+		rows = append(rows, row)
+	}
+
+	if e.includes(KindPayment) {
+		res, err := e.Client.GetOrderPayments(ctx, tapsilat.GetOrderPaymentsRequest{OrderReferenceID: o.ReferenceID})
+		if err != nil {
+			return nil, fmt.Errorf("export: payments for %s: %w", o.ReferenceID, err)
+		}
+		for _, payment := range res.Payments {
+			row := base
+			row.Kind = KindPayment
+			row.ID = payment.ID
+			row.Date = payment.Date
+			row.Amount = payment.Amount
+			row.PaymentMode = payment.PaymentMode
+			row.Type = payment.Type
+			row.MaskedCard = payment.MaskedCard
+			row.CardHolderName = payment.CardHolderName
+			row.Paid = payment.Paid
+			row.AcquirerResponse = payment.AcquirerResponse
+			rows = append(rows, row)
+		}
+	}
+
+	if e.includes(KindRefund) {
+		res, err := e.Client.GetOrderTransactions(ctx, o.ReferenceID)
+		if err != nil {
+			return nil, fmt.Errorf("export: transactions for %s: %w", o.ReferenceID, err)
+		}
+		for _, tx := range transactionList(res) {
+			if !isRefund(tx) {
+				continue
+			}
+			row := base
+			row.Kind = KindRefund
+			row.ID = stringField(tx, "id", "transaction_id")
+			row.Date = stringField(tx, "date", "created_at", "transaction_date")
+			row.Amount = amountField(tx, "amount", "refund_amount")
+			row.Type = stringField(tx, "type", "transaction_type")
+			rows = append(rows, row)
+		}
+	}
+
+	return rows, nil
+}
+
+func (e *Exporter) writeRows(writer Writer, rows []Row) error {
+	columns := e.columns()
+	values := make([]string, len(columns))
+	for _, row := range rows {
+		for i, column := range columns {
+			values[i] = column.Value(row)
+		}
+		if err := writer.WriteRow(values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Exporter) includes(kind RowKind) bool {
+	if len(e.Kinds) == 0 {
+		return kind == KindPayment || kind == KindRefund
+	}
+	return slices.Contains(e.Kinds, kind)
+}
+
+// kinds returns the selected row kinds in a fixed order, so that checkpoints
+// compare them regardless of how Kinds was written.
+func (e *Exporter) kinds() []RowKind {
+	if len(e.Kinds) == 0 {
+		return []RowKind{KindPayment, KindRefund}
+	}
+	kinds := slices.Clone(e.Kinds)
+	slices.Sort(kinds)
+	return slices.Compact(kinds)
+}
+
+func (e *Exporter) columns() []Column {
+	if len(e.Columns) == 0 {
+		return DefaultColumns
+	}
+	return e.Columns
+}
+
+func (e *Exporter) columnNames() []string {
+	columns := e.columns()
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.Name
+	}
+	return names
+}
+
+func (e *Exporter) minorUnits(currency string) int {
+	code := strings.ToUpper(strings.TrimSpace(currency))
+	if units, ok := e.MinorUnits[code]; ok {
+		return units
+	}
+	return MinorUnits(code)
+}
+
+func (e *Exporter) perPage() int {
+	if e.PerPage <= 0 {
+		return 100
+	}
+	return e.PerPage
+}
+
+func (e *Exporter) workers() int {
+	if e.Workers <= 0 {
+		return 1
+	}
+	return e.Workers
+}
+
+func (e *Exporter) dateLayout() string {
+	if e.DateLayout == "" {
+		return "2006-01-02"
+	}
+	return e.DateLayout
+}
+
+// MinorUnits returns the number of decimals used for the currency code, as
+// listed in tapsilat.DefaultCurrencyRegistry.
+func MinorUnits(currency string) int {
+	return tapsilat.CurrencyMinorUnits(currency)
+}
+
+// FormatAmount formats amount with the minor units of the row currency.
+func FormatAmount(amount float64, minorUnits int) string {
+	return strconv.FormatFloat(amount, 'f', minorUnits, 64)
+}
+
+// order is the subset of an order list row the exporter needs.
+type order struct {
+	ReferenceID         string      `json:"reference_id"`
+	ConversationID      string      `json:"conversation_id"`
+	ExternalReferenceID string      `json:"external_reference_id"`
+	Amount              looseAmount `json:"amount"`
+	Currency            string      `json:"currency"`
+	Status              any         `json:"status"`
+	StatusEnum          string      `json:"status_enum"`
+	CreatedAt           string      `json:"created_at"`
+}
+
+func (o order) status() string {
+	if o.StatusEnum != "" {
+		return o.StatusEnum
+	}
+	if o.Status == nil {
+		return ""
+	}
+	return fmt.Sprint(o.Status)
+}
+
+func referenceIDs(orders []order) []string {
+	ids := make([]string, len(orders))
+	for i, o := range orders {
+		ids[i] = o.ReferenceID
+	}
+	return ids
+}
+
+func decodeOrders(rows any) ([]order, error) {
+	if rows == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(rows)
+	if err != nil {
+		return nil, err
+	}
+	var orders []order
+	if err := json.Unmarshal(raw, &orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// looseAmount accepts amounts encoded as JSON numbers or numeric strings.
+type looseAmount float64
+
+func (a *looseAmount) UnmarshalJSON(data []byte) error {
+	text := strings.Trim(string(data), `"`)
+	if text == "" || text == "null" {
+		return nil
+	}
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return fmt.Errorf("invalid amount %s", data)
+	}
+	*a = looseAmount(value)
+	return nil
+}
+
+func transactionList(res map[string]any) []map[string]any {
+	for _, key := range []string{"transactions", "rows", "items"} {
+		list, ok := res[key].([]any)
+		if !ok {
+			continue
+		}
+		out := make([]map[string]any, 0, len(list))
+		for _, item := range list {
+			if tx, ok := item.(map[string]any); ok {
+				out = append(out, tx)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func isRefund(tx map[string]any) bool {
+	kind := strings.ToLower(stringField(tx, "type", "transaction_type"))
+	return strings.Contains(kind, "refund")
+}
+
+func stringField(values map[string]any, keys ...string) string {
+	for _, key := range keys {
+		if value, ok := values[key]; ok && value != nil {
+			return fmt.Sprint(value)
+		}
+	}
+	return ""
+}
+
+func amountField(values map[string]any, keys ...string) float64 {
+	for _, key := range keys {
+		switch value := values[key].(type) {
+		case json.Number:
+			f, _ := value.Float64()
+			return f
+		case float64:
+			return value
+		case string:
+			f, err := strconv.ParseFloat(value, 64)
+			if err == nil {
+				return f
+			}
+		}
+	}
+	return 0
+}
+
+var errUnknownFormat = errors.New("export: unknown format")
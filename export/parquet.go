@@ -0,0 +1,279 @@
+package export
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+)
+
+// parquetWriter writes an uncompressed Parquet file in which every column is
+// a required UTF-8 string. Each Flush emits one row group, so the writer only
+// needs the row group metadata written so far to resume after a restart.
+type parquetWriter struct {
+	w       io.Writer
+	columns []string
+	offset  int64
+	groups  []parquetRowGroup
+	pending [][]string
+	rows    int64
+}
+
+type parquetRowGroup struct {
+	Rows    int64                `json:"rows"`
+	Columns []parquetColumnChunk `json:"columns"`
+}
+
+type parquetColumnChunk struct {
+	Offset int64 `json:"offset"`
+	Size   int64 `json:"size"`
+}
+
+type parquetState struct {
+	Offset int64             `json:"offset"`
+	Groups []parquetRowGroup `json:"groups"`
+}
+
+var parquetMagic = []byte("PAR1")
+
+func newParquetWriter(w io.Writer, columns []string, state []byte) (*parquetWriter, error) {
+	writer := &parquetWriter{
+		w:       w,
+		columns: columns,
+		pending: make([][]string, len(columns)),
+	}
+	if state != nil {
+		var saved parquetState
+		if err := json.Unmarshal(state, &saved); err != nil {
+			return nil, err
+		}
+		writer.offset = saved.Offset
+		writer.groups = saved.Groups
+		return writer, nil
+	}
+	if err := writer.write(parquetMagic); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (p *parquetWriter) WriteRow(values []string) error {
+	for i := range p.columns {
+		p.pending[i] = append(p.pending[i], values[i])
+	}
+	p.rows++
+	return nil
+}
+
+// Flush writes the buffered rows as a single row group.
+func (p *parquetWriter) Flush() error {
+	if p.rows == 0 {
+		return nil
+	}
+
+	group := parquetRowGroup{Rows: p.rows}
+	for i := range p.columns {
+		var data []byte
+		for _, value := range p.pending[i] {
+			data = binary.LittleEndian.AppendUint32(data, uint32(len(value)))
+			data = append(data, value...)
+		}
+
+		var header thriftWriter
+		header.i32(1, 0) // type: DATA_PAGE
+		header.i32(2, int32(len(data)))
+		header.i32(3, int32(len(data)))
+		header.beginStruct(5) // data_page_header
+		header.i32(1, int32(p.rows))
+		header.i32(2, 0) // encoding: PLAIN
+		header.i32(3, 3) // definition_level_encoding: RLE
+		header.i32(4, 3) // repetition_level_encoding: RLE
+		header.endStruct()
+		header.stop()
+
+		chunk := parquetColumnChunk{Offset: p.offset, Size: int64(len(header.buf) + len(data))}
+		if err := p.write(header.buf); err != nil {
+			return err
+		}
+		if err := p.write(data); err != nil {
+			return err
+		}
+		group.Columns = append(group.Columns, chunk)
+		p.pending[i] = p.pending[i][:0]
+	}
+
+	p.groups = append(p.groups, group)
+	p.rows = 0
+	return nil
+}
+
+func (p *parquetWriter) State() ([]byte, error) {
+	return json.Marshal(parquetState{Offset: p.offset, Groups: p.groups})
+}
+
+// Close flushes pending rows and writes the file footer.
+func (p *parquetWriter) Close() error {
+	if err := p.Flush(); err != nil {
+		return err
+	}
+
+	var totalRows int64
+	for _, group := range p.groups {
+		totalRows += group.Rows
+	}
+
+	var meta thriftWriter
+	meta.i32(1, 1) // version
+	meta.beginList(2, thriftStruct, len(p.columns)+1)
+	meta.listStruct(func() {
+		meta.binary(4, "schema")
+		meta.i32(5, int32(len(p.columns)))
+	})
+	for _, column := range p.columns {
+		meta.listStruct(func() {
+			meta.i32(1, 6) // type: BYTE_ARRAY
+			meta.i32(3, 0) // repetition_type: REQUIRED
+			meta.binary(4, column)
+			meta.i32(6, 0) // converted_type: UTF8
+		})
+	}
+	meta.i64(3, totalRows)
+	meta.beginList(4, thriftStruct, len(p.groups))
+	for _, group := range p.groups {
+		meta.listStruct(func() {
+			var groupSize int64
+			meta.beginList(1, thriftStruct, len(group.Columns))
+			for i, chunk := range group.Columns {
+				groupSize += chunk.Size
+				meta.listStruct(func() {
+					meta.i64(2, chunk.Offset)
+					meta.beginStruct(3) // meta_data
+					meta.i32(1, 6)      // type: BYTE_ARRAY
+					meta.beginList(2, thriftI32, 1)
+					meta.listI32(0) // encodings: PLAIN
+					meta.beginList(3, thriftBinary, 1)
+					meta.listBinary(p.columns[i])
+					meta.i32(4, 0) // codec: UNCOMPRESSED
+					meta.i64(5, group.Rows)
+					meta.i64(6, chunk.Size)
+					meta.i64(7, chunk.Size)
+					meta.i64(9, chunk.Offset)
+					meta.endStruct()
+				})
+			}
+			meta.i64(2, groupSize)
+			meta.i64(3, group.Rows)
+		})
+	}
+	meta.binary(6, "tapsilat-go")
+	meta.stop()
+
+	if err := p.write(meta.buf); err != nil {
+		return err
+	}
+	if err := p.write(binary.LittleEndian.AppendUint32(nil, uint32(len(meta.buf)))); err != nil {
+		return err
+	}
+	return p.write(parquetMagic)
+}
+
+func (p *parquetWriter) write(data []byte) error {
+	n, err := p.w.Write(data)
+	p.offset += int64(n)
+	return err
+}
+
+// Thrift compact protocol type ids used by the Parquet metadata.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter is a minimal Thrift compact protocol encoder covering the
+// field types needed for Parquet page headers and file metadata.
+type thriftWriter struct {
+	buf       []byte
+	lastField []int16
+	last      int16
+}
+
+func (t *thriftWriter) fieldHeader(id int16, typ byte) {
+	delta := id - t.last
+	if delta > 0 && delta <= 15 {
+		t.buf = append(t.buf, byte(delta)<<4|typ)
+	} else {
+		t.buf = append(t.buf, typ)
+		t.varint(uint64(zigzag(int64(id))))
+	}
+	t.last = id
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	t.buf = binary.AppendUvarint(t.buf, v)
+}
+
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.fieldHeader(id, thriftI32)
+	t.varint(zigzag(int64(v)))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.fieldHeader(id, thriftI64)
+	t.varint(zigzag(v))
+}
+
+func (t *thriftWriter) binary(id int16, v string) {
+	t.fieldHeader(id, thriftBinary)
+	t.varint(uint64(len(v)))
+	t.buf = append(t.buf, v...)
+}
+
+func (t *thriftWriter) beginStruct(id int16) {
+	t.fieldHeader(id, thriftStruct)
+	t.lastField = append(t.lastField, t.last)
+	t.last = 0
+}
+
+func (t *thriftWriter) endStruct() {
+	t.stop()
+	t.last = t.lastField[len(t.lastField)-1]
+	t.lastField = t.lastField[:len(t.lastField)-1]
+}
+
+func (t *thriftWriter) stop() {
+	t.buf = append(t.buf, 0)
+}
+
+func (t *thriftWriter) beginList(id int16, elem byte, size int) {
+	t.fieldHeader(id, thriftList)
+	if size < 15 {
+		t.buf = append(t.buf, byte(size)<<4|elem)
+		return
+	}
+	t.buf = append(t.buf, 0xF0|elem)
+	t.varint(uint64(size))
+}
+
+func (t *thriftWriter) listStruct(fields func()) {
+	t.lastField = append(t.lastField, t.last)
+	t.last = 0
+	fields()
+	t.stop()
+	t.last = t.lastField[len(t.lastField)-1]
+	t.lastField = t.lastField[:len(t.lastField)-1]
+}
+
+func (t *thriftWriter) listI32(v int32) {
+	t.varint(zigzag(int64(v)))
+}
+
+func (t *thriftWriter) listBinary(v string) {
+	t.varint(uint64(len(v)))
+	t.buf = append(t.buf, v...)
+}
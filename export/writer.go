@@ -0,0 +1,121 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+)
+
+// Format selects the output encoding.
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatJSONL   Format = "jsonl"
+	FormatParquet Format = "parquet"
+)
+
+// Writer encodes exported rows. Flush is called after every page; State
+// returns whatever the writer needs to continue appending after a restart.
+type Writer interface {
+	WriteRow(values []string) error
+	Flush() error
+	State() ([]byte, error)
+	Close() error
+}
+
+// NewWriter creates a Writer for format that writes the given columns to w.
+func NewWriter(format Format, w io.Writer, columns []string) (Writer, error) {
+	return newWriter(format, w, columns, nil)
+}
+
+// newWriter creates a Writer, resuming from state when it is non-nil.
+func newWriter(format Format, w io.Writer, columns []string, state []byte) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, columns, state != nil)
+	case FormatJSONL:
+		return newJSONLWriter(w, columns), nil
+	case FormatParquet:
+		return newParquetWriter(w, columns, state)
+	default:
+		return nil, errUnknownFormat
+	}
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer, columns []string, resume bool) (*csvWriter, error) {
+	writer := &csvWriter{w: csv.NewWriter(w)}
+	if !resume {
+		if err := writer.w.Write(columns); err != nil {
+			return nil, err
+		}
+	}
+	return writer, nil
+}
+
+func (c *csvWriter) WriteRow(values []string) error {
+	return c.w.Write(values)
+}
+
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) State() ([]byte, error) {
+	return []byte("{}"), nil
+}
+
+func (c *csvWriter) Close() error {
+	return c.Flush()
+}
+
+// jsonlWriter writes one JSON object per line, keeping the column order.
+type jsonlWriter struct {
+	keys [][]byte
+	buf  *bufio.Writer
+}
+
+func newJSONLWriter(w io.Writer, columns []string) *jsonlWriter {
+	keys := make([][]byte, len(columns))
+	for i, column := range columns {
+		keys[i], _ = json.Marshal(column)
+	}
+	return &jsonlWriter{keys: keys, buf: bufio.NewWriter(w)}
+}
+
+func (j *jsonlWriter) WriteRow(values []string) error {
+	line := []byte{'{'}
+	for i, key := range j.keys {
+		if i > 0 {
+			line = append(line, ',')
+		}
+		value, err := json.Marshal(values[i])
+		if err != nil {
+			return err
+		}
+		line = append(line, key...)
+		line = append(line, ':')
+		line = append(line, value...)
+	}
+	line = append(line, '}', '\n')
+	_, err := j.buf.Write(line)
+	return err
+}
+
+func (j *jsonlWriter) Flush() error {
+	return j.buf.Flush()
+}
+
+func (j *jsonlWriter) State() ([]byte, error) {
+	return []byte("{}"), nil
+}
+
+func (j *jsonlWriter) Close() error {
+	return j.Flush()
+}
@@ -0,0 +1,57 @@
+// Package parallel runs indexed calls with bounded concurrency for the
+// loaders and syncers of this module.
+package parallel
+
+import (
+	"context"
+	"sync"
+)
+
+// Each calls fn for 0..n-1 with at most workers calls in flight, and returns
+// the first error fn returns, or ctx.Err() when every call succeeded. After
+// an error the context passed to running calls is canceled and calls not yet
+// started are skipped. Calls keep being started when ctx is done, so fn sees
+// ctx.Err() itself; a fn that always returns nil is called for every index.
+// A workers value below 1 runs one call at a time.
+func Each(ctx context.Context, workers, n int, fn func(ctx context.Context, i int) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	failed := make(chan struct{})
+	slots := make(chan struct{}, max(workers, 1))
+loop:
+	for i := range n {
+		select {
+		case slots <- struct{}{}:
+		case <-failed:
+			break loop
+		}
+		select {
+		case <-failed:
+			break loop
+		default:
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := fn(ctx, i); err != nil {
+				once.Do(func() {
+					firstErr = err
+					close(failed)
+					cancel()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
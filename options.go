@@ -0,0 +1,118 @@
+package tapsilat
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RequestOption changes how a single request is sent. Attach options to the
+// context of a call with ContextWithOptions; every method of *API honors
+// them, including the requests it sends on its own, such as the checkout URL
+// lookup of CreateOrder. WithIdempotencyKey is the exception: it only goes
+// to the request that changes something.
+type RequestOption func(*requestOptions)
+
+type requestOptions struct {
+	header    http.Header
+	timeout   time.Duration
+	raw       []*[]byte
+	responses []*Response
+}
+
+// WithHeader sets a request header, such as a correlation ID. The
+// Authorization header is always set from the client's token.
+func WithHeader(key, value string) RequestOption {
+	return func(o *requestOptions) {
+		if o.header == nil {
+			o.header = http.Header{}
+		}
+		o.header.Set(key, value)
+	}
+}
+
+// WithTimeout bounds the request, including a retry after a 401, to d. The
+// client's own Timeout still applies to each attempt.
+func WithTimeout(d time.Duration) RequestOption {
+	return func(o *requestOptions) {
+		o.timeout = d
+	}
+}
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// WithIdempotencyKey sets the Idempotency-Key header on the request that
+// changes something. Reads never carry it: GET requests, reads sent as POST
+// such as GetSubscription, and the lookups a call makes around its change,
+// such as the checkout URL lookup of CreateOrder or the reads of the Modify
+// methods. The cancel sent by the SubscriptionEditor fallback does not carry
+// it either, so the key stays with the subscription created after it. Use a
+// new key for each operation.
+func WithIdempotencyKey(key string) RequestOption {
+	return WithHeader(idempotencyKeyHeader, key)
+}
+
+// WithLocale sets the Accept-Language header, such as "tr" or "en".
+func WithLocale(locale string) RequestOption {
+	return WithHeader("Accept-Language", locale)
+}
+
+// WithRawResponse stores the raw response body in *body, also when the
+// request fails with an *APIError. Like WithResponse, a call that sends
+// several requests stores the body of the one carrying its result.
+func WithRawResponse(body *[]byte) RequestOption {
+	return func(o *requestOptions) {
+		o.raw = append(o.raw, body)
+	}
+}
+
+type requestOptionsKey struct{}
+
+type noIdempotencyKey struct{}
+
+type noCapture struct{}
+
+// captureMu guards the writes of WithRawResponse and WithResponse, which
+// loaders sending requests concurrently make to the same variables.
+var captureMu sync.Mutex
+
+// withoutCapture returns ctx for a request whose response must not be stored
+// by WithRawResponse and WithResponse: a lookup made after the request that
+// carries the call's result, or a load that may outlive the call.
+func withoutCapture(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCapture{}, true)
+}
+
+// withoutIdempotencyKey returns ctx for a request that must not carry the
+// Idempotency-Key of the call, such as a read sent as POST.
+func withoutIdempotencyKey(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noIdempotencyKey{}, true)
+}
+
+// ContextWithOptions returns a context whose requests are sent with opts, in
+// addition to the options already attached to ctx.
+func ContextWithOptions(ctx context.Context, opts ...RequestOption) context.Context {
+	parent, _ := ctx.Value(requestOptionsKey{}).([]RequestOption)
+	combined := make([]RequestOption, 0, len(parent)+len(opts))
+	combined = append(combined, parent...)
+	combined = append(combined, opts...)
+	return context.WithValue(ctx, requestOptionsKey{}, combined)
+}
+
+func requestOptionsFrom(ctx context.Context, method string) requestOptions {
+	var options requestOptions
+	opts, _ := ctx.Value(requestOptionsKey{}).([]RequestOption)
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+	if method == http.MethodGet || ctx.Value(noIdempotencyKey{}) != nil {
+		options.header.Del(idempotencyKeyHeader)
+	}
+	if ctx.Value(noCapture{}) != nil {
+		options.raw, options.responses = nil, nil
+	}
+	return options
+}
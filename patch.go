@@ -0,0 +1,198 @@
+package tapsilat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Ptr returns a pointer to v, for filling in the fields of the Patch types:
+//
+//	api.PatchVpos(ctx, id, tapsilat.VposPatch{Priority: tapsilat.Ptr(int64(0))})
+func Ptr[T any](v T) *T {
+	return &v
+}
+
+// ConflictError is returned by the Modify helpers when a field they were
+// about to change was changed by someone else after it was read. Nothing is
+// sent; read the record again and retry.
+type ConflictError struct {
+	Resource string
+	ID       string
+	Fields   []string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s %s was modified concurrently: %s", e.Resource, e.ID, strings.Join(e.Fields, ", "))
+}
+
+// ModifySubmerchant reads a submerchant, applies modify to a copy and sends
+// only the fields it changed with PatchSubmerchant. Nothing is sent when
+// modify changes nothing or returns an error. Before sending, the submerchant
+// is read again, and a *ConflictError is returned if any changed field no
+// longer holds the value modify saw. Changing a field that SubmerchantPatch
+// does not have, such as ID, is a *FieldValidationError.
+func (t *API) ModifySubmerchant(ctx context.Context, id string, modify func(*Submerchant) error) (SubmerchantMutationResponse, error) {
+	var response SubmerchantMutationResponse
+	patch, err := readModifyPatch[Submerchant, SubmerchantPatch](ctx, "submerchant", id, t.GetSubmerchant, modify)
+	if err != nil || patch == nil {
+		return response, err
+	}
+	return t.PatchSubmerchant(ctx, id, *patch)
+}
+
+// ModifyVpos is ModifySubmerchant for a VPOS, sent with PatchVpos.
+func (t *API) ModifyVpos(ctx context.Context, id string, modify func(*Vpos) error) (VposMutationResponse, error) {
+	var response VposMutationResponse
+	patch, err := readModifyPatch[Vpos, VposPatch](ctx, "vpos", id, t.GetVpos, modify)
+	if err != nil || patch == nil {
+		return response, err
+	}
+	return t.PatchVpos(ctx, id, *patch)
+}
+
+// ModifyVposSubmerchant is ModifySubmerchant for a VPOS submerchant, sent with
+// PatchVposSubmerchant.
+func (t *API) ModifyVposSubmerchant(ctx context.Context, id string, modify func(*VposSubmerchant) error) (VposSubmerchantMutationResponse, error) {
+	var response VposSubmerchantMutationResponse
+	patch, err := readModifyPatch[VposSubmerchant, VposSubmerchantPatch](ctx, "vpos submerchant", id, t.GetVposSubmerchant, modify)
+	if err != nil || patch == nil {
+		return response, err
+	}
+	return t.PatchVposSubmerchant(ctx, id, *patch)
+}
+
+// readModifyPatch reads a record, applies modify to a copy and returns the
+// changed fields as a patch of type P, or nil when nothing changed. Records
+// are compared as JSON objects, so a field modify clears is sent as its zero
+// value.
+func readModifyPatch[T, P any](ctx context.Context, resource, id string, get func(context.Context, string) (T, error), modify func(*T) error) (*P, error) {
+	current, err := get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	before, err := jsonObject(current)
+	if err != nil {
+		return nil, err
+	}
+	if err := modify(&current); err != nil {
+		return nil, err
+	}
+	after, err := jsonObject(current)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := diffJSONObjects(before, after)
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	var patch P
+	allowed := jsonFieldNames(reflect.TypeOf(patch))
+	var issues []FieldIssue
+	for _, field := range sortedKeys(changes) {
+		if !allowed[field] {
+			issues = append(issues, FieldIssue{Field: field, Reason: FieldInvalid, Message: "cannot be modified"})
+		}
+	}
+	if len(issues) > 0 {
+		return nil, &FieldValidationError{Issues: issues}
+	}
+
+	latest, err := get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	fresh, err := jsonObject(latest)
+	if err != nil {
+		return nil, err
+	}
+	var conflicts []string
+	for _, field := range sortedKeys(changes) {
+		// Someone else making the same change is not a conflict.
+		if !reflect.DeepEqual(before[field], fresh[field]) && !reflect.DeepEqual(after[field], fresh[field]) {
+			conflicts = append(conflicts, field)
+		}
+	}
+	if len(conflicts) > 0 {
+		return nil, &ConflictError{Resource: resource, ID: id, Fields: conflicts}
+	}
+
+	body, err := json.Marshal(changes)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(body, &patch); err != nil {
+		return nil, err
+	}
+	return &patch, nil
+}
+
+func jsonObject(v any) (map[string]any, error) {
+	body, err := MarshalRequest(v)
+	if err != nil {
+		return nil, err
+	}
+	var object map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&object); err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+// diffJSONObjects returns the fields of after that differ from before. Fields
+// present in before but left out of after by omitempty were cleared, and are
+// returned as the zero value of their JSON type.
+func diffJSONObjects(before, after map[string]any) map[string]any {
+	changes := map[string]any{}
+	for field, value := range after {
+		if !reflect.DeepEqual(before[field], value) {
+			changes[field] = value
+		}
+	}
+	for field, value := range before {
+		if _, ok := after[field]; ok {
+			continue
+		}
+		switch value.(type) {
+		case string:
+			changes[field] = ""
+		case json.Number:
+			changes[field] = json.Number("0")
+		case bool:
+			changes[field] = false
+		case []any:
+			changes[field] = []any{}
+		default:
+			changes[field] = nil
+		}
+	}
+	return changes
+}
+
+func jsonFieldNames(typ reflect.Type) map[string]bool {
+	names := map[string]bool{}
+	for i := range typ.NumField() {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,352 @@
+package tapsilat
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultPoolIdleTimeout is how long a Pool keeps an unused tenant client.
+const DefaultPoolIdleTimeout = 30 * time.Minute
+
+// Pool hands out one *API per tenant, each authenticated with the tenant's
+// organization token. All clients share one transport, and so one connection
+// pool. Each keeps its own reference data caches. Clients unused for
+// IdleTimeout are evicted and rebuilt on the next Get.
+//
+// The token and the rate limit belong to the tenant rather than to its
+// client, so a client evicted while a caller still holds it keeps sharing
+// them with its replacement: SetToken rotates both, and both count against
+// one limit. Remove forgets them. A token looked up with TokenSource is
+// looked up again when the API rejects it with 401.
+//
+// Set the fields before the first Get.
+type Pool struct {
+	EndPoint string
+	// Transport is shared by every tenant client. Nil uses a clone of
+	// http.DefaultTransport.
+	Transport http.RoundTripper
+	// Timeout is the per-request timeout of tenant clients.
+	Timeout time.Duration
+	// Rate limits each tenant to this many requests per second; zero means
+	// no limit.
+	Rate float64
+	// IdleTimeout is how long an unused tenant client is kept. Zero uses
+	// DefaultPoolIdleTimeout.
+	IdleTimeout time.Duration
+	// ReferenceCacheTTL is passed on to every tenant client.
+	ReferenceCacheTTL time.Duration
+	// ReferenceCacheDir, when set, persists each tenant's reference data in a
+	// subdirectory named by PoolTenantDir.
+	ReferenceCacheDir string
+	// TokenSource looks up the token of a tenant that was not registered with
+	// SetToken.
+	TokenSource func(ctx context.Context, tenant string) (string, error)
+	// Configure, when set, is called for every new tenant client.
+	Configure func(tenant string, api *API)
+	Now       func() time.Time
+
+	mu        sync.Mutex
+	clients   map[string]*poolClient
+	tenants   map[string]*poolTenant
+	lastSweep time.Time
+	transport http.RoundTripper
+}
+
+type poolClient struct {
+	api      *API
+	lastUsed time.Time
+}
+
+// poolTenant is the state of a tenant shared by all of its clients.
+type poolTenant struct {
+	pool    *Pool
+	name    string
+	limiter *rateLimitedTransport
+
+	mu sync.RWMutex
+	// registered reports that the token was set with SetToken rather than
+	// looked up with TokenSource.
+	registered bool
+	token      Secret
+	// refreshMu lets one 401 at a time look the token up again.
+	refreshMu sync.Mutex
+}
+
+func (t *poolTenant) Token(ctx context.Context) (Secret, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.token, nil
+}
+
+// Refresh looks the token up again with Pool.TokenSource after the API
+// rejected it. Tokens registered with SetToken are kept; rotate them with
+// SetToken.
+func (t *poolTenant) Refresh(ctx context.Context, rejected Secret) error {
+	if t.pool.TokenSource == nil {
+		return nil
+	}
+	t.refreshMu.Lock()
+	defer t.refreshMu.Unlock()
+	if registered, current := t.state(); registered || current != rejected {
+		return nil
+	}
+	token, err := t.pool.lookupToken(ctx, t.name)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// SetToken wins over a lookup that raced with it.
+	if !t.registered {
+		t.token = Secret(token)
+	}
+	return nil
+}
+
+func (t *poolTenant) state() (registered bool, token Secret) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.registered, t.token
+}
+
+func (t *poolTenant) setToken(token string, registered bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.registered && !registered {
+		return
+	}
+	t.registered = registered
+	t.token = Secret(token)
+}
+
+// PoolTenantDir returns the subdirectory of Pool.ReferenceCacheDir that holds
+// the reference data of tenant. It is derived from a hash of the tenant, so
+// every tenant gets its own directory whatever characters its name has.
+func PoolTenantDir(tenant string) string {
+	sum := sha256.Sum256([]byte(tenant))
+	return "tenant-" + hex.EncodeToString(sum[:16])
+}
+
+// NewPool creates a pool of clients for the given endpoint.
+func NewPool(endpoint string) *Pool {
+	return &Pool{EndPoint: endpoint, Timeout: 30 * time.Second}
+}
+
+// SetToken registers the token of a tenant. Every client of the tenant,
+// including one evicted but still held by a caller, uses it from the next
+// request on; requests in flight finish with the old token.
+func (p *Pool) SetToken(tenant, token string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.tenantLocked(tenant).setToken(token, true)
+}
+
+// Get returns the client of a tenant, creating it on first use. Getting a
+// client marks it as used, and idle clients of other tenants are evicted.
+func (p *Pool) Get(ctx context.Context, tenant string) (*API, error) {
+	p.mu.Lock()
+	now := p.clock()
+	p.sweepLocked(now)
+	if client, ok := p.clients[tenant]; ok {
+		client.lastUsed = now
+		p.mu.Unlock()
+		return client.api, nil
+	}
+	state, ok := p.tenants[tenant]
+	p.mu.Unlock()
+	registered := false
+	if ok {
+		registered, _ = state.state()
+	}
+
+	var token string
+	if !registered {
+		if p.TokenSource == nil {
+			return nil, fmt.Errorf("tapsilat: no token for tenant %q", tenant)
+		}
+		var err error
+		if token, err = p.lookupToken(ctx, tenant); err != nil {
+			return nil, err
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// Another Get may have created the client while the token was looked up.
+	if client, ok := p.clients[tenant]; ok {
+		client.lastUsed = now
+		return client.api, nil
+	}
+	state = p.tenantLocked(tenant)
+	if !registered {
+		// A SetToken since the lookup wins.
+		state.setToken(token, false)
+	}
+	if p.clients == nil {
+		p.clients = map[string]*poolClient{}
+	}
+	api := p.newClientLocked(tenant, state)
+	p.clients[tenant] = &poolClient{api: api, lastUsed: now}
+	return api, nil
+}
+
+func (p *Pool) tenantLocked(tenant string) *poolTenant {
+	if p.tenants == nil {
+		p.tenants = map[string]*poolTenant{}
+	}
+	state, ok := p.tenants[tenant]
+	if !ok {
+		state = &poolTenant{pool: p, name: tenant}
+		p.tenants[tenant] = state
+	}
+	return state
+}
+
+func (p *Pool) lookupToken(ctx context.Context, tenant string) (string, error) {
+	token, err := p.TokenSource(ctx, tenant)
+	if err != nil {
+		return "", fmt.Errorf("tapsilat: token for tenant %q: %w", tenant, err)
+	}
+	return token, nil
+}
+
+func (p *Pool) newClientLocked(tenant string, state *poolTenant) *API {
+	if p.transport == nil {
+		p.transport = p.Transport
+		if p.transport == nil {
+			p.transport = http.DefaultTransport.(*http.Transport).Clone()
+		}
+	}
+	var transport http.RoundTripper = p.transport
+	if p.Rate > 0 {
+		if state.limiter == nil {
+			state.limiter = &rateLimitedTransport{base: p.transport, interval: time.Duration(float64(time.Second) / p.Rate)}
+		}
+		transport = state.limiter
+	}
+
+	token, _ := state.Token(context.Background())
+	api := NewCustomAPIWithClient(p.EndPoint, token.Reveal(), &http.Client{Transport: transport, Timeout: p.Timeout})
+	api.TokenSource = state
+	api.ReferenceCacheTTL = p.ReferenceCacheTTL
+	if p.ReferenceCacheDir != "" {
+		api.ReferenceCacheDir = filepath.Join(p.ReferenceCacheDir, PoolTenantDir(tenant))
+	}
+	if p.Configure != nil {
+		p.Configure(tenant, api)
+	}
+	return api
+}
+
+// Remove drops the client, the token and the rate limit of a tenant.
+func (p *Pool) Remove(tenant string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.clients, tenant)
+	delete(p.tenants, tenant)
+}
+
+// EvictIdle drops the clients unused for IdleTimeout and returns how many
+// were dropped. Tokens and rate limits are kept. Get calls it as needed, so it
+// only has to be called to free memory sooner.
+func (p *Pool) EvictIdle() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.evictLocked(p.clock())
+}
+
+func (p *Pool) sweepLocked(now time.Time) {
+	if now.Sub(p.lastSweep) < p.idleTimeout()/2 {
+		return
+	}
+	p.evictLocked(now)
+}
+
+func (p *Pool) evictLocked(now time.Time) int {
+	p.lastSweep = now
+	evicted := 0
+	for tenant, client := range p.clients {
+		if now.Sub(client.lastUsed) >= p.idleTimeout() {
+			delete(p.clients, tenant)
+			evicted++
+		}
+	}
+	return evicted
+}
+
+// Tenants returns the tenants with a live client, sorted.
+func (p *Pool) Tenants() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	tenants := make([]string, 0, len(p.clients))
+	for tenant := range p.clients {
+		tenants = append(tenants, tenant)
+	}
+	sort.Strings(tenants)
+	return tenants
+}
+
+// CloseIdleConnections closes the idle connections of the shared transport.
+func (p *Pool) CloseIdleConnections() {
+	p.mu.Lock()
+	transport := p.transport
+	p.mu.Unlock()
+	if closer, ok := transport.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+func (p *Pool) idleTimeout() time.Duration {
+	if p.IdleTimeout <= 0 {
+		return DefaultPoolIdleTimeout
+	}
+	return p.IdleTimeout
+}
+
+func (p *Pool) clock() time.Time {
+	if p.Now != nil {
+		return p.Now()
+	}
+	return time.Now()
+}
+
+// rateLimitedTransport spaces the requests of one tenant at least interval
+// apart before handing them to the shared transport.
+type rateLimitedTransport struct {
+	base     http.RoundTripper
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	now := time.Now()
+	wait := t.next.Sub(now)
+	t.next = now.Add(max(wait, 0) + t.interval)
+	t.mu.Unlock()
+
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			// Give the slot back, so canceled requests do not delay the
+			// tenant's next ones.
+			t.mu.Lock()
+			t.next = t.next.Add(-t.interval)
+			t.mu.Unlock()
+			return nil, req.Context().Err()
+		}
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,479 @@
+// Package reconcile compares a local payment ledger with the orders and
+// payments recorded on Tapsilat for a date range.
+package reconcile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tapsilat/tapsilat-go"
+	"github.com/tapsilat/tapsilat-go/internal/parallel"
+)
+
+// OrderClient is the subset of *tapsilat.API used by the reconciler.
+type OrderClient interface {
+	GetOrderList(ctx context.Context, page, perPage int, startDate, endDate, organizationID, relatedReferenceID string) (tapsilat.PaginatedData, error)
+	GetOrderPayments(ctx context.Context, payload tapsilat.GetOrderPaymentsRequest) (tapsilat.GetOrderPaymentsResponse, error)
+}
+
+// LedgerEntry is a single order as recorded in the local ledger. At least one
+// of ReferenceID, ConversationID or ExternalReferenceID must be set.
+type LedgerEntry struct {
+	ReferenceID         string  `json:"reference_id,omitempty"`
+	ConversationID      string  `json:"conversation_id,omitempty"`
+	ExternalReferenceID string  `json:"external_reference_id,omitempty"`
+	Amount              float64 `json:"amount"`
+	Currency            string  `json:"currency,omitempty"`
+	Status              string  `json:"status,omitempty"`
+}
+
+// LedgerSource provides ledger entries for a date range.
+type LedgerSource interface {
+	Entries(ctx context.Context, start, end time.Time) ([]LedgerEntry, error)
+}
+
+// LedgerSourceFunc adapts a function to the LedgerSource interface.
+type LedgerSourceFunc func(ctx context.Context, start, end time.Time) ([]LedgerEntry, error)
+
+func (f LedgerSourceFunc) Entries(ctx context.Context, start, end time.Time) ([]LedgerEntry, error) {
+	return f(ctx, start, end)
+}
+
+// Record is an order fetched from Tapsilat together with its payments.
+type Record struct {
+	ReferenceID         string                  `json:"reference_id,omitempty"`
+	ConversationID      string                  `json:"conversation_id,omitempty"`
+	ExternalReferenceID string                  `json:"external_reference_id,omitempty"`
+	Amount              float64                 `json:"amount"`
+	PaidAmount          float64                 `json:"paid_amount"`
+	RefundedAmount      float64                 `json:"refunded_amount"`
+	Currency            string                  `json:"currency,omitempty"`
+	Status              string                  `json:"status,omitempty"`
+	CreatedAt           string                  `json:"created_at,omitempty"`
+	Payments            []tapsilat.OrderPayment `json:"payments,omitempty"`
+}
+
+// SettledAmount returns the sum of paid payments, falling back to the order's
+// paid amount when no payments were returned, less refunds. Refunds are the
+// order's refunded amount, or when that is zero the paid payments whose type
+// is a refund.
+func (r Record) SettledAmount() float64 {
+	paid := r.PaidAmount
+	var refunded float64
+	if len(r.Payments) > 0 {
+		paid = 0
+		for _, payment := range r.Payments {
+			switch {
+			case !payment.Paid:
+			case isRefund(payment.Type):
+				refunded += math.Abs(payment.Amount)
+			default:
+				paid += payment.Amount
+			}
+		}
+	}
+	if r.RefundedAmount != 0 {
+		refunded = r.RefundedAmount
+	}
+	return paid - refunded
+}
+
+func isRefund(paymentType string) bool {
+	return strings.Contains(strings.ToLower(paymentType), "refund")
+}
+
+// Reconciler fetches orders for a date range and diffs them against a ledger.
+type Reconciler struct {
+	Client OrderClient
+	Ledger LedgerSource
+
+	// Workers bounds the number of concurrent GetOrderList/GetOrderPayments calls.
+	Workers int
+	// PerPage is the page size used for GetOrderList.
+	PerPage int
+	// OrganizationID optionally restricts GetOrderList to one organization.
+	OrganizationID string
+	// DateLayout formats the start_date/end_date query parameters.
+	DateLayout string
+	// Tolerance is the largest absolute amount difference treated as equal.
+	Tolerance float64
+	// StatusEqual compares a ledger status with a Tapsilat status. The default
+	// compares case-insensitively, accepting either the status id or its name.
+	StatusEqual func(ledger, remote string) bool
+}
+
+// New creates a Reconciler with default settings.
+func New(client OrderClient, ledger LedgerSource) *Reconciler {
+	return &Reconciler{
+		Client:      client,
+		Ledger:      ledger,
+		Workers:     4,
+		PerPage:     100,
+		DateLayout:  "2006-01-02",
+		Tolerance:   0.005,
+		StatusEqual: DefaultStatusEqual,
+	}
+}
+
+// Run reconciles the ledger with Tapsilat for the given date range.
+func (r *Reconciler) Run(ctx context.Context, start, end time.Time) (*Report, error) {
+	entries, err := r.Ledger.Entries(ctx, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("reconcile: load ledger: %w", err)
+	}
+
+	records, err := r.FetchRecords(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	report := r.compare(entries, records)
+	report.Start = start
+	report.End = end
+	return report, nil
+}
+
+// FetchRecords walks every GetOrderList page for the date range and loads the
+// payments of each order using at most Workers concurrent requests.
+func (r *Reconciler) FetchRecords(ctx context.Context, start, end time.Time) ([]Record, error) {
+	startDate := start.Format(r.dateLayout())
+	endDate := end.Format(r.dateLayout())
+
+	first, err := r.Client.GetOrderList(ctx, 1, r.perPage(), startDate, endDate, r.OrganizationID, "")
+	if err != nil {
+		return nil, fmt.Errorf("reconcile: list orders page 1: %w", err)
+	}
+	records, err := decodeRecords(first.Rows)
+	if err != nil {
+		return nil, fmt.Errorf("reconcile: decode orders page 1: %w", err)
+	}
+
+	pages := make([][]Record, max(first.TotalPages-1, 0))
+	err = parallel.Each(ctx, r.workers(), len(pages), func(ctx context.Context, i int) error {
+		page := i + 2
+		res, err := r.Client.GetOrderList(ctx, page, r.perPage(), startDate, endDate, r.OrganizationID, "")
+		if err != nil {
+			return fmt.Errorf("reconcile: list orders page %d: %w", page, err)
+		}
+		rows, err := decodeRecords(res.Rows)
+		if err != nil {
+			return fmt.Errorf("reconcile: decode orders page %d: %w", page, err)
+		}
+		pages[i] = rows
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, rows := range pages {
+		records = append(records, rows...)
+	}
+
+	err = parallel.Each(ctx, r.workers(), len(records), func(ctx context.Context, i int) error {
+		res, err := r.Client.GetOrderPayments(ctx, tapsilat.GetOrderPaymentsRequest{
+			OrderReferenceID: records[i].ReferenceID,
+		})
+		if err != nil {
+			return fmt.Errorf("reconcile: payments for %s: %w", records[i].ReferenceID, err)
+		}
+		records[i].Payments = res.Payments
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func (r *Reconciler) compare(entries []LedgerEntry, records []Record) *Report {
+	report := &Report{}
+	index := newRecordIndex(records)
+	matched := make([]bool, len(records))
+
+	for _, entry := range entries {
+		pos, key := index.lookup(entry)
+		if pos < 0 {
+			ledger := entry
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{
+				Kind:   KindMissing,
+				Key:    key,
+				Ledger: &ledger,
+				Detail: "no Tapsilat order matches the ledger entry",
+			})
+			continue
+		}
+		ledger := entry
+		remote := records[pos]
+		if matched[pos] {
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{
+				Kind:   KindDuplicate,
+				Key:    key,
+				Ledger: &ledger,
+				Remote: &remote,
+				Detail: "an earlier ledger entry already matches this Tapsilat order",
+			})
+			continue
+		}
+		matched[pos] = true
+		report.Matched++
+
+		if entry.Currency != "" && remote.Currency != "" && currencyCode(entry.Currency) != currencyCode(remote.Currency) {
+			// Amounts in different currencies are not compared.
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{
+				Kind:   KindCurrencyMismatch,
+				Key:    key,
+				Ledger: &ledger,
+				Remote: &remote,
+				Detail: fmt.Sprintf("ledger currency %s, Tapsilat currency %s", entry.Currency, remote.Currency),
+			})
+		} else if settled := remote.SettledAmount(); math.Abs(settled-entry.Amount) > r.Tolerance {
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{
+				Kind:   KindAmountMismatch,
+				Key:    key,
+				Ledger: &ledger,
+				Remote: &remote,
+				Detail: fmt.Sprintf("ledger amount %.2f, settled amount %.2f", entry.Amount, settled),
+			})
+		}
+		if entry.Status != "" && !r.statusEqual(entry.Status, remote.Status) {
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{
+				Kind:   KindStatusMismatch,
+				Key:    key,
+				Ledger: &ledger,
+				Remote: &remote,
+				Detail: fmt.Sprintf("ledger status %q, Tapsilat status %q", entry.Status, remote.Status),
+			})
+		}
+	}
+
+	for i, record := range records {
+		if matched[i] {
+			continue
+		}
+		remote := record
+		report.Discrepancies = append(report.Discrepancies, Discrepancy{
+			Kind:   KindExtra,
+			Key:    recordKey(record),
+			Remote: &remote,
+			Detail: "Tapsilat order has no ledger entry",
+		})
+	}
+
+	return report
+}
+
+func (r *Reconciler) statusEqual(ledger, remote string) bool {
+	if r.StatusEqual != nil {
+		return r.StatusEqual(ledger, remote)
+	}
+	return DefaultStatusEqual(ledger, remote)
+}
+
+func (r *Reconciler) workers() int {
+	if r.Workers <= 0 {
+		return 1
+	}
+	return r.Workers
+}
+
+func (r *Reconciler) perPage() int {
+	if r.PerPage <= 0 {
+		return 100
+	}
+	return r.PerPage
+}
+
+func (r *Reconciler) dateLayout() string {
+	if r.DateLayout == "" {
+		return "2006-01-02"
+	}
+	return r.DateLayout
+}
+
+// DefaultStatusEqual compares two order statuses case-insensitively. Numeric
+// statuses are translated through tapsilat.OrderStatuesMap first.
+func DefaultStatusEqual(ledger, remote string) bool {
+	return strings.EqualFold(statusName(ledger), statusName(remote))
+}
+
+// currencyCode returns the alphabetic code of an alphabetic or numeric
+// currency code, so "949" and "try" both compare as "TRY".
+func currencyCode(code string) string {
+	if info, ok := tapsilat.LookupCurrency(code); ok {
+		return info.Code
+	}
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func statusName(status string) string {
+	status = strings.TrimSpace(status)
+	id, err := strconv.Atoi(status)
+	if err != nil {
+		return status
+	}
+	for _, v := range tapsilat.OrderStatuesMap {
+		if v.Id == id {
+			return v.Status
+		}
+	}
+	return status
+}
+
+type recordIndex struct {
+	byReference    map[string]int
+	byConversation map[string]int
+	byExternal     map[string]int
+}
+
+func newRecordIndex(records []Record) recordIndex {
+	index := recordIndex{
+		byReference:    make(map[string]int, len(records)),
+		byConversation: make(map[string]int, len(records)),
+		byExternal:     make(map[string]int, len(records)),
+	}
+	for i, record := range records {
+		if record.ReferenceID != "" {
+			index.byReference[record.ReferenceID] = i
+		}
+		if record.ConversationID != "" {
+			index.byConversation[record.ConversationID] = i
+		}
+		if record.ExternalReferenceID != "" {
+			index.byExternal[record.ExternalReferenceID] = i
+		}
+	}
+	return index
+}
+
+func (idx recordIndex) lookup(entry LedgerEntry) (int, string) {
+	if entry.ConversationID != "" {
+		if pos, ok := idx.byConversation[entry.ConversationID]; ok {
+			return pos, entry.ConversationID
+		}
+	}
+	if entry.ReferenceID != "" {
+		if pos, ok := idx.byReference[entry.ReferenceID]; ok {
+			return pos, entry.ReferenceID
+		}
+	}
+	if entry.ExternalReferenceID != "" {
+		if pos, ok := idx.byExternal[entry.ExternalReferenceID]; ok {
+			return pos, entry.ExternalReferenceID
+		}
+	}
+	return -1, ledgerKey(entry)
+}
+
+func ledgerKey(entry LedgerEntry) string {
+	switch {
+	case entry.ConversationID != "":
+		return entry.ConversationID
+	case entry.ReferenceID != "":
+		return entry.ReferenceID
+	default:
+		return entry.ExternalReferenceID
+	}
+}
+
+func recordKey(record Record) string {
+	switch {
+	case record.ConversationID != "":
+		return record.ConversationID
+	case record.ReferenceID != "":
+		return record.ReferenceID
+	default:
+		return record.ExternalReferenceID
+	}
+}
+
+// orderRow is the subset of an order list row the reconciler needs. Amounts
+// and statuses are returned either as strings or numbers depending on the
+// endpoint version, so they are decoded loosely.
+type orderRow struct {
+	ReferenceID         string      `json:"reference_id"`
+	ConversationID      string      `json:"conversation_id"`
+	ExternalReferenceID string      `json:"external_reference_id"`
+	Amount              looseAmount `json:"amount"`
+	PaidAmount          looseAmount `json:"paid_amount"`
+	RefundedAmount      looseAmount `json:"refunded_amount"`
+	Currency            string      `json:"currency"`
+	Status              looseString `json:"status"`
+	StatusEnum          string      `json:"status_enum"`
+	CreatedAt           string      `json:"created_at"`
+}
+
+func decodeRecords(rows any) ([]Record, error) {
+	if rows == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(rows)
+	if err != nil {
+		return nil, err
+	}
+	var decoded []orderRow
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return nil, err
+	}
+
+	records := make([]Record, 0, len(decoded))
+	for _, row := range decoded {
+		status := string(row.Status)
+		if row.StatusEnum != "" {
+			status = row.StatusEnum
+		}
+		records = append(records, Record{
+			ReferenceID:         row.ReferenceID,
+			ConversationID:      row.ConversationID,
+			ExternalReferenceID: row.ExternalReferenceID,
+			Amount:              float64(row.Amount),
+			PaidAmount:          float64(row.PaidAmount),
+			RefundedAmount:      float64(row.RefundedAmount),
+			Currency:            row.Currency,
+			Status:              status,
+			CreatedAt:           row.CreatedAt,
+		})
+	}
+	return records, nil
+}
+
+// looseAmount accepts amounts encoded as JSON numbers or numeric strings.
+type looseAmount float64
+
+func (a *looseAmount) UnmarshalJSON(data []byte) error {
+	text := strings.Trim(string(data), `"`)
+	if text == "" || text == "null" {
+		return nil
+	}
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return fmt.Errorf("invalid amount %s", data)
+	}
+	*a = looseAmount(value)
+	return nil
+}
+
+// looseString accepts both JSON strings and numbers.
+type looseString string
+
+func (s *looseString) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		*s = looseString(str)
+		return nil
+	}
+	var num json.Number
+	if err := json.Unmarshal(data, &num); err != nil {
+		return err
+	}
+	*s = looseString(num.String())
+	return nil
+}
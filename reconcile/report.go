@@ -0,0 +1,140 @@
+package reconcile
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+// DiscrepancyKind classifies a reconciliation difference.
+type DiscrepancyKind string
+
+const (
+	// KindMissing is a ledger entry with no matching Tapsilat order.
+	KindMissing DiscrepancyKind = "missing"
+	// KindExtra is a Tapsilat order with no matching ledger entry.
+	KindExtra DiscrepancyKind = "extra"
+	// KindAmountMismatch is a matched pair whose settled amounts differ.
+	KindAmountMismatch DiscrepancyKind = "amount_mismatch"
+	// KindStatusMismatch is a matched pair whose statuses differ.
+	KindStatusMismatch DiscrepancyKind = "status_mismatch"
+	// KindCurrencyMismatch is a matched pair whose currencies differ. Their
+	// amounts are not compared.
+	KindCurrencyMismatch DiscrepancyKind = "currency_mismatch"
+	// KindDuplicate is a ledger entry matching the same Tapsilat order as an
+	// earlier entry. Only the earlier entry is compared and counted as matched.
+	KindDuplicate DiscrepancyKind = "duplicate"
+)
+
+// Discrepancy describes a single difference between the ledger and Tapsilat.
+type Discrepancy struct {
+	Kind   DiscrepancyKind `json:"kind"`
+	Key    string          `json:"key"`
+	Ledger *LedgerEntry    `json:"ledger,omitempty"`
+	Remote *Record         `json:"remote,omitempty"`
+	Detail string          `json:"detail,omitempty"`
+}
+
+// Report is the result of a reconciliation run.
+type Report struct {
+	Start         time.Time     `json:"start"`
+	End           time.Time     `json:"end"`
+	Matched       int           `json:"matched"`
+	Discrepancies []Discrepancy `json:"discrepancies"`
+}
+
+// Count returns the number of discrepancies of the given kind.
+func (r *Report) Count(kind DiscrepancyKind) int {
+	count := 0
+	for _, d := range r.Discrepancies {
+		if d.Kind == kind {
+			count++
+		}
+	}
+	return count
+}
+
+// WriteJSON writes the report as an indented JSON document.
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+var csvHeader = []string{
+	"kind",
+	"key",
+	"reference_id",
+	"conversation_id",
+	"external_reference_id",
+	"ledger_amount",
+	"remote_amount",
+	"currency",
+	"ledger_status",
+	"remote_status",
+	"detail",
+}
+
+// WriteCSV writes one row per discrepancy, preceded by a header row.
+func (r *Report) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, d := range r.Discrepancies {
+		var referenceID, conversationID, externalReferenceID, currency string
+		var ledgerAmount, remoteAmount, ledgerStatus, remoteStatus string
+		if d.Ledger != nil {
+			referenceID = d.Ledger.ReferenceID
+			conversationID = d.Ledger.ConversationID
+			externalReferenceID = d.Ledger.ExternalReferenceID
+			currency = d.Ledger.Currency
+			ledgerAmount = formatAmount(d.Ledger.Amount)
+			ledgerStatus = d.Ledger.Status
+		}
+		if d.Remote != nil {
+			referenceID = firstNonEmpty(d.Remote.ReferenceID, referenceID)
+			conversationID = firstNonEmpty(d.Remote.ConversationID, conversationID)
+			externalReferenceID = firstNonEmpty(d.Remote.ExternalReferenceID, externalReferenceID)
+			currency = firstNonEmpty(d.Remote.Currency, currency)
+			remoteAmount = formatAmount(d.Remote.SettledAmount())
+			remoteStatus = d.Remote.Status
+		}
+
+		row := []string{
+			string(d.Kind),
+			d.Key,
+			referenceID,
+			conversationID,
+			externalReferenceID,
+			ledgerAmount,
+			remoteAmount,
+			currency,
+			ledgerStatus,
+			remoteStatus,
+			d.Detail,
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,355 @@
+package tapsilat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultReferenceCacheTTL is how long reference data is cached when
+// API.ReferenceCacheTTL is zero.
+const DefaultReferenceCacheTTL = time.Hour
+
+// DefaultRefCacheLoadTimeout bounds a RefCache load when
+// RefCache.LoadTimeout is zero.
+const DefaultRefCacheLoadTimeout = 30 * time.Second
+
+// RefCache caches a single reference-data value such as the organization
+// currency list. Concurrent misses share one load, values expire after TTL,
+// and when PersistPath is set the value is also kept on disk so it survives
+// restarts. Fields must be set before the first call to Get.
+type RefCache[T any] struct {
+	Name string
+	// TTL is how long a loaded value is used. Zero or a negative TTL never
+	// expires it.
+	TTL         time.Duration
+	PersistPath string
+	Load        func(ctx context.Context) (T, error)
+	// LoadTimeout bounds each Load. A load is shared by every waiter, so it
+	// runs on its own context, without the values or cancellation of the
+	// caller that started it. Zero uses DefaultRefCacheLoadTimeout.
+	LoadTimeout time.Duration
+	Now         func() time.Time
+
+	mu       sync.Mutex
+	value    T
+	loadedAt time.Time
+	ready    bool
+	diskRead bool
+	gen      uint64
+	inflight *refCacheCall[T]
+	stats    RefCacheStats
+}
+
+// RefCacheStats reports how a RefCache has been used.
+type RefCacheStats struct {
+	Name string `json:"name"`
+	// Hits counts Get calls served from the cached value.
+	Hits uint64 `json:"hits"`
+	// Misses counts Get calls that found no value or an expired one.
+	Misses uint64 `json:"misses"`
+	// Shared counts calls that joined a load already in flight.
+	Shared uint64 `json:"shared"`
+	// Loads and LoadErrors count completed loads and failed loads.
+	Loads      uint64 `json:"loads"`
+	LoadErrors uint64 `json:"load_errors"`
+	// Refreshes counts explicit Refresh calls.
+	Refreshes     uint64    `json:"refreshes"`
+	Invalidations uint64    `json:"invalidations"`
+	DiskHits      uint64    `json:"disk_hits"`
+	LoadedAt      time.Time `json:"loaded_at,omitzero"`
+	ExpiresAt     time.Time `json:"expires_at,omitzero"`
+}
+
+type refCacheCall[T any] struct {
+	gen   uint64
+	done  chan struct{}
+	value T
+	err   error
+}
+
+type refCacheFile[T any] struct {
+	LoadedAt time.Time `json:"loaded_at"`
+	Value    T         `json:"value"`
+}
+
+// NewRefCache creates a RefCache that loads its value with load.
+func NewRefCache[T any](name string, ttl time.Duration, load func(ctx context.Context) (T, error)) *RefCache[T] {
+	return &RefCache[T]{Name: name, TTL: ttl, Load: load}
+}
+
+// Get returns the cached value, loading it when missing or expired.
+func (c *RefCache[T]) Get(ctx context.Context) (T, error) {
+	c.mu.Lock()
+	if !c.ready && !c.diskRead {
+		c.diskRead = true
+		c.readDisk()
+	}
+	if c.ready && !c.expired() {
+		value := c.value
+		c.stats.Hits++
+		c.mu.Unlock()
+		return value, nil
+	}
+	c.stats.Misses++
+	call := c.startLoad()
+	c.mu.Unlock()
+
+	return c.wait(ctx, call)
+}
+
+// Refresh loads a fresh value regardless of expiry. A refresh that starts
+// while another load is in flight joins that load.
+func (c *RefCache[T]) Refresh(ctx context.Context) (T, error) {
+	c.mu.Lock()
+	c.stats.Refreshes++
+	call := c.startLoad()
+	c.mu.Unlock()
+
+	return c.wait(ctx, call)
+}
+
+// Invalidate drops the cached value, including the persisted copy.
+func (c *RefCache[T]) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero T
+	c.value = zero
+	c.loadedAt = time.Time{}
+	c.ready = false
+	c.diskRead = true
+	c.stats.Invalidations++
+	// Loads started before the invalidation must not repopulate the cache.
+	c.gen++
+	c.inflight = nil
+	if c.PersistPath != "" {
+		_ = os.Remove(c.PersistPath)
+	}
+}
+
+// LoadedAt returns when the cached value was loaded, or the zero time.
+func (c *RefCache[T]) LoadedAt() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.loadedAt
+}
+
+// Stats returns a snapshot of the cache counters.
+func (c *RefCache[T]) Stats() RefCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Name = c.Name
+	if c.ready {
+		stats.LoadedAt = c.loadedAt
+		if c.TTL > 0 {
+			stats.ExpiresAt = c.loadedAt.Add(c.TTL)
+		}
+	}
+	return stats
+}
+
+func (c *RefCache[T]) expired() bool {
+	return c.TTL > 0 && c.now().Sub(c.loadedAt) > c.TTL
+}
+
+// startLoad returns the in-flight load, starting one if needed. c.mu must be held.
+func (c *RefCache[T]) startLoad() *refCacheCall[T] {
+	if c.inflight != nil {
+		c.stats.Shared++
+		return c.inflight
+	}
+	call := &refCacheCall[T]{gen: c.gen, done: make(chan struct{})}
+	c.inflight = call
+
+	// The load is shared by every waiter, so it must not be canceled by one
+	// caller giving up, nor carry one caller's token, headers or response
+	// capture.
+	timeout := c.LoadTimeout
+	if timeout <= 0 {
+		timeout = DefaultRefCacheLoadTimeout
+	}
+	go func() {
+		loadCtx, cancel := context.WithTimeout(context.Background(), timeout)
+		value, err := c.Load(loadCtx)
+		cancel()
+
+		c.mu.Lock()
+		c.stats.Loads++
+		if err != nil {
+			c.stats.LoadErrors++
+		}
+		if err == nil && call.gen == c.gen {
+			c.value = value
+			c.loadedAt = c.now()
+			c.ready = true
+			c.writeDisk()
+		}
+		if c.inflight == call {
+			c.inflight = nil
+		}
+		c.mu.Unlock()
+
+		call.value, call.err = value, err
+		close(call.done)
+	}()
+	return call
+}
+
+func (c *RefCache[T]) wait(ctx context.Context, call *refCacheCall[T]) (T, error) {
+	select {
+	case <-call.done:
+		return call.value, call.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// readDisk loads a persisted value if it exists and is still fresh. c.mu must be held.
+func (c *RefCache[T]) readDisk() {
+	if c.PersistPath == "" {
+		return
+	}
+	data, err := os.ReadFile(c.PersistPath)
+	if err != nil {
+		return
+	}
+	var file refCacheFile[T]
+	if json.Unmarshal(data, &file) != nil {
+		return
+	}
+	c.value = file.Value
+	c.loadedAt = file.LoadedAt
+	c.ready = true
+	if c.expired() {
+		var zero T
+		c.value = zero
+		c.ready = false
+		return
+	}
+	c.stats.DiskHits++
+}
+
+// writeDisk persists the current value. Failures only cost a reload on the
+// next start, so they are ignored. c.mu must be held.
+func (c *RefCache[T]) writeDisk() {
+	if c.PersistPath == "" {
+		return
+	}
+	data, err := json.Marshal(refCacheFile[T]{LoadedAt: c.loadedAt, Value: c.value})
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(c.PersistPath), 0o755); err != nil {
+		return
+	}
+	tmp := c.PersistPath + ".tmp"
+	if os.WriteFile(tmp, data, 0o600) != nil {
+		return
+	}
+	_ = os.Rename(tmp, c.PersistPath)
+}
+
+func (c *RefCache[T]) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+// referenceCaches groups the reference-data caches owned by an API client.
+type referenceCaches struct {
+	currencies        *RefCache[OrganizationCurrenciesResponse]
+	currencyPresets   *RefCache[OrganizationCurrencyPresetsResponse]
+	acquirers         *RefCache[VposAcquirerListResponse]
+	cardSchemes       *RefCache[CardSchemeListResponse]
+	acquirerTemplates *RefCache[VposAcquirerTemplateListResponse]
+}
+
+func (t *API) references() *referenceCaches {
+	t.refsOnce.Do(func() {
+		ttl := t.ReferenceCacheTTL
+		if ttl == 0 {
+			ttl = DefaultReferenceCacheTTL
+		}
+		currencyTTL := t.CurrencyCacheTTL
+		if currencyTTL == 0 {
+			currencyTTL = ttl
+		}
+		persist := func(name string) string {
+			if t.ReferenceCacheDir == "" {
+				return ""
+			}
+			return filepath.Join(t.ReferenceCacheDir, name+".json")
+		}
+
+		t.refs = &referenceCaches{
+			currencies:        NewRefCache("currencies", currencyTTL, t.GetOrganizationCurrencies),
+			currencyPresets:   NewRefCache("currency_presets", ttl, t.ListOrganizationCurrencyPresets),
+			acquirers:         NewRefCache("vpos_acquirers", ttl, t.ListVposAcquirers),
+			cardSchemes:       NewRefCache("card_schemes", ttl, t.ListCardSchemes),
+			acquirerTemplates: NewRefCache("vpos_acquirer_templates", ttl, t.ListVposAcquirerTemplates),
+		}
+		t.refs.currencies.Now = t.Now
+		t.refs.currencyPresets.Now = t.Now
+		t.refs.acquirers.Now = t.Now
+		t.refs.cardSchemes.Now = t.Now
+		t.refs.acquirerTemplates.Now = t.Now
+		// Loads run without the caller's context, so bound them by the
+		// client's own timeout.
+		t.refs.currencies.LoadTimeout = t.Timeout
+		t.refs.currencyPresets.LoadTimeout = t.Timeout
+		t.refs.acquirers.LoadTimeout = t.Timeout
+		t.refs.cardSchemes.LoadTimeout = t.Timeout
+		t.refs.acquirerTemplates.LoadTimeout = t.Timeout
+		t.refs.currencies.PersistPath = persist(t.refs.currencies.Name)
+		t.refs.currencyPresets.PersistPath = persist(t.refs.currencyPresets.Name)
+		t.refs.acquirers.PersistPath = persist(t.refs.acquirers.Name)
+		t.refs.cardSchemes.PersistPath = persist(t.refs.cardSchemes.Name)
+		t.refs.acquirerTemplates.PersistPath = persist(t.refs.acquirerTemplates.Name)
+	})
+	return t.refs
+}
+
+// RefreshReferenceData reloads every cached reference data set.
+func (t *API) RefreshReferenceData(ctx context.Context) error {
+	refs := t.references()
+	t.forgetCurrencyMisses()
+	_, currenciesErr := refs.currencies.Refresh(ctx)
+	_, presetsErr := refs.currencyPresets.Refresh(ctx)
+	_, acquirersErr := refs.acquirers.Refresh(ctx)
+	_, schemesErr := refs.cardSchemes.Refresh(ctx)
+	_, templatesErr := refs.acquirerTemplates.Refresh(ctx)
+	return errors.Join(currenciesErr, presetsErr, acquirersErr, schemesErr, templatesErr)
+}
+
+// ReferenceCacheStats returns the stats of every reference data cache keyed
+// by cache name.
+func (t *API) ReferenceCacheStats() map[string]RefCacheStats {
+	refs := t.references()
+	return map[string]RefCacheStats{
+		refs.currencies.Name:        refs.currencies.Stats(),
+		refs.currencyPresets.Name:   refs.currencyPresets.Stats(),
+		refs.acquirers.Name:         refs.acquirers.Stats(),
+		refs.cardSchemes.Name:       refs.cardSchemes.Stats(),
+		refs.acquirerTemplates.Name: refs.acquirerTemplates.Stats(),
+	}
+}
+
+// InvalidateReferenceData drops every cached reference data set.
+func (t *API) InvalidateReferenceData() {
+	refs := t.references()
+	refs.currencies.Invalidate()
+	refs.currencyPresets.Invalidate()
+	refs.acquirers.Invalidate()
+	refs.cardSchemes.Invalidate()
+	refs.acquirerTemplates.Invalidate()
+	t.forgetCurrencyMisses()
+}
@@ -0,0 +1,66 @@
+package tapsilat
+
+import (
+	"net/http"
+	"time"
+)
+
+// requestIDHeaders are the response headers that may carry the request ID,
+// in order of preference.
+var requestIDHeaders = []string{"X-Request-Id", "Request-Id", "X-Correlation-Id", "X-Trace-Id"}
+
+// Response describes the HTTP response behind a call. Get it with the
+// WithResponse option.
+type Response struct {
+	StatusCode int
+	Status     string
+	Header     http.Header
+	// RequestID is the request ID reported by the server, if any. Quote it in
+	// support tickets.
+	RequestID string
+	// Body is the raw response body, including fields the DTOs do not model.
+	Body []byte
+	// Latency is the time from sending the request to reading the whole
+	// response, including a retry after a 401.
+	Latency time.Duration
+	// Attempts is 2 when the request was retried after a 401.
+	Attempts int
+}
+
+// WithResponse stores the HTTP response of the call in *response, also when
+// the call fails with an *APIError. *response is left untouched when no
+// response arrives.
+//
+// A call that sends several requests stores the one carrying its result:
+// the create of CreateOrder rather than its checkout URL lookup, the final
+// PATCH of the Modify methods. Loaders that send requests concurrently, such
+// as LoadSuborganizationTree or the vposroute and submerchantsync loaders,
+// store whichever finishes last; the writes are synchronized, but read
+// *response only after the call returns. Reference data loaded in the
+// background for a cache is never stored.
+func WithResponse(response *Response) RequestOption {
+	return func(o *requestOptions) {
+		o.responses = append(o.responses, response)
+	}
+}
+
+func newResponse(resp *http.Response, body []byte, latency time.Duration, attempts int) Response {
+	return Response{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Header:     resp.Header,
+		RequestID:  requestID(resp.Header),
+		Body:       body,
+		Latency:    latency,
+		Attempts:   attempts,
+	}
+}
+
+func requestID(header http.Header) string {
+	for _, key := range requestIDHeaders {
+		if id := header.Get(key); id != "" {
+			return id
+		}
+	}
+	return ""
+}
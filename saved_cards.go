@@ -0,0 +1,59 @@
+package tapsilat
+
+import (
+	"context"
+	"errors"
+)
+
+// DefaultSavedCardsPerPage is the page size used when walking saved cards.
+const DefaultSavedCardsPerPage = 100
+
+// errStopWalk stops WalkSavedCards without reporting an error.
+var errStopWalk = errors.New("stop walking saved cards")
+
+// SavedCardLister is the part of *API used to page through saved cards.
+type SavedCardLister interface {
+	ListSavedCards(ctx context.Context, page, perPage int) (ListSavedCardsResponse, error)
+}
+
+// WalkSavedCards calls fn for every saved card, requesting ListSavedCards
+// page by page until the last page. Returning an error from fn stops the walk
+// and returns that error.
+func WalkSavedCards(ctx context.Context, lister SavedCardLister, perPage int, fn func(SavedCard) error) error {
+	if perPage <= 0 {
+		perPage = DefaultSavedCardsPerPage
+	}
+
+	for page := 1; ; page++ {
+		response, err := lister.ListSavedCards(ctx, page, perPage)
+		if err != nil {
+			return err
+		}
+		for _, card := range response.Rows {
+			if err := fn(card); err != nil {
+				return err
+			}
+		}
+		if len(response.Rows) == 0 || int64(page) >= response.TotalPages {
+			return nil
+		}
+	}
+}
+
+// FindSavedCard returns the saved card with the given ID, walking every page
+// of ListSavedCards. The boolean is false when no card matches.
+func FindSavedCard(ctx context.Context, lister SavedCardLister, id string) (SavedCard, bool, error) {
+	var found SavedCard
+	ok := false
+	err := WalkSavedCards(ctx, lister, 0, func(card SavedCard) error {
+		if card.ID != id {
+			return nil
+		}
+		found, ok = card, true
+		return errStopWalk
+	})
+	if errors.Is(err, errStopWalk) {
+		err = nil
+	}
+	return found, ok, err
+}
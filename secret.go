@@ -0,0 +1,278 @@
+package tapsilat
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sync"
+)
+
+const redactedSecret = "[REDACTED]"
+
+// Secret is a credential such as a VPOS password or the API token. It prints
+// as [REDACTED] with fmt, log/slog and encoding/json, so that DTOs can be
+// logged and dumped as they are; an empty Secret prints as empty. Request
+// bodies built by the API still carry the value. Use Reveal to read it.
+//
+// Because MarshalJSON redacts, JSON does not round-trip a Secret: a Vpos, a
+// vposroute.Snapshot or any other value holding one that is persisted as
+// JSON reads back with "[REDACTED]" in place of the credential. Store the
+// revealed values separately when they must survive.
+type Secret string
+
+// Reveal returns the secret value.
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redactedSecret
+}
+
+// GoString redacts the value for %#v.
+func (s Secret) GoString() string {
+	return fmt.Sprintf("tapsilat.Secret(%q)", s.String())
+}
+
+// Format redacts the value for every verb, including %s, %q, %x and %#v.
+func (s Secret) Format(f fmt.State, verb rune) {
+	if verb == 'v' && f.Flag('#') {
+		_, _ = fmt.Fprint(f, s.GoString())
+		return
+	}
+	_, _ = fmt.Fprintf(f, fmt.FormatString(f, verb), s.String())
+}
+
+// LogValue redacts the value in log/slog output.
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(s.String())
+}
+
+// MarshalJSON redacts the value. The API reveals secrets in request bodies
+// itself, see MarshalRequest.
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+var secretType = reflect.TypeFor[Secret]()
+
+// MarshalRequest encodes payload as the API sends it: like json.Marshal, but
+// with Secret values revealed. The payload is copied into a mirror type that
+// has a string wherever the original has a Secret, with the same field order
+// and tags. A Secret the mirror cannot reach, behind an interface, as a map
+// key or in a struct with unexported fields, is an error rather than being
+// sent as [REDACTED].
+func MarshalRequest(payload any) ([]byte, error) {
+	value := reflect.ValueOf(payload)
+	if !value.IsValid() {
+		return json.Marshal(payload)
+	}
+	entry := revealedType(value.Type())
+	if entry.err != nil {
+		return nil, entry.err
+	}
+	if entry.dynamic {
+		if err := checkDynamicSecrets(value, map[uintptr]bool{}); err != nil {
+			return nil, err
+		}
+	}
+	if !entry.ok {
+		return json.Marshal(payload)
+	}
+	return json.Marshal(revealSecrets(value, entry.typ).Interface())
+}
+
+var revealedTypes sync.Map // reflect.Type -> revealedTypeEntry
+
+type revealedTypeEntry struct {
+	// typ is the mirror type; ok reports that it differs from the original.
+	typ reflect.Type
+	ok  bool
+	// dynamic reports that values of the type can hold interfaces, whose
+	// contents are checked at run time.
+	dynamic bool
+	err     error
+}
+
+// revealedType returns the mirror type of typ with Secret replaced by string.
+func revealedType(typ reflect.Type) revealedTypeEntry {
+	if entry, ok := revealedTypes.Load(typ); ok {
+		return entry.(revealedTypeEntry)
+	}
+	entry := buildRevealedType(typ, map[reflect.Type]bool{})
+	revealedTypes.Store(typ, entry)
+	return entry
+}
+
+func buildRevealedType(typ reflect.Type, visiting map[reflect.Type]bool) revealedTypeEntry {
+	if entry, ok := revealedTypes.Load(typ); ok {
+		return entry.(revealedTypeEntry)
+	}
+	entry := revealedTypeEntry{typ: typ}
+	if visiting[typ] {
+		// A recursive type is mirrored as itself below its first level.
+		return entry
+	}
+	visiting[typ] = true
+	defer delete(visiting, typ)
+
+	switch typ.Kind() {
+	case reflect.String:
+		if typ == secretType {
+			entry.typ, entry.ok = reflect.TypeFor[string](), true
+		}
+	case reflect.Interface:
+		entry.dynamic = true
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		elem := buildRevealedType(typ.Elem(), visiting)
+		entry.dynamic, entry.err = elem.dynamic, elem.err
+		if elem.ok {
+			entry.ok = true
+			switch typ.Kind() {
+			case reflect.Pointer:
+				entry.typ = reflect.PointerTo(elem.typ)
+			case reflect.Slice:
+				entry.typ = reflect.SliceOf(elem.typ)
+			default:
+				entry.typ = reflect.ArrayOf(typ.Len(), elem.typ)
+			}
+		}
+	case reflect.Map:
+		key := buildRevealedType(typ.Key(), visiting)
+		elem := buildRevealedType(typ.Elem(), visiting)
+		entry.dynamic = key.dynamic || elem.dynamic
+		switch {
+		case key.ok || key.err != nil:
+			entry.err = fmt.Errorf("tapsilat: cannot reveal Secret map keys of %s", typ)
+		case elem.err != nil:
+			entry.err = elem.err
+		case elem.ok:
+			entry.typ, entry.ok = reflect.MapOf(typ.Key(), elem.typ), true
+		}
+	case reflect.Struct:
+		fields := make([]reflect.StructField, typ.NumField())
+		unexported := false
+		for i := range fields {
+			field := typ.Field(i)
+			fields[i] = field
+			if !field.IsExported() {
+				unexported = true
+				continue
+			}
+			fieldEntry := buildRevealedType(field.Type, visiting)
+			if fieldEntry.err != nil {
+				entry.err = fieldEntry.err
+				return entry
+			}
+			entry.dynamic = entry.dynamic || fieldEntry.dynamic
+			if fieldEntry.ok {
+				fields[i].Type, entry.ok = fieldEntry.typ, true
+			}
+		}
+		if entry.ok && unexported {
+			entry.ok, entry.err = false, fmt.Errorf("tapsilat: cannot reveal Secret fields of %s, which has unexported fields", typ)
+			return entry
+		}
+		if entry.ok {
+			entry.typ = reflect.StructOf(fields)
+		}
+	}
+	return entry
+}
+
+// checkDynamicSecrets fails when an interface in value holds a Secret, which
+// json.Marshal would send as [REDACTED].
+func checkDynamicSecrets(value reflect.Value, seen map[uintptr]bool) error {
+	switch value.Kind() {
+	case reflect.Interface:
+		if value.IsNil() {
+			return nil
+		}
+		elem := value.Elem()
+		entry := revealedType(elem.Type())
+		switch {
+		case entry.err != nil:
+			return entry.err
+		case entry.ok:
+			return fmt.Errorf("tapsilat: cannot reveal a Secret held in an interface (%s); use a typed field", elem.Type())
+		case entry.dynamic:
+			return checkDynamicSecrets(elem, seen)
+		}
+	case reflect.Pointer:
+		if value.IsNil() || seen[value.Pointer()] {
+			return nil
+		}
+		seen[value.Pointer()] = true
+		return checkDynamicSecrets(value.Elem(), seen)
+	case reflect.Slice, reflect.Array:
+		for i := range value.Len() {
+			if err := checkDynamicSecrets(value.Index(i), seen); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := value.MapRange()
+		for iter.Next() {
+			if err := checkDynamicSecrets(iter.Key(), seen); err != nil {
+				return err
+			}
+			if err := checkDynamicSecrets(iter.Value(), seen); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		for i := range value.NumField() {
+			if value.Type().Field(i).IsExported() {
+				if err := checkDynamicSecrets(value.Field(i), seen); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func revealSecrets(value reflect.Value, revealed reflect.Type) reflect.Value {
+	if value.Type() == revealed {
+		return value
+	}
+	out := reflect.New(revealed).Elem()
+	switch value.Kind() {
+	case reflect.String:
+		out.SetString(value.String())
+	case reflect.Pointer:
+		if !value.IsNil() {
+			elem := reflect.New(revealed.Elem())
+			elem.Elem().Set(revealSecrets(value.Elem(), revealed.Elem()))
+			out.Set(elem)
+		}
+	case reflect.Slice:
+		if !value.IsNil() {
+			out.Set(reflect.MakeSlice(revealed, value.Len(), value.Len()))
+			for i := range value.Len() {
+				out.Index(i).Set(revealSecrets(value.Index(i), revealed.Elem()))
+			}
+		}
+	case reflect.Array:
+		for i := range value.Len() {
+			out.Index(i).Set(revealSecrets(value.Index(i), revealed.Elem()))
+		}
+	case reflect.Map:
+		if !value.IsNil() {
+			out.Set(reflect.MakeMapWithSize(revealed, value.Len()))
+			iter := value.MapRange()
+			for iter.Next() {
+				out.SetMapIndex(iter.Key(), revealSecrets(iter.Value(), revealed.Elem()))
+			}
+		}
+	case reflect.Struct:
+		for i := range value.NumField() {
+			out.Field(i).Set(revealSecrets(value.Field(i), revealed.Field(i).Type))
+		}
+	}
+	return out
+}
@@ -0,0 +1,175 @@
+package tapsilat
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// Submerchant types accepted in SubmerchantType.
+const (
+	SubmerchantTypePersonal            = "PERSONAL"
+	SubmerchantTypePrivateCompany      = "PRIVATE_COMPANY"
+	SubmerchantTypeLimitedOrJointStock = "LIMITED_OR_JOINT_STOCK_COMPANY"
+)
+
+// submerchantRequiredFields lists the JSON fields each submerchant type must
+// fill in, in addition to submerchantCommonFields.
+var submerchantRequiredFields = map[string][]string{
+	// A person selling under their own name: identified by their TCKN.
+	SubmerchantTypePersonal: {"identity_number", "contact_name", "contact_surname"},
+	// A sole proprietorship: the owner's TCKN plus the registered title.
+	SubmerchantTypePrivateCompany: {"identity_number", "tax_office", "legal_company_title"},
+	// A limited or joint-stock company: identified by the company VKN.
+	SubmerchantTypeLimitedOrJointStock: {"tax_number", "tax_office", "legal_company_title"},
+}
+
+var submerchantCommonFields = []string{
+	"name", "email", "gsm_number", "address", "iban", "currency_id", "sub_merchant_external_id", "sub_merchant_type",
+}
+
+// ValidateSubmerchant checks a submerchant before onboarding without calling
+// the API: required fields for its SubmerchantType, the IBAN (mod-97), the
+// TCKN and VKN check digits, the email and the GSM number. It returns the
+// request with those fields normalized, or a *FieldValidationError listing
+// every missing and invalid field. CurrencyID is only checked for presence;
+// use (*API).PrepareSubmerchant to resolve it.
+func ValidateSubmerchant(payload SubmerchantCreateRequest) (SubmerchantCreateRequest, error) {
+	var issues []FieldIssue
+	invalid := func(field string, err error) {
+		message := err.Error()
+		var validationErr *ValidationError
+		if errors.As(err, &validationErr) {
+			message = validationErr.Message
+		}
+		issues = append(issues, FieldIssue{Field: field, Reason: FieldInvalid, Message: message})
+	}
+
+	payload.SubmerchantType = strings.ToUpper(strings.TrimSpace(payload.SubmerchantType))
+	required := submerchantCommonFields
+	if typeFields, ok := submerchantRequiredFields[payload.SubmerchantType]; ok {
+		required = append(append([]string(nil), required...), typeFields...)
+	} else if payload.SubmerchantType != "" {
+		issues = append(issues, FieldIssue{
+			Field:   "sub_merchant_type",
+			Reason:  FieldInvalid,
+			Message: "must be one of " + SubmerchantTypePersonal + ", " + SubmerchantTypePrivateCompany + ", " + SubmerchantTypeLimitedOrJointStock,
+		})
+	}
+
+	values := submerchantFieldValues(payload)
+	for _, field := range required {
+		if strings.TrimSpace(values[field]) == "" {
+			issues = append(issues, FieldIssue{Field: field, Reason: FieldMissing, Message: "is required"})
+		}
+	}
+
+	if payload.Email != "" {
+		if email, err := ValidateEmail(payload.Email); err != nil {
+			invalid("email", err)
+		} else {
+			payload.Email = email
+		}
+	}
+	if payload.GsmNumber != "" {
+		if gsm, err := ValidateGSMNumber(payload.GsmNumber); err != nil {
+			invalid("gsm_number", err)
+		} else {
+			payload.GsmNumber = gsm
+		}
+	}
+	if payload.Iban != "" {
+		if iban, err := ValidateIBAN(payload.Iban); err != nil {
+			invalid("iban", err)
+		} else {
+			payload.Iban = iban
+		}
+	}
+	if payload.IdentityNumber != "" {
+		if id, err := ValidateTCKN(payload.IdentityNumber); err != nil {
+			invalid("identity_number", err)
+		} else {
+			payload.IdentityNumber = id
+		}
+	}
+	if payload.TaxNumber != "" {
+		// Sole proprietors may use their TCKN as tax number.
+		vkn, err := ValidateVKN(payload.TaxNumber)
+		if err != nil && payload.SubmerchantType == SubmerchantTypePrivateCompany {
+			if tckn, tcknErr := ValidateTCKN(payload.TaxNumber); tcknErr == nil {
+				vkn, err = tckn, nil
+			}
+		}
+		if err != nil {
+			invalid("tax_number", err)
+		} else {
+			payload.TaxNumber = vkn
+		}
+	}
+
+	if len(issues) > 0 {
+		return payload, &FieldValidationError{Issues: issues}
+	}
+	return payload, nil
+}
+
+// PrepareSubmerchant validates a submerchant like ValidateSubmerchant and
+// resolves CurrencyID from an ID or currency code via ResolveCurrencyID. An
+// unknown currency is reported as an invalid currency_id field.
+func (t *API) PrepareSubmerchant(ctx context.Context, payload SubmerchantCreateRequest) (SubmerchantCreateRequest, error) {
+	payload, err := ValidateSubmerchant(payload)
+	var fieldErr *FieldValidationError
+	if err != nil && !errors.As(err, &fieldErr) {
+		return payload, err
+	}
+
+	if strings.TrimSpace(payload.CurrencyID) != "" {
+		currencyID, resolveErr := t.ResolveCurrencyID(ctx, payload.CurrencyID)
+		var validationErr *ValidationError
+		switch {
+		case resolveErr == nil:
+			payload.CurrencyID = currencyID
+		case errors.As(resolveErr, &validationErr):
+			if fieldErr == nil {
+				fieldErr = &FieldValidationError{}
+			}
+			fieldErr.Issues = append(fieldErr.Issues, FieldIssue{Field: "currency_id", Reason: FieldInvalid, Message: validationErr.Message})
+		default:
+			return payload, resolveErr
+		}
+	}
+
+	if fieldErr != nil {
+		return payload, fieldErr
+	}
+	return payload, nil
+}
+
+// OnboardSubmerchant prepares a submerchant with PrepareSubmerchant and
+// creates it. Nothing is sent when validation fails.
+func (t *API) OnboardSubmerchant(ctx context.Context, payload SubmerchantCreateRequest) (SubmerchantMutationResponse, error) {
+	payload, err := t.PrepareSubmerchant(ctx, payload)
+	if err != nil {
+		return SubmerchantMutationResponse{}, err
+	}
+	return t.CreateSubmerchant(ctx, payload)
+}
+
+func submerchantFieldValues(payload SubmerchantCreateRequest) map[string]string {
+	return map[string]string{
+		"name":                     payload.Name,
+		"email":                    payload.Email,
+		"gsm_number":               payload.GsmNumber,
+		"address":                  payload.Address,
+		"iban":                     payload.Iban,
+		"tax_office":               payload.TaxOffice,
+		"legal_company_title":      payload.LegalCompanyTitle,
+		"currency_id":              payload.CurrencyID,
+		"sub_merchant_external_id": payload.SubmerchantExternalID,
+		"identity_number":          payload.IdentityNumber,
+		"sub_merchant_type":        payload.SubmerchantType,
+		"tax_number":               payload.TaxNumber,
+		"contact_name":             payload.ContactName,
+		"contact_surname":          payload.ContactSurname,
+	}
+}
@@ -0,0 +1,159 @@
+package submerchantsync
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Status is the outcome of a change.
+type Status string
+
+const (
+	StatusOK     Status = "ok"
+	StatusFailed Status = "failed"
+	// StatusSkipped is an unchanged, invalid or unmanaged change.
+	StatusSkipped Status = "skipped"
+	// StatusPlanned is a change reported by a dry run.
+	StatusPlanned Status = "planned"
+)
+
+// Result is the outcome of one planned change.
+type Result struct {
+	Row            int      `json:"row,omitempty"`
+	ExternalID     string   `json:"external_id"`
+	Op             Op       `json:"op"`
+	Status         Status   `json:"status"`
+	SubmerchantID  string   `json:"submerchant_id,omitempty"`
+	SubmerchantKey string   `json:"submerchant_key,omitempty"`
+	Fields         []string `json:"fields,omitempty"`
+	Detail         string   `json:"detail,omitempty"`
+	Error          string   `json:"error,omitempty"`
+}
+
+func newResult(change Change) Result {
+	result := Result{
+		Row:           change.Row,
+		ExternalID:    change.ExternalID,
+		Op:            change.Op,
+		SubmerchantID: change.SubmerchantID,
+		Detail:        change.Detail,
+	}
+	for _, field := range change.Fields {
+		result.Fields = append(result.Fields, field.Field)
+	}
+	return result
+}
+
+// Report holds one result per planned change, in plan order.
+type Report struct {
+	Results []Result `json:"results"`
+}
+
+func plannedReport(plan *Plan) *Report {
+	report := &Report{Results: make([]Result, 0, len(plan.Changes))}
+	for _, change := range plan.Changes {
+		result := newResult(change)
+		result.Status = StatusPlanned
+		if change.Op == OpUnchanged || change.Op == OpInvalid || change.Op == OpUnmanaged {
+			result.Status = StatusSkipped
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report
+}
+
+// Count returns the number of results with the given status.
+func (r *Report) Count(status Status) int {
+	count := 0
+	for _, result := range r.Results {
+		if result.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// WriteJSON writes the report as an indented JSON document.
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+var csvHeader = []string{
+	"row",
+	"external_id",
+	"op",
+	"status",
+	"submerchant_id",
+	"submerchant_key",
+	"fields",
+	"detail",
+	"error",
+}
+
+// WriteCSV writes one row per result, preceded by a header row. Changed
+// fields are separated by spaces.
+func (r *Report) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, result := range r.Results {
+		row := []string{
+			strconv.Itoa(result.Row),
+			result.ExternalID,
+			string(result.Op),
+			string(result.Status),
+			result.SubmerchantID,
+			result.SubmerchantKey,
+			strings.Join(result.Fields, " "),
+			result.Detail,
+			result.Error,
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteDiff writes the plan as a readable diff: one line per change marked
+// "+" for create, "~" for update, "-" for delete and "!" for invalid rows,
+// followed by one indented line per changed field. Unchanged rows are left
+// out.
+func (p *Plan) WriteDiff(w io.Writer) error {
+	for _, change := range p.Changes {
+		if change.Op == OpUnchanged {
+			continue
+		}
+		marker := map[Op]string{OpCreate: "+", OpUpdate: "~", OpDelete: "-", OpInvalid: "!", OpUnmanaged: "?"}[change.Op]
+		name := change.ExternalID
+		if name == "" {
+			name = change.SubmerchantID
+		}
+		line := fmt.Sprintf("%s %s %s", marker, name, change.Op)
+		if change.Row > 0 {
+			line += fmt.Sprintf(" (row %d)", change.Row)
+		}
+		if change.Detail != "" {
+			line += ": " + change.Detail
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+		for _, field := range change.Fields {
+			if _, err := fmt.Fprintf(w, "    %s: %q -> %q\n", field.Field, field.From, field.To); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package submerchantsync
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/tapsilat/tapsilat-go"
+)
+
+// Action is what a source row asks for.
+type Action string
+
+const (
+	// ActionUpsert creates the submerchant or updates the non-empty fields of
+	// the existing one. It is the default for rows without an action.
+	ActionUpsert Action = "upsert"
+	// ActionDelete deletes the submerchant with the row's external ID.
+	ActionDelete Action = "delete"
+)
+
+// Row is one submerchant read from a source. Number is its 1-based position
+// among the data rows.
+type Row struct {
+	Number      int
+	Action      Action
+	Submerchant tapsilat.SubmerchantCreateRequest
+}
+
+// Field is a submerchant field that can be read from a source and compared
+// with the stored submerchant.
+type Field struct {
+	Name string
+	Get  func(*tapsilat.SubmerchantCreateRequest) string
+	Set  func(*tapsilat.SubmerchantCreateRequest, string)
+	// Compared fields are diffed against the stored submerchant; the others
+	// are only sent with the request.
+	Compared bool
+}
+
+func stringField(name string, compared bool, field func(*tapsilat.SubmerchantCreateRequest) *string) Field {
+	return Field{
+		Name:     name,
+		Get:      func(r *tapsilat.SubmerchantCreateRequest) string { return *field(r) },
+		Set:      func(r *tapsilat.SubmerchantCreateRequest, v string) { *field(r) = v },
+		Compared: compared,
+	}
+}
+
+// Fields lists the source columns, named after the JSON fields of
+// tapsilat.SubmerchantCreateRequest.
+var Fields = []Field{
+	stringField("sub_merchant_external_id", true, func(r *tapsilat.SubmerchantCreateRequest) *string { return &r.SubmerchantExternalID }),
+	stringField("sub_merchant_type", true, func(r *tapsilat.SubmerchantCreateRequest) *string { return &r.SubmerchantType }),
+	stringField("name", true, func(r *tapsilat.SubmerchantCreateRequest) *string { return &r.Name }),
+	stringField("email", true, func(r *tapsilat.SubmerchantCreateRequest) *string { return &r.Email }),
+	stringField("gsm_number", true, func(r *tapsilat.SubmerchantCreateRequest) *string { return &r.GsmNumber }),
+	stringField("address", true, func(r *tapsilat.SubmerchantCreateRequest) *string { return &r.Address }),
+	stringField("iban", true, func(r *tapsilat.SubmerchantCreateRequest) *string { return &r.Iban }),
+	stringField("tax_office", true, func(r *tapsilat.SubmerchantCreateRequest) *string { return &r.TaxOffice }),
+	stringField("legal_company_title", true, func(r *tapsilat.SubmerchantCreateRequest) *string { return &r.LegalCompanyTitle }),
+	stringField("currency_id", true, func(r *tapsilat.SubmerchantCreateRequest) *string { return &r.CurrencyID }),
+	stringField("identity_number", true, func(r *tapsilat.SubmerchantCreateRequest) *string { return &r.IdentityNumber }),
+	stringField("tax_number", true, func(r *tapsilat.SubmerchantCreateRequest) *string { return &r.TaxNumber }),
+	stringField("contact_name", true, func(r *tapsilat.SubmerchantCreateRequest) *string { return &r.ContactName }),
+	stringField("contact_surname", true, func(r *tapsilat.SubmerchantCreateRequest) *string { return &r.ContactSurname }),
+	stringField("status", true, func(r *tapsilat.SubmerchantCreateRequest) *string { return &r.Status }),
+	stringField("organization_id", false, func(r *tapsilat.SubmerchantCreateRequest) *string { return &r.OrganizationID }),
+	stringField("locale", false, func(r *tapsilat.SubmerchantCreateRequest) *string { return &r.Locale }),
+	stringField("conversation_id", false, func(r *tapsilat.SubmerchantCreateRequest) *string { return &r.ConversationID }),
+}
+
+func fieldByName(name string) (Field, bool) {
+	for _, field := range Fields {
+		if field.Name == name {
+			return field, true
+		}
+	}
+	return Field{}, false
+}
+
+// ReadCSV reads rows from CSV with a header row. Columns are named after
+// Fields, plus an optional "action" column; unknown columns are an error so
+// that typos do not silently drop data.
+func ReadCSV(r io.Reader) ([]Row, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("submerchantsync: read csv header: %w", err)
+	}
+
+	actionColumn := -1
+	fields := make([]Field, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if name == "action" {
+			actionColumn = i
+			continue
+		}
+		field, ok := fieldByName(name)
+		if !ok {
+			return nil, fmt.Errorf("submerchantsync: unknown csv column %q", name)
+		}
+		fields[i] = field
+	}
+
+	var rows []Row
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("submerchantsync: read csv row %d: %w", len(rows)+1, err)
+		}
+
+		row := Row{Number: len(rows) + 1}
+		for i, value := range record {
+			value = strings.TrimSpace(value)
+			if i == actionColumn {
+				row.Action = Action(strings.ToLower(value))
+				continue
+			}
+			fields[i].Set(&row.Submerchant, value)
+		}
+		rows = append(rows, row)
+	}
+}
+
+// ReadJSON reads rows from a JSON array of objects with the fields of
+// tapsilat.SubmerchantCreateRequest and an optional "action".
+func ReadJSON(r io.Reader) ([]Row, error) {
+	var items []struct {
+		Action Action `json:"action"`
+		tapsilat.SubmerchantCreateRequest
+	}
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&items); err != nil {
+		return nil, fmt.Errorf("submerchantsync: read json: %w", err)
+	}
+
+	rows := make([]Row, 0, len(items))
+	for i, item := range items {
+		rows = append(rows, Row{
+			Number:      i + 1,
+			Action:      Action(strings.ToLower(strings.TrimSpace(string(item.Action)))),
+			Submerchant: item.SubmerchantCreateRequest,
+		})
+	}
+	return rows, nil
+}
@@ -0,0 +1,454 @@
+// Package submerchantsync creates, updates and deletes submerchants in bulk
+// from a CSV or JSON source, matching them to the stored submerchants by
+// SubmerchantExternalID.
+package submerchantsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tapsilat/tapsilat-go"
+	"github.com/tapsilat/tapsilat-go/internal/parallel"
+)
+
+// Client is the subset of *tapsilat.API used by the syncer.
+type Client interface {
+	ListSubmerchants(ctx context.Context, page, perPage int) (tapsilat.SubmerchantListResponse, error)
+	GetSubmerchant(ctx context.Context, id string) (tapsilat.Submerchant, error)
+	CreateSubmerchant(ctx context.Context, payload tapsilat.SubmerchantCreateRequest) (tapsilat.SubmerchantMutationResponse, error)
+	UpdateSubmerchant(ctx context.Context, id string, payload tapsilat.SubmerchantUpdateRequest) (tapsilat.SubmerchantMutationResponse, error)
+	DeleteSubmerchant(ctx context.Context, id string) (tapsilat.SubmerchantMutationResponse, error)
+	ResolveCurrencyID(ctx context.Context, ref string) (string, error)
+}
+
+// Op is the operation planned for a submerchant.
+type Op string
+
+const (
+	OpCreate    Op = "create"
+	OpUpdate    Op = "update"
+	OpDelete    Op = "delete"
+	OpUnchanged Op = "unchanged"
+	// OpInvalid is a row that failed validation and is skipped.
+	OpInvalid Op = "invalid"
+	// OpUnmanaged is a stored submerchant without an external ID, reported
+	// when Prune is set. The source cannot name it, so it is never deleted.
+	OpUnmanaged Op = "unmanaged"
+)
+
+// FieldChange is a field whose stored value differs from the source.
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// Change is the planned operation for one source row, or for a stored
+// submerchant missing from the source when Prune is set (Row is then 0).
+type Change struct {
+	Row           int           `json:"row,omitempty"`
+	ExternalID    string        `json:"external_id"`
+	Op            Op            `json:"op"`
+	SubmerchantID string        `json:"submerchant_id,omitempty"`
+	Fields        []FieldChange `json:"fields,omitempty"`
+	Detail        string        `json:"detail,omitempty"`
+
+	payload tapsilat.SubmerchantCreateRequest
+	update  tapsilat.SubmerchantUpdateRequest
+}
+
+// Plan is the set of changes needed to bring the stored submerchants in line
+// with the source.
+type Plan struct {
+	Changes []Change `json:"changes"`
+}
+
+// Count returns the number of changes with the given operation.
+func (p *Plan) Count(op Op) int {
+	count := 0
+	for _, change := range p.Changes {
+		if change.Op == op {
+			count++
+		}
+	}
+	return count
+}
+
+// Syncer plans and applies a bulk submerchant sync.
+type Syncer struct {
+	Client Client
+
+	// Workers bounds the number of concurrent API calls.
+	Workers int
+	// PerPage is the page size used for ListSubmerchants.
+	PerPage int
+	// Rate limits API calls per second across all workers. Zero disables the
+	// limit.
+	Rate float64
+	// Prune deletes stored submerchants whose external ID is not in the
+	// source. Stored submerchants without an external ID are reported as
+	// OpUnmanaged instead. Rows with the delete action are deleted either way.
+	Prune bool
+	// DryRun makes Run plan without applying any change.
+	DryRun bool
+
+	limiterOnce sync.Once
+	limiter     *limiter
+}
+
+// New creates a Syncer with default settings.
+func New(client Client) *Syncer {
+	return &Syncer{
+		Client:  client,
+		Workers: 4,
+		PerPage: 100,
+		Rate:    10,
+	}
+}
+
+// Run plans the sync and, unless DryRun is set, applies it.
+func (s *Syncer) Run(ctx context.Context, rows []Row) (*Plan, *Report, error) {
+	plan, err := s.Plan(ctx, rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	if s.DryRun {
+		return plan, plannedReport(plan), nil
+	}
+	return plan, s.Apply(ctx, plan), nil
+}
+
+// Plan loads the stored submerchants and diffs them with the source rows.
+// Loading costs one GetSubmerchant call per stored submerchant on top of the
+// list pages, since list rows do not carry the external ID; Rate and Workers
+// apply to these calls too. Rows are validated with tapsilat.ValidateSubmerchant, and their currency
+// is resolved with ResolveCurrencyID; invalid rows are planned as OpInvalid.
+// For updates, empty source fields keep their stored value, and Apply sends
+// only the fields listed in the change.
+func (s *Syncer) Plan(ctx context.Context, rows []Row) (*Plan, error) {
+	stored, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byExternalID := make(map[string][]tapsilat.Submerchant, len(stored))
+	for _, submerchant := range stored {
+		byExternalID[submerchant.SubmerchantExternalID] = append(byExternalID[submerchant.SubmerchantExternalID], submerchant)
+	}
+
+	plan := &Plan{Changes: make([]Change, 0, len(rows))}
+	seen := make(map[string]int, len(rows))
+	for _, row := range rows {
+		externalID := strings.TrimSpace(row.Submerchant.SubmerchantExternalID)
+		row.Submerchant.SubmerchantExternalID = externalID
+		change := Change{Row: row.Number, ExternalID: externalID}
+		matches := byExternalID[externalID]
+
+		switch {
+		case externalID == "":
+			change.Op, change.Detail = OpInvalid, "sub_merchant_external_id is required"
+		case seen[externalID] > 0:
+			change.Op, change.Detail = OpInvalid, fmt.Sprintf("duplicate of row %d", seen[externalID])
+		case len(matches) > 1:
+			change.Op, change.Detail = OpInvalid, fmt.Sprintf("%d stored submerchants share this external ID", len(matches))
+		case row.Action == ActionDelete:
+			change.Op, change.Detail = OpUnchanged, "not stored, nothing to delete"
+			if len(matches) == 1 {
+				change.Op, change.Detail = OpDelete, ""
+				change.SubmerchantID = matches[0].ID
+			}
+		case row.Action != "" && row.Action != ActionUpsert:
+			change.Op, change.Detail = OpInvalid, fmt.Sprintf("unknown action %q", row.Action)
+		case len(matches) == 1:
+			change, err = s.planUpdate(ctx, change, row, matches[0])
+		default:
+			change, err = s.planCreate(ctx, change, row)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("submerchantsync: plan row %d: %w", row.Number, err)
+		}
+		if externalID != "" && seen[externalID] == 0 {
+			seen[externalID] = row.Number
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+
+	if s.Prune {
+		for _, submerchant := range stored {
+			if strings.TrimSpace(submerchant.SubmerchantExternalID) == "" {
+				plan.Changes = append(plan.Changes, Change{
+					Op:            OpUnmanaged,
+					SubmerchantID: submerchant.ID,
+					Detail:        "no external ID, not pruned",
+				})
+				continue
+			}
+			if _, ok := seen[submerchant.SubmerchantExternalID]; ok {
+				continue
+			}
+			plan.Changes = append(plan.Changes, Change{
+				ExternalID:    submerchant.SubmerchantExternalID,
+				Op:            OpDelete,
+				SubmerchantID: submerchant.ID,
+				Detail:        "not in source",
+			})
+		}
+	}
+	return plan, nil
+}
+
+func (s *Syncer) planCreate(ctx context.Context, change Change, row Row) (Change, error) {
+	payload, err := s.prepare(ctx, row.Submerchant)
+	if err != nil {
+		return invalid(change, err)
+	}
+	change.Op = OpCreate
+	change.payload = payload
+	return change, nil
+}
+
+func (s *Syncer) planUpdate(ctx context.Context, change Change, row Row, stored tapsilat.Submerchant) (Change, error) {
+	change.SubmerchantID = stored.ID
+	current := fromSubmerchant(stored)
+	merged := current
+	for _, field := range Fields {
+		if value := field.Get(&row.Submerchant); value != "" {
+			field.Set(&merged, value)
+		}
+	}
+
+	payload, err := s.prepare(ctx, merged)
+	if err != nil {
+		return invalid(change, err)
+	}
+	for _, field := range Fields {
+		if !field.Compared {
+			continue
+		}
+		if from, to := field.Get(&current), field.Get(&payload); from != to {
+			change.Fields = append(change.Fields, FieldChange{Field: field.Name, From: from, To: to})
+		}
+	}
+
+	change.Op = OpUnchanged
+	if len(change.Fields) > 0 {
+		change.Op = OpUpdate
+		change.update = updateRequest(change.Fields)
+	}
+	return change, nil
+}
+
+// updateRequest holds only the changed fields, so an update neither echoes
+// server-owned fields back nor overwrites fields changed since the plan.
+func updateRequest(changes []FieldChange) tapsilat.SubmerchantUpdateRequest {
+	var payload tapsilat.SubmerchantCreateRequest
+	for _, change := range changes {
+		if field, ok := fieldByName(change.Field); ok {
+			field.Set(&payload, change.To)
+		}
+	}
+	return tapsilat.SubmerchantUpdateRequest(payload)
+}
+
+// prepare validates a payload and resolves its currency. The currency lookup
+// is cached by the client, so it is not rate limited.
+func (s *Syncer) prepare(ctx context.Context, payload tapsilat.SubmerchantCreateRequest) (tapsilat.SubmerchantCreateRequest, error) {
+	payload, err := tapsilat.ValidateSubmerchant(payload)
+	if err != nil {
+		return payload, err
+	}
+	currencyID, err := s.Client.ResolveCurrencyID(ctx, payload.CurrencyID)
+	if err != nil {
+		return payload, err
+	}
+	payload.CurrencyID = currencyID
+	return payload, nil
+}
+
+// invalid plans change as OpInvalid for a validation error, and returns any
+// other error, such as a failed currency lookup, to abort the plan.
+func invalid(change Change, err error) (Change, error) {
+	var validationErr *tapsilat.ValidationError
+	if !errors.As(err, &validationErr) {
+		return change, err
+	}
+
+	change.Op = OpInvalid
+	change.Detail = validationErr.Message
+	var fieldErr *tapsilat.FieldValidationError
+	if errors.As(err, &fieldErr) {
+		messages := make([]string, 0, len(fieldErr.Issues))
+		for _, issue := range fieldErr.Issues {
+			messages = append(messages, issue.Field+" "+issue.Message)
+		}
+		change.Detail = strings.Join(messages, "; ")
+	}
+	return change, nil
+}
+
+// load lists every stored submerchant and fetches each one, as list rows do
+// not carry the external ID. A sync of N stored submerchants therefore costs
+// N GetSubmerchant calls before anything is planned, whatever the source.
+func (s *Syncer) load(ctx context.Context) ([]tapsilat.Submerchant, error) {
+	var ids []string
+	for page := 1; ; page++ {
+		if err := s.wait(ctx); err != nil {
+			return nil, err
+		}
+		res, err := s.Client.ListSubmerchants(ctx, page, s.perPage())
+		if err != nil {
+			return nil, fmt.Errorf("submerchantsync: list submerchants page %d: %w", page, err)
+		}
+		for _, row := range res.Rows {
+			ids = append(ids, row.ID)
+		}
+		if len(res.Rows) == 0 || int64(page) >= res.TotalPages {
+			break
+		}
+	}
+
+	stored := make([]tapsilat.Submerchant, len(ids))
+	errs := make([]error, len(ids))
+	// Errors are collected per submerchant, so every fetch runs.
+	_ = parallel.Each(ctx, s.workers(), len(ids), func(ctx context.Context, i int) error {
+		if err := s.wait(ctx); err != nil {
+			errs[i] = err
+			return nil
+		}
+		stored[i], errs[i] = s.Client.GetSubmerchant(ctx, ids[i])
+		if errs[i] != nil {
+			errs[i] = fmt.Errorf("submerchantsync: get submerchant %s: %w", ids[i], errs[i])
+		}
+		return nil
+	})
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// Apply runs the planned creates, updates and deletes. A failed change does
+// not stop the others; its error is recorded in the report.
+func (s *Syncer) Apply(ctx context.Context, plan *Plan) *Report {
+	report := &Report{Results: make([]Result, len(plan.Changes))}
+	_ = parallel.Each(ctx, s.workers(), len(plan.Changes), func(ctx context.Context, i int) error {
+		change := plan.Changes[i]
+		result := newResult(change)
+		switch change.Op {
+		case OpCreate, OpUpdate, OpDelete:
+		default:
+			result.Status = StatusSkipped
+			report.Results[i] = result
+			return nil
+		}
+
+		var res tapsilat.SubmerchantMutationResponse
+		err := s.wait(ctx)
+		if err == nil {
+			switch change.Op {
+			case OpCreate:
+				res, err = s.Client.CreateSubmerchant(ctx, change.payload)
+			case OpUpdate:
+				res, err = s.Client.UpdateSubmerchant(ctx, change.SubmerchantID, change.update)
+			case OpDelete:
+				res, err = s.Client.DeleteSubmerchant(ctx, change.SubmerchantID)
+			}
+		}
+		if err != nil {
+			result.Status = StatusFailed
+			result.Error = err.Error()
+		} else {
+			result.Status = StatusOK
+			result.SubmerchantKey = res.SubmerchantKey
+		}
+		report.Results[i] = result
+		return nil
+	})
+	return report
+}
+
+func (s *Syncer) wait(ctx context.Context) error {
+	s.limiterOnce.Do(func() {
+		if s.Rate > 0 {
+			s.limiter = &limiter{interval: time.Duration(float64(time.Second) / s.Rate)}
+		}
+	})
+	return s.limiter.Wait(ctx)
+}
+
+func (s *Syncer) perPage() int {
+	if s.PerPage <= 0 {
+		return 100
+	}
+	return s.PerPage
+}
+
+func (s *Syncer) workers() int {
+	if s.Workers <= 0 {
+		return 1
+	}
+	return s.Workers
+}
+
+// limiter spaces calls at least interval apart.
+type limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func (l *limiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := at.Sub(now)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// fromSubmerchant copies the fields a source row can set. Status is only
+// compared; the server-owned SubmerchantKey, OrganizationID and SystemTime
+// are left out.
+func fromSubmerchant(s tapsilat.Submerchant) tapsilat.SubmerchantCreateRequest {
+	return tapsilat.SubmerchantCreateRequest{
+		Locale:                s.Locale,
+		ConversationID:        s.ConversationID,
+		Name:                  s.Name,
+		Email:                 s.Email,
+		GsmNumber:             s.GsmNumber,
+		Address:               s.Address,
+		Iban:                  s.Iban,
+		TaxOffice:             s.TaxOffice,
+		LegalCompanyTitle:     s.LegalCompanyTitle,
+		CurrencyID:            s.CurrencyID,
+		SubmerchantExternalID: s.SubmerchantExternalID,
+		IdentityNumber:        s.IdentityNumber,
+		SubmerchantType:       s.SubmerchantType,
+		TaxNumber:             s.TaxNumber,
+		Status:                s.Status,
+		ContactName:           s.ContactName,
+		ContactSurname:        s.ContactSurname,
+	}
+}
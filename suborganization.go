@@ -0,0 +1,394 @@
+package tapsilat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tapsilat/tapsilat-go/internal/parallel"
+)
+
+// SuborganizationClient is the subset of *API used to load the
+// suborganization tree.
+type SuborganizationClient interface {
+	GetSuborganizations(ctx context.Context, page, perPage int) (SuborganizationListResponse, error)
+	GetSuborganizationDetail(ctx context.Context, id string) (SuborganizationDetail, error)
+	GetSubmerchantBySuborganization(ctx context.Context, suborganizationID string) (SuborganizationSubmerchantMapping, error)
+	ListVposWithFilter(ctx context.Context, page, perPage int, filter VposListFilter) (VposListResponse, error)
+}
+
+// SuborganizationNode is a suborganization with its linked submerchant, the
+// VPOS listed for it and its child suborganizations.
+type SuborganizationNode struct {
+	SuborganizationDetail
+	// SubmerchantID is empty when no submerchant is linked.
+	SubmerchantID string                 `json:"submerchant_id,omitempty"`
+	Vpos          []VposListItem         `json:"vpos,omitempty"`
+	Children      []*SuborganizationNode `json:"children,omitempty"`
+	// Parent is nil for roots.
+	Parent *SuborganizationNode `json:"-"`
+	// Depth is 0 for roots.
+	Depth int `json:"depth"`
+}
+
+// Path returns the nodes from the root down to n.
+func (n *SuborganizationNode) Path() []*SuborganizationNode {
+	var path []*SuborganizationNode
+	for node := n; node != nil; node = node.Parent {
+		path = append([]*SuborganizationNode{node}, path...)
+	}
+	return path
+}
+
+// Walk calls fn for n and its descendants, parents before children. It stops
+// at the first error.
+func (n *SuborganizationNode) Walk(fn func(*SuborganizationNode) error) error {
+	if err := fn(n); err != nil {
+		return err
+	}
+	for _, child := range n.Children {
+		if err := child.Walk(fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SuborganizationTree holds every suborganization of the organization.
+// Suborganizations whose parent is not listed are roots.
+type SuborganizationTree struct {
+	Roots []*SuborganizationNode `json:"roots"`
+
+	nodes map[string]*SuborganizationNode
+}
+
+// Node returns the suborganization with the given ID.
+func (t *SuborganizationTree) Node(id string) (*SuborganizationNode, bool) {
+	node, ok := t.nodes[id]
+	return node, ok
+}
+
+// Len returns the number of suborganizations in the tree.
+func (t *SuborganizationTree) Len() int {
+	return len(t.nodes)
+}
+
+// Walk calls fn for every suborganization, depth first in list order.
+func (t *SuborganizationTree) Walk(fn func(*SuborganizationNode) error) error {
+	for _, root := range t.Roots {
+		if err := root.Walk(fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SuborganizationBySubmerchant returns the suborganization linked to a
+// submerchant.
+func (t *SuborganizationTree) SuborganizationBySubmerchant(submerchantID string) (*SuborganizationNode, bool) {
+	for _, node := range t.nodes {
+		if node.SubmerchantID != "" && node.SubmerchantID == submerchantID {
+			return node, true
+		}
+	}
+	return nil, false
+}
+
+// SuborganizationTreeLoader builds the suborganization tree from the API.
+type SuborganizationTreeLoader struct {
+	Client SuborganizationClient
+
+	// Workers bounds the number of concurrent API calls.
+	Workers int
+	// PerPage is the page size used for list calls.
+	PerPage int
+}
+
+// NewSuborganizationTreeLoader creates a loader with default settings.
+func NewSuborganizationTreeLoader(client SuborganizationClient) *SuborganizationTreeLoader {
+	return &SuborganizationTreeLoader{Client: client, Workers: 4, PerPage: 100}
+}
+
+// LoadSuborganizationTree loads the suborganization tree with the default
+// loader settings.
+func (t *API) LoadSuborganizationTree(ctx context.Context) (*SuborganizationTree, error) {
+	return NewSuborganizationTreeLoader(t).Load(ctx)
+}
+
+// Load lists every suborganization, then loads its detail, linked submerchant
+// and VPOS, and links children to parents by ParentID. A ParentID cycle is
+// an error.
+func (l *SuborganizationTreeLoader) Load(ctx context.Context) (*SuborganizationTree, error) {
+	var items []SuborganizationListItem
+	for page := 1; ; page++ {
+		res, err := l.Client.GetSuborganizations(ctx, page, l.perPage())
+		if err != nil {
+			return nil, fmt.Errorf("list suborganizations page %d: %w", page, err)
+		}
+		items = append(items, res.Rows...)
+		if int64(page) >= res.TotalPages || len(res.Rows) == 0 {
+			break
+		}
+	}
+
+	nodes := make([]*SuborganizationNode, len(items))
+	err := parallel.Each(ctx, l.Workers, len(items), func(ctx context.Context, i int) error {
+		node, err := l.loadNode(ctx, items[i])
+		nodes[i] = node
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tree := &SuborganizationTree{nodes: make(map[string]*SuborganizationNode, len(nodes))}
+	for _, node := range nodes {
+		tree.nodes[node.ID] = node
+	}
+	for _, node := range nodes {
+		parent, ok := tree.nodes[node.ParentID]
+		if !ok || node.ParentID == node.ID {
+			tree.Roots = append(tree.Roots, node)
+			continue
+		}
+		node.Parent = parent
+		parent.Children = append(parent.Children, node)
+	}
+
+	seen := 0
+	err = tree.Walk(func(node *SuborganizationNode) error {
+		seen++
+		if node.Parent != nil {
+			node.Depth = node.Parent.Depth + 1
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if seen != len(nodes) {
+		for _, node := range nodes {
+			if node.Parent != nil && node.Depth == 0 {
+				return nil, fmt.Errorf("suborganization %s is in or below a parent_id cycle", node.ID)
+			}
+		}
+	}
+	return tree, nil
+}
+
+func (l *SuborganizationTreeLoader) loadNode(ctx context.Context, item SuborganizationListItem) (*SuborganizationNode, error) {
+	detail, err := l.Client.GetSuborganizationDetail(ctx, item.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get suborganization %s: %w", item.ID, err)
+	}
+	if detail.ID == "" {
+		detail.ID = item.ID
+	}
+	if detail.Name == "" {
+		detail.Name = item.Name
+	}
+	node := &SuborganizationNode{SuborganizationDetail: detail}
+
+	mapping, err := l.Client.GetSubmerchantBySuborganization(ctx, item.ID)
+	var apiErr *APIError
+	switch {
+	case err == nil:
+		node.SubmerchantID = mapping.SubmerchantID
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
+		// No submerchant is linked.
+	default:
+		return nil, fmt.Errorf("get submerchant of suborganization %s: %w", item.ID, err)
+	}
+
+	filter := VposListFilter{SuborganizationID: item.ID}
+	for page := 1; ; page++ {
+		res, err := l.Client.ListVposWithFilter(ctx, page, l.perPage(), filter)
+		if err != nil {
+			return nil, fmt.Errorf("list vpos of suborganization %s page %d: %w", item.ID, page, err)
+		}
+		node.Vpos = append(node.Vpos, res.Rows...)
+		if int64(page) >= res.TotalPages || len(res.Rows) == 0 {
+			return node, nil
+		}
+	}
+}
+
+func (l *SuborganizationTreeLoader) perPage() int {
+	if l.PerPage <= 0 {
+		return 100
+	}
+	return l.PerPage
+}
+
+// Tenant is a view of the API scoped to one suborganization. It only has
+// the calls that can be restricted to the suborganization, plus the
+// organization-wide reference data they need, so it can be passed where a
+// vposroute.Client is expected. Use the *API for anything else.
+type Tenant struct {
+	SuborganizationID string
+
+	api *API
+
+	mu       sync.Mutex
+	vpos     map[string]bool
+	listMu   sync.Mutex
+	listedAt time.Time
+}
+
+// ForSuborganization returns a view of the API scoped to a suborganization.
+func (t *API) ForSuborganization(suborganizationID string) *Tenant {
+	return &Tenant{SuborganizationID: suborganizationID, api: t}
+}
+
+// Suborganization returns the detail of the tenant's suborganization.
+func (t *Tenant) Suborganization(ctx context.Context) (SuborganizationDetail, error) {
+	return t.api.GetSuborganizationDetail(ctx, t.SuborganizationID)
+}
+
+// Submerchant returns the submerchant linked to the tenant's suborganization.
+func (t *Tenant) Submerchant(ctx context.Context) (Submerchant, error) {
+	mapping, err := t.api.GetSubmerchantBySuborganization(ctx, t.SuborganizationID)
+	if err != nil {
+		return Submerchant{}, err
+	}
+	return t.api.GetSubmerchant(ctx, mapping.SubmerchantID)
+}
+
+// ListVpos lists the VPOS of the tenant's suborganization.
+func (t *Tenant) ListVpos(ctx context.Context, page, perPage int) (VposListResponse, error) {
+	return t.ListVposWithFilter(ctx, page, perPage, VposListFilter{})
+}
+
+// ListVposWithFilter lists VPOS with the tenant's suborganization filled in.
+// A filter for another suborganization is a *ValidationError.
+func (t *Tenant) ListVposWithFilter(ctx context.Context, page, perPage int, filter VposListFilter) (VposListResponse, error) {
+	if filter.SuborganizationID != "" && filter.SuborganizationID != t.SuborganizationID {
+		return VposListResponse{}, &ValidationError{
+			StatusCode: 400,
+			Code:       0,
+			Message:    fmt.Sprintf("suborganization_id %s is outside tenant %s", filter.SuborganizationID, t.SuborganizationID),
+		}
+	}
+	filter.SuborganizationID = t.SuborganizationID
+	response, err := t.api.ListVposWithFilter(ctx, page, perPage, filter)
+	if err == nil {
+		t.mu.Lock()
+		if t.vpos == nil {
+			t.vpos = make(map[string]bool)
+		}
+		for _, row := range response.Rows {
+			t.vpos[row.ID] = true
+		}
+		t.mu.Unlock()
+	}
+	return response, err
+}
+
+// GetVpos returns a VPOS of the tenant's suborganization. A VPOS the tenant
+// has not listed yet is looked up in its VPOS list first; one outside the
+// suborganization is a *ValidationError. The complete list is trusted for
+// the reference cache TTL, or DefaultReferenceCacheTTL when that is not
+// positive, so unknown IDs do not list every page again meanwhile.
+func (t *Tenant) GetVpos(ctx context.Context, id string) (Vpos, error) {
+	if !t.ownsVpos(id) {
+		if err := t.listAllVpos(ctx); err != nil {
+			return Vpos{}, err
+		}
+		if !t.ownsVpos(id) {
+			return Vpos{}, &ValidationError{
+				StatusCode: 400,
+				Code:       0,
+				Message:    fmt.Sprintf("vpos %s is outside tenant %s", id, t.SuborganizationID),
+			}
+		}
+	}
+	return t.api.GetVpos(ctx, id)
+}
+
+// listAllVpos lists every VPOS page of the suborganization unless a complete
+// list is still fresh. Concurrent callers share one listing.
+func (t *Tenant) listAllVpos(ctx context.Context) error {
+	t.listMu.Lock()
+	defer t.listMu.Unlock()
+
+	ttl := t.api.ReferenceCacheTTL
+	if ttl <= 0 {
+		ttl = DefaultReferenceCacheTTL
+	}
+	now := t.now()
+	if !t.listedAt.IsZero() && now.Sub(t.listedAt) <= ttl {
+		return nil
+	}
+	for page := 1; ; page++ {
+		response, err := t.ListVposWithFilter(ctx, page, 100, VposListFilter{})
+		if err != nil {
+			return err
+		}
+		if len(response.Rows) == 0 || int64(page) >= response.TotalPages {
+			break
+		}
+	}
+	t.listedAt = now
+	return nil
+}
+
+func (t *Tenant) ownsVpos(id string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.vpos[id]
+}
+
+func (t *Tenant) now() time.Time {
+	if t.api.Now != nil {
+		return t.api.Now()
+	}
+	return time.Now()
+}
+
+// CachedCardSchemes returns the organization's card schemes, which are shared
+// by every suborganization.
+func (t *Tenant) CachedCardSchemes(ctx context.Context) ([]CardScheme, error) {
+	return t.api.CachedCardSchemes(ctx)
+}
+
+// GetOrganizationCurrencies returns the organization's currencies, which are
+// shared by every suborganization.
+func (t *Tenant) GetOrganizationCurrencies(ctx context.Context) (OrganizationCurrenciesResponse, error) {
+	return t.api.GetOrganizationCurrencies(ctx)
+}
+
+// ListSubmerchants lists the submerchant linked to the tenant's
+// suborganization, if any. A suborganization has at most one, so Total is 0
+// or 1 and only the first page has a row.
+func (t *Tenant) ListSubmerchants(ctx context.Context, page, perPage int) (SubmerchantListResponse, error) {
+	response := SubmerchantListResponse{Page: int64(page), PerPage: int64(perPage)}
+	if page < 1 || perPage < 1 {
+		return response, &ValidationError{StatusCode: 400, Code: 0, Message: "page and per_page must be at least 1"}
+	}
+	submerchant, err := t.Submerchant(ctx)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return response, nil
+	}
+	if err != nil {
+		return response, err
+	}
+
+	response.Total, response.TotalPages = 1, 1
+	if page == 1 {
+		response.Rows = []SubmerchantListItem{{
+			ID:              submerchant.ID,
+			Name:            submerchant.Name,
+			Email:           submerchant.Email,
+			SubmerchantType: submerchant.SubmerchantType,
+			SubmerchantKey:  submerchant.SubmerchantKey,
+			Labels:          submerchant.Labels,
+			Status:          submerchant.Status,
+			Acquirer:        submerchant.Acquirer,
+		}}
+	}
+	return response, nil
+}
@@ -0,0 +1,461 @@
+package tapsilat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSubscriptionOverdueAfter is how long a pending subscription order
+// may stay unpaid past its payment date before it counts as overdue.
+const DefaultSubscriptionOverdueAfter = 24 * time.Hour
+
+// SubscriptionClient is the part of *API used by SubscriptionManager.
+type SubscriptionClient interface {
+	GetSubscription(ctx context.Context, payload SubscriptionGetRequest) (SubscriptionDetail, error)
+	CancelSubscription(ctx context.Context, payload SubscriptionCancelRequest) error
+	RedirectSubscription(ctx context.Context, payload SubscriptionRedirectRequest) (SubscriptionRedirectResponse, error)
+	ListSubscriptions(ctx context.Context, page, perPage int) (PaginatedData, error)
+}
+
+// SubscriptionHealth summarizes the payment state of a subscription.
+type SubscriptionHealth string
+
+const (
+	SubscriptionHealthActive   SubscriptionHealth = "active"
+	SubscriptionHealthPastDue  SubscriptionHealth = "past_due"
+	SubscriptionHealthCanceled SubscriptionHealth = "canceled"
+)
+
+// SubscriptionState is a subscription with its orders interpreted.
+type SubscriptionState struct {
+	ReferenceID string             `json:"reference_id"`
+	Detail      SubscriptionDetail `json:"detail"`
+	Health      SubscriptionHealth `json:"health"`
+	// NextChargeAt is the next charge date projected from the last order,
+	// zero for canceled subscriptions or when it cannot be computed.
+	NextChargeAt time.Time           `json:"next_charge_at,omitzero"`
+	Failed       []SubscriptionOrder `json:"failed,omitempty"`
+	Overdue      []SubscriptionOrder `json:"overdue,omitempty"`
+	// ConsecutiveFailures counts failed and overdue orders since the last
+	// successful one.
+	ConsecutiveFailures int `json:"consecutive_failures"`
+}
+
+// DunningAction is what a dunning step does.
+type DunningAction string
+
+const (
+	// DunningNotify calls SubscriptionManager.Notify.
+	DunningNotify DunningAction = "notify"
+	// DunningRequestCardUpdate creates a RedirectSubscription link and passes
+	// it to SubscriptionManager.Notify so the customer can update the card.
+	DunningRequestCardUpdate DunningAction = "request_card_update"
+	// DunningCancel cancels the subscription.
+	DunningCancel DunningAction = "cancel"
+)
+
+// DunningStep runs Action once a subscription reaches AfterFailures
+// consecutive failed or overdue orders.
+type DunningStep struct {
+	AfterFailures int           `json:"after_failures"`
+	Action        DunningAction `json:"action"`
+}
+
+// DefaultDunningSteps notify on the first failure, ask for a new card on the
+// second and cancel on the third.
+var DefaultDunningSteps = []DunningStep{
+	{AfterFailures: 1, Action: DunningNotify},
+	{AfterFailures: 2, Action: DunningRequestCardUpdate},
+	{AfterFailures: 3, Action: DunningCancel},
+}
+
+// SubscriptionEventType is the kind of a SubscriptionEvent.
+type SubscriptionEventType string
+
+const (
+	SubscriptionEventRenewed             SubscriptionEventType = "renewed"
+	SubscriptionEventPaymentFailed       SubscriptionEventType = "payment_failed"
+	SubscriptionEventPaymentOverdue      SubscriptionEventType = "payment_overdue"
+	SubscriptionEventRecovered           SubscriptionEventType = "recovered"
+	SubscriptionEventDunningNotified     SubscriptionEventType = "dunning_notified"
+	SubscriptionEventCardUpdateRequested SubscriptionEventType = "card_update_requested"
+	SubscriptionEventCanceled            SubscriptionEventType = "canceled"
+)
+
+// SubscriptionEvent is a lifecycle change noticed by SubscriptionManager.
+type SubscriptionEvent struct {
+	Type                SubscriptionEventType `json:"type"`
+	ReferenceID         string                `json:"reference_id"`
+	ExternalReferenceID string                `json:"external_reference_id,omitempty"`
+	Order               *SubscriptionOrder    `json:"order,omitempty"`
+	Failures            int                   `json:"failures,omitempty"`
+	RedirectURL         string                `json:"redirect_url,omitempty"`
+	At                  time.Time             `json:"at"`
+}
+
+// SubscriptionManager tracks subscriptions, interprets their orders, runs
+// dunning steps and emits lifecycle events. Events and dunning steps are
+// emitted once per subscription; the manager keeps that progress in memory.
+type SubscriptionManager struct {
+	Client SubscriptionClient
+	// Steps are the dunning steps, DefaultDunningSteps when nil.
+	Steps []DunningStep
+	// OverdueAfter is the grace period for pending orders,
+	// DefaultSubscriptionOverdueAfter when zero.
+	OverdueAfter time.Duration
+	// Notify is called for DunningNotify and DunningRequestCardUpdate steps;
+	// redirectURL is empty for DunningNotify.
+	Notify func(ctx context.Context, state SubscriptionState, step DunningStep, redirectURL string) error
+	// OnEvent receives every emitted event.
+	OnEvent func(event SubscriptionEvent)
+	PerPage int
+	Now     func() time.Time
+
+	mu       sync.Mutex
+	progress map[string]*subscriptionProgress
+}
+
+// subscriptionStatusOverdue marks a pending order past its payment date in
+// the manager's progress; the server never reports it.
+const subscriptionStatusOverdue SubscriptionPaymentStatus = "overdue"
+
+// subscriptionProgress is what the manager remembers of one subscription.
+// Its mutex is held for a whole Check, so concurrent checks of the same
+// subscription run one after the other and never repeat a step.
+type subscriptionProgress struct {
+	mu        sync.Mutex
+	orders    map[string]SubscriptionPaymentStatus
+	stepsDone int
+	canceled  bool
+}
+
+// NewSubscriptionManager creates a manager using client, usually an *API,
+// with the default dunning steps.
+func NewSubscriptionManager(client SubscriptionClient) *SubscriptionManager {
+	return &SubscriptionManager{Client: client}
+}
+
+// State fetches a subscription and interprets its orders without emitting
+// events or running dunning steps.
+func (m *SubscriptionManager) State(ctx context.Context, referenceID string) (SubscriptionState, error) {
+	detail, err := m.Client.GetSubscription(ctx, SubscriptionGetRequest{ReferenceID: referenceID})
+	if err != nil {
+		return SubscriptionState{}, err
+	}
+	return m.stateOf(referenceID, detail), nil
+}
+
+// Check fetches a subscription, emits events for order changes since the
+// previous check and runs the dunning step that is due. When several steps
+// became due since the previous check, as on the first check of a
+// subscription that already failed three times, only the last of them runs
+// and the earlier ones are skipped. It returns the state after the step ran
+// and the emitted events. Checks of the same subscription are serialized.
+func (m *SubscriptionManager) Check(ctx context.Context, referenceID string) (SubscriptionState, []SubscriptionEvent, error) {
+	m.mu.Lock()
+	if m.progress == nil {
+		m.progress = make(map[string]*subscriptionProgress)
+	}
+	progress, ok := m.progress[referenceID]
+	if !ok {
+		progress = &subscriptionProgress{orders: make(map[string]SubscriptionPaymentStatus)}
+		m.progress[referenceID] = progress
+	}
+	m.mu.Unlock()
+
+	progress.mu.Lock()
+	defer progress.mu.Unlock()
+
+	state, err := m.State(ctx, referenceID)
+	if err != nil {
+		return SubscriptionState{}, nil, err
+	}
+	now := m.now()
+
+	events := orderEvents(state, progress, now)
+	if state.ConsecutiveFailures == 0 {
+		progress.stepsDone = 0
+	}
+	var stepErr error
+	if state.Health != SubscriptionHealthCanceled && !progress.canceled {
+		due := -1
+		steps := m.steps()
+		for i, step := range steps {
+			if i >= progress.stepsDone && state.ConsecutiveFailures >= step.AfterFailures {
+				due = i
+			}
+		}
+		if due >= 0 {
+			step := steps[due]
+			event, err := m.runStep(ctx, &state, step, now)
+			if err != nil {
+				stepErr = fmt.Errorf("dunning step %s for subscription %s: %w", step.Action, referenceID, err)
+			} else {
+				progress.stepsDone = due + 1
+				if step.Action == DunningCancel {
+					progress.canceled = true
+				}
+				events = append(events, event)
+			}
+		}
+	}
+
+	for _, event := range events {
+		if m.OnEvent != nil {
+			m.OnEvent(event)
+		}
+	}
+	return state, events, stepErr
+}
+
+// CheckAll checks every active subscription, walking all ListSubscriptions
+// pages. It keeps going when a subscription fails and returns the joined
+// errors.
+func (m *SubscriptionManager) CheckAll(ctx context.Context) ([]SubscriptionEvent, error) {
+	perPage := m.PerPage
+	if perPage <= 0 {
+		perPage = 100
+	}
+
+	var events []SubscriptionEvent
+	var errs []error
+	for page := 1; ; page++ {
+		response, err := m.Client.ListSubscriptions(ctx, page, perPage)
+		if err != nil {
+			return events, errors.Join(append(errs, err)...)
+		}
+		items, err := decodeSubscriptionListItems(response.Rows)
+		if err != nil {
+			return events, errors.Join(append(errs, err)...)
+		}
+		for _, item := range items {
+			if !item.IsActive || item.ReferenceID == "" {
+				continue
+			}
+			_, itemEvents, err := m.Check(ctx, item.ReferenceID)
+			events = append(events, itemEvents...)
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if len(items) == 0 || page >= response.TotalPages {
+			return events, errors.Join(errs...)
+		}
+	}
+}
+
+func (m *SubscriptionManager) runStep(ctx context.Context, state *SubscriptionState, step DunningStep, now time.Time) (SubscriptionEvent, error) {
+	event := SubscriptionEvent{
+		ReferenceID:         state.ReferenceID,
+		ExternalReferenceID: state.Detail.ExternalReferenceID,
+		Failures:            state.ConsecutiveFailures,
+		At:                  now,
+	}
+
+	switch step.Action {
+	case DunningNotify:
+		event.Type = SubscriptionEventDunningNotified
+		if m.Notify != nil {
+			if err := m.Notify(ctx, *state, step, ""); err != nil {
+				return event, err
+			}
+		}
+	case DunningRequestCardUpdate:
+		redirect, err := m.Client.RedirectSubscription(ctx, SubscriptionRedirectRequest{SubscriptionID: state.ReferenceID})
+		if err != nil {
+			return event, err
+		}
+		event.Type = SubscriptionEventCardUpdateRequested
+		event.RedirectURL = redirect.URL
+		if m.Notify != nil {
+			if err := m.Notify(ctx, *state, step, redirect.URL); err != nil {
+				return event, err
+			}
+		}
+	case DunningCancel:
+		if err := m.Client.CancelSubscription(ctx, SubscriptionCancelRequest{ReferenceID: state.ReferenceID}); err != nil {
+			return event, err
+		}
+		event.Type = SubscriptionEventCanceled
+		state.Health = SubscriptionHealthCanceled
+		state.Detail.IsActive = false
+		state.NextChargeAt = time.Time{}
+	default:
+		return event, fmt.Errorf("unknown dunning action %q", step.Action)
+	}
+	return event, nil
+}
+
+// orderEvents returns events for orders whose status changed since the last
+// check. Must be called with progress.mu held.
+func orderEvents(state SubscriptionState, progress *subscriptionProgress, now time.Time) []SubscriptionEvent {
+	overdue := make(map[string]bool, len(state.Overdue))
+	for _, order := range state.Overdue {
+		overdue[subscriptionOrderKey(order)] = true
+	}
+
+	var events []SubscriptionEvent
+	hadFailures := false
+	for _, status := range progress.orders {
+		if status == SubscriptionStatusFailure || status == subscriptionStatusOverdue {
+			hadFailures = true
+		}
+	}
+
+	for _, order := range sortedSubscriptionOrders(state.Detail.Orders) {
+		key := subscriptionOrderKey(order)
+		status := order.Status.Normalize()
+		if overdue[key] {
+			status = subscriptionStatusOverdue
+		}
+		if progress.orders[key] == status {
+			continue
+		}
+		progress.orders[key] = status
+
+		event := SubscriptionEvent{
+			ReferenceID:         state.ReferenceID,
+			ExternalReferenceID: state.Detail.ExternalReferenceID,
+			Order:               &order,
+			At:                  now,
+		}
+		switch status {
+		case SubscriptionStatusSuccess:
+			event.Type = SubscriptionEventRenewed
+			if hadFailures {
+				event.Type = SubscriptionEventRecovered
+				hadFailures = false
+			}
+		case SubscriptionStatusFailure:
+			event.Type = SubscriptionEventPaymentFailed
+			hadFailures = true
+		case subscriptionStatusOverdue:
+			event.Type = SubscriptionEventPaymentOverdue
+			hadFailures = true
+		default:
+			continue
+		}
+		event.Failures = state.ConsecutiveFailures
+		events = append(events, event)
+	}
+	return events
+}
+
+func (m *SubscriptionManager) stateOf(referenceID string, detail SubscriptionDetail) SubscriptionState {
+	now := m.now()
+	grace := m.OverdueAfter
+	if grace == 0 {
+		grace = DefaultSubscriptionOverdueAfter
+	}
+
+	state := SubscriptionState{
+		ReferenceID: referenceID,
+		Detail:      detail,
+		Health:      SubscriptionHealthActive,
+	}
+
+	var lastCharge time.Time
+	for _, order := range sortedSubscriptionOrders(detail.Orders) {
+		paymentDate, hasDate := parseSubscriptionTime(order.PaymentDate)
+		switch order.Status.Normalize() {
+		case SubscriptionStatusSuccess:
+			state.ConsecutiveFailures = 0
+		case SubscriptionStatusFailure:
+			state.Failed = append(state.Failed, order)
+			state.ConsecutiveFailures++
+		case SubscriptionStatusPending, "":
+			if hasDate && now.Sub(paymentDate) > grace {
+				state.Overdue = append(state.Overdue, order)
+				state.ConsecutiveFailures++
+			}
+		}
+		if hasDate && paymentDate.After(lastCharge) {
+			lastCharge = paymentDate
+		}
+	}
+
+	if !detail.IsActive {
+		state.Health = SubscriptionHealthCanceled
+		return state
+	}
+	if state.ConsecutiveFailures > 0 {
+		state.Health = SubscriptionHealthPastDue
+	}
+	if lastCharge.IsZero() {
+		lastCharge, _ = parseSubscriptionTime(detail.DueDate)
+	}
+	if !lastCharge.IsZero() {
+		state.NextChargeAt = detail.Period.NextCharge(lastCharge, now, detail.PaymentDate)
+	}
+	return state
+}
+
+func (m *SubscriptionManager) steps() []DunningStep {
+	if m.Steps == nil {
+		return DefaultDunningSteps
+	}
+	return m.Steps
+}
+
+func (m *SubscriptionManager) now() time.Time {
+	if m.Now != nil {
+		return m.Now()
+	}
+	return time.Now()
+}
+
+var subscriptionTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"02.01.2006",
+}
+
+func parseSubscriptionTime(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	for _, layout := range subscriptionTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func sortedSubscriptionOrders(orders []SubscriptionOrder) []SubscriptionOrder {
+	sorted := slices.Clone(orders)
+	slices.SortStableFunc(sorted, func(a, b SubscriptionOrder) int {
+		at, _ := parseSubscriptionTime(a.PaymentDate)
+		bt, _ := parseSubscriptionTime(b.PaymentDate)
+		return at.Compare(bt)
+	})
+	return sorted
+}
+
+func subscriptionOrderKey(order SubscriptionOrder) string {
+	if order.ReferenceID != "" {
+		return order.ReferenceID
+	}
+	return order.PaymentDate
+}
+
+func decodeSubscriptionListItems(rows any) ([]SubscriptionListItem, error) {
+	if rows == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(rows)
+	if err != nil {
+		return nil, err
+	}
+	var items []SubscriptionListItem
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("decode subscription list: %w", err)
+	}
+	return items, nil
+}
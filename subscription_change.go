@@ -0,0 +1,510 @@
+package tapsilat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metadata keys written on a subscription recreated by SubscriptionEditor, so
+// it can be traced back to the subscription it replaces.
+const (
+	SubscriptionMetadataReplaces     = "replaces_reference_id"
+	SubscriptionMetadataOriginal     = "original_reference_id"
+	SubscriptionMetadataChangeReason = "change_reason"
+)
+
+// Change reasons written under SubscriptionMetadataChangeReason.
+const (
+	SubscriptionChangePlan   = "plan_change"
+	SubscriptionChangeCard   = "card_swap"
+	SubscriptionChangeResume = "resume"
+)
+
+// ErrNativeEndpointUnavailable is returned by SubscriptionEditor when the
+// server answers an update, pause or resume with 405 or 501 and
+// AllowRecreate is not set. The API error is wrapped alongside it.
+var ErrNativeEndpointUnavailable = errors.New("tapsilat: native subscription endpoint unavailable")
+
+// SubscriptionEditorClient is the part of *API used by SubscriptionEditor.
+type SubscriptionEditorClient interface {
+	GetSubscription(ctx context.Context, payload SubscriptionGetRequest) (SubscriptionDetail, error)
+	CreateSubscription(ctx context.Context, payload SubscriptionCreateRequest) (SubscriptionCreateResponse, error)
+	CancelSubscription(ctx context.Context, payload SubscriptionCancelRequest) error
+	UpdateSubscription(ctx context.Context, payload SubscriptionUpdateRequest) error
+	PauseSubscription(ctx context.Context, payload SubscriptionPauseRequest) error
+	ResumeSubscription(ctx context.Context, payload SubscriptionPauseRequest) error
+}
+
+// SubscriptionPlan is the billing plan of a subscription. Zero fields keep
+// the current value.
+type SubscriptionPlan struct {
+	Amount      float64
+	Period      SubscriptionPeriod
+	Cycle       SubscriptionCycle
+	PaymentDate int
+	Title       string
+}
+
+// SubscriptionChangeResult describes how a change was applied.
+type SubscriptionChangeResult struct {
+	// ReferenceID is the subscription carrying the change. It differs from
+	// PreviousReferenceID when the subscription was recreated.
+	ReferenceID         string `json:"reference_id"`
+	PreviousReferenceID string `json:"previous_reference_id,omitempty"`
+	// Recreated reports that the change fell back to cancel-and-recreate.
+	Recreated bool `json:"recreated"`
+	// Canceled reports that a pause fell back to canceling the subscription;
+	// Resume recreates it.
+	Canceled bool `json:"canceled,omitempty"`
+	// Proration is set for plan changes when the current billing period is
+	// known. The SDK does not charge or refund it.
+	Proration *Proration `json:"proration,omitempty"`
+}
+
+// Proration is the prorated difference between two plan amounts for the
+// rest of a billing period.
+type Proration struct {
+	Currency          string  `json:"currency,omitempty"`
+	RemainingFraction float64 `json:"remaining_fraction"`
+	// UnusedCredit is the part of the old amount not yet consumed.
+	UnusedCredit float64 `json:"unused_credit"`
+	// NewCharge is the new amount for the rest of the period.
+	NewCharge float64 `json:"new_charge"`
+	// Net is NewCharge minus UnusedCredit: positive to charge the customer,
+	// negative to refund.
+	Net float64 `json:"net"`
+}
+
+// ProrateSubscription prorates a change from oldAmount to newAmount made at
+// at, inside the billing period [periodStart, periodEnd). Amounts are
+// rounded to the currency minor units.
+func ProrateSubscription(oldAmount, newAmount float64, periodStart, periodEnd, at time.Time, currency string) Proration {
+	fraction := 0.0
+	if total := periodEnd.Sub(periodStart); total > 0 {
+		fraction = float64(periodEnd.Sub(at)) / float64(total)
+		fraction = max(0, min(1, fraction))
+	}
+
+	credit := AmountToMinor(oldAmount*fraction, currency)
+	charge := AmountToMinor(newAmount*fraction, currency)
+	return Proration{
+		Currency:          currency,
+		RemainingFraction: fraction,
+		UnusedCredit:      MinorToAmount(credit, currency),
+		NewCharge:         MinorToAmount(charge, currency),
+		Net:               MinorToAmount(charge-credit, currency),
+	}
+}
+
+// SubscriptionEditor changes the plan or card of a subscription and pauses
+// or resumes it with UpdateSubscription, PauseSubscription and
+// ResumeSubscription. When the server does not provide them (405 or 501) it
+// returns ErrNativeEndpointUnavailable, unless AllowRecreate is set; a 404 is
+// returned as is, since it also means an unknown subscription. With
+// AllowRecreate, or ForceRecreate, the editor falls back to canceling the
+// subscription and creating a new one:
+//
+//   - The new subscription is built from the template passed to the call,
+//     with plan, currency, payment date, title, ExternalReferenceID and
+//     metadata taken from the current subscription where the template leaves
+//     them empty.
+//   - Its metadata records the subscription it replaces, the first
+//     subscription of the chain and the change reason. The API does not
+//     confirm it stores subscription metadata, so keep the ReferenceID and
+//     PreviousReferenceID of the result as well.
+//   - The new request is validated before anything is canceled, so an
+//     incomplete template fails without touching the subscription.
+//   - The old subscription is canceled before the new one is created, so
+//     both never charge in the same period. If creating still fails the old
+//     one stays canceled and the error says so.
+type SubscriptionEditor struct {
+	Client SubscriptionEditorClient
+	// AllowRecreate falls back to cancel-and-recreate when a native endpoint
+	// is unavailable.
+	AllowRecreate bool
+	// ForceRecreate skips the native endpoints and always recreates.
+	ForceRecreate bool
+	// Paused keeps the subscriptions canceled by the Pause fallback, which are
+	// the only ones Resume recreates. NewSubscriptionEditor keeps them in
+	// memory; use a persistent store when Resume may run in another process.
+	Paused PausedSubscriptionStore
+	Now    func() time.Time
+
+	pausedOnce sync.Once
+}
+
+// PausedSubscriptionStore remembers the subscriptions canceled by the
+// SubscriptionEditor.Pause fallback until they are resumed.
+type PausedSubscriptionStore interface {
+	MarkPaused(ctx context.Context, referenceID string) error
+	IsPaused(ctx context.Context, referenceID string) (bool, error)
+	ClearPaused(ctx context.Context, referenceID string) error
+}
+
+// MemoryPausedSubscriptionStore is an in-memory PausedSubscriptionStore.
+type MemoryPausedSubscriptionStore struct {
+	mu     sync.Mutex
+	paused map[string]bool
+}
+
+// MarkPaused records that referenceID was canceled by a pause.
+func (s *MemoryPausedSubscriptionStore) MarkPaused(ctx context.Context, referenceID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.paused == nil {
+		s.paused = make(map[string]bool)
+	}
+	s.paused[referenceID] = true
+	return nil
+}
+
+// IsPaused reports whether referenceID was canceled by a pause.
+func (s *MemoryPausedSubscriptionStore) IsPaused(ctx context.Context, referenceID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.paused[referenceID], nil
+}
+
+// ClearPaused forgets referenceID.
+func (s *MemoryPausedSubscriptionStore) ClearPaused(ctx context.Context, referenceID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.paused, referenceID)
+	return nil
+}
+
+// NewSubscriptionEditor creates an editor using client, usually an *API,
+// keeping paused subscriptions in memory.
+func NewSubscriptionEditor(client SubscriptionEditorClient) *SubscriptionEditor {
+	return &SubscriptionEditor{Client: client, Paused: &MemoryPausedSubscriptionStore{}}
+}
+
+// ChangePlan changes the amount, period, cycle, payment date or title of a
+// subscription and prorates the amount change over the current period.
+func (e *SubscriptionEditor) ChangePlan(ctx context.Context, referenceID string, plan SubscriptionPlan, template SubscriptionCreateRequest) (SubscriptionChangeResult, error) {
+	detail, err := e.Client.GetSubscription(ctx, SubscriptionGetRequest{ReferenceID: referenceID})
+	if err != nil {
+		return SubscriptionChangeResult{}, err
+	}
+
+	var proration *Proration
+	if start, end, ok := currentSubscriptionPeriod(detail); ok && plan.Amount > 0 {
+		oldAmount, err := ParseAmount(detail.Amount, detail.Currency, "")
+		if err == nil {
+			p := ProrateSubscription(oldAmount, plan.Amount, start, end, e.now(), detail.Currency)
+			proration = &p
+		}
+	}
+
+	result, err := e.update(ctx, referenceID, detail, SubscriptionUpdateRequest{
+		ReferenceID: referenceID,
+		Amount:      plan.Amount,
+		Period:      plan.Period,
+		Cycle:       plan.Cycle,
+		PaymentDate: plan.PaymentDate,
+		Title:       plan.Title,
+	}, template, SubscriptionChangePlan)
+	result.Proration = proration
+	return result, err
+}
+
+// SwapCard charges future renewals of a subscription to cardID.
+func (e *SubscriptionEditor) SwapCard(ctx context.Context, referenceID, cardID string, template SubscriptionCreateRequest) (SubscriptionChangeResult, error) {
+	if cardID == "" {
+		return SubscriptionChangeResult{}, &ValidationError{StatusCode: 400, Code: 0, Message: "card_id is required"}
+	}
+	detail, err := e.Client.GetSubscription(ctx, SubscriptionGetRequest{ReferenceID: referenceID})
+	if err != nil {
+		return SubscriptionChangeResult{}, err
+	}
+	return e.update(ctx, referenceID, detail, SubscriptionUpdateRequest{
+		ReferenceID: referenceID,
+		CardID:      cardID,
+	}, template, SubscriptionChangeCard)
+}
+
+// Pause stops charging an active subscription. The subscription is read
+// first, so an unknown reference fails before anything is changed. When the
+// fallback is allowed and there is no native endpoint, the subscription is
+// canceled, recorded in Paused, and Resume recreates it.
+func (e *SubscriptionEditor) Pause(ctx context.Context, referenceID string) (SubscriptionChangeResult, error) {
+	result := SubscriptionChangeResult{ReferenceID: referenceID}
+	detail, err := e.Client.GetSubscription(ctx, SubscriptionGetRequest{ReferenceID: referenceID})
+	if err != nil {
+		return result, err
+	}
+	if !detail.IsActive {
+		return result, &ValidationError{StatusCode: 400, Code: 0, Message: fmt.Sprintf("subscription %s is not active", referenceID)}
+	}
+	if !e.ForceRecreate {
+		err := e.Client.PauseSubscription(ctx, SubscriptionPauseRequest{ReferenceID: referenceID})
+		if !isMissingEndpoint(err) {
+			return result, err
+		}
+		if !e.AllowRecreate {
+			return result, endpointUnavailable(err)
+		}
+	}
+	if err := e.Client.CancelSubscription(ctx, SubscriptionCancelRequest{ReferenceID: referenceID}); err != nil {
+		return result, err
+	}
+	result.Canceled = true
+	if err := e.paused().MarkPaused(ctx, referenceID); err != nil {
+		return result, fmt.Errorf("subscription %s was canceled but recording the pause failed: %w", referenceID, err)
+	}
+	return result, nil
+}
+
+// Resume resumes a paused subscription. Without a native endpoint an active
+// subscription is left alone. When the fallback is allowed, a subscription
+// canceled by the Pause fallback is recreated from template, and an inactive
+// one that Pause did not cancel is an error, since it was canceled on
+// purpose.
+func (e *SubscriptionEditor) Resume(ctx context.Context, referenceID string, template SubscriptionCreateRequest) (SubscriptionChangeResult, error) {
+	result := SubscriptionChangeResult{ReferenceID: referenceID}
+	detail, err := e.Client.GetSubscription(ctx, SubscriptionGetRequest{ReferenceID: referenceID})
+	if err != nil {
+		return result, err
+	}
+	if !e.ForceRecreate {
+		err := e.Client.ResumeSubscription(ctx, SubscriptionPauseRequest{ReferenceID: referenceID})
+		if !isMissingEndpoint(err) {
+			return result, err
+		}
+		if !detail.IsActive && !e.AllowRecreate {
+			return result, endpointUnavailable(err)
+		}
+	}
+	if detail.IsActive {
+		return result, nil
+	}
+	paused, err := e.paused().IsPaused(ctx, referenceID)
+	if err != nil {
+		return result, err
+	}
+	if !paused {
+		return result, &ValidationError{StatusCode: 400, Code: 0, Message: fmt.Sprintf("subscription %s was not paused by SubscriptionEditor and is not recreated", referenceID)}
+	}
+	result, err = e.recreate(ctx, referenceID, detail, SubscriptionUpdateRequest{}, template, SubscriptionChangeResume, true)
+	if err != nil {
+		return result, err
+	}
+	return result, e.paused().ClearPaused(ctx, referenceID)
+}
+
+func (e *SubscriptionEditor) update(ctx context.Context, referenceID string, detail SubscriptionDetail, update SubscriptionUpdateRequest, template SubscriptionCreateRequest, reason string) (SubscriptionChangeResult, error) {
+	if !e.ForceRecreate {
+		err := e.Client.UpdateSubscription(ctx, update)
+		if !isMissingEndpoint(err) {
+			return SubscriptionChangeResult{ReferenceID: referenceID}, err
+		}
+		if !e.AllowRecreate {
+			return SubscriptionChangeResult{ReferenceID: referenceID}, endpointUnavailable(err)
+		}
+	}
+	return e.recreate(ctx, referenceID, detail, update, template, reason, false)
+}
+
+func (e *SubscriptionEditor) recreate(ctx context.Context, referenceID string, detail SubscriptionDetail, update SubscriptionUpdateRequest, template SubscriptionCreateRequest, reason string, alreadyCanceled bool) (SubscriptionChangeResult, error) {
+	result := SubscriptionChangeResult{ReferenceID: referenceID}
+	request, err := recreateSubscriptionRequest(referenceID, detail, update, template, reason)
+	if err != nil {
+		return result, err
+	}
+	if err := validateRecreateRequest(ctx, e.Client, request); err != nil {
+		return result, err
+	}
+
+	if !alreadyCanceled {
+		// The call's Idempotency-Key belongs to the subscription created below.
+		if err := e.Client.CancelSubscription(withoutIdempotencyKey(ctx), SubscriptionCancelRequest{ReferenceID: referenceID}); err != nil {
+			return result, err
+		}
+	}
+	created, err := e.Client.CreateSubscription(ctx, request)
+	if err != nil {
+		return result, fmt.Errorf("subscription %s was canceled but recreating it failed: %w", referenceID, err)
+	}
+
+	result.ReferenceID = created.ReferenceID
+	result.PreviousReferenceID = referenceID
+	result.Recreated = true
+	return result, nil
+}
+
+// validateRecreateRequest runs the checks CreateSubscription would run, and
+// requires the user and card that GetSubscription does not return, so the
+// request cannot fail client-side after the old subscription is canceled.
+// Currencies are checked against the client's CurrencyRegistry when it has
+// one, as *API does, and against DefaultCurrencyRegistry otherwise.
+func validateRecreateRequest(ctx context.Context, client SubscriptionEditorClient, request SubscriptionCreateRequest) error {
+	var issues []FieldIssue
+	if request.Amount <= 0 {
+		issues = append(issues, FieldIssue{Field: "amount", Reason: FieldMissing, Message: "is required"})
+	}
+	if strings.TrimSpace(request.User.ID) == "" && strings.TrimSpace(request.User.Email) == "" {
+		issues = append(issues, FieldIssue{Field: "user", Reason: FieldMissing, Message: "id or email is required"})
+	}
+	if strings.TrimSpace(request.CardID) == "" {
+		issues = append(issues, FieldIssue{Field: "card_id", Reason: FieldMissing, Message: "is required"})
+	}
+	if len(issues) > 0 {
+		return &FieldValidationError{Issues: issues}
+	}
+	if err := request.Validate(); err != nil {
+		return err
+	}
+	if request.Currency != "" {
+		registry := DefaultCurrencyRegistry
+		if withRegistry, ok := client.(interface {
+			CurrencyRegistry(ctx context.Context) (*CurrencyRegistry, error)
+		}); ok {
+			if _, known := LookupCurrency(request.Currency); !known {
+				merged, err := withRegistry.CurrencyRegistry(ctx)
+				if err != nil {
+					return err
+				}
+				registry = merged
+			}
+		}
+		if _, err := registry.Validate(request.Currency); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recreateSubscriptionRequest builds the request that replaces referenceID.
+// The old payment day is kept only while the period stays the same, and a
+// payment day the period does not accept is an error.
+func recreateSubscriptionRequest(referenceID string, detail SubscriptionDetail, update SubscriptionUpdateRequest, template SubscriptionCreateRequest, reason string) (SubscriptionCreateRequest, error) {
+	request := template
+	if request.Amount == 0 {
+		request.Amount, _ = ParseAmount(detail.Amount, detail.Currency, "")
+	}
+	if request.Currency == "" {
+		request.Currency = detail.Currency
+	}
+	if request.Period == 0 {
+		request.Period = detail.Period
+	}
+	if request.Title == "" {
+		request.Title = detail.Title
+	}
+	if request.ExternalReferenceID == "" {
+		request.ExternalReferenceID = detail.ExternalReferenceID
+	}
+
+	if update.Amount > 0 {
+		request.Amount = update.Amount
+	}
+	if update.Period > 0 {
+		request.Period = update.Period
+	}
+	if update.Cycle > 0 {
+		request.Cycle = update.Cycle
+	}
+	if update.PaymentDate > 0 {
+		request.PaymentDate = update.PaymentDate
+	}
+	if update.Title != "" {
+		request.Title = update.Title
+	}
+	if update.CardID != "" {
+		request.CardID = update.CardID
+	}
+	if request.PaymentDate == 0 && request.Period == detail.Period {
+		request.PaymentDate = detail.PaymentDate
+	}
+	if err := request.Period.ValidatePaymentDay(request.PaymentDate); err != nil {
+		return request, err
+	}
+
+	original := referenceID
+	for _, item := range detail.Metadata {
+		if item.Key == SubscriptionMetadataOriginal && item.Value != "" {
+			original = item.Value
+		}
+	}
+	// The old subscription's metadata carries over; the template overrides
+	// keys it sets, and the chain keys are written last.
+	metadata := make([]OrderMetadata, 0, len(detail.Metadata)+len(template.Metadata)+3)
+	index := map[string]int{}
+	for _, item := range append(append([]OrderMetadata(nil), detail.Metadata...), template.Metadata...) {
+		switch item.Key {
+		case SubscriptionMetadataReplaces, SubscriptionMetadataOriginal, SubscriptionMetadataChangeReason:
+			continue
+		}
+		if i, ok := index[item.Key]; ok {
+			metadata[i] = item
+			continue
+		}
+		index[item.Key] = len(metadata)
+		metadata = append(metadata, item)
+	}
+	request.Metadata = append(metadata,
+		OrderMetadata{Key: SubscriptionMetadataReplaces, Value: referenceID},
+		OrderMetadata{Key: SubscriptionMetadataOriginal, Value: original},
+		OrderMetadata{Key: SubscriptionMetadataChangeReason, Value: reason},
+	)
+	return request, nil
+}
+
+// currentSubscriptionPeriod returns the billing period that started with the
+// last successful order.
+func currentSubscriptionPeriod(detail SubscriptionDetail) (time.Time, time.Time, bool) {
+	var start time.Time
+	for _, order := range detail.Orders {
+		if !order.Status.Is(SubscriptionStatusSuccess) {
+			continue
+		}
+		if paymentDate, ok := parseSubscriptionTime(order.PaymentDate); ok && paymentDate.After(start) {
+			start = paymentDate
+		}
+	}
+	if start.IsZero() {
+		return time.Time{}, time.Time{}, false
+	}
+	end := detail.Period.NextCharge(start, start, detail.PaymentDate)
+	return start, end, !end.IsZero()
+}
+
+// isMissingEndpoint reports whether err means the server has no such
+// endpoint. A 404 is not enough: the endpoints answer it for unknown
+// subscriptions too.
+func isMissingEndpoint(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.StatusCode {
+	case http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return true
+	}
+	return false
+}
+
+func endpointUnavailable(err error) error {
+	return fmt.Errorf("%w: %w", ErrNativeEndpointUnavailable, err)
+}
+
+func (e *SubscriptionEditor) paused() PausedSubscriptionStore {
+	e.pausedOnce.Do(func() {
+		if e.Paused == nil {
+			e.Paused = &MemoryPausedSubscriptionStore{}
+		}
+	})
+	return e.Paused
+}
+
+func (e *SubscriptionEditor) now() time.Time {
+	if e.Now != nil {
+		return e.Now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,367 @@
+package tapsilat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SubscriptionPeriod is the billing period of a subscription in days, as
+// sent in the period field. Periods of 28 to 31 days are billed monthly and
+// 365 or 366 days yearly, on the payment day of the month; any other value
+// bills every N days.
+type SubscriptionPeriod int
+
+const (
+	SubscriptionPeriodMonthly SubscriptionPeriod = 30
+	SubscriptionPeriodYearly  SubscriptionPeriod = 365
+)
+
+// SubscriptionPeriodDays returns a period that bills every n days.
+func SubscriptionPeriodDays(n int) SubscriptionPeriod {
+	return SubscriptionPeriod(n)
+}
+
+// IsMonthly reports whether the period bills once a calendar month.
+func (p SubscriptionPeriod) IsMonthly() bool {
+	return p >= 28 && p <= 31
+}
+
+// IsYearly reports whether the period bills once a calendar year.
+func (p SubscriptionPeriod) IsYearly() bool {
+	return p == 365 || p == 366
+}
+
+// Days returns the period length in days.
+func (p SubscriptionPeriod) Days() int {
+	return int(p)
+}
+
+func (p SubscriptionPeriod) String() string {
+	switch {
+	case p.IsMonthly():
+		return "monthly"
+	case p.IsYearly():
+		return "yearly"
+	case p == 1:
+		return "daily"
+	default:
+		return fmt.Sprintf("every %d days", int(p))
+	}
+}
+
+// Validate checks that the period is positive.
+func (p SubscriptionPeriod) Validate() error {
+	if p <= 0 {
+		return &ValidationError{
+			StatusCode: 400,
+			Code:       0,
+			Message:    fmt.Sprintf("subscription period must be positive, got %d", int(p)),
+		}
+	}
+	return nil
+}
+
+// ValidatePaymentDay checks a payment day against the period. Zero leaves
+// the choice to the server. Monthly and yearly periods accept 1 to 31; days
+// past the end of a shorter month charge on its last day. Day-based periods
+// are not tied to the calendar and accept no payment day.
+func (p SubscriptionPeriod) ValidatePaymentDay(day int) error {
+	if day == 0 {
+		return nil
+	}
+	if !p.IsMonthly() && !p.IsYearly() {
+		return &ValidationError{
+			StatusCode: 400,
+			Code:       0,
+			Message:    fmt.Sprintf("payment day is only supported for monthly and yearly subscriptions, period is %s", p),
+		}
+	}
+	if day < 1 || day > 31 {
+		return &ValidationError{
+			StatusCode: 400,
+			Code:       0,
+			Message:    fmt.Sprintf("payment day must be between 1 and 31, got %d", day),
+		}
+	}
+	return nil
+}
+
+// Advance moves t forward by n periods. Monthly and yearly periods land on
+// paymentDay, or on the day of t when paymentDay is zero, clamped to the
+// length of the target month.
+func (p SubscriptionPeriod) Advance(t time.Time, n, paymentDay int) time.Time {
+	switch {
+	case p.IsMonthly():
+		return addSubscriptionMonths(t, n, paymentDay)
+	case p.IsYearly():
+		return addSubscriptionMonths(t, 12*n, paymentDay)
+	default:
+		return t.AddDate(0, 0, n*int(p))
+	}
+}
+
+// NextCharge returns the first charge after now for a subscription last
+// charged at last, or the zero time when the period is not positive.
+func (p SubscriptionPeriod) NextCharge(last, now time.Time, paymentDay int) time.Time {
+	if p <= 0 || last.IsZero() {
+		return time.Time{}
+	}
+	n := 1
+	if !p.IsMonthly() && !p.IsYearly() && now.After(last) {
+		n = int(now.Sub(last)/(time.Duration(p)*24*time.Hour)) + 1
+	}
+	next := p.Advance(last, n, paymentDay)
+	for !next.After(now) {
+		n++
+		next = p.Advance(last, n, paymentDay)
+	}
+	return next
+}
+
+// addSubscriptionMonths moves t by months, landing on paymentDay (or the
+// original day when zero) clamped to the length of the target month.
+func addSubscriptionMonths(t time.Time, months, paymentDay int) time.Time {
+	day := paymentDay
+	if day <= 0 {
+		day = t.Day()
+	}
+	first := time.Date(t.Year(), t.Month()+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), 0, t.Location())
+	lastDay := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(day, lastDay)-1)
+}
+
+// SubscriptionCycle is the number of charges a subscription makes before it
+// ends. Zero means it renews until canceled.
+type SubscriptionCycle int
+
+const SubscriptionCycleUnlimited SubscriptionCycle = 0
+
+// Finite reports whether the subscription ends after a fixed number of
+// charges.
+func (c SubscriptionCycle) Finite() bool {
+	return c > 0
+}
+
+// Remaining returns how many charges are left after charged charges. ok is
+// false for unlimited cycles.
+func (c SubscriptionCycle) Remaining(charged int) (remaining int, ok bool) {
+	if !c.Finite() {
+		return 0, false
+	}
+	return max(0, int(c)-charged), true
+}
+
+func (c SubscriptionCycle) String() string {
+	if !c.Finite() {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%d charges", int(c))
+}
+
+// Validate checks that the cycle is not negative.
+func (c SubscriptionCycle) Validate() error {
+	if c < 0 {
+		return &ValidationError{
+			StatusCode: 400,
+			Code:       0,
+			Message:    fmt.Sprintf("subscription cycle must not be negative, got %d", int(c)),
+		}
+	}
+	return nil
+}
+
+// SubscriptionPaymentStatus is the payment status of a subscription or of
+// one of its orders.
+type SubscriptionPaymentStatus string
+
+// Normalize returns the status lower-cased and trimmed, as the server does
+// not use a consistent case.
+func (s SubscriptionPaymentStatus) Normalize() SubscriptionPaymentStatus {
+	return SubscriptionPaymentStatus(strings.ToLower(strings.TrimSpace(string(s))))
+}
+
+// Is reports whether s equals status, ignoring case.
+func (s SubscriptionPaymentStatus) Is(status SubscriptionPaymentStatus) bool {
+	return s.Normalize() == status.Normalize()
+}
+
+// Validate checks the period, cycle and payment day of the request.
+func (r SubscriptionCreateRequest) Validate() error {
+	if r.Period != 0 {
+		if err := r.Period.Validate(); err != nil {
+			return err
+		}
+		if err := r.Period.ValidatePaymentDay(r.PaymentDate); err != nil {
+			return err
+		}
+	} else if r.PaymentDate < 0 || r.PaymentDate > 31 {
+		return SubscriptionPeriodMonthly.ValidatePaymentDay(r.PaymentDate)
+	}
+	return r.Cycle.Validate()
+}
+
+// SubscriptionSchedule describes when a subscription charges and how much.
+// Start is the first charge.
+type SubscriptionSchedule struct {
+	Start      time.Time
+	Period     SubscriptionPeriod
+	PaymentDay int
+	Cycle      SubscriptionCycle
+	Amount     float64
+	Currency   string
+}
+
+// ScheduledCharge is one projected charge. Index counts from zero at the
+// schedule start.
+type ScheduledCharge struct {
+	Index  int       `json:"index"`
+	At     time.Time `json:"at"`
+	Amount float64   `json:"amount"`
+}
+
+// SubscriptionProjection is a window of projected charges.
+type SubscriptionProjection struct {
+	Currency string            `json:"currency,omitempty"`
+	Charges  []ScheduledCharge `json:"charges"`
+	// Total is the sum of Charges, rounded to the currency minor units.
+	Total float64 `json:"total"`
+	// More reports whether the schedule continues past the last charge
+	// returned.
+	More bool `json:"more"`
+}
+
+// NewSubscriptionSchedule builds the schedule of a subscription request whose
+// first charge is at start.
+func NewSubscriptionSchedule(request SubscriptionCreateRequest, start time.Time) SubscriptionSchedule {
+	return SubscriptionSchedule{
+		Start:      start,
+		Period:     request.Period,
+		PaymentDay: request.PaymentDate,
+		Cycle:      request.Cycle,
+		Amount:     request.Amount,
+		Currency:   request.Currency,
+	}
+}
+
+// Validate checks the period, cycle and payment day of the schedule.
+func (s SubscriptionSchedule) Validate() error {
+	if err := s.Period.Validate(); err != nil {
+		return err
+	}
+	if err := s.Period.ValidatePaymentDay(s.PaymentDay); err != nil {
+		return err
+	}
+	if s.Start.IsZero() {
+		return &ValidationError{StatusCode: 400, Code: 0, Message: "subscription schedule start is required"}
+	}
+	return s.Cycle.Validate()
+}
+
+// ChargeAt returns the date of the charge with the given index, and false
+// when the schedule has no such charge.
+func (s SubscriptionSchedule) ChargeAt(index int) (time.Time, bool) {
+	if index < 0 || s.Period <= 0 || (s.Cycle.Finite() && index >= int(s.Cycle)) {
+		return time.Time{}, false
+	}
+	if index == 0 {
+		return s.Start, true
+	}
+	day := s.PaymentDay
+	if day == 0 {
+		day = s.Start.Day()
+	}
+	return s.Period.Advance(s.Start, index, day), true
+}
+
+// End returns the last charge of a finite schedule.
+func (s SubscriptionSchedule) End() (time.Time, bool) {
+	if !s.Cycle.Finite() {
+		return time.Time{}, false
+	}
+	return s.ChargeAt(int(s.Cycle) - 1)
+}
+
+// Project returns up to limit charges falling at or after from. Unlimited
+// schedules always report More.
+func (s SubscriptionSchedule) Project(from time.Time, limit int) (SubscriptionProjection, error) {
+	projection := SubscriptionProjection{Currency: s.Currency, Charges: []ScheduledCharge{}}
+	if err := s.Validate(); err != nil {
+		return projection, err
+	}
+
+	index := s.firstIndexFrom(from)
+	amount := AmountToMinor(s.Amount, s.Currency)
+	var total int64
+	for len(projection.Charges) < limit {
+		at, ok := s.ChargeAt(index)
+		if !ok {
+			break
+		}
+		projection.Charges = append(projection.Charges, ScheduledCharge{
+			Index:  index,
+			At:     at,
+			Amount: MinorToAmount(amount, s.Currency),
+		})
+		total += amount
+		index++
+	}
+	_, projection.More = s.ChargeAt(index)
+	projection.Total = MinorToAmount(total, s.Currency)
+	return projection, nil
+}
+
+// Between returns every charge in [from, to).
+func (s SubscriptionSchedule) Between(from, to time.Time) (SubscriptionProjection, error) {
+	projection := SubscriptionProjection{Currency: s.Currency, Charges: []ScheduledCharge{}}
+	if err := s.Validate(); err != nil {
+		return projection, err
+	}
+
+	amount := AmountToMinor(s.Amount, s.Currency)
+	var total int64
+	index := s.firstIndexFrom(from)
+	for {
+		at, ok := s.ChargeAt(index)
+		if !ok {
+			break
+		}
+		if !at.Before(to) {
+			projection.More = true
+			break
+		}
+		projection.Charges = append(projection.Charges, ScheduledCharge{
+			Index:  index,
+			At:     at,
+			Amount: MinorToAmount(amount, s.Currency),
+		})
+		total += amount
+		index++
+	}
+	projection.Total = MinorToAmount(total, s.Currency)
+	return projection, nil
+}
+
+// firstIndexFrom returns the index of the first charge at or after from.
+func (s SubscriptionSchedule) firstIndexFrom(from time.Time) int {
+	if !from.After(s.Start) {
+		return 0
+	}
+	days := int(from.Sub(s.Start) / (24 * time.Hour))
+	var index int
+	switch {
+	case s.Period.IsMonthly():
+		index = days / 31
+	case s.Period.IsYearly():
+		index = days / 366
+	default:
+		index = days / int(s.Period)
+	}
+	for {
+		at, ok := s.ChargeAt(index)
+		if !ok || !at.Before(from) {
+			return index
+		}
+		index++
+	}
+}
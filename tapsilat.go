@@ -3,7 +3,6 @@ package tapsilat
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -16,13 +15,44 @@ import (
 // TapsilatAPI is the main struct for the Tapsilat API
 type API struct {
 	EndPoint string `json:"end_point"`
-	Token    string `json:"token"`
+	Token    Secret `json:"token"`
 	Timeout  time.Duration
 	client   *http.Client
 
-	currencyRefsMu     sync.RWMutex
-	currencyIDsByUnit  map[string]string
-	currencyCacheReady bool
+	// TokenSource, when set, supplies the bearer token of every request in
+	// place of Token. Set it before the first request.
+	TokenSource TokenSource `json:"-"`
+	// StrictDecoding makes every call fail with a *FieldValidationError when
+	// a response has fields the DTO does not model, lacks fields it requires,
+	// or sends a number for a string or the reverse. Meant for tests; see
+	// UnmarshalStrict.
+	StrictDecoding bool `json:"-"`
+
+	// ReferenceCacheTTL controls how long currencies, VPOS acquirers, card
+	// schemes and acquirer templates are cached. Zero uses
+	// DefaultReferenceCacheTTL and a negative TTL never expires them. Set it
+	// before the first request.
+	ReferenceCacheTTL time.Duration
+	// ReferenceCacheDir, when set, persists cached reference data as JSON
+	// files in this directory so it survives restarts. Use one directory per
+	// organization token. Set it before the first request.
+	ReferenceCacheDir string
+	// CurrencyCacheTTL overrides ReferenceCacheTTL for the organization
+	// currency list, which changes more often when currencies are added from
+	// the panel. Zero uses ReferenceCacheTTL and a negative TTL never expires
+	// the list. Set it before the first request.
+	CurrencyCacheTTL time.Duration
+	// Now, when set, replaces time.Now for the expiry of cached reference
+	// data. Set it before the first request.
+	Now func() time.Time `json:"-"`
+
+	refsOnce sync.Once
+	refs     *referenceCaches
+
+	currencyMissMu sync.Mutex
+	currencyMisses map[string]time.Time
+
+	tokenMu sync.RWMutex
 }
 
 // NewAPI creates a new TapsilatAPI struct
@@ -30,7 +60,7 @@ func NewAPI(token string) *API {
 	timeout := 30 * time.Second
 	return &API{
 		EndPoint: "https://panel.tapsilat.dev/api/v1",
-		Token:    token,
+		Token:    Secret(token),
 		Timeout:  timeout,
 		client:   &http.Client{Timeout: timeout},
 	}
@@ -41,15 +71,41 @@ func NewCustomAPI(endpoint, token string) *API {
 	timeout := 30 * time.Second
 	return &API{
 		EndPoint: endpoint,
-		Token:    token,
+		Token:    Secret(token),
 		Timeout:  timeout,
 		client:   &http.Client{Timeout: timeout},
 	}
 }
 
+// NewCustomAPIWithClient creates a new TapsilatAPI struct that sends its
+// requests through client, for example to share one transport between many
+// clients.
+func NewCustomAPIWithClient(endpoint, token string, client *http.Client) *API {
+	return &API{
+		EndPoint: endpoint,
+		Token:    Secret(token),
+		Timeout:  client.Timeout,
+		client:   client,
+	}
+}
+
+// SetToken replaces the bearer token used by later requests. Unlike writing
+// Token directly, it is safe while requests are in flight.
+func (t *API) SetToken(token string) {
+	t.tokenMu.Lock()
+	defer t.tokenMu.Unlock()
+	t.Token = Secret(token)
+}
+
+func (t *API) bearerToken() Secret {
+	t.tokenMu.RLock()
+	defer t.tokenMu.RUnlock()
+	return t.Token
+}
+
 func (t *API) post(ctx context.Context, path string, payload any, response any) error {
 	url := t.EndPoint + path
-	jsonPayload, err := json.Marshal(payload)
+	jsonPayload, err := MarshalRequest(payload)
 	if err != nil {
 		return err
 	}
@@ -64,7 +120,7 @@ func (t *API) post(ctx context.Context, path string, payload any, response any)
 
 func (t *API) patch(ctx context.Context, path string, payload any, response any) error {
 	url := t.EndPoint + path
-	jsonPayload, err := json.Marshal(payload)
+	jsonPayload, err := MarshalRequest(payload)
 	if err != nil {
 		return err
 	}
@@ -96,31 +152,84 @@ func (t *API) delete(ctx context.Context, path string, response any) error {
 }
 
 func (t *API) do(req *http.Request, response any) error {
-	req.Header.Set("Authorization", "Bearer "+t.Token)
 	req.Header.Set("Accept", "application/json")
+	options := requestOptionsFrom(req.Context(), req.Method)
+	for key, values := range options.header {
+		req.Header[key] = values
+	}
+	if options.timeout > 0 {
+		ctx, cancel := context.WithTimeout(req.Context(), options.timeout)
+		defer cancel()
+		req = req.WithContext(ctx)
+	}
 
-	resp, err := t.client.Do(req)
+	source := t.tokenSource(req.Context())
+	token, err := source.Token(req.Context())
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	start, attempts := time.Now(), 1
+	resp, body, err := t.send(req, token)
 	if err != nil {
 		return err
 	}
 
+	// Retry once when a refreshed token differs from the rejected one.
+	if resp.StatusCode == http.StatusUnauthorized && (req.Body == nil || req.GetBody != nil) {
+		if refreshable, ok := source.(RefreshableTokenSource); ok {
+			if err := refreshable.Refresh(req.Context(), token); err != nil {
+				return err
+			}
+		}
+		if refreshed, err := source.Token(req.Context()); err == nil && refreshed != token {
+			retry := req.Clone(req.Context())
+			if req.GetBody != nil {
+				if retry.Body, err = req.GetBody(); err != nil {
+					return err
+				}
+			}
+			attempts++
+			if resp, body, err = t.send(retry, refreshed); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(options.raw) > 0 || len(options.responses) > 0 {
+		envelope := newResponse(resp, body, time.Since(start), attempts)
+		captureMu.Lock()
+		for _, raw := range options.raw {
+			*raw = body
+		}
+		for _, response := range options.responses {
+			*response = envelope
+		}
+		captureMu.Unlock()
+	}
+
 	// Check for HTTP errors
 	if resp.StatusCode >= 400 {
-		return newAPIError(resp.StatusCode, resp.Status, body)
+		apiErr := newAPIError(resp.StatusCode, resp.Status, body)
+		apiErr.RequestID = requestID(resp.Header)
+		return apiErr
 	}
 
-	decode := json.NewDecoder(bytes.NewReader(body))
-	decode.UseNumber()
-	if err := decode.Decode(response); err != nil {
-		return err
+	return decodeJSON(body, response, t.StrictDecoding)
+}
+
+func (t *API) send(req *http.Request, token Secret) (*http.Response, []byte, error) {
+	req.Header.Set("Authorization", "Bearer "+token.Reveal())
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, nil, err
 	}
-	return nil
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, body, nil
 }
 
 func (t *API) CreateOrder(ctx context.Context, payload Order) (OrderResponse, error) {
@@ -135,6 +244,15 @@ func (t *API) CreateOrder(ctx context.Context, payload Order) (OrderResponse, er
 		payload.Buyer.GsmNumber = cleanedGSM
 	}
 
+	// Validate currency code if provided
+	if payload.Currency != "" {
+		currency, err := t.validateCurrency(ctx, payload.Currency)
+		if err != nil {
+			return response, err
+		}
+		payload.Currency = currency
+	}
+
 	err := t.post(ctx, "/order/create", payload, &response)
 	if err != nil {
 		return response, err
@@ -142,7 +260,7 @@ func (t *API) CreateOrder(ctx context.Context, payload Order) (OrderResponse, er
 
 	// If order creation successful and we have a reference ID, get the checkout URL
 	if response.ReferenceID != "" {
-		checkoutURL, err := t.GetCheckoutURL(ctx, response.ReferenceID)
+		checkoutURL, err := t.GetCheckoutURL(withoutCapture(ctx), response.ReferenceID)
 		if err == nil && checkoutURL != "" {
 			response.CheckoutURL = checkoutURL
 		}
@@ -232,7 +350,7 @@ func (t *API) GetOrderTransactions(ctx context.Context, referenceID string) (map
 // can be identified by its order ID, order reference ID or conversation ID.
 func (t *API) GetOrderPayments(ctx context.Context, payload GetOrderPaymentsRequest) (GetOrderPaymentsResponse, error) {
 	var response GetOrderPaymentsResponse
-	err := t.post(ctx, "/order/payments", payload, &response)
+	err := t.post(withoutIdempotencyKey(ctx), "/order/payments", payload, &response)
 	return response, err
 }
 
@@ -387,14 +505,33 @@ func (t *API) ListSubmerchants(ctx context.Context, page, perPage int) (Submerch
 	return response, err
 }
 
+// UpdateSubmerchant sends the non-empty fields of payload; the others keep
+// their stored value. CurrencyID may be an ID or a currency code.
 func (t *API) UpdateSubmerchant(ctx context.Context, id string, payload SubmerchantUpdateRequest) (SubmerchantMutationResponse, error) {
 	var response SubmerchantMutationResponse
-	currencyID, err := t.normalizeCurrencyID(ctx, payload.CurrencyID)
-	if err != nil {
-		return response, err
+	if payload.CurrencyID != "" {
+		currencyID, err := t.normalizeCurrencyID(ctx, payload.CurrencyID)
+		if err != nil {
+			return response, err
+		}
+		payload.CurrencyID = currencyID
 	}
-	payload.CurrencyID = currencyID
-	err = t.patch(ctx, "/submerchants/"+id, payload, &response)
+	err := t.patch(ctx, "/submerchants/"+id, payload, &response)
+	return response, err
+}
+
+// PatchSubmerchant sends the non-nil fields of payload, including empty
+// strings, to clear stored values.
+func (t *API) PatchSubmerchant(ctx context.Context, id string, payload SubmerchantPatch) (SubmerchantMutationResponse, error) {
+	var response SubmerchantMutationResponse
+	if payload.CurrencyID != nil && *payload.CurrencyID != "" {
+		currencyID, err := t.normalizeCurrencyID(ctx, *payload.CurrencyID)
+		if err != nil {
+			return response, err
+		}
+		payload.CurrencyID = &currencyID
+	}
+	err := t.patch(ctx, "/submerchants/"+id, payload, &response)
 	return response, err
 }
 
@@ -457,14 +594,33 @@ func (t *API) GetVpos(ctx context.Context, id string) (Vpos, error) {
 	return response, err
 }
 
+// UpdateVpos sends the non-empty fields of payload; the others keep their
+// stored value. Currencies may hold IDs or currency codes.
 func (t *API) UpdateVpos(ctx context.Context, id string, payload VposUpdateRequest) (VposMutationResponse, error) {
 	var response VposMutationResponse
-	currencies, err := t.normalizeCurrencyIDs(ctx, payload.Currencies)
-	if err != nil {
-		return response, err
+	if len(payload.Currencies) > 0 {
+		currencies, err := t.normalizeCurrencyIDs(ctx, payload.Currencies)
+		if err != nil {
+			return response, err
+		}
+		payload.Currencies = currencies
 	}
-	payload.Currencies = currencies
-	err = t.patch(ctx, "/vpos/"+id, payload, &response)
+	err := t.patch(ctx, "/vpos/"+id, payload, &response)
+	return response, err
+}
+
+// PatchVpos sends the non-nil fields of payload, so Priority 0, false flags
+// and empty lists can be set without re-sending the credentials.
+func (t *API) PatchVpos(ctx context.Context, id string, payload VposPatch) (VposMutationResponse, error) {
+	var response VposMutationResponse
+	if payload.Currencies != nil && len(*payload.Currencies) > 0 {
+		currencies, err := t.normalizeCurrencyIDs(ctx, *payload.Currencies)
+		if err != nil {
+			return response, err
+		}
+		payload.Currencies = &currencies
+	}
+	err := t.patch(ctx, "/vpos/"+id, payload, &response)
 	return response, err
 }
 
@@ -517,12 +673,22 @@ func (t *API) GetVposSubmerchant(ctx context.Context, id string) (VposSubmerchan
 	return response, err
 }
 
+// UpdateVposSubmerchant sends the non-empty fields of payload; the others
+// keep their stored value.
 func (t *API) UpdateVposSubmerchant(ctx context.Context, id string, payload VposSubmerchantUpdateRequest) (VposSubmerchantMutationResponse, error) {
 	var response VposSubmerchantMutationResponse
 	err := t.patch(ctx, "/vpos-submerchant/"+id, payload, &response)
 	return response, err
 }
 
+// PatchVposSubmerchant sends the non-nil fields of payload, including empty
+// strings, to clear stored values.
+func (t *API) PatchVposSubmerchant(ctx context.Context, id string, payload VposSubmerchantPatch) (VposSubmerchantMutationResponse, error) {
+	var response VposSubmerchantMutationResponse
+	err := t.patch(ctx, "/vpos-submerchant/"+id, payload, &response)
+	return response, err
+}
+
 func (t *API) DeleteVposSubmerchant(ctx context.Context, id string) (VposSubmerchantMutationResponse, error) {
 	var response VposSubmerchantMutationResponse
 	err := t.delete(ctx, "/vpos-submerchant/"+id, &response)
@@ -545,7 +711,7 @@ func (t *API) GetSubmerchantBySuborganization(ctx context.Context, suborganizati
 
 func (t *API) GetSubscription(ctx context.Context, payload SubscriptionGetRequest) (SubscriptionDetail, error) {
 	var response SubscriptionDetail
-	err := t.post(ctx, "/subscription", payload, &response)
+	err := t.post(withoutIdempotencyKey(ctx), "/subscription", payload, &response)
 	return response, err
 }
 
@@ -557,6 +723,19 @@ func (t *API) CancelSubscription(ctx context.Context, payload SubscriptionCancel
 
 func (t *API) CreateSubscription(ctx context.Context, payload SubscriptionCreateRequest) (SubscriptionCreateResponse, error) {
 	var response SubscriptionCreateResponse
+
+	if err := payload.Validate(); err != nil {
+		return response, err
+	}
+
+	if payload.Currency != "" {
+		currency, err := t.validateCurrency(ctx, payload.Currency)
+		if err != nil {
+			return response, err
+		}
+		payload.Currency = currency
+	}
+
 	err := t.post(ctx, "/subscription/create", payload, &response)
 	return response, err
 }
@@ -574,10 +753,38 @@ func (t *API) RedirectSubscription(ctx context.Context, payload SubscriptionRedi
 	return response, err
 }
 
+// UpdateSubscription changes the plan or card of a subscription in place.
+func (t *API) UpdateSubscription(ctx context.Context, payload SubscriptionUpdateRequest) error {
+	var response map[string]any
+	err := t.post(ctx, "/subscription/update", payload, &response)
+	return err
+}
+
+// PauseSubscription stops charging a subscription until it is resumed.
+func (t *API) PauseSubscription(ctx context.Context, payload SubscriptionPauseRequest) error {
+	var response map[string]any
+	err := t.post(ctx, "/subscription/pause", payload, &response)
+	return err
+}
+
+// ResumeSubscription resumes charging a paused subscription.
+func (t *API) ResumeSubscription(ctx context.Context, payload SubscriptionPauseRequest) error {
+	var response map[string]any
+	err := t.post(ctx, "/subscription/resume", payload, &response)
+	return err
+}
+
 // TokenizeCard tokenizes a card and returns 3D secure form details when required.
 func (t *API) TokenizeCard(ctx context.Context, payload CardTokenizeRequest) (CardTokenizeResponse, error) {
 	var response CardTokenizeResponse
-	err := t.post(ctx, "/tokenization/card/tokenize", payload, &response)
+
+	// Validate card data before it leaves the client
+	payload, err := payload.Validate()
+	if err != nil {
+		return response, err
+	}
+
+	err = t.post(ctx, "/tokenization/card/tokenize", payload, &response)
 	return response, err
 }
 
@@ -12,7 +12,7 @@ func TestAPICreation(t *testing.T) {
 		api := tapsilat.NewAPI("test_token")
 
 		assert.NotNil(t, api)
-		assert.Equal(t, "test_token", api.Token)
+		assert.Equal(t, "test_token", api.Token.Reveal())
 		assert.Equal(t, "https://panel.tapsilat.dev/api/v1", api.EndPoint)
 		assert.NotZero(t, api.Timeout)
 	})
@@ -22,7 +22,7 @@ func TestAPICreation(t *testing.T) {
 		api := tapsilat.NewCustomAPI(customEndpoint, "custom_token")
 
 		assert.NotNil(t, api)
-		assert.Equal(t, "custom_token", api.Token)
+		assert.Equal(t, "custom_token", api.Token.Reveal())
 		assert.Equal(t, customEndpoint, api.EndPoint)
 		assert.NotZero(t, api.Timeout)
 	})
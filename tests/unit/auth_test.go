@@ -0,0 +1,121 @@
+package unit_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tapsilat "github.com/tapsilat/tapsilat-go"
+)
+
+type authRecorder struct {
+	mu     sync.Mutex
+	auth   []string
+	bodies []string
+	valid  map[string]bool
+}
+
+func (a *authRecorder) server(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		auth := r.Header.Get("Authorization")
+		a.mu.Lock()
+		a.auth = append(a.auth, auth)
+		a.bodies = append(a.bodies, string(body))
+		valid := a.valid == nil || a.valid[auth]
+		a.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if !valid {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"message":"invalid token"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"currencies":[],"user_id":"u_1"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestTokenSources(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("RefreshesAndRetriesOnceAfter401", func(t *testing.T) {
+		recorder := &authRecorder{valid: map[string]bool{"Bearer fetched-2": true}}
+		api := tapsilat.NewCustomAPI(recorder.server(t).URL, "unused")
+		fetches := 0
+		api.TokenSource = &tapsilat.RefreshingTokenSource{
+			Fetch: func(ctx context.Context) (string, time.Time, error) {
+				fetches++
+				return fmt.Sprintf("fetched-%d", fetches), time.Time{}, nil
+			},
+		}
+
+		_, err := api.CreateOrganizationUser(ctx, tapsilat.OrgCreateUserRequest{Email: "jane@example.com"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Bearer fetched-1", "Bearer fetched-2"}, recorder.auth)
+		assert.Equal(t, recorder.bodies[0], recorder.bodies[1])
+		assert.Contains(t, recorder.bodies[1], "jane@example.com")
+
+		recorder.valid = map[string]bool{}
+		_, err = api.GetOrganizationCurrencies(ctx)
+		var apiErr *tapsilat.APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+		assert.Equal(t, 3, fetches)
+		assert.Len(t, recorder.auth, 4)
+	})
+
+	t.Run("StaticTokenIsNotRetried", func(t *testing.T) {
+		recorder := &authRecorder{valid: map[string]bool{}}
+		api := tapsilat.NewCustomAPI(recorder.server(t).URL, "static")
+
+		_, err := api.GetOrganizationCurrencies(ctx)
+		require.Error(t, err)
+		assert.Equal(t, []string{"Bearer static"}, recorder.auth)
+	})
+
+	t.Run("ReadsRotatedFileAndEnv", func(t *testing.T) {
+		recorder := &authRecorder{}
+		api := tapsilat.NewCustomAPI(recorder.server(t).URL, "unused")
+		path := filepath.Join(t.TempDir(), "token")
+		require.NoError(t, os.WriteFile(path, []byte("file-1\n"), 0o600))
+		api.TokenSource = tapsilat.NewFileTokenSource(path)
+
+		_, err := api.GetOrganizationCurrencies(ctx)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path, []byte("file-rotated\n"), 0o600))
+		_, err = api.GetOrganizationCurrencies(ctx)
+		require.NoError(t, err)
+
+		t.Setenv("TAPSILAT_TEST_TOKEN", "env-1")
+		api.TokenSource = tapsilat.EnvTokenSource("TAPSILAT_TEST_TOKEN")
+		_, err = api.GetOrganizationCurrencies(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Bearer file-1", "Bearer file-rotated", "Bearer env-1"}, recorder.auth)
+
+		t.Setenv("TAPSILAT_TEST_TOKEN", "")
+		_, err = api.GetOrganizationCurrencies(ctx)
+		require.EqualError(t, err, "tapsilat: environment variable TAPSILAT_TEST_TOKEN is empty")
+	})
+
+	t.Run("ContextOverridesClientToken", func(t *testing.T) {
+		recorder := &authRecorder{}
+		api := tapsilat.NewCustomAPI(recorder.server(t).URL, "org")
+
+		_, err := api.GetOrganizationCurrencies(tapsilat.ContextWithToken(ctx, "override"))
+		require.NoError(t, err)
+		api.SetToken("rotated")
+		_, err = api.GetOrganizationCurrencies(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Bearer override", "Bearer rotated"}, recorder.auth)
+	})
+}
@@ -0,0 +1,174 @@
+package unit_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tapsilat "github.com/tapsilat/tapsilat-go"
+)
+
+func TestCardValidation(t *testing.T) {
+	t.Run("LuhnCheck", func(t *testing.T) {
+		assert.True(t, tapsilat.LuhnValid("4111 1111 1111 1111"))
+		assert.True(t, tapsilat.LuhnValid("5526080000000006"))
+		assert.False(t, tapsilat.LuhnValid("4111111111111112"))
+		assert.False(t, tapsilat.LuhnValid("4111a11111111111"))
+	})
+
+	t.Run("DetectsBrandFromBIN", func(t *testing.T) {
+		cases := map[string]tapsilat.CardBrand{
+			"4111111111111111": tapsilat.CardBrandVisa,
+			"5526080000000006": tapsilat.CardBrandMastercard,
+			"2223000048400011": tapsilat.CardBrandMastercard,
+			"378282246310005":  tapsilat.CardBrandAmex,
+			"9792030394440796": tapsilat.CardBrandTroy,
+			"6011111111111117": tapsilat.CardBrandDiscover,
+			"3530111333300000": tapsilat.CardBrandJCB,
+			"6200000000000005": tapsilat.CardBrandUnionPay,
+			"6759649826438453": tapsilat.CardBrandMaestro,
+			"6304000000000000": tapsilat.CardBrandMaestro,
+			"5600000000000003": tapsilat.CardBrandMaestro,
+			"6000000000000000": tapsilat.CardBrandUnknown,
+			"6600000000000000": tapsilat.CardBrandUnknown,
+			"1234567890123456": tapsilat.CardBrandUnknown,
+		}
+		for number, brand := range cases {
+			assert.Equal(t, brand, tapsilat.DetectCardBrand(number), number)
+		}
+	})
+
+	t.Run("ValidateCardNumber", func(t *testing.T) {
+		number, brand, err := tapsilat.ValidateCardNumber(" 4111-1111-1111-1111 ")
+		require.NoError(t, err)
+		assert.Equal(t, "4111111111111111", number)
+		assert.Equal(t, tapsilat.CardBrandVisa, brand)
+
+		_, _, err = tapsilat.ValidateCardNumber("4111111111111112")
+		assert.Contains(t, err.Error(), "Card number is invalid")
+
+		_, _, err = tapsilat.ValidateCardNumber("37828224631000")
+		require.Error(t, err)
+	})
+
+	t.Run("CVVLengthByBrand", func(t *testing.T) {
+		_, err := tapsilat.ValidateCVV("123", tapsilat.CardBrandVisa)
+		require.NoError(t, err)
+		_, err = tapsilat.ValidateCVV("1234", tapsilat.CardBrandAmex)
+		require.NoError(t, err)
+		_, err = tapsilat.ValidateCVV("123", tapsilat.CardBrandAmex)
+		assert.Contains(t, err.Error(), "CVV must be 4 digits")
+		_, err = tapsilat.ValidateCVV("12a", tapsilat.CardBrandVisa)
+		require.Error(t, err)
+	})
+
+	t.Run("ExpiryInThePast", func(t *testing.T) {
+		now := time.Date(2026, time.March, 31, 23, 0, 0, 0, time.UTC)
+
+		month, year, err := tapsilat.ValidateCardExpiry("3", "26", now)
+		require.NoError(t, err)
+		assert.Equal(t, "03", month)
+		assert.Equal(t, "26", year)
+
+		_, _, err = tapsilat.ValidateCardExpiry("02", "2026", now)
+		assert.Contains(t, err.Error(), "Card expired in 02/2026")
+
+		_, _, err = tapsilat.ValidateCardExpiry("13", "2030", now)
+		require.Error(t, err)
+		_, _, err = tapsilat.ValidateCardExpiry("12", "203", now)
+		require.Error(t, err)
+	})
+
+	t.Run("HolderName", func(t *testing.T) {
+		name, err := tapsilat.ValidateHolderName("  Ayşe \t Yılmaz-Öztürk ")
+		require.NoError(t, err)
+		assert.Equal(t, "Ayşe Yılmaz-Öztürk", name)
+
+		_, err = tapsilat.ValidateHolderName("John 4111")
+		require.Error(t, err)
+		_, err = tapsilat.ValidateHolderName("   ")
+		require.Error(t, err)
+	})
+
+	t.Run("ResolveCardSchemeMatchesListCardSchemes", func(t *testing.T) {
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"items":[{"id":"cs_1","name":"VISA"},{"id":"cs_2","name":"MasterCard"},{"id":"cs_3","name":"American Express"}]}`))
+		}))
+		defer server.Close()
+
+		api := tapsilat.NewCustomAPI(server.URL, "token_cs")
+		scheme, err := api.ResolveCardScheme(context.Background(), "5526080000000006")
+		require.NoError(t, err)
+		assert.Equal(t, "cs_2", scheme.ID)
+		scheme, err = api.ResolveCardScheme(context.Background(), "378282246310005")
+		require.NoError(t, err)
+		assert.Equal(t, "cs_3", scheme.ID)
+		_, err = api.ResolveCardScheme(context.Background(), "9792030394440796")
+		require.Error(t, err)
+		assert.Equal(t, int32(1), requests.Load())
+	})
+}
+
+func TestTokenizeCardValidatesBeforeSending(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	api := tapsilat.NewCustomAPI(server.URL, "token_tok")
+	_, err := api.TokenizeCard(context.Background(), tapsilat.CardTokenizeRequest{
+		CardNumber:  "4111111111111112",
+		HolderName:  "John Doe",
+		ExpiryMonth: "12",
+		ExpiryYear:  futureExpiryYear(),
+		CVV:         "123",
+	})
+	var validationErr *tapsilat.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, int32(0), requests.Load())
+}
+
+func TestCardTokenizeRequestRedaction(t *testing.T) {
+	request := tapsilat.CardTokenizeRequest{
+		CardNumber:  "5526080000000006",
+		HolderName:  "John Doe",
+		ExpiryMonth: "12",
+		ExpiryYear:  "2030",
+		CVV:         "123",
+		UserId:      "user_1",
+	}
+
+	for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+		text := fmt.Sprintf(format, request)
+		assert.NotContains(t, text, "5526080000000006", format)
+		assert.NotContains(t, text, "CVV:123", format)
+		assert.Contains(t, text, "552608******0006", format)
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	logger.Info("tokenize", "request", request)
+	assert.NotContains(t, buf.String(), "5526080000000006")
+	assert.Contains(t, buf.String(), `"cvv":"***"`)
+	assert.Contains(t, buf.String(), `"user_id":"user_1"`)
+}
+
+// futureExpiryYear returns a four digit expiry year a few years ahead, so
+// card requests built in tests do not expire.
+func futureExpiryYear() string {
+	return strconv.Itoa(time.Now().Year() + 5)
+}
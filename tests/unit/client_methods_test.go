@@ -5,7 +5,10 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -211,6 +214,144 @@ func TestOrganizationCurrencyProvisioningMethods(t *testing.T) {
 	})
 }
 
+func TestOrganizationCurrencyCache(t *testing.T) {
+	newCurrencyServer := func(requestCount *atomic.Int32, release <-chan struct{}) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/organization/currencies", r.URL.Path)
+			count := requestCount.Add(1)
+			if release != nil {
+				<-release
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if count == 1 {
+				_, _ = w.Write([]byte(`{"currencies":[{"id":"9f4050e8-1111-4f25-b4ef-aaaaaaaaaaaa","currency_unit":"TRY"}]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"currencies":[{"id":"9f4050e8-1111-4f25-b4ef-aaaaaaaaaaaa","currency_unit":"TRY"},{"id":"9f4050e8-3333-4f25-b4ef-cccccccccccc","currency_unit":"EUR"}]}`))
+		}))
+	}
+
+	t.Run("ConcurrentColdFillSharesOneRequest", func(t *testing.T) {
+		var requestCount atomic.Int32
+		release := make(chan struct{})
+		server := newCurrencyServer(&requestCount, release)
+		defer server.Close()
+
+		api := tapsilat.NewCustomAPI(server.URL, "token_currency_cache")
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				id, err := api.ResolveCurrencyID(context.Background(), "try")
+				assert.NoError(t, err)
+				assert.Equal(t, "9f4050e8-1111-4f25-b4ef-aaaaaaaaaaaa", id)
+			}()
+		}
+		require.Eventually(t, func() bool { return api.CurrencyCacheStats().Misses == 8 }, time.Second, time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		assert.Equal(t, int32(1), requestCount.Load())
+		stats := api.CurrencyCacheStats()
+		assert.Equal(t, "currencies", stats.Name)
+		assert.Equal(t, uint64(1), stats.Loads)
+		assert.Equal(t, uint64(8), stats.Misses)
+		assert.Equal(t, uint64(7), stats.Shared)
+		assert.False(t, stats.LoadedAt.IsZero())
+	})
+
+	t.Run("RefreshesOnceWhenUnitIsMissing", func(t *testing.T) {
+		var requestCount atomic.Int32
+		server := newCurrencyServer(&requestCount, nil)
+		defer server.Close()
+
+		api := tapsilat.NewCustomAPI(server.URL, "token_currency_cache")
+		_, err := api.ResolveCurrencyID(context.Background(), "TRY")
+		require.NoError(t, err)
+
+		id, err := api.ResolveCurrencyID(context.Background(), "EUR")
+		require.NoError(t, err)
+		assert.Equal(t, "9f4050e8-3333-4f25-b4ef-cccccccccccc", id)
+		assert.Equal(t, int32(2), requestCount.Load())
+
+		for range 3 {
+			_, err = api.ResolveCurrencyID(context.Background(), "XYZ")
+			var validationErr *tapsilat.ValidationError
+			require.ErrorAs(t, err, &validationErr)
+		}
+		assert.Equal(t, int32(3), requestCount.Load())
+		assert.Equal(t, uint64(2), api.CurrencyCacheStats().Refreshes)
+	})
+
+	t.Run("AlternatingUnknownUnitsReloadOnce", func(t *testing.T) {
+		var requestCount atomic.Int32
+		server := newCurrencyServer(&requestCount, nil)
+		defer server.Close()
+
+		now := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+		api := tapsilat.NewCustomAPI(server.URL, "token_currency_cache")
+		api.CurrencyCacheTTL = -1
+		api.Now = func() time.Time { return now }
+		for range 5 {
+			for _, unit := range []string{"ABC", "XYZ"} {
+				_, err := api.ResolveCurrencyID(context.Background(), unit)
+				var validationErr *tapsilat.ValidationError
+				require.ErrorAs(t, err, &validationErr)
+			}
+		}
+		// The first load, then one reload per unknown unit.
+		assert.Equal(t, int32(3), requestCount.Load())
+		assert.Equal(t, uint64(2), api.CurrencyCacheStats().Refreshes)
+
+		now = now.Add(tapsilat.DefaultReferenceCacheTTL + time.Second)
+		_, err := api.ResolveCurrencyID(context.Background(), "ABC")
+		require.Error(t, err)
+		assert.Equal(t, int32(4), requestCount.Load())
+	})
+
+	t.Run("ExpiresAfterCurrencyCacheTTL", func(t *testing.T) {
+		var requestCount atomic.Int32
+		server := newCurrencyServer(&requestCount, nil)
+		defer server.Close()
+
+		now := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+		api := tapsilat.NewCustomAPI(server.URL, "token_currency_cache")
+		api.CurrencyCacheTTL = time.Minute
+		api.Now = func() time.Time { return now }
+		_, err := api.ResolveCurrencyID(context.Background(), "TRY")
+		require.NoError(t, err)
+		now = now.Add(time.Minute)
+		_, err = api.ResolveCurrencyID(context.Background(), "TRY")
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), requestCount.Load())
+
+		now = now.Add(time.Second)
+		_, err = api.ResolveCurrencyID(context.Background(), "TRY")
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), requestCount.Load())
+		assert.Contains(t, api.ReferenceCacheStats(), "currencies")
+	})
+
+	t.Run("NegativeTTLNeverExpires", func(t *testing.T) {
+		var requestCount atomic.Int32
+		server := newCurrencyServer(&requestCount, nil)
+		defer server.Close()
+
+		now := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+		api := tapsilat.NewCustomAPI(server.URL, "token_currency_cache")
+		api.CurrencyCacheTTL = -1
+		api.Now = func() time.Time { return now }
+		_, err := api.ResolveCurrencyID(context.Background(), "TRY")
+		require.NoError(t, err)
+		now = now.AddDate(10, 0, 0)
+		_, err = api.ResolveCurrencyID(context.Background(), "TRY")
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), requestCount.Load())
+		assert.True(t, api.CurrencyCacheStats().ExpiresAt.IsZero())
+	})
+}
+
 func TestCreateSubmerchant(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
@@ -885,7 +1026,7 @@ func TestTokenizeCard(t *testing.T) {
 				"card_number":"5526080000000006",
 				"holder_name":"John Doe",
 				"expiry_month":"12",
-				"expiry_year":"2030",
+				"expiry_year":"`+futureExpiryYear()+`",
 				"cvv":"123",
 				"name":"My Test Card",
 				"default":true,
@@ -915,7 +1056,7 @@ func TestTokenizeCard(t *testing.T) {
 			CardNumber:         "5526080000000006",
 			HolderName:         "John Doe",
 			ExpiryMonth:        "12",
-			ExpiryYear:         "2030",
+			ExpiryYear:         futureExpiryYear(),
 			CVV:                "123",
 			Name:               "My Test Card",
 			Default:            true,
@@ -940,7 +1081,13 @@ func TestTokenizeCard(t *testing.T) {
 		defer server.Close()
 
 		api := tapsilat.NewCustomAPI(server.URL, "token_tok")
-		_, err := api.TokenizeCard(context.Background(), tapsilat.CardTokenizeRequest{})
+		_, err := api.TokenizeCard(context.Background(), tapsilat.CardTokenizeRequest{
+			CardNumber:  "5526080000000006",
+			HolderName:  "John Doe",
+			ExpiryMonth: "12",
+			ExpiryYear:  futureExpiryYear(),
+			CVV:         "123",
+		})
 		require.Error(t, err)
 
 		var apiErr *tapsilat.APIError
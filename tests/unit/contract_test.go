@@ -0,0 +1,170 @@
+package unit_test
+
+import (
+	"context"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tapsilat "github.com/tapsilat/tapsilat-go"
+	"github.com/tapsilat/tapsilat-go/contract"
+)
+
+func TestContractHarness(t *testing.T) {
+	doc, err := contract.Default()
+	require.NoError(t, err)
+
+	t.Run("ReportsKnownDrift", func(t *testing.T) {
+		report := contract.Check(context.Background(), doc, contract.Calls())
+		require.NoError(t, report.Err())
+		assert.Equal(t, doc.Operations(), report.Covered())
+
+		var issues []string
+		for _, issue := range report.Issues() {
+			issues = append(issues, issue.String())
+		}
+		// Known drift: fix the DTO, then remove its lines here.
+		assert.Equal(t, []string{
+			"ListSubmerchants (GET /submerchants) response: rows is not a field of tapsilat.SubmerchantListResponse",
+			"GetSuborganization (GET /organization/suborganizations/{id}) response: availability_status is not a field of tapsilat.SuborganizationListItem",
+			"GetSuborganization (GET /organization/suborganizations/{id}) response: created_at is not a field of tapsilat.SuborganizationListItem",
+			"GetSuborganization (GET /organization/suborganizations/{id}) response: parent_id is not a field of tapsilat.SuborganizationListItem",
+			"GetSuborganization (GET /organization/suborganizations/{id}) response: public_status is not a field of tapsilat.SuborganizationListItem",
+			"GetSuborganization (GET /organization/suborganizations/{id}) response: updated_at is not a field of tapsilat.SuborganizationListItem",
+		}, issues)
+	})
+
+	t.Run("MarksUnverifiedEntries", func(t *testing.T) {
+		assert.Equal(t, []string{
+			"POST /subscription/pause",
+			"POST /subscription/resume",
+			"POST /subscription/update",
+			"SavedCard.created_at",
+			"SavedCard.expiry_month",
+			"SavedCard.expiry_year",
+			"SavedCard.user_email",
+			"SavedCard.user_id",
+			"SubscriptionCreateRequest.metadata",
+			"SubscriptionDetail.metadata",
+			"SubscriptionPauseRequest",
+			"SubscriptionUpdateRequest",
+		}, doc.Unverified())
+	})
+
+	t.Run("CoversEveryRequestMethod", func(t *testing.T) {
+		var called []string
+		for _, call := range contract.Calls() {
+			called = append(called, call.Method)
+		}
+		sort.Strings(called)
+		assert.Equal(t, requestMethods(t), called)
+	})
+
+	t.Run("ReportsRequestDrift", func(t *testing.T) {
+		drifted, err := contract.Load(contract.Spec())
+		require.NoError(t, err)
+		order := drifted.Components.Schemas["Order"]
+		delete(order.Properties, "locale")
+		order.Required = append(order.Required, "installment")
+
+		var createOrder []contract.Call
+		for _, call := range contract.Calls() {
+			if call.Method == "CreateOrder" {
+				createOrder = append(createOrder, call)
+			}
+		}
+		report := contract.Check(context.Background(), drifted, createOrder)
+		require.NoError(t, report.Err())
+		assert.Equal(t, []contract.Issue{
+			{Method: "CreateOrder", Operation: "POST /order/create", In: contract.InRequest, FieldIssue: tapsilat.FieldIssue{
+				Field: "locale", Reason: tapsilat.FieldUnknown, Message: "is not a property of Order",
+			}},
+			{Method: "CreateOrder", Operation: "POST /order/create", In: contract.InRequest, FieldIssue: tapsilat.FieldIssue{
+				Field: "installment", Reason: tapsilat.FieldMissing, Message: "is missing",
+			}},
+		}, report.Issues())
+	})
+
+	t.Run("MatchesFixtures", func(t *testing.T) {
+		for fixture, path := range map[string]string{
+			"submerchant_read.json":                    "/submerchants/sub_1",
+			"vpos_read.json":                           "/vpos/v_1",
+			"vpos_submerchant_list.json":               "/vpos-submerchant",
+			"submerchant_suborganization_mapping.json": "/submerchants/sub_1/suborganization",
+			"suborganization_submerchant_mapping.json": "/organization/suborganizations/org_sub_1/submerchant",
+		} {
+			op, _, _ := doc.OperationFor("GET", path)
+			require.NotNil(t, op, path)
+			issues, err := doc.Validate(op.ResponseSchema(), readContractFixture(t, fixture))
+			require.NoError(t, err)
+			assert.Empty(t, issues, fixture)
+		}
+	})
+
+	t.Run("PrefersLiteralSegments", func(t *testing.T) {
+		op, template, params := doc.OperationFor("GET", "/vpos/acquirers")
+		require.NotNil(t, op)
+		assert.Equal(t, "/vpos/acquirers", template)
+		assert.Empty(t, params)
+
+		op, template, params = doc.OperationFor("GET", "/order/ref_1/status")
+		require.NotNil(t, op)
+		assert.Equal(t, "/order/{reference_id}/status", template)
+		assert.Equal(t, map[string]string{"reference_id": "ref_1"}, params)
+	})
+}
+
+// requestMethods returns the exported methods of *API in tapsilat.go that
+// send a request, directly or through another such method.
+func requestMethods(t *testing.T) []string {
+	t.Helper()
+	_, thisFile, _, ok := runtime.Caller(0)
+	require.True(t, ok)
+	file, err := parser.ParseFile(token.NewFileSet(), filepath.Join(filepath.Dir(thisFile), "..", "..", "tapsilat.go"), nil, 0)
+	require.NoError(t, err)
+
+	calls := map[string][]string{}
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv == nil || fn.Body == nil {
+			continue
+		}
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			if call, ok := n.(*ast.CallExpr); ok {
+				if sel, ok := call.Fun.(*ast.SelectorExpr); ok {
+					if ident, ok := sel.X.(*ast.Ident); ok && ident.Name == "t" {
+						calls[fn.Name.Name] = append(calls[fn.Name.Name], sel.Sel.Name)
+					}
+				}
+			}
+			return true
+		})
+	}
+
+	sending := map[string]bool{"get": true, "post": true, "patch": true, "delete": true}
+	for changed := true; changed; {
+		changed = false
+		for method, callees := range calls {
+			if !sending[method] && slices.ContainsFunc(callees, func(callee string) bool { return sending[callee] }) {
+				sending[method] = true
+				changed = true
+			}
+		}
+	}
+
+	var methods []string
+	for method := range sending {
+		if ast.IsExported(method) {
+			methods = append(methods, method)
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
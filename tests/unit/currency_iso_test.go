@@ -0,0 +1,217 @@
+package unit_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tapsilat "github.com/tapsilat/tapsilat-go"
+)
+
+func TestCurrencyRegistry(t *testing.T) {
+	t.Run("LooksUpAlphabeticAndNumericCodes", func(t *testing.T) {
+		info, ok := tapsilat.LookupCurrency("try")
+		require.True(t, ok)
+		assert.Equal(t, tapsilat.CurrencyInfo{Code: "TRY", Numeric: "949", MinorUnits: 2, Symbol: "₺", Name: "Turkish Lira"}, info)
+
+		info, ok = tapsilat.LookupCurrency("392")
+		require.True(t, ok)
+		assert.Equal(t, "JPY", info.Code)
+		assert.Equal(t, 0, info.MinorUnits)
+
+		_, ok = tapsilat.LookupCurrency("XYZ")
+		assert.False(t, ok)
+	})
+
+	t.Run("MinorUnitsDefaultToTwo", func(t *testing.T) {
+		assert.Equal(t, 3, tapsilat.CurrencyMinorUnits("KWD"))
+		assert.Equal(t, 0, tapsilat.CurrencyMinorUnits("JPY"))
+		assert.Equal(t, 2, tapsilat.CurrencyMinorUnits("XYZ"))
+	})
+
+	t.Run("MergePresetsDoesNotChangeDefaultRegistry", func(t *testing.T) {
+		registry := tapsilat.DefaultCurrencyRegistry.Clone()
+		registry.MergePresets([]tapsilat.OrganizationCurrencyPreset{
+			{CurrencyCode: "949", CurrencyUnit: "TRY", Name: "Türk Lirası", MinorUnit: 2},
+			{CurrencyCode: "PTS", Name: "Loyalty Points"},
+		})
+
+		info, ok := registry.Lookup("TRY")
+		require.True(t, ok)
+		assert.Equal(t, "Türk Lirası", info.Name)
+		assert.Equal(t, "₺", info.Symbol)
+
+		points, ok := registry.Lookup("pts")
+		require.True(t, ok)
+		assert.Equal(t, 0, points.MinorUnits)
+		assert.Equal(t, "PTS", points.Symbol)
+
+		_, ok = tapsilat.LookupCurrency("PTS")
+		assert.False(t, ok)
+	})
+
+	t.Run("ValidateCurrencyCode", func(t *testing.T) {
+		code, err := tapsilat.ValidateCurrencyCode(" eur ")
+		require.NoError(t, err)
+		assert.Equal(t, "EUR", code)
+
+		code, err = tapsilat.ValidateCurrencyCode("840")
+		require.NoError(t, err)
+		assert.Equal(t, "USD", code)
+
+		_, err = tapsilat.ValidateCurrencyCode("TL")
+		var validationErr *tapsilat.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		assert.Contains(t, err.Error(), "Invalid currency code: TL")
+	})
+
+	t.Run("APIRegistryMergesOrganizationPresets", func(t *testing.T) {
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/organization/currency-presets", r.URL.Path)
+			requests.Add(1)
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"items":[{"currency_code":"PTS","currency_unit":"PTS","name":"Points","minor_unit":0}]}`))
+		}))
+		defer server.Close()
+
+		api := tapsilat.NewCustomAPI(server.URL, "token_presets")
+		registry, err := api.CurrencyRegistry(context.Background())
+		require.NoError(t, err)
+		info, ok := registry.Lookup("PTS")
+		require.True(t, ok)
+		assert.Equal(t, "Points", info.Name)
+
+		_, err = api.CurrencyRegistry(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), requests.Load())
+	})
+}
+
+func TestAmountHelpers(t *testing.T) {
+	t.Run("FormatAmountByLocale", func(t *testing.T) {
+		assert.Equal(t, "₺1.234,50", tapsilat.FormatAmount(1234.5, "TRY", "tr-TR"))
+		assert.Equal(t, "$1,234.50", tapsilat.FormatAmount(1234.5, "USD", "en"))
+		assert.Equal(t, "1.234,50\u00a0€", tapsilat.FormatAmount(1234.5, "EUR", "de"))
+		assert.Equal(t, "1\u202f234,50\u00a0€", tapsilat.FormatAmount(1234.5, "EUR", "fr"))
+		assert.Equal(t, "¥1,235", tapsilat.FormatAmount(1234.5, "JPY", ""))
+		assert.Equal(t, "-KD0.005", tapsilat.FormatAmount(-0.005, "KWD", "en"))
+	})
+
+	t.Run("ParseAPIAmounts", func(t *testing.T) {
+		for input, expected := range map[string]float64{
+			"100.00":    100,
+			"100":       100,
+			"1,234.56":  1234.56,
+			"1.234,56":  1234.56,
+			"12,5":      12.5,
+			"1,234":     1234,
+			"1.234.567": 1234567,
+			"₺ 99.999":  100,
+			"-5.10 TRY": -5.1,
+		} {
+			amount, err := tapsilat.ParseAmount(input, "TRY", "")
+			require.NoError(t, err, input)
+			assert.InDelta(t, expected, amount, 1e-9, input)
+		}
+	})
+
+	t.Run("ParseLocalizedAmounts", func(t *testing.T) {
+		amount, err := tapsilat.ParseAmount("₺1.234,50", "TRY", "tr")
+		require.NoError(t, err)
+		assert.InDelta(t, 1234.5, amount, 1e-9)
+
+		amount, err = tapsilat.ParseAmount("1,234.50", "USD", "en-US")
+		require.NoError(t, err)
+		assert.InDelta(t, 1234.5, amount, 1e-9)
+
+		for input, expected := range map[string]float64{
+			"100.00":   100,
+			"1.234,56": 1234.56,
+			"1.234":    1234,
+			"1234,5":   1234.5,
+		} {
+			amount, err = tapsilat.ParseAmount(input, "TRY", "tr")
+			require.NoError(t, err, input)
+			assert.InDelta(t, expected, amount, 1e-9, input)
+		}
+		for _, input := range []string{"10.00.00", "12.34,5", "1,234.56"} {
+			_, err = tapsilat.ParseAmount(input, "TRY", "tr")
+			require.Error(t, err, input)
+		}
+
+		_, err = tapsilat.ParseAmount("12a.00", "TRY", "")
+		require.Error(t, err)
+		_, err = tapsilat.ParseAmount("", "TRY", "")
+		require.Error(t, err)
+	})
+
+	t.Run("MinorUnitConversion", func(t *testing.T) {
+		assert.Equal(t, int64(10046), tapsilat.AmountToMinor(100.456, "TRY"))
+		assert.Equal(t, int64(101), tapsilat.AmountToMinor(1.005, "TRY"))
+		assert.Equal(t, int64(102), tapsilat.AmountToMinor(1.015, "TRY"))
+		assert.Equal(t, int64(-101), tapsilat.AmountToMinor(-1.005, "TRY"))
+		assert.Equal(t, int64(1235), tapsilat.AmountToMinor(1.2345, "KWD"))
+		assert.Equal(t, int64(1500), tapsilat.AmountToMinor(1500, "JPY"))
+		assert.InDelta(t, 1.234, tapsilat.MinorToAmount(1234, "KWD"), 1e-9)
+	})
+}
+
+func TestCreateOrderRejectsUnknownCurrency(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"items":[{"currency_code":"PTS","currency_unit":"PTS","name":"Points","minor_unit":0}]}`))
+	}))
+	defer server.Close()
+
+	api := tapsilat.NewCustomAPI(server.URL, "token_currency")
+	_, err := api.CreateOrder(context.Background(), tapsilat.Order{Amount: 10, Currency: "TL"})
+	var validationErr *tapsilat.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+
+	_, err = api.CreateSubscription(context.Background(), tapsilat.SubscriptionCreateRequest{Amount: 10, Currency: "TL"})
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []string{"/organization/currency-presets"}, paths)
+}
+
+func TestCreateOrderAcceptsOrganizationCurrencyPresets(t *testing.T) {
+	var paths, currencies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/organization/currency-presets":
+			_, _ = w.Write([]byte(`{"items":[{"currency_code":"PTS","currency_unit":"PTS","name":"Points","minor_unit":0}]}`))
+		case "/order/create":
+			var order tapsilat.Order
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&order))
+			currencies = append(currencies, order.Currency)
+			_, _ = w.Write([]byte(`{"order_id":"o_1"}`))
+		case "/subscription/create":
+			var request tapsilat.SubscriptionCreateRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+			currencies = append(currencies, request.Currency)
+			_, _ = w.Write([]byte(`{"reference_id":"sub_1"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	api := tapsilat.NewCustomAPI(server.URL, "token_currency")
+	_, err := api.CreateOrder(context.Background(), tapsilat.Order{Amount: 10, Currency: "pts"})
+	require.NoError(t, err)
+	_, err = api.CreateSubscription(context.Background(), tapsilat.SubscriptionCreateRequest{Amount: 10, Currency: "pts"})
+	require.NoError(t, err)
+	_, err = api.CreateOrder(context.Background(), tapsilat.Order{Amount: 10, Currency: "TRY"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/organization/currency-presets", "/order/create", "/subscription/create", "/order/create"}, paths)
+	assert.Equal(t, []string{"PTS", "PTS", "TRY"}, currencies)
+}
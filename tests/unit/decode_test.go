@@ -0,0 +1,72 @@
+package unit_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tapsilat "github.com/tapsilat/tapsilat-go"
+)
+
+func TestLenientDecoding(t *testing.T) {
+	body := []byte(`{
+		"reference_id": "ref_1",
+		"status": "3",
+		"code": 1.0,
+		"amount": 100.5,
+		"basket_items": [{"id": "item_1", "price": "49.90", "status": "2", "gift_wrap": true}],
+		"risk_score": {"value": 12}
+	}`)
+
+	var order tapsilat.OrderDetail
+	require.NoError(t, tapsilat.Unmarshal(body, &order))
+	assert.Equal(t, int32(3), order.Status)
+	assert.Equal(t, 1, order.Code)
+	assert.Equal(t, "100.5", order.Amount)
+	require.Len(t, order.BasketItems, 1)
+	assert.Equal(t, 49.90, order.BasketItems[0].Price)
+	assert.Equal(t, uint64(2), order.BasketItems[0].Status)
+	assert.JSONEq(t, `{"value":12}`, string(order.Extra["risk_score"]))
+	assert.Len(t, order.Extra, 1)
+
+	var typeErr *json.UnmarshalTypeError
+	require.ErrorAs(t, tapsilat.Unmarshal([]byte(`{"status":"paid"}`), &order), &typeErr)
+	assert.Equal(t, "status", typeErr.Field)
+	require.ErrorAs(t, tapsilat.Unmarshal([]byte(`{"basket_items":[{"name":{"tr":"Kalem"}}]}`), &order), &typeErr)
+	assert.Equal(t, "basket_items[0].name", typeErr.Field)
+
+	var generic map[string]any
+	require.NoError(t, tapsilat.Unmarshal([]byte(`{"amount":10.25}`), &generic))
+	assert.Equal(t, json.Number("10.25"), generic["amount"])
+}
+
+func TestStrictDecoding(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":3,"new_field":"x"}`))
+	}))
+	defer server.Close()
+	api := tapsilat.NewCustomAPI(server.URL, "token")
+
+	status, err := api.GetOrderStatus(context.Background(), "ref_1")
+	require.NoError(t, err)
+	assert.Equal(t, "3", status.Status)
+	assert.JSONEq(t, `"x"`, string(status.Extra["new_field"]))
+
+	api.StrictDecoding = true
+	_, err = api.GetOrderStatus(context.Background(), "ref_1")
+	var fieldErr *tapsilat.FieldValidationError
+	require.ErrorAs(t, err, &fieldErr)
+	assert.Equal(t, []tapsilat.FieldIssue{
+		{Field: "new_field", Reason: tapsilat.FieldUnknown, Message: "is not a field of tapsilat.OrderStatus"},
+		{Field: "status", Reason: tapsilat.FieldInvalid, Message: "is a number, expected string"},
+		{Field: "error", Reason: tapsilat.FieldMissing, Message: "is missing"},
+	}, fieldErr.Issues)
+
+	var settings tapsilat.OrganizationSettings
+	require.NoError(t, tapsilat.UnmarshalStrict([]byte(`{"ttl":60}`), &settings))
+}
@@ -0,0 +1,417 @@
+package unit_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tapsilat "github.com/tapsilat/tapsilat-go"
+	"github.com/tapsilat/tapsilat-go/export"
+)
+
+type exportServer struct {
+	failPage2 atomic.Bool
+	newOrder  atomic.Bool
+	page1Hits atomic.Int32
+}
+
+func (s *exportServer) handler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/order/list":
+			switch r.URL.Query().Get("page") {
+			case "1":
+				s.page1Hits.Add(1)
+				if s.newOrder.Load() {
+					_, _ = w.Write([]byte(`{"page":1,"total_pages":2,"rows":[
+						{"reference_id":"ref_0","conversation_id":"conv_0","amount":"5.00","currency":"TRY","status_enum":"Paid"}
+					]}`))
+					return
+				}
+				_, _ = w.Write([]byte(`{"page":1,"total_pages":2,"rows":[
+					{"reference_id":"ref_1","conversation_id":"conv_1","amount":"100.00","currency":"TRY","status_enum":"Paid"}
+				]}`))
+			case "2":
+				if s.failPage2.Load() {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					_, _ = w.Write([]byte(`{"error":"unavailable"}`))
+					return
+				}
+				_, _ = w.Write([]byte(`{"page":2,"total_pages":2,"rows":[
+					{"reference_id":"ref_2","conversation_id":"conv_2","amount":1500,"currency":"JPY","status":3}
+				]}`))
+			}
+		case r.URL.Path == "/order/payments":
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			var req tapsilat.GetOrderPaymentsRequest
+			require.NoError(t, json.Unmarshal(body, &req))
+			if req.OrderReferenceID == "ref_1" {
+				_, _ = w.Write([]byte(`{"payments":[{"id":"p_1","date":"2024-03-02","amount":100.5,"payment_mode":"auth","paid":true}]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"payments":[{"id":"p_2","date":"2024-03-03","amount":1500,"paid":true}]}`))
+		case strings.HasSuffix(r.URL.Path, "/transactions"):
+			if r.URL.Path == "/order/ref_1/transactions" {
+				_, _ = w.Write([]byte(`{"transactions":[
+					{"id":"t_1","type":"payment","amount":100.5},
+					{"id":"t_2","type":"REFUND","amount":"20.25","created_at":"2024-03-04"}
+				]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"transactions":[]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)
+
+	t.Run("StreamsPaymentsAndRefundsAsCSV", func(t *testing.T) {
+		srv := &exportServer{}
+		server := httptest.NewServer(srv.handler(t))
+		defer server.Close()
+
+		exporter := export.New(tapsilat.NewCustomAPI(server.URL, "token_export"), export.FormatCSV)
+		var out bytes.Buffer
+		stats, err := exporter.Export(context.Background(), &out, start, end)
+		require.NoError(t, err)
+		assert.Equal(t, 2, stats.Orders)
+		assert.Equal(t, int64(3), stats.Rows)
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		require.Len(t, lines, 4)
+		assert.Equal(t, "kind,order_reference_id,conversation_id,external_reference_id,id,date,amount,currency,payment_mode,type,paid", lines[0])
+		assert.Equal(t, "payment,ref_1,conv_1,,p_1,2024-03-02,100.50,TRY,auth,,true", lines[1])
+		assert.Equal(t, "refund,ref_1,conv_1,,t_2,2024-03-04,20.25,TRY,,REFUND,false", lines[2])
+		assert.Equal(t, "payment,ref_2,conv_2,,p_2,2024-03-03,1500,JPY,,,true", lines[3])
+	})
+
+	t.Run("WritesSelectedColumnsAsJSONLines", func(t *testing.T) {
+		srv := &exportServer{}
+		server := httptest.NewServer(srv.handler(t))
+		defer server.Close()
+
+		columns, err := export.Columns("kind", "order_status", "amount")
+		require.NoError(t, err)
+		exporter := export.New(tapsilat.NewCustomAPI(server.URL, "token_export"), export.FormatJSONL)
+		exporter.Columns = columns
+		exporter.Kinds = []export.RowKind{export.KindOrder}
+
+		var out bytes.Buffer
+		_, err = exporter.Export(context.Background(), &out, start, end)
+		require.NoError(t, err)
+		assert.Equal(t, `{"kind":"order","order_status":"Paid","amount":"100.00"}`+"\n"+
+			`{"kind":"order","order_status":"3","amount":"1500"}`+"\n", out.String())
+
+		_, err = export.Columns("nope")
+		require.Error(t, err)
+	})
+
+	t.Run("ResumesFileExportFromCheckpoint", func(t *testing.T) {
+		srv := &exportServer{}
+		srv.failPage2.Store(true)
+		server := httptest.NewServer(srv.handler(t))
+		defer server.Close()
+
+		path := filepath.Join(t.TempDir(), "payments.csv")
+		exporter := export.New(tapsilat.NewCustomAPI(server.URL, "token_export"), export.FormatCSV)
+
+		_, err := exporter.ExportFile(context.Background(), path, start, end)
+		require.Error(t, err)
+		_, err = os.Stat(path + ".checkpoint")
+		require.NoError(t, err)
+
+		srv.failPage2.Store(false)
+		stats, err := exporter.ExportFile(context.Background(), path, start, end)
+		require.NoError(t, err)
+		assert.Equal(t, export.Stats{Orders: 2, Rows: 3, Pages: 2, Resumed: true}, stats)
+		// Page 1 is listed again only to check it still holds the same orders.
+		assert.Equal(t, int32(2), srv.page1Hits.Load())
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		require.Len(t, lines, 4)
+		assert.True(t, strings.HasPrefix(lines[0], "kind,"))
+		assert.True(t, strings.HasPrefix(lines[3], "payment,ref_2"))
+
+		_, err = os.Stat(path + ".checkpoint")
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("StartsOverWhenTheFileIsShorterThanTheCheckpoint", func(t *testing.T) {
+		srv := &exportServer{}
+		srv.failPage2.Store(true)
+		server := httptest.NewServer(srv.handler(t))
+		defer server.Close()
+
+		path := filepath.Join(t.TempDir(), "payments.csv")
+		exporter := export.New(tapsilat.NewCustomAPI(server.URL, "token_export"), export.FormatCSV)
+		_, err := exporter.ExportFile(context.Background(), path, start, end)
+		require.Error(t, err)
+		require.NoError(t, os.Remove(path))
+
+		srv.failPage2.Store(false)
+		stats, err := exporter.ExportFile(context.Background(), path, start, end)
+		require.NoError(t, err)
+		assert.Equal(t, export.Stats{Orders: 2, Rows: 3, Pages: 2}, stats)
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		require.Len(t, lines, 4)
+		assert.True(t, strings.HasPrefix(lines[0], "kind,"))
+		assert.NotContains(t, string(data), "\x00")
+	})
+
+	t.Run("StartsOverWhenTheCheckpointedPageChanged", func(t *testing.T) {
+		srv := &exportServer{}
+		srv.failPage2.Store(true)
+		server := httptest.NewServer(srv.handler(t))
+		defer server.Close()
+
+		path := filepath.Join(t.TempDir(), "payments.csv")
+		exporter := export.New(tapsilat.NewCustomAPI(server.URL, "token_export"), export.FormatCSV)
+		_, err := exporter.ExportFile(context.Background(), path, start, end)
+		require.Error(t, err)
+
+		srv.newOrder.Store(true)
+		srv.failPage2.Store(false)
+		stats, err := exporter.ExportFile(context.Background(), path, start, end)
+		require.NoError(t, err)
+		assert.False(t, stats.Resumed)
+		assert.Equal(t, 2, stats.Pages)
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "ref_1")
+		assert.Contains(t, string(data), "ref_0")
+		assert.Equal(t, 1, strings.Count(string(data), "kind,"))
+	})
+
+	t.Run("StartsOverWhenTheKindsChanged", func(t *testing.T) {
+		srv := &exportServer{}
+		srv.failPage2.Store(true)
+		server := httptest.NewServer(srv.handler(t))
+		defer server.Close()
+
+		path := filepath.Join(t.TempDir(), "payments.csv")
+		exporter := export.New(tapsilat.NewCustomAPI(server.URL, "token_export"), export.FormatCSV)
+		exporter.Kinds = []export.RowKind{export.KindPayment}
+		_, err := exporter.ExportFile(context.Background(), path, start, end)
+		require.Error(t, err)
+
+		srv.failPage2.Store(false)
+		exporter.Kinds = []export.RowKind{export.KindRefund, export.KindPayment}
+		stats, err := exporter.ExportFile(context.Background(), path, start, end)
+		require.NoError(t, err)
+		assert.False(t, stats.Resumed)
+		assert.Equal(t, export.Stats{Orders: 2, Rows: 3, Pages: 2}, stats)
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		require.Len(t, lines, 4)
+		assert.True(t, strings.HasPrefix(lines[2], "refund,ref_1"))
+	})
+
+	t.Run("WritesParquetFooter", func(t *testing.T) {
+		srv := &exportServer{}
+		server := httptest.NewServer(srv.handler(t))
+		defer server.Close()
+
+		exporter := export.New(tapsilat.NewCustomAPI(server.URL, "token_export"), export.FormatParquet)
+		var out bytes.Buffer
+		_, err := exporter.Export(context.Background(), &out, start, end)
+		require.NoError(t, err)
+
+		data := out.Bytes()
+		require.Greater(t, len(data), 12)
+		assert.Equal(t, "PAR1", string(data[:4]))
+		assert.Equal(t, "PAR1", string(data[len(data)-4:]))
+		assert.Contains(t, string(data), "order_reference_id")
+	})
+
+	t.Run("ReadsBackResumedParquet", func(t *testing.T) {
+		srv := &exportServer{}
+		srv.failPage2.Store(true)
+		server := httptest.NewServer(srv.handler(t))
+		defer server.Close()
+
+		path := filepath.Join(t.TempDir(), "payments.parquet")
+		exporter := export.New(tapsilat.NewCustomAPI(server.URL, "token_export"), export.FormatParquet)
+		_, err := exporter.ExportFile(context.Background(), path, start, end)
+		require.Error(t, err)
+		srv.failPage2.Store(false)
+		stats, err := exporter.ExportFile(context.Background(), path, start, end)
+		require.NoError(t, err)
+		require.True(t, stats.Resumed)
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		file := readParquet(t, data)
+		var names []string
+		for _, column := range export.DefaultColumns {
+			names = append(names, column.Name)
+		}
+		assert.Equal(t, names, file.columns)
+		assert.Equal(t, int64(3), file.numRows)
+		assert.Equal(t, 2, file.rowGroups)
+		assert.Equal(t, [][]string{
+			{"payment", "ref_1", "conv_1", "", "p_1", "2024-03-02", "100.50", "TRY", "auth", "", "true"},
+			{"refund", "ref_1", "conv_1", "", "t_2", "2024-03-04", "20.25", "TRY", "", "REFUND", "false"},
+			{"payment", "ref_2", "conv_2", "", "p_2", "2024-03-03", "1500", "JPY", "", "", "true"},
+		}, file.rows)
+	})
+}
+
+type parquetFile struct {
+	columns   []string
+	numRows   int64
+	rowGroups int
+	rows      [][]string
+}
+
+// readParquet reads back the files the exporter writes: required UTF-8
+// columns, one PLAIN data page per column chunk and no compression.
+func readParquet(t *testing.T, data []byte) parquetFile {
+	t.Helper()
+	require.Greater(t, len(data), 12)
+	require.Equal(t, "PAR1", string(data[:4]))
+	require.Equal(t, "PAR1", string(data[len(data)-4:]))
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footer := &thriftReader{data: data[len(data)-8-footerLen : len(data)-8]}
+	meta := footer.readStruct(t)
+
+	var file parquetFile
+	schema := meta[2].([]any)
+	require.Equal(t, int64(len(schema)-1), schema[0].(map[int16]any)[5])
+	for _, element := range schema[1:] {
+		fields := element.(map[int16]any)
+		assert.Equal(t, int64(6), fields[1], "BYTE_ARRAY")
+		assert.Equal(t, int64(0), fields[3], "REQUIRED")
+		assert.Equal(t, int64(0), fields[6], "UTF8")
+		file.columns = append(file.columns, fields[4].(string))
+	}
+	file.numRows = meta[3].(int64)
+
+	for _, group := range meta[4].([]any) {
+		groupFields := group.(map[int16]any)
+		chunks := groupFields[1].([]any)
+		require.Len(t, chunks, len(file.columns))
+		groupRows := int(groupFields[3].(int64))
+		rows := make([][]string, groupRows)
+		for i := range rows {
+			rows[i] = make([]string, len(file.columns))
+		}
+		for c, chunk := range chunks {
+			columnMeta := chunk.(map[int16]any)[3].(map[int16]any)
+			assert.Equal(t, []any{file.columns[c]}, columnMeta[3])
+			assert.Equal(t, int64(0), columnMeta[4], "UNCOMPRESSED")
+			pageReader := &thriftReader{data: data, pos: int(columnMeta[9].(int64))}
+			header := pageReader.readStruct(t)
+			require.Equal(t, int64(0), header[1], "DATA_PAGE")
+			values := header[5].(map[int16]any)
+			require.Equal(t, int64(groupRows), values[1])
+			page := data[pageReader.pos : pageReader.pos+int(header[2].(int64))]
+			for r := range groupRows {
+				size := int(binary.LittleEndian.Uint32(page))
+				rows[r][c] = string(page[4 : 4+size])
+				page = page[4+size:]
+			}
+			assert.Empty(t, page)
+		}
+		file.rows = append(file.rows, rows...)
+		file.rowGroups++
+	}
+	return file
+}
+
+// thriftReader decodes the Thrift compact protocol into maps keyed by field
+// id, with integers as int64, binaries as string and lists as []any.
+type thriftReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *thriftReader) uvarint(t *testing.T) uint64 {
+	value, n := binary.Uvarint(r.data[r.pos:])
+	require.Positive(t, n)
+	r.pos += n
+	return value
+}
+
+func (r *thriftReader) zigzag(t *testing.T) int64 {
+	value := r.uvarint(t)
+	return int64(value>>1) ^ -int64(value&1)
+}
+
+func (r *thriftReader) readStruct(t *testing.T) map[int16]any {
+	fields := map[int16]any{}
+	var last int16
+	for {
+		header := r.data[r.pos]
+		r.pos++
+		if header == 0 {
+			return fields
+		}
+		typ := header & 0x0f
+		if delta := int16(header >> 4); delta != 0 {
+			last += delta
+		} else {
+			last = int16(r.zigzag(t))
+		}
+		switch typ {
+		case 1, 2:
+			fields[last] = typ == 1
+		default:
+			fields[last] = r.readValue(t, typ)
+		}
+	}
+}
+
+func (r *thriftReader) readValue(t *testing.T, typ byte) any {
+	switch typ {
+	case 5, 6:
+		return r.zigzag(t)
+	case 8:
+		size := int(r.uvarint(t))
+		value := string(r.data[r.pos : r.pos+size])
+		r.pos += size
+		return value
+	case 9:
+		header := r.data[r.pos]
+		r.pos++
+		size := int(header >> 4)
+		if size == 15 {
+			size = int(r.uvarint(t))
+		}
+		list := make([]any, size)
+		for i := range list {
+			list[i] = r.readValue(t, header&0x0f)
+		}
+		return list
+	case 12:
+		return r.readStruct(t)
+	default:
+		t.Fatalf("unsupported thrift type %d", typ)
+		return nil
+	}
+}
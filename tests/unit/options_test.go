@@ -0,0 +1,100 @@
+package unit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tapsilat "github.com/tapsilat/tapsilat-go"
+)
+
+func TestRequestOptions(t *testing.T) {
+	var headers []http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header.Clone())
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", "req-"+r.URL.Path)
+		switch r.URL.Path {
+		case "/order/create":
+			_, _ = w.Write([]byte(`{"order_id":"o_1","reference_id":"ref_1"}`))
+		case "/order/ref_1":
+			_, _ = w.Write([]byte(`{"checkout_url":"https://checkout.example/ref_1"}`))
+		case "/organization/settings":
+			time.Sleep(200 * time.Millisecond)
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"order not found"}`))
+		}
+	}))
+	defer server.Close()
+	api := tapsilat.NewCustomAPI(server.URL, "token")
+
+	t.Run("AppliesHeadersToEveryRequestOfACall", func(t *testing.T) {
+		headers = nil
+		ctx := tapsilat.ContextWithOptions(context.Background(),
+			tapsilat.WithHeader("X-Correlation-ID", "corr-1"),
+			tapsilat.WithLocale("tr"),
+		)
+		ctx = tapsilat.ContextWithOptions(ctx, tapsilat.WithIdempotencyKey("order-42"))
+
+		order, err := api.CreateOrder(ctx, tapsilat.Order{})
+		require.NoError(t, err)
+		assert.Equal(t, "https://checkout.example/ref_1", order.CheckoutURL)
+		require.Len(t, headers, 2)
+		for _, header := range headers {
+			assert.Equal(t, "corr-1", header.Get("X-Correlation-ID"))
+			assert.Equal(t, "tr", header.Get("Accept-Language"))
+			assert.Equal(t, "Bearer token", header.Get("Authorization"))
+		}
+		assert.Equal(t, "order-42", headers[0].Get("Idempotency-Key"))
+		assert.Empty(t, headers[1].Get("Idempotency-Key"), "checkout URL lookup")
+
+		headers = nil
+		_, err = api.GetOrderStatus(context.Background(), "missing")
+		require.Error(t, err)
+		assert.Empty(t, headers[0].Get("Idempotency-Key"))
+	})
+
+	t.Run("KeepsIdempotencyKeyOffReads", func(t *testing.T) {
+		headers = nil
+		ctx := tapsilat.ContextWithOptions(context.Background(), tapsilat.WithIdempotencyKey("sub-7"))
+		_, _ = api.GetSubscription(ctx, tapsilat.SubscriptionGetRequest{ReferenceID: "sub_1"})
+		_, _ = api.GetOrderPayments(ctx, tapsilat.GetOrderPaymentsRequest{OrderReferenceID: "ref_1"})
+		_ = api.CancelSubscription(ctx, tapsilat.SubscriptionCancelRequest{ReferenceID: "sub_1"})
+		require.Len(t, headers, 3)
+		assert.Empty(t, headers[0].Get("Idempotency-Key"))
+		assert.Empty(t, headers[1].Get("Idempotency-Key"))
+		assert.Equal(t, "sub-7", headers[2].Get("Idempotency-Key"))
+	})
+
+	t.Run("StoresTheResponseCarryingTheResult", func(t *testing.T) {
+		var response tapsilat.Response
+		var raw []byte
+		ctx := tapsilat.ContextWithOptions(context.Background(), tapsilat.WithResponse(&response), tapsilat.WithRawResponse(&raw))
+		order, err := api.CreateOrder(ctx, tapsilat.Order{})
+		require.NoError(t, err)
+		assert.NotEmpty(t, order.CheckoutURL)
+		assert.Equal(t, "req-/order/create", response.RequestID)
+		assert.JSONEq(t, `{"order_id":"o_1","reference_id":"ref_1"}`, string(raw))
+	})
+
+	t.Run("KeepsRawResponseOnError", func(t *testing.T) {
+		var raw []byte
+		ctx := tapsilat.ContextWithOptions(context.Background(), tapsilat.WithRawResponse(&raw))
+		_, err := api.GetOrderStatus(ctx, "missing")
+		var apiErr *tapsilat.APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.JSONEq(t, `{"message":"order not found"}`, string(raw))
+	})
+
+	t.Run("BoundsTheCallWithATimeout", func(t *testing.T) {
+		ctx := tapsilat.ContextWithOptions(context.Background(), tapsilat.WithTimeout(20*time.Millisecond))
+		_, err := api.GetOrganizationSettings(ctx)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
@@ -0,0 +1,62 @@
+package unit_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tapsilat/tapsilat-go/internal/parallel"
+)
+
+func TestParallelEach(t *testing.T) {
+	t.Run("BoundsConcurrency", func(t *testing.T) {
+		var running, peak atomic.Int32
+		seen := make([]bool, 20)
+		err := parallel.Each(context.Background(), 3, len(seen), func(ctx context.Context, i int) error {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			seen[i] = true
+			return nil
+		})
+		require.NoError(t, err)
+		assert.LessOrEqual(t, peak.Load(), int32(3))
+		assert.NotContains(t, seen, false)
+	})
+
+	t.Run("StopsAfterTheFirstError", func(t *testing.T) {
+		boom := errors.New("boom")
+		var calls atomic.Int32
+		err := parallel.Each(context.Background(), 1, 10, func(ctx context.Context, i int) error {
+			calls.Add(1)
+			if i == 2 {
+				return boom
+			}
+			return nil
+		})
+		require.ErrorIs(t, err, boom)
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("CallsEveryIndexWhenTheContextIsDone", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		var canceled atomic.Int32
+		err := parallel.Each(ctx, 2, 5, func(ctx context.Context, i int) error {
+			if ctx.Err() != nil {
+				canceled.Add(1)
+			}
+			return nil
+		})
+		require.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, int32(5), canceled.Load())
+	})
+}
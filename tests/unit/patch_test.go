@@ -0,0 +1,182 @@
+package unit_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tapsilat "github.com/tapsilat/tapsilat-go"
+)
+
+// recordServer serves a fixed GET body per path and records PATCH bodies.
+type recordServer struct {
+	mu      sync.Mutex
+	gets    map[string][]string
+	patches []string
+}
+
+func (s *recordServer) handler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+			bodies := s.gets[r.URL.Path]
+			if len(bodies) == 0 {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			// Later reads see the next body, if any.
+			body := bodies[0]
+			if len(bodies) > 1 {
+				s.gets[r.URL.Path] = bodies[1:]
+			}
+			_, _ = w.Write([]byte(body))
+		case http.MethodPatch:
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			s.patches = append(s.patches, r.URL.Path+" "+string(body))
+			_, _ = w.Write([]byte(`{"code":200,"message":"updated"}`))
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+const storedVpos = `{"id":"v_1","name":"Akbank POS","bank_name":"Akbank","provider":"akbank","password":"secret","api_key":"key","priority":5,"main":true,"card_schemes":["visa"],"currencies":["9f4050e8-1111-4f25-b4ef-aaaaaaaaaaaa"]}`
+
+func TestPartialUpdates(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("UpdateSendsOnlyNonEmptyFields", func(t *testing.T) {
+		rec := &recordServer{}
+		server := httptest.NewServer(rec.handler(t))
+		defer server.Close()
+		api := tapsilat.NewCustomAPI(server.URL, "token")
+
+		_, err := api.UpdateSubmerchant(ctx, "sub_1", tapsilat.SubmerchantUpdateRequest{Status: "passive"})
+		require.NoError(t, err)
+		_, err = api.UpdateVpos(ctx, "v_1", tapsilat.VposUpdateRequest{Priority: 3})
+		require.NoError(t, err)
+		_, err = api.UpdateVposSubmerchant(ctx, "vs_1", tapsilat.VposSubmerchantUpdateRequest{Title: "Tenant"})
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{
+			`/submerchants/sub_1 {"status":"passive"}`,
+			`/vpos/v_1 {"priority":3}`,
+			`/vpos-submerchant/vs_1 {"title":"Tenant"}`,
+		}, rec.patches)
+	})
+
+	t.Run("PatchSendsExplicitZeroValues", func(t *testing.T) {
+		rec := &recordServer{}
+		server := httptest.NewServer(rec.handler(t))
+		defer server.Close()
+		api := tapsilat.NewCustomAPI(server.URL, "token")
+
+		_, err := api.PatchVpos(ctx, "v_1", tapsilat.VposPatch{
+			Priority:    tapsilat.Ptr(int64(0)),
+			Main:        tapsilat.Ptr(false),
+			CardSchemes: &[]string{},
+		})
+		require.NoError(t, err)
+		_, err = api.PatchSubmerchant(ctx, "sub_1", tapsilat.SubmerchantPatch{ContactName: tapsilat.Ptr("")})
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{
+			`/vpos/v_1 {"priority":0,"main":false,"card_schemes":[]}`,
+			`/submerchants/sub_1 {"contact_name":""}`,
+		}, rec.patches)
+	})
+
+	t.Run("ModifySendsOnlyChangedFields", func(t *testing.T) {
+		rec := &recordServer{gets: map[string][]string{"/vpos/v_1": {storedVpos}}}
+		server := httptest.NewServer(rec.handler(t))
+		defer server.Close()
+		api := tapsilat.NewCustomAPI(server.URL, "token")
+
+		res, err := api.ModifyVpos(ctx, "v_1", func(v *tapsilat.Vpos) error {
+			v.Priority = 0
+			v.Main = false
+			v.Name = "Akbank POS 2"
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, uint64(200), res.Code)
+		assert.Equal(t, []string{`/vpos/v_1 {"name":"Akbank POS 2","priority":0,"main":false}`}, rec.patches)
+	})
+
+	t.Run("ModifyWithoutChangesSendsNothing", func(t *testing.T) {
+		rec := &recordServer{gets: map[string][]string{"/vpos-submerchant/vs_1": {`{"id":"vs_1","title":"Tenant"}`}}}
+		server := httptest.NewServer(rec.handler(t))
+		defer server.Close()
+		api := tapsilat.NewCustomAPI(server.URL, "token")
+
+		_, err := api.ModifyVposSubmerchant(ctx, "vs_1", func(v *tapsilat.VposSubmerchant) error {
+			v.Title = "Tenant"
+			return nil
+		})
+		require.NoError(t, err)
+
+		_, err = api.ModifyVposSubmerchant(ctx, "vs_1", func(v *tapsilat.VposSubmerchant) error {
+			v.Title = "Other"
+			return errors.New("stop")
+		})
+		require.EqualError(t, err, "stop")
+		assert.Empty(t, rec.patches)
+	})
+
+	t.Run("ModifyDetectsConflicts", func(t *testing.T) {
+		rec := &recordServer{gets: map[string][]string{"/submerchants/sub_1": {
+			`{"id":"sub_1","status":"active","email":"old@example.com","contact_name":"Jane"}`,
+			`{"id":"sub_1","status":"passive","email":"old@example.com","contact_name":"Jane"}`,
+		}}}
+		server := httptest.NewServer(rec.handler(t))
+		defer server.Close()
+		api := tapsilat.NewCustomAPI(server.URL, "token")
+
+		_, err := api.ModifySubmerchant(ctx, "sub_1", func(s *tapsilat.Submerchant) error {
+			s.Status = "suspended"
+			s.ContactName = ""
+			return nil
+		})
+		var conflict *tapsilat.ConflictError
+		require.ErrorAs(t, err, &conflict)
+		assert.Equal(t, []string{"status"}, conflict.Fields)
+		assert.Equal(t, "submerchant sub_1 was modified concurrently: status", conflict.Error())
+		assert.Empty(t, rec.patches)
+
+		// The record is stable now: the cleared field is sent as "".
+		_, err = api.ModifySubmerchant(ctx, "sub_1", func(s *tapsilat.Submerchant) error {
+			s.Email = "new@example.com"
+			s.ContactName = ""
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{`/submerchants/sub_1 {"email":"new@example.com","contact_name":""}`}, rec.patches)
+	})
+
+	t.Run("ModifyRejectsReadOnlyFields", func(t *testing.T) {
+		rec := &recordServer{gets: map[string][]string{"/vpos/v_1": {storedVpos}}}
+		server := httptest.NewServer(rec.handler(t))
+		defer server.Close()
+		api := tapsilat.NewCustomAPI(server.URL, "token")
+
+		_, err := api.ModifyVpos(ctx, "v_1", func(v *tapsilat.Vpos) error {
+			v.Provider = "garanti"
+			v.Priority = 1
+			return nil
+		})
+		var fieldErr *tapsilat.FieldValidationError
+		require.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, []string{"provider"}, fieldErr.Fields(tapsilat.FieldInvalid))
+		assert.Empty(t, rec.patches)
+	})
+}
@@ -0,0 +1,254 @@
+package unit_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tapsilat "github.com/tapsilat/tapsilat-go"
+)
+
+type countingTransport struct {
+	mu       sync.Mutex
+	requests int
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	c.requests++
+	c.mu.Unlock()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestPool(t *testing.T) {
+	ctx := context.Background()
+	var (
+		mu     sync.Mutex
+		tokens []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		tokens = append(tokens, r.Header.Get("Authorization"))
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"currencies":[]}`))
+	}))
+	defer server.Close()
+
+	t.Run("SharesTransportAndKeepsTenantsApart", func(t *testing.T) {
+		transport := &countingTransport{}
+		pool := tapsilat.NewPool(server.URL)
+		pool.Transport = transport
+		pool.SetToken("acme", "token-acme")
+		pool.TokenSource = func(ctx context.Context, tenant string) (string, error) {
+			if tenant == "globex" {
+				return "token-globex", nil
+			}
+			return "", errors.New("unknown tenant")
+		}
+
+		acme, err := pool.Get(ctx, "acme")
+		require.NoError(t, err)
+		globex, err := pool.Get(ctx, "globex")
+		require.NoError(t, err)
+		again, err := pool.Get(ctx, "acme")
+		require.NoError(t, err)
+		assert.Same(t, acme, again)
+		assert.NotSame(t, acme, globex)
+		assert.Equal(t, []string{"acme", "globex"}, pool.Tenants())
+
+		_, err = pool.Get(ctx, "initech")
+		require.ErrorContains(t, err, `token for tenant "initech": unknown tenant`)
+
+		mu.Lock()
+		tokens = nil
+		mu.Unlock()
+		_, err = acme.GetOrganizationCurrencies(ctx)
+		require.NoError(t, err)
+		_, err = globex.GetOrganizationCurrencies(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Bearer token-acme", "Bearer token-globex"}, tokens)
+		assert.Equal(t, 2, transport.requests)
+	})
+
+	t.Run("RotatesTokens", func(t *testing.T) {
+		pool := tapsilat.NewPool(server.URL)
+		pool.SetToken("acme", "old")
+		api, err := pool.Get(ctx, "acme")
+		require.NoError(t, err)
+		pool.SetToken("acme", "new")
+
+		mu.Lock()
+		tokens = nil
+		mu.Unlock()
+		_, err = api.GetOrganizationCurrencies(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Bearer new"}, tokens)
+	})
+
+	t.Run("LooksUpRejectedTokensAgain", func(t *testing.T) {
+		rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if r.Header.Get("Authorization") != "Bearer token-2" {
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"error":"token expired"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"currencies":[]}`))
+		}))
+		defer rejecting.Close()
+
+		var lookups atomic.Int32
+		pool := tapsilat.NewPool(rejecting.URL)
+		pool.TokenSource = func(ctx context.Context, tenant string) (string, error) {
+			return fmt.Sprintf("token-%d", lookups.Add(1)), nil
+		}
+		api, err := pool.Get(ctx, "acme")
+		require.NoError(t, err)
+		_, err = api.GetOrganizationCurrencies(ctx)
+		require.NoError(t, err)
+		_, err = api.GetOrganizationCurrencies(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), lookups.Load())
+
+		pool.SetToken("globex", "token-registered")
+		globex, err := pool.Get(ctx, "globex")
+		require.NoError(t, err)
+		_, err = globex.GetOrganizationCurrencies(ctx)
+		var apiErr *tapsilat.APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+		assert.Equal(t, int32(2), lookups.Load())
+	})
+
+	t.Run("GivesBackSlotsOfCanceledRequests", func(t *testing.T) {
+		pool := tapsilat.NewPool(server.URL)
+		pool.Rate = 10
+		pool.SetToken("acme", "token")
+		api, err := pool.Get(ctx, "acme")
+		require.NoError(t, err)
+
+		_, err = api.GetOrganizationCurrencies(ctx)
+		require.NoError(t, err)
+		canceled, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		var wg sync.WaitGroup
+		for range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := api.GetOrganizationCurrencies(canceled)
+				assert.ErrorIs(t, err, context.DeadlineExceeded)
+			}()
+		}
+		wg.Wait()
+
+		// Without the slots back, this request would wait two seconds.
+		start := time.Now()
+		_, err = api.GetOrganizationCurrencies(ctx)
+		require.NoError(t, err)
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+	})
+
+	t.Run("LimitsRatePerTenant", func(t *testing.T) {
+		pool := tapsilat.NewPool(server.URL)
+		pool.Rate = 20
+		pool.SetToken("acme", "token")
+		pool.SetToken("globex", "token")
+		acme, err := pool.Get(ctx, "acme")
+		require.NoError(t, err)
+		globex, err := pool.Get(ctx, "globex")
+		require.NoError(t, err)
+
+		start := time.Now()
+		for range 3 {
+			_, err = acme.GetOrganizationCurrencies(ctx)
+			require.NoError(t, err)
+		}
+		assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+		start = time.Now()
+		_, err = globex.GetOrganizationCurrencies(ctx)
+		require.NoError(t, err)
+		assert.Less(t, time.Since(start), 50*time.Millisecond)
+	})
+
+	t.Run("EvictsIdleTenants", func(t *testing.T) {
+		now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+		pool := tapsilat.NewPool(server.URL)
+		pool.IdleTimeout = time.Minute
+		pool.Now = func() time.Time { return now }
+		pool.SetToken("acme", "token")
+		pool.SetToken("globex", "token")
+
+		acme, err := pool.Get(ctx, "acme")
+		require.NoError(t, err)
+		now = now.Add(40 * time.Second)
+		_, err = pool.Get(ctx, "globex")
+		require.NoError(t, err)
+
+		now = now.Add(30 * time.Second)
+		assert.Equal(t, 1, pool.EvictIdle())
+		assert.Equal(t, []string{"globex"}, pool.Tenants())
+
+		rebuilt, err := pool.Get(ctx, "acme")
+		require.NoError(t, err)
+		assert.NotSame(t, acme, rebuilt)
+
+		pool.Remove("acme")
+		_, err = pool.Get(ctx, "acme")
+		require.ErrorContains(t, err, `no token for tenant "acme"`)
+	})
+
+	t.Run("SharesTokenAndRateLimitAcrossEviction", func(t *testing.T) {
+		now := time.Now()
+		pool := tapsilat.NewPool(server.URL)
+		pool.Rate = 20
+		pool.IdleTimeout = time.Minute
+		pool.Now = func() time.Time { return now }
+		pool.SetToken("acme", "old")
+
+		held, err := pool.Get(ctx, "acme")
+		require.NoError(t, err)
+		now = now.Add(2 * time.Minute)
+		assert.Equal(t, 1, pool.EvictIdle())
+		rebuilt, err := pool.Get(ctx, "acme")
+		require.NoError(t, err)
+		require.NotSame(t, held, rebuilt)
+
+		pool.SetToken("acme", "new")
+		mu.Lock()
+		tokens = nil
+		mu.Unlock()
+		start := time.Now()
+		for _, api := range []*tapsilat.API{held, rebuilt, held} {
+			_, err = api.GetOrganizationCurrencies(ctx)
+			require.NoError(t, err)
+		}
+		assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+		assert.Equal(t, []string{"Bearer new", "Bearer new", "Bearer new"}, tokens)
+	})
+
+	t.Run("KeepsTenantCacheDirectoriesApart", func(t *testing.T) {
+		pool := tapsilat.NewPool(server.URL)
+		pool.ReferenceCacheDir = t.TempDir()
+		dirs := map[string]string{}
+		for _, tenant := range []string{"a/b", "b", "", "/", "..", "A"} {
+			pool.SetToken(tenant, "token")
+			api, err := pool.Get(ctx, tenant)
+			require.NoError(t, err)
+			assert.Equal(t, pool.ReferenceCacheDir, filepath.Dir(api.ReferenceCacheDir), tenant)
+			assert.NotContains(t, dirs, api.ReferenceCacheDir, tenant)
+			dirs[api.ReferenceCacheDir] = tenant
+		}
+	})
+}
@@ -0,0 +1,259 @@
+package unit_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tapsilat "github.com/tapsilat/tapsilat-go"
+	"github.com/tapsilat/tapsilat-go/reconcile"
+)
+
+func newReconcileServer(t *testing.T, paymentCalls *int32) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/order/list":
+			assert.Equal(t, "2024-03-01", r.URL.Query().Get("start_date"))
+			assert.Equal(t, "2024-03-31", r.URL.Query().Get("end_date"))
+			switch r.URL.Query().Get("page") {
+			case "1":
+				_, _ = w.Write([]byte(`{"page":1,"per_page":2,"total":3,"total_pages":2,"rows":[
+					{"reference_id":"ref_1","conversation_id":"conv_1","amount":"100.00","paid_amount":"100.00","currency":"TRY","status":3},
+					{"reference_id":"ref_2","conversation_id":"conv_2","amount":50,"paid_amount":50,"currency":"TRY","status_enum":"Paid"}
+				]}`))
+			case "2":
+				_, _ = w.Write([]byte(`{"page":2,"per_page":2,"total":3,"total_pages":2,"rows":[
+					{"reference_id":"ref_3","external_reference_id":"ext_3","amount":"20.00","paid_amount":"0","currency":"TRY","status":8}
+				]}`))
+			default:
+				t.Errorf("unexpected page %s", r.URL.Query().Get("page"))
+			}
+		case "/order/payments":
+			atomic.AddInt32(paymentCalls, 1)
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			var req tapsilat.GetOrderPaymentsRequest
+			require.NoError(t, json.Unmarshal(body, &req))
+			switch req.OrderReferenceID {
+			case "ref_1":
+				_, _ = w.Write([]byte(`{"payments":[{"id":"p_1","amount":100,"paid":true}]}`))
+			case "ref_2":
+				_, _ = w.Write([]byte(`{"payments":[{"id":"p_2","amount":40,"paid":true}]}`))
+			default:
+				_, _ = w.Write([]byte(`{"payments":[]}`))
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+// newSinglePageReconcileServer serves rows as a single order list page and
+// the given payments JSON by order reference ID.
+func newSinglePageReconcileServer(t *testing.T, rows string, payments map[string]string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/order/list":
+			_, _ = w.Write([]byte(`{"page":1,"per_page":10,"total_pages":1,"rows":` + rows + `}`))
+		case "/order/payments":
+			var req tapsilat.GetOrderPaymentsRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			body, ok := payments[req.OrderReferenceID]
+			if !ok {
+				body = `{"payments":[]}`
+			}
+			_, _ = w.Write([]byte(body))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestReconcile(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)
+
+	ledger := reconcile.LedgerSourceFunc(func(ctx context.Context, s, e time.Time) ([]reconcile.LedgerEntry, error) {
+		return []reconcile.LedgerEntry{
+			{ConversationID: "conv_1", Amount: 100, Currency: "TRY", Status: "paid"},
+			{ReferenceID: "ref_2", Amount: 50, Currency: "TRY", Status: "Paid"},
+			{ExternalReferenceID: "ext_3", Amount: 0, Currency: "TRY", Status: "Refunded"},
+			{ConversationID: "conv_404", Amount: 10, Currency: "TRY"},
+		}, nil
+	})
+
+	t.Run("ReportsEveryDiscrepancyKind", func(t *testing.T) {
+		var paymentCalls int32
+		server := newReconcileServer(t, &paymentCalls)
+		defer server.Close()
+
+		r := reconcile.New(tapsilat.NewCustomAPI(server.URL, "token_rec"), ledger)
+		r.PerPage = 2
+		r.Workers = 2
+
+		report, err := r.Run(context.Background(), start, end)
+		require.NoError(t, err)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&paymentCalls))
+		assert.Equal(t, 3, report.Matched)
+		assert.Equal(t, 1, report.Count(reconcile.KindMissing))
+		assert.Equal(t, 1, report.Count(reconcile.KindAmountMismatch))
+		assert.Equal(t, 1, report.Count(reconcile.KindStatusMismatch))
+		assert.Equal(t, 0, report.Count(reconcile.KindExtra))
+
+		for _, d := range report.Discrepancies {
+			switch d.Kind {
+			case reconcile.KindMissing:
+				assert.Equal(t, "conv_404", d.Key)
+			case reconcile.KindAmountMismatch:
+				assert.Equal(t, "ref_2", d.Key)
+			case reconcile.KindStatusMismatch:
+				assert.Equal(t, "ext_3", d.Key)
+				assert.Equal(t, "8", d.Remote.Status)
+			}
+		}
+	})
+
+	t.Run("ReportsExtraRemoteOrders", func(t *testing.T) {
+		var paymentCalls int32
+		server := newReconcileServer(t, &paymentCalls)
+		defer server.Close()
+
+		empty := reconcile.LedgerSourceFunc(func(ctx context.Context, s, e time.Time) ([]reconcile.LedgerEntry, error) {
+			return nil, nil
+		})
+		r := reconcile.New(tapsilat.NewCustomAPI(server.URL, "token_rec"), empty)
+		r.PerPage = 2
+
+		report, err := r.Run(context.Background(), start, end)
+		require.NoError(t, err)
+		assert.Equal(t, 3, report.Count(reconcile.KindExtra))
+	})
+
+	t.Run("FlagsDuplicateLedgerEntries", func(t *testing.T) {
+		var paymentCalls int32
+		server := newReconcileServer(t, &paymentCalls)
+		defer server.Close()
+
+		duplicated := reconcile.LedgerSourceFunc(func(ctx context.Context, s, e time.Time) ([]reconcile.LedgerEntry, error) {
+			return []reconcile.LedgerEntry{
+				{ConversationID: "conv_1", Amount: 100, Currency: "TRY", Status: "paid"},
+				{ReferenceID: "ref_1", Amount: 100, Currency: "TRY", Status: "paid"},
+			}, nil
+		})
+		r := reconcile.New(tapsilat.NewCustomAPI(server.URL, "token_rec"), duplicated)
+		r.PerPage = 2
+
+		report, err := r.Run(context.Background(), start, end)
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Matched)
+		require.Equal(t, 1, report.Count(reconcile.KindDuplicate))
+		assert.Equal(t, 0, report.Count(reconcile.KindAmountMismatch))
+		assert.Equal(t, 2, report.Count(reconcile.KindExtra))
+		for _, d := range report.Discrepancies {
+			if d.Kind == reconcile.KindDuplicate {
+				assert.Equal(t, "ref_1", d.Key)
+				assert.Equal(t, "ref_1", d.Remote.ReferenceID)
+			}
+		}
+	})
+
+	t.Run("SubtractsRefunds", func(t *testing.T) {
+		server := newSinglePageReconcileServer(t, `[
+			{"reference_id":"ref_r1","amount":"100.00","paid_amount":"100.00","refunded_amount":"30.00","currency":"TRY","status_enum":"Paid"},
+			{"reference_id":"ref_r2","amount":"80.00","paid_amount":"80.00","currency":"TRY","status_enum":"Paid"}
+		]`, map[string]string{
+			"ref_r2": `{"payments":[{"id":"p_1","amount":80,"paid":true},{"id":"p_2","amount":20,"paid":true,"type":"REFUND"}]}`,
+		})
+		defer server.Close()
+
+		refunded := reconcile.LedgerSourceFunc(func(ctx context.Context, s, e time.Time) ([]reconcile.LedgerEntry, error) {
+			return []reconcile.LedgerEntry{
+				{ReferenceID: "ref_r1", Amount: 70, Currency: "TRY", Status: "Paid"},
+				{ReferenceID: "ref_r2", Amount: 80, Currency: "TRY", Status: "Paid"},
+			}, nil
+		})
+		report, err := reconcile.New(tapsilat.NewCustomAPI(server.URL, "token_rec"), refunded).Run(context.Background(), start, end)
+		require.NoError(t, err)
+		assert.Equal(t, 2, report.Matched)
+		require.Equal(t, 1, report.Count(reconcile.KindAmountMismatch))
+		for _, d := range report.Discrepancies {
+			assert.Equal(t, "ref_r2", d.Key)
+			assert.InDelta(t, 60, d.Remote.SettledAmount(), 0.001)
+		}
+	})
+
+	t.Run("ComparesCurrencies", func(t *testing.T) {
+		server := newSinglePageReconcileServer(t, `[
+			{"reference_id":"ref_c1","amount":"100.00","paid_amount":"100.00","currency":"TRY","status_enum":"Paid"},
+			{"reference_id":"ref_c2","amount":"100.00","paid_amount":"100.00","currency":"TRY","status_enum":"Paid"}
+		]`, nil)
+		defer server.Close()
+
+		currencies := reconcile.LedgerSourceFunc(func(ctx context.Context, s, e time.Time) ([]reconcile.LedgerEntry, error) {
+			return []reconcile.LedgerEntry{
+				{ReferenceID: "ref_c1", Amount: 3, Currency: "USD", Status: "Paid"},
+				{ReferenceID: "ref_c2", Amount: 100, Currency: "949", Status: "Paid"},
+			}, nil
+		})
+		report, err := reconcile.New(tapsilat.NewCustomAPI(server.URL, "token_rec"), currencies).Run(context.Background(), start, end)
+		require.NoError(t, err)
+		assert.Equal(t, 2, report.Matched)
+		require.Equal(t, 1, report.Count(reconcile.KindCurrencyMismatch))
+		assert.Equal(t, 0, report.Count(reconcile.KindAmountMismatch))
+		assert.Equal(t, "ref_c1", report.Discrepancies[0].Key)
+	})
+
+	t.Run("ExportsCSVAndJSON", func(t *testing.T) {
+		var paymentCalls int32
+		server := newReconcileServer(t, &paymentCalls)
+		defer server.Close()
+
+		r := reconcile.New(tapsilat.NewCustomAPI(server.URL, "token_rec"), ledger)
+		r.PerPage = 2
+		report, err := r.Run(context.Background(), start, end)
+		require.NoError(t, err)
+
+		var csvOut bytes.Buffer
+		require.NoError(t, report.WriteCSV(&csvOut))
+		lines := strings.Split(strings.TrimSpace(csvOut.String()), "\n")
+		require.Len(t, lines, 4)
+		assert.True(t, strings.HasPrefix(lines[0], "kind,key,reference_id"))
+		assert.Contains(t, csvOut.String(), "amount_mismatch,ref_2,ref_2,conv_2,,50.00,40.00,TRY")
+
+		var jsonOut bytes.Buffer
+		require.NoError(t, report.WriteJSON(&jsonOut))
+		var decoded reconcile.Report
+		require.NoError(t, json.Unmarshal(jsonOut.Bytes(), &decoded))
+		assert.Equal(t, 3, decoded.Matched)
+		assert.Len(t, decoded.Discrepancies, 3)
+	})
+
+	t.Run("PropagatesAPIErrors", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"error":"boom"}`))
+		}))
+		defer server.Close()
+
+		r := reconcile.New(tapsilat.NewCustomAPI(server.URL, "token_rec"), ledger)
+		_, err := r.Run(context.Background(), start, end)
+		require.Error(t, err)
+
+		var apiErr *tapsilat.APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, "boom", apiErr.Message)
+	})
+}
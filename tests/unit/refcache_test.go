@@ -0,0 +1,227 @@
+package unit_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tapsilat "github.com/tapsilat/tapsilat-go"
+)
+
+func TestRefCache(t *testing.T) {
+	t.Run("DeduplicatesConcurrentMisses", func(t *testing.T) {
+		var loads atomic.Int32
+		release := make(chan struct{})
+		cache := tapsilat.NewRefCache("numbers", time.Minute, func(ctx context.Context) ([]int, error) {
+			loads.Add(1)
+			<-release
+			return []int{1, 2, 3}, nil
+		})
+
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				value, err := cache.Get(context.Background())
+				assert.NoError(t, err)
+				assert.Equal(t, []int{1, 2, 3}, value)
+			}()
+		}
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+		wg.Wait()
+		assert.Equal(t, int32(1), loads.Load())
+	})
+
+	t.Run("ExpiresAfterTTL", func(t *testing.T) {
+		var loads atomic.Int32
+		cache := tapsilat.NewRefCache("numbers", 10*time.Millisecond, func(ctx context.Context) (int32, error) {
+			return loads.Add(1), nil
+		})
+
+		first, err := cache.Get(context.Background())
+		require.NoError(t, err)
+		cached, err := cache.Get(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, first, cached)
+
+		time.Sleep(20 * time.Millisecond)
+		expired, err := cache.Get(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), expired)
+	})
+
+	t.Run("RefreshAndInvalidateReload", func(t *testing.T) {
+		var loads atomic.Int32
+		cache := tapsilat.NewRefCache("numbers", time.Minute, func(ctx context.Context) (int32, error) {
+			return loads.Add(1), nil
+		})
+
+		_, err := cache.Get(context.Background())
+		require.NoError(t, err)
+		refreshed, err := cache.Refresh(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), refreshed)
+
+		cache.Invalidate()
+		reloaded, err := cache.Get(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, int32(3), reloaded)
+	})
+
+	t.Run("DoesNotCacheErrors", func(t *testing.T) {
+		var loads atomic.Int32
+		cache := tapsilat.NewRefCache("numbers", time.Minute, func(ctx context.Context) (int32, error) {
+			if loads.Add(1) == 1 {
+				return 0, errors.New("temporary")
+			}
+			return 42, nil
+		})
+
+		_, err := cache.Get(context.Background())
+		require.Error(t, err)
+		value, err := cache.Get(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, int32(42), value)
+	})
+
+	t.Run("LoadsWithoutTheCallersContext", func(t *testing.T) {
+		type key struct{}
+		release := make(chan struct{})
+		cache := tapsilat.NewRefCache("numbers", time.Minute, func(ctx context.Context) (int32, error) {
+			assert.Nil(t, ctx.Value(key{}))
+			_, hasDeadline := ctx.Deadline()
+			assert.True(t, hasDeadline)
+			<-release
+			return 7, ctx.Err()
+		})
+
+		ctx, cancel := context.WithCancel(context.WithValue(context.Background(), key{}, "first caller"))
+		done := make(chan error)
+		go func() {
+			_, err := cache.Get(ctx)
+			done <- err
+		}()
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+		require.ErrorIs(t, <-done, context.Canceled)
+
+		close(release)
+		value, err := cache.Get(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, int32(7), value)
+	})
+
+	t.Run("BoundsLoadsByLoadTimeout", func(t *testing.T) {
+		cache := tapsilat.NewRefCache("numbers", time.Minute, func(ctx context.Context) (int32, error) {
+			<-ctx.Done()
+			return 0, ctx.Err()
+		})
+		cache.LoadTimeout = 10 * time.Millisecond
+
+		_, err := cache.Get(context.Background())
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("LoadsWithTheClientsOwnToken", func(t *testing.T) {
+		var authorization atomic.Value
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorization.Store(r.Header.Get("Authorization"))
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"items":[{"id":"cs_1","name":"Visa"}]}`))
+		}))
+		defer server.Close()
+
+		api := tapsilat.NewCustomAPI(server.URL, "token_refs")
+		ctx := tapsilat.ContextWithOptions(tapsilat.ContextWithToken(context.Background(), "token_other"), tapsilat.WithHeader("X-Tenant", "other"))
+		_, err := api.CachedCardSchemes(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "Bearer token_refs", authorization.Load())
+	})
+
+	t.Run("PersistsToDisk", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "numbers.json")
+		first := tapsilat.NewRefCache("numbers", time.Minute, func(ctx context.Context) ([]string, error) {
+			return []string{"TRY", "USD"}, nil
+		})
+		first.PersistPath = path
+		_, err := first.Get(context.Background())
+		require.NoError(t, err)
+
+		second := tapsilat.NewRefCache("numbers", time.Minute, func(ctx context.Context) ([]string, error) {
+			t.Fatal("persisted value should be used")
+			return nil, nil
+		})
+		second.PersistPath = path
+		value, err := second.Get(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"TRY", "USD"}, value)
+	})
+}
+
+func TestVposReferenceHelpers(t *testing.T) {
+	counts := map[string]*atomic.Int32{
+		"/vpos/acquirers":          {},
+		"/vpos/card-schemes":       {},
+		"/vpos/acquirer-templates": {},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		counter, ok := counts[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		counter.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/vpos/acquirers":
+			_, _ = w.Write([]byte(`{"items":[{"id":"acq_1","name":"Akbank","prefix":"akbank"},{"id":"acq_2","name":"Garanti","prefix":"garanti"}]}`))
+		case "/vpos/card-schemes":
+			_, _ = w.Write([]byte(`{"items":[{"id":"cs_1","name":"Visa"},{"id":"cs_2","name":"Mastercard"}]}`))
+		case "/vpos/acquirer-templates":
+			_, _ = w.Write([]byte(`{"items":[{"acquirer_id":"acq_1","name":"Akbank","required_fields":["client_id","store_key"]}]}`))
+		}
+	}))
+	defer server.Close()
+
+	api := tapsilat.NewCustomAPI(server.URL, "token_refs")
+	ctx := context.Background()
+
+	acquirerID, err := api.ResolveAcquirerID(ctx, "AKBANK")
+	require.NoError(t, err)
+	assert.Equal(t, "acq_1", acquirerID)
+	acquirerID, err = api.ResolveAcquirerID(ctx, "acq_2")
+	require.NoError(t, err)
+	assert.Equal(t, "acq_2", acquirerID)
+	_, err = api.ResolveAcquirerID(ctx, "unknown")
+	var validationErr *tapsilat.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+
+	schemes, err := api.ResolveCardSchemeIDs(ctx, []string{"visa", "cs_2", "VISA"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cs_1", "cs_2"}, schemes)
+
+	template, err := api.GetVposAcquirerTemplate(ctx, "Akbank")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"client_id", "store_key"}, template.RequiredFields)
+	_, err = api.GetVposAcquirerTemplate(ctx, "garanti")
+	require.Error(t, err)
+
+	assert.Equal(t, int32(1), counts["/vpos/acquirers"].Load())
+	assert.Equal(t, int32(1), counts["/vpos/card-schemes"].Load())
+	assert.Equal(t, int32(1), counts["/vpos/acquirer-templates"].Load())
+
+	api.InvalidateReferenceData()
+	_, err = api.CachedCardSchemes(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), counts["/vpos/card-schemes"].Load())
+}
@@ -0,0 +1,69 @@
+package unit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tapsilat "github.com/tapsilat/tapsilat-go"
+)
+
+func TestResponseEnvelope(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", "req-"+r.URL.Path[1:])
+		w.Header().Set("X-RateLimit-Remaining", "41")
+		if r.Header.Get("Authorization") == "Bearer stale" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/organization/settings" {
+			time.Sleep(5 * time.Millisecond)
+			_, _ = w.Write([]byte(`{"ttl":60,"new_field":"unmodeled"}`))
+			return
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte(`{"code":"4220","message":"invalid"}`))
+	}))
+	defer server.Close()
+	api := tapsilat.NewCustomAPI(server.URL, "token")
+
+	t.Run("DescribesSuccessfulCalls", func(t *testing.T) {
+		var response tapsilat.Response
+		_, err := api.GetOrganizationSettings(tapsilat.ContextWithOptions(context.Background(), tapsilat.WithResponse(&response)))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.Equal(t, "req-organization/settings", response.RequestID)
+		assert.Equal(t, "41", response.Header.Get("X-RateLimit-Remaining"))
+		assert.Contains(t, string(response.Body), `"new_field":"unmodeled"`)
+		assert.GreaterOrEqual(t, response.Latency, 5*time.Millisecond)
+		assert.Equal(t, 1, response.Attempts)
+	})
+
+	t.Run("DescribesFailedAndRetriedCalls", func(t *testing.T) {
+		var response tapsilat.Response
+		ctx := tapsilat.ContextWithOptions(context.Background(), tapsilat.WithResponse(&response))
+		_, err := api.GetOrderStatus(ctx, "ref_1")
+		var apiErr *tapsilat.APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, "req-order/ref_1/status", apiErr.RequestID)
+		assert.Equal(t, http.StatusUnprocessableEntity, response.StatusCode)
+		assert.Equal(t, apiErr.RequestID, response.RequestID)
+
+		tokens := []string{"stale", "fresh"}
+		retrying := tapsilat.NewCustomAPI(server.URL, "")
+		retrying.TokenSource = &tapsilat.RefreshingTokenSource{Fetch: func(context.Context) (string, time.Time, error) {
+			token := tokens[0]
+			tokens = tokens[1:]
+			return token, time.Time{}, nil
+		}}
+		_, err = retrying.GetOrganizationSettings(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 2, response.Attempts)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+	})
+}
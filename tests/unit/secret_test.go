@@ -0,0 +1,112 @@
+package unit_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tapsilat "github.com/tapsilat/tapsilat-go"
+)
+
+func TestSecretRedaction(t *testing.T) {
+	vpos := tapsilat.Vpos{ID: "v_1", Username: "api", Password: "hunter2", ApiSecret: "s3cr3t"}
+
+	for _, format := range []string{"%v", "%+v", "%#v", "%s", "%q", "%x", "%10s"} {
+		out := fmt.Sprintf(format, vpos)
+		assert.NotContains(t, out, "hunter2", format)
+		assert.NotContains(t, out, fmt.Sprintf("%x", "hunter2"), format)
+	}
+	assert.Equal(t, "[REDACTED]", vpos.Password.String())
+	assert.Equal(t, `tapsilat.Secret("[REDACTED]")`, fmt.Sprintf("%#v", vpos.Password))
+	assert.Equal(t, "", tapsilat.Secret("").String())
+	assert.Equal(t, "hunter2", vpos.Password.Reveal())
+
+	body, err := json.Marshal(vpos)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"v_1","username":"api","password":"[REDACTED]","api_secret":"[REDACTED]"}`, string(body))
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+	logger.Info("vpos", "vpos", vpos, "password", vpos.Password)
+	slog.New(slog.NewTextHandler(&logs, nil)).Info("vpos", "vpos", vpos)
+	assert.Contains(t, logs.String(), `"password":"[REDACTED]"`)
+	assert.NotContains(t, logs.String(), "hunter2")
+
+	api := tapsilat.NewAPI("org_token")
+	assert.NotContains(t, fmt.Sprintf("%+v", api), "org_token")
+}
+
+func TestSecretsAreSentToTheAPI(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer org_token", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+			_, _ = w.Write([]byte(`{"id":"v_1","password":"old","store_key":"sk","priority":1}`))
+		default:
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			bodies = append(bodies, string(body))
+			_, _ = w.Write([]byte(`{"code":200}`))
+		}
+	}))
+	defer server.Close()
+	api := tapsilat.NewCustomAPI(server.URL, "org_token")
+	ctx := context.Background()
+
+	_, err := api.UpdateVpos(ctx, "v_1", tapsilat.VposUpdateRequest{Password: "hunter2", ApiSecret: "s3cr3t"})
+	require.NoError(t, err)
+	_, err = api.PatchVpos(ctx, "v_1", tapsilat.VposPatch{StoreKey: tapsilat.Ptr[tapsilat.Secret]("")})
+	require.NoError(t, err)
+
+	vpos, err := api.GetVpos(ctx, "v_1")
+	require.NoError(t, err)
+	assert.Equal(t, "old", vpos.Password.Reveal())
+	_, err = api.ModifyVpos(ctx, "v_1", func(v *tapsilat.Vpos) error {
+		v.Password = "new"
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		`{"password":"hunter2","api_secret":"s3cr3t"}`,
+		`{"store_key":""}`,
+		`{"password":"new"}`,
+	}, bodies)
+}
+
+func TestMarshalRequestRevealsOrFails(t *testing.T) {
+	body, err := tapsilat.MarshalRequest(map[string]tapsilat.Secret{"password": "hunter2"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"password":"hunter2"}`, string(body))
+
+	body, err = tapsilat.MarshalRequest(struct {
+		Keys [1]tapsilat.Secret `json:"keys"`
+		Note any                `json:"note"`
+	}{Keys: [1]tapsilat.Secret{"k1"}, Note: "plain"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"keys":["k1"],"note":"plain"}`, string(body))
+
+	for name, payload := range map[string]any{
+		"Interface": map[string]any{"password": tapsilat.Secret("hunter2")},
+		"Nested":    []any{map[string]any{"vpos": tapsilat.Vpos{Password: "hunter2"}}},
+		"MapKey":    map[tapsilat.Secret]string{"hunter2": "x"},
+		"Unexported": struct {
+			Password tapsilat.Secret
+			note     string
+		}{Password: "hunter2", note: "x"},
+	} {
+		body, err := tapsilat.MarshalRequest(payload)
+		assert.Error(t, err, name)
+		assert.NotContains(t, string(body), "REDACTED", name)
+	}
+}
@@ -0,0 +1,121 @@
+package unit_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tapsilat "github.com/tapsilat/tapsilat-go"
+)
+
+func validSubmerchant() tapsilat.SubmerchantCreateRequest {
+	return tapsilat.SubmerchantCreateRequest{
+		Name:                  "Tenant A",
+		Email:                 "tenant@example.com",
+		GsmNumber:             "+90 555 111 22 33",
+		Address:               "Istanbul",
+		Iban:                  "tr33 0006 1005 1978 6457 8413 26",
+		TaxOffice:             "Besiktas",
+		LegalCompanyTitle:     "Tenant A Ltd",
+		CurrencyID:            "try",
+		SubmerchantExternalID: "tenant-ext-1",
+		SubmerchantType:       "limited_or_joint_stock_company",
+		TaxNumber:             "1234567890",
+	}
+}
+
+func TestValidateSubmerchant(t *testing.T) {
+	t.Run("NormalizesValidRequest", func(t *testing.T) {
+		payload, err := tapsilat.ValidateSubmerchant(validSubmerchant())
+		require.NoError(t, err)
+		assert.Equal(t, tapsilat.SubmerchantTypeLimitedOrJointStock, payload.SubmerchantType)
+		assert.Equal(t, "TR330006100519786457841326", payload.Iban)
+		assert.Equal(t, "+905551112233", payload.GsmNumber)
+	})
+
+	t.Run("RequiredFieldsDependOnType", func(t *testing.T) {
+		payload := validSubmerchant()
+		payload.SubmerchantType = tapsilat.SubmerchantTypePersonal
+		payload.TaxNumber = ""
+		_, err := tapsilat.ValidateSubmerchant(payload)
+
+		var fieldErr *tapsilat.FieldValidationError
+		require.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, []string{"identity_number", "contact_name", "contact_surname"}, fieldErr.Fields(tapsilat.FieldMissing))
+		assert.Empty(t, fieldErr.Fields(tapsilat.FieldInvalid))
+
+		var validationErr *tapsilat.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		assert.Contains(t, validationErr.Message, "identity_number: is required")
+
+		payload.SubmerchantType = tapsilat.SubmerchantTypePrivateCompany
+		payload.IdentityNumber = "10000000146"
+		payload.TaxNumber = "10000000146"
+		_, err = tapsilat.ValidateSubmerchant(payload)
+		require.NoError(t, err)
+	})
+
+	t.Run("ReportsEveryInvalidField", func(t *testing.T) {
+		payload := validSubmerchant()
+		payload.Email = "tenant"
+		payload.GsmNumber = "abc"
+		payload.Iban = "TR00"
+		payload.TaxNumber = "1234567891"
+		payload.IdentityNumber = "10000000147"
+		payload.Name = " "
+		_, err := tapsilat.ValidateSubmerchant(payload)
+
+		var fieldErr *tapsilat.FieldValidationError
+		require.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, []string{"name"}, fieldErr.Fields(tapsilat.FieldMissing))
+		assert.Equal(t, []string{"email", "gsm_number", "iban", "identity_number", "tax_number"}, fieldErr.Fields(tapsilat.FieldInvalid))
+
+		payload = validSubmerchant()
+		payload.SubmerchantType = "cooperative"
+		_, err = tapsilat.ValidateSubmerchant(payload)
+		require.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, []string{"sub_merchant_type"}, fieldErr.Fields(tapsilat.FieldInvalid))
+	})
+}
+
+func TestOnboardSubmerchant(t *testing.T) {
+	var created []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/organization/currencies":
+			_, _ = w.Write([]byte(`{"currencies":[{"id":"9f4050e8-1111-4f25-b4ef-aaaaaaaaaaaa","currency_unit":"TRY"}]}`))
+		case "/submerchants":
+			var body map[string]any
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			created = append(created, body)
+			_, _ = w.Write([]byte(`{"code":200,"message":"created","sub_merchant_key":"sm_1"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	api := tapsilat.NewCustomAPI(server.URL, "token_sm")
+	ctx := context.Background()
+
+	res, err := api.OnboardSubmerchant(ctx, validSubmerchant())
+	require.NoError(t, err)
+	assert.Equal(t, "sm_1", res.SubmerchantKey)
+	require.Len(t, created, 1)
+	assert.Equal(t, "9f4050e8-1111-4f25-b4ef-aaaaaaaaaaaa", created[0]["currency_id"])
+	assert.Equal(t, "TR330006100519786457841326", created[0]["iban"])
+
+	payload := validSubmerchant()
+	payload.CurrencyID = "XYZ"
+	payload.Email = ""
+	_, err = api.OnboardSubmerchant(ctx, payload)
+	var fieldErr *tapsilat.FieldValidationError
+	require.ErrorAs(t, err, &fieldErr)
+	assert.Equal(t, []string{"email"}, fieldErr.Fields(tapsilat.FieldMissing))
+	assert.Equal(t, []string{"currency_id"}, fieldErr.Fields(tapsilat.FieldInvalid))
+	assert.Len(t, created, 1)
+}
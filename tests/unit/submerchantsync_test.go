@@ -0,0 +1,283 @@
+package unit_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tapsilat "github.com/tapsilat/tapsilat-go"
+	"github.com/tapsilat/tapsilat-go/submerchantsync"
+)
+
+const syncCurrencyID = "9f4050e8-1111-4f25-b4ef-aaaaaaaaaaaa"
+
+type fakeSubmerchantClient struct {
+	mu       sync.Mutex
+	stored   []tapsilat.Submerchant
+	calls    []string
+	updates  map[string]string
+	failOn   string
+	inFlight int
+	maxPar   int
+}
+
+func (c *fakeSubmerchantClient) record(call string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, call)
+}
+
+func (c *fakeSubmerchantClient) ListSubmerchants(ctx context.Context, page, perPage int) (tapsilat.SubmerchantListResponse, error) {
+	start := min((page-1)*perPage, len(c.stored))
+	end := min(start+perPage, len(c.stored))
+	res := tapsilat.SubmerchantListResponse{Page: int64(page), TotalPages: int64((len(c.stored) + perPage - 1) / perPage)}
+	for _, s := range c.stored[start:end] {
+		res.Rows = append(res.Rows, tapsilat.SubmerchantListItem{ID: s.ID, Name: s.Name})
+	}
+	return res, nil
+}
+
+func (c *fakeSubmerchantClient) GetSubmerchant(ctx context.Context, id string) (tapsilat.Submerchant, error) {
+	for _, s := range c.stored {
+		if s.ID == id {
+			return s, nil
+		}
+	}
+	return tapsilat.Submerchant{}, errors.New("not found")
+}
+
+func (c *fakeSubmerchantClient) mutate(call string) (tapsilat.SubmerchantMutationResponse, error) {
+	c.mu.Lock()
+	c.inFlight++
+	c.maxPar = max(c.maxPar, c.inFlight)
+	c.mu.Unlock()
+	time.Sleep(5 * time.Millisecond)
+	c.mu.Lock()
+	c.inFlight--
+	c.mu.Unlock()
+
+	c.record(call)
+	if c.failOn != "" && strings.Contains(call, c.failOn) {
+		return tapsilat.SubmerchantMutationResponse{}, errors.New("server rejected " + call)
+	}
+	return tapsilat.SubmerchantMutationResponse{Code: 200, SubmerchantKey: "key-" + call}, nil
+}
+
+func (c *fakeSubmerchantClient) CreateSubmerchant(ctx context.Context, payload tapsilat.SubmerchantCreateRequest) (tapsilat.SubmerchantMutationResponse, error) {
+	return c.mutate("create:" + payload.SubmerchantExternalID)
+}
+
+func (c *fakeSubmerchantClient) UpdateSubmerchant(ctx context.Context, id string, payload tapsilat.SubmerchantUpdateRequest) (tapsilat.SubmerchantMutationResponse, error) {
+	body, err := tapsilat.MarshalRequest(payload)
+	if err != nil {
+		return tapsilat.SubmerchantMutationResponse{}, err
+	}
+	c.mu.Lock()
+	if c.updates == nil {
+		c.updates = map[string]string{}
+	}
+	c.updates[id] = string(body)
+	c.mu.Unlock()
+	return c.mutate("update:" + id + ":" + payload.Email + ":" + payload.CurrencyID)
+}
+
+func (c *fakeSubmerchantClient) DeleteSubmerchant(ctx context.Context, id string) (tapsilat.SubmerchantMutationResponse, error) {
+	return c.mutate("delete:" + id)
+}
+
+func (c *fakeSubmerchantClient) ResolveCurrencyID(ctx context.Context, ref string) (string, error) {
+	if strings.EqualFold(ref, "TRY") || ref == syncCurrencyID {
+		return syncCurrencyID, nil
+	}
+	return "", &tapsilat.ValidationError{StatusCode: 400, Message: "unknown currency reference " + ref}
+}
+
+func storedSubmerchant(id, externalID, email string) tapsilat.Submerchant {
+	return tapsilat.Submerchant{
+		ID:                    id,
+		Name:                  "Tenant " + externalID,
+		Email:                 email,
+		GsmNumber:             "+905551112233",
+		Address:               "Istanbul",
+		Iban:                  "TR330006100519786457841326",
+		TaxOffice:             "Besiktas",
+		LegalCompanyTitle:     "Tenant Ltd",
+		CurrencyID:            syncCurrencyID,
+		SubmerchantExternalID: externalID,
+		SubmerchantType:       tapsilat.SubmerchantTypeLimitedOrJointStock,
+		TaxNumber:             "1234567890",
+	}
+}
+
+const syncCSV = `sub_merchant_external_id,action,sub_merchant_type,name,email,gsm_number,address,iban,tax_office,legal_company_title,currency_id,tax_number
+ext-new,,LIMITED_OR_JOINT_STOCK_COMPANY,New Tenant,new@example.com,+905551112233,Istanbul,TR330006100519786457841326,Besiktas,New Ltd,TRY,1234567890
+ext-1,upsert,,,changed@example.com,,,,,,TRY,
+ext-2,,,,,,,,,,,
+ext-3,delete,,,,,,,,,,
+ext-bad,,LIMITED_OR_JOINT_STOCK_COMPANY,Bad,bad@example.com,+905551112233,Istanbul,TR00,Besiktas,Bad Ltd,TRY,1234567890
+ext-1,,,,,,,,,,,
+`
+
+func TestSubmerchantSync(t *testing.T) {
+	newClient := func() *fakeSubmerchantClient {
+		return &fakeSubmerchantClient{stored: []tapsilat.Submerchant{
+			storedSubmerchant("sm_1", "ext-1", "old@example.com"),
+			storedSubmerchant("sm_2", "ext-2", "two@example.com"),
+			storedSubmerchant("sm_3", "ext-3", "three@example.com"),
+			storedSubmerchant("sm_4", "ext-4", "four@example.com"),
+		}}
+	}
+	ctx := context.Background()
+
+	t.Run("ReadSources", func(t *testing.T) {
+		rows, err := submerchantsync.ReadCSV(strings.NewReader(syncCSV))
+		require.NoError(t, err)
+		require.Len(t, rows, 6)
+		assert.Equal(t, submerchantsync.ActionDelete, rows[3].Action)
+		assert.Equal(t, "new@example.com", rows[0].Submerchant.Email)
+
+		_, err = submerchantsync.ReadCSV(strings.NewReader("sub_merchant_external_id,emial\next,x\n"))
+		require.ErrorContains(t, err, `unknown csv column "emial"`)
+
+		rows, err = submerchantsync.ReadJSON(strings.NewReader(`[{"sub_merchant_external_id":"ext-1","email":"a@example.com"},{"sub_merchant_external_id":"ext-2","action":"DELETE"}]`))
+		require.NoError(t, err)
+		require.Len(t, rows, 2)
+		assert.Equal(t, 2, rows[1].Number)
+		assert.Equal(t, submerchantsync.ActionDelete, rows[1].Action)
+
+		_, err = submerchantsync.ReadJSON(strings.NewReader(`[{"emial":"a@example.com"}]`))
+		require.Error(t, err)
+	})
+
+	t.Run("DryRunPlansWithoutWriting", func(t *testing.T) {
+		client := newClient()
+		rows, err := submerchantsync.ReadCSV(strings.NewReader(syncCSV))
+		require.NoError(t, err)
+
+		syncer := submerchantsync.New(client)
+		syncer.PerPage = 3
+		syncer.Rate = 0
+		syncer.DryRun = true
+		syncer.Prune = true
+		client.stored = append(client.stored, storedSubmerchant("sm_5", "", "five@example.com"))
+		plan, report, err := syncer.Run(ctx, rows)
+		require.NoError(t, err)
+		assert.Empty(t, client.calls)
+
+		ops := make([]submerchantsync.Op, 0, len(plan.Changes))
+		for _, change := range plan.Changes {
+			ops = append(ops, change.Op)
+		}
+		assert.Equal(t, []submerchantsync.Op{
+			submerchantsync.OpCreate,
+			submerchantsync.OpUpdate,
+			submerchantsync.OpUnchanged,
+			submerchantsync.OpDelete,
+			submerchantsync.OpInvalid,
+			submerchantsync.OpInvalid,
+			submerchantsync.OpDelete,
+			submerchantsync.OpUnmanaged,
+		}, ops)
+		assert.Equal(t, []submerchantsync.FieldChange{{Field: "email", From: "old@example.com", To: "changed@example.com"}}, plan.Changes[1].Fields)
+		assert.Equal(t, "duplicate of row 2", plan.Changes[5].Detail)
+		assert.Equal(t, "sm_4", plan.Changes[6].SubmerchantID)
+		assert.Equal(t, 4, report.Count(submerchantsync.StatusPlanned))
+		assert.Equal(t, "sm_5", plan.Changes[7].SubmerchantID)
+		assert.Equal(t, 4, report.Count(submerchantsync.StatusSkipped))
+
+		var diff bytes.Buffer
+		require.NoError(t, plan.WriteDiff(&diff))
+		assert.Equal(t, strings.Join([]string{
+			"+ ext-new create (row 1)",
+			"~ ext-1 update (row 2)",
+			`    email: "old@example.com" -> "changed@example.com"`,
+			"- ext-3 delete (row 4)",
+			"! ext-bad invalid (row 5): iban Invalid IBAN format: TR00",
+			"! ext-1 invalid (row 6): duplicate of row 2",
+			"- ext-4 delete: not in source",
+			"? sm_5 unmanaged: no external ID, not pruned",
+		}, "\n")+"\n", diff.String())
+	})
+
+	t.Run("AppliesWithBoundedConcurrencyAndReportsErrors", func(t *testing.T) {
+		client := newClient()
+		client.failOn = "delete:sm_3"
+		rows, err := submerchantsync.ReadCSV(strings.NewReader(syncCSV))
+		require.NoError(t, err)
+
+		syncer := submerchantsync.New(client)
+		syncer.Workers = 2
+		syncer.Rate = 200
+		started := time.Now()
+		_, report, err := syncer.Run(ctx, rows)
+		require.NoError(t, err)
+
+		// One list page, four gets and three writes, spaced 5ms apart.
+		assert.GreaterOrEqual(t, time.Since(started), 7*5*time.Millisecond)
+		assert.LessOrEqual(t, client.maxPar, 2)
+		assert.ElementsMatch(t, []string{
+			"create:ext-new",
+			"update:sm_1:changed@example.com:",
+			"delete:sm_3",
+		}, client.calls)
+
+		assert.Equal(t, 2, report.Count(submerchantsync.StatusOK))
+		assert.Equal(t, 1, report.Count(submerchantsync.StatusFailed))
+		assert.Equal(t, "server rejected delete:sm_3", report.Results[3].Error)
+		assert.Equal(t, "key-create:ext-new", report.Results[0].SubmerchantKey)
+
+		var out bytes.Buffer
+		require.NoError(t, report.WriteCSV(&out))
+		records, err := csv.NewReader(&out).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 7)
+		assert.Equal(t, []string{"2", "ext-1", "update", "ok", "sm_1", "key-update:sm_1:changed@example.com:", "email", "", ""}, records[2])
+		assert.Equal(t, "failed", records[4][3])
+	})
+
+	t.Run("UpdatesSendOnlyChangedFields", func(t *testing.T) {
+		client := newClient()
+		stored := storedSubmerchant("sm_1", "ext-1", "old@example.com")
+		stored.SubmerchantKey = "key_1"
+		stored.OrganizationID = "org_1"
+		stored.Status = "ACTIVE"
+		stored.SystemTime = 1700000000
+		client.stored = []tapsilat.Submerchant{stored}
+
+		rows, err := submerchantsync.ReadJSON(strings.NewReader(`[{"sub_merchant_external_id":"ext-1","email":"changed@example.com","name":"Tenant ext-1","currency_id":"TRY"}]`))
+		require.NoError(t, err)
+		syncer := submerchantsync.New(client)
+		syncer.Rate = 0
+		_, report, err := syncer.Run(ctx, rows)
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Count(submerchantsync.StatusOK))
+		assert.Equal(t, map[string]string{"sm_1": `{"email":"changed@example.com"}`}, client.updates)
+	})
+
+	t.Run("AbortsWhenStoredSubmerchantsCannotBeLoaded", func(t *testing.T) {
+		client := newClient()
+		client.stored = append(client.stored, tapsilat.Submerchant{ID: "sm_gone"})
+		syncer := submerchantsync.New(&missingGetClient{client})
+		syncer.Rate = 0
+		_, _, err := syncer.Run(ctx, nil)
+		require.ErrorContains(t, err, "get submerchant sm_gone")
+	})
+}
+
+type missingGetClient struct {
+	*fakeSubmerchantClient
+}
+
+func (c *missingGetClient) GetSubmerchant(ctx context.Context, id string) (tapsilat.Submerchant, error) {
+	if id == "sm_gone" {
+		return tapsilat.Submerchant{}, errors.New("not found")
+	}
+	return c.fakeSubmerchantClient.GetSubmerchant(ctx, id)
+}
@@ -0,0 +1,213 @@
+package unit_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tapsilat "github.com/tapsilat/tapsilat-go"
+	"github.com/tapsilat/tapsilat-go/vposroute"
+)
+
+var _ vposroute.Client = (*tapsilat.Tenant)(nil)
+
+func suborganizationServer(t *testing.T, parents map[string]string, paths *[]string) *httptest.Server {
+	var mu sync.Mutex
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		*paths = append(*paths, r.URL.RequestURI())
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+
+		const prefix = "/organization/suborganizations"
+		switch {
+		case r.URL.Path == prefix:
+			page := r.URL.Query().Get("page")
+			rows := map[string]string{
+				"1": `[{"id":"root","name":"Root"},{"id":"shop_a","name":"Shop A"}]`,
+				"2": `[{"id":"shop_b","name":"Shop B"},{"id":"kiosk","name":"Kiosk"}]`,
+			}[page]
+			_, _ = w.Write([]byte(`{"page":` + page + `,"total_pages":2,"rows":` + rows + `}`))
+		case strings.HasSuffix(r.URL.Path, "/submerchant"):
+			id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, prefix+"/"), "/submerchant")
+			if id == "root" {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"message":"not found"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"suborganization_id":"` + id + `","submerchant_id":"sm_` + id + `"}`))
+		case strings.HasPrefix(r.URL.Path, prefix+"/"):
+			id := strings.TrimPrefix(r.URL.Path, prefix+"/")
+			body, _ := json.Marshal(tapsilat.SuborganizationDetail{ID: id, ParentID: parents[id]})
+			_, _ = w.Write(body)
+		case r.URL.Path == "/vpos":
+			id := r.URL.Query().Get("suborganization_id")
+			_, _ = w.Write([]byte(`{"page":1,"total_pages":1,"rows":[{"id":"v_` + id + `","name":"POS ` + id + `"}]}`))
+		case strings.HasPrefix(r.URL.Path, "/vpos/"):
+			_, _ = w.Write([]byte(`{"id":"` + strings.TrimPrefix(r.URL.Path, "/vpos/") + `"}`))
+		case r.URL.Path == "/submerchants/sm_shop_a":
+			_, _ = w.Write([]byte(`{"id":"sm_shop_a","name":"Shop A Ltd","status":"active"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestSuborganizationTree(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("BuildsTreeWithSubmerchantsAndVpos", func(t *testing.T) {
+		var paths []string
+		server := suborganizationServer(t, map[string]string{"shop_a": "root", "shop_b": "root", "kiosk": "shop_a"}, &paths)
+		defer server.Close()
+		api := tapsilat.NewCustomAPI(server.URL, "token")
+
+		loader := tapsilat.NewSuborganizationTreeLoader(api)
+		loader.PerPage = 2
+		tree, err := loader.Load(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 4, tree.Len())
+		require.Len(t, tree.Roots, 1)
+
+		root := tree.Roots[0]
+		assert.Equal(t, "Root", root.Name)
+		assert.Empty(t, root.SubmerchantID)
+		require.Len(t, root.Children, 2)
+		assert.Equal(t, "shop_a", root.Children[0].ID)
+
+		kiosk, ok := tree.Node("kiosk")
+		require.True(t, ok)
+		assert.Equal(t, 2, kiosk.Depth)
+		assert.Equal(t, "sm_kiosk", kiosk.SubmerchantID)
+		assert.Equal(t, "v_kiosk", kiosk.Vpos[0].ID)
+		var path []string
+		for _, node := range kiosk.Path() {
+			path = append(path, node.ID)
+		}
+		assert.Equal(t, []string{"root", "shop_a", "kiosk"}, path)
+
+		node, ok := tree.SuborganizationBySubmerchant("sm_shop_b")
+		require.True(t, ok)
+		assert.Equal(t, "shop_b", node.ID)
+
+		var walked []string
+		require.NoError(t, tree.Walk(func(node *tapsilat.SuborganizationNode) error {
+			walked = append(walked, node.ID)
+			return nil
+		}))
+		assert.Equal(t, []string{"root", "shop_a", "kiosk", "shop_b"}, walked)
+	})
+
+	t.Run("StoresOneResponseOfConcurrentRequests", func(t *testing.T) {
+		var paths []string
+		server := suborganizationServer(t, map[string]string{"shop_a": "root", "shop_b": "root", "kiosk": "shop_a"}, &paths)
+		defer server.Close()
+		api := tapsilat.NewCustomAPI(server.URL, "token")
+
+		var response tapsilat.Response
+		var raw []byte
+		loadCtx := tapsilat.ContextWithOptions(ctx, tapsilat.WithResponse(&response), tapsilat.WithRawResponse(&raw))
+		_, err := api.LoadSuborganizationTree(loadCtx)
+		require.NoError(t, err)
+		assert.NotZero(t, response.StatusCode)
+		assert.Equal(t, response.Body, raw)
+	})
+
+	t.Run("RejectsParentCycles", func(t *testing.T) {
+		var paths []string
+		server := suborganizationServer(t, map[string]string{"shop_a": "kiosk", "kiosk": "shop_a"}, &paths)
+		defer server.Close()
+		api := tapsilat.NewCustomAPI(server.URL, "token")
+
+		_, err := api.LoadSuborganizationTree(ctx)
+		require.ErrorContains(t, err, "parent_id cycle")
+	})
+}
+
+func TestTenantScopedClient(t *testing.T) {
+	ctx := context.Background()
+	var paths []string
+	server := suborganizationServer(t, nil, &paths)
+	defer server.Close()
+	api := tapsilat.NewCustomAPI(server.URL, "token")
+	tenant := api.ForSuborganization("shop_a")
+
+	vpos, err := tenant.ListVpos(ctx, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, "v_shop_a", vpos.Rows[0].ID)
+	assert.Contains(t, paths, "/vpos?page=1&per_page=10&suborganization_id=shop_a")
+
+	_, err = tenant.ListVposWithFilter(ctx, 1, 10, tapsilat.VposListFilter{SuborganizationID: "shop_b"})
+	var validationErr *tapsilat.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+
+	submerchants, err := tenant.ListSubmerchants(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, submerchants.Rows, 1)
+	assert.Equal(t, "Shop A Ltd", submerchants.Rows[0].Name)
+	assert.Equal(t, int64(1), submerchants.Total)
+
+	submerchants, err = tenant.ListSubmerchants(ctx, 2, 10)
+	require.NoError(t, err)
+	assert.Empty(t, submerchants.Rows)
+	assert.Equal(t, int64(1), submerchants.TotalPages)
+	_, err = tenant.ListSubmerchants(ctx, 1, 0)
+	require.ErrorAs(t, err, &validationErr)
+
+	submerchants, err = api.ForSuborganization("root").ListSubmerchants(ctx, 1, 10)
+	require.NoError(t, err)
+	assert.Empty(t, submerchants.Rows)
+	assert.Zero(t, submerchants.TotalPages)
+
+	paths = nil
+	vposDetail, err := api.ForSuborganization("kiosk").GetVpos(ctx, "v_kiosk")
+	require.NoError(t, err)
+	assert.Equal(t, "v_kiosk", vposDetail.ID)
+	assert.Equal(t, []string{"/vpos?page=1&per_page=100&suborganization_id=kiosk", "/vpos/v_kiosk"}, paths)
+
+	_, err = tenant.GetVpos(ctx, "v_kiosk")
+	require.ErrorAs(t, err, &validationErr)
+}
+
+func TestTenantGetVposCachesTheCompleteList(t *testing.T) {
+	ctx := context.Background()
+	var paths []string
+	server := suborganizationServer(t, nil, &paths)
+	defer server.Close()
+	api := tapsilat.NewCustomAPI(server.URL, "token")
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	api.Now = func() time.Time { return now }
+	api.ReferenceCacheTTL = time.Minute
+	tenant := api.ForSuborganization("kiosk")
+
+	lists := func() int {
+		count := 0
+		for _, path := range paths {
+			if strings.HasPrefix(path, "/vpos?") {
+				count++
+			}
+		}
+		return count
+	}
+
+	var validationErr *tapsilat.ValidationError
+	for range 3 {
+		_, err := tenant.GetVpos(ctx, "v_shop_a")
+		require.ErrorAs(t, err, &validationErr)
+	}
+	_, err := tenant.GetVpos(ctx, "v_kiosk")
+	require.NoError(t, err)
+	assert.Equal(t, 1, lists())
+
+	now = now.Add(2 * time.Minute)
+	_, err = tenant.GetVpos(ctx, "v_shop_a")
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, 2, lists())
+}
@@ -0,0 +1,339 @@
+package unit_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tapsilat "github.com/tapsilat/tapsilat-go"
+)
+
+type subscriptionCall struct {
+	Path           string
+	Body           map[string]any
+	IdempotencyKey string
+}
+
+// newSubscriptionEditServer answers the update, pause and resume endpoints
+// with nativeStatus, or with success when it is zero.
+func newSubscriptionEditServer(t *testing.T, nativeStatus int) (*httptest.Server, func() []subscriptionCall) {
+	t.Helper()
+	var mu sync.Mutex
+	var calls []subscriptionCall
+	active := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		var payload map[string]any
+		_ = json.Unmarshal(body, &payload)
+		mu.Lock()
+		calls = append(calls, subscriptionCall{Path: r.URL.Path, Body: payload, IdempotencyKey: r.Header.Get("Idempotency-Key")})
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/subscription":
+			if payload["reference_id"] != "sub_1" {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"error":"subscription not found"}`))
+				return
+			}
+			mu.Lock()
+			isActive := active
+			mu.Unlock()
+			_, _ = fmt.Fprintf(w, `{
+				"amount":"100.00","currency":"TRY","period":30,"payment_date":1,"title":"Basic",
+				"external_reference_id":"ext_1","is_active":%t,
+				"metadata":[{"key":"segment","value":"smb"},{"key":"plan","value":"basic"},{"key":"original_reference_id","value":"sub_0"}],
+				"orders":[{"reference_id":"o1","payment_date":"2026-03-01","status":"success"}]
+			}`, isActive)
+		case "/subscription/update", "/subscription/pause", "/subscription/resume":
+			if nativeStatus != 0 {
+				w.WriteHeader(nativeStatus)
+				_, _ = w.Write([]byte(`{"error":"unavailable"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{}`))
+		case "/subscription/cancel":
+			mu.Lock()
+			active = false
+			mu.Unlock()
+			_, _ = w.Write([]byte(`{}`))
+		case "/subscription/create":
+			_, _ = w.Write([]byte(`{"reference_id":"sub_2"}`))
+		default:
+			w.WriteHeader(http.StatusTeapot)
+		}
+	}))
+	return server, func() []subscriptionCall {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]subscriptionCall(nil), calls...)
+	}
+}
+
+func callPaths(calls []subscriptionCall) []string {
+	paths := make([]string, 0, len(calls))
+	for _, call := range calls {
+		paths = append(paths, call.Path)
+	}
+	return paths
+}
+
+func TestProrateSubscription(t *testing.T) {
+	start := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, time.March, 31, 0, 0, 0, 0, time.UTC)
+
+	upgrade := tapsilat.ProrateSubscription(100, 250, start, end, time.Date(2026, time.March, 11, 0, 0, 0, 0, time.UTC), "TRY")
+	assert.InDelta(t, 2.0/3, upgrade.RemainingFraction, 1e-9)
+	assert.Equal(t, 66.67, upgrade.UnusedCredit)
+	assert.Equal(t, 166.67, upgrade.NewCharge)
+	assert.Equal(t, 100.0, upgrade.Net)
+
+	downgrade := tapsilat.ProrateSubscription(3000, 1500, start, end, time.Date(2026, time.March, 16, 0, 0, 0, 0, time.UTC), "JPY")
+	assert.Equal(t, -750.0, downgrade.Net)
+
+	after := tapsilat.ProrateSubscription(100, 250, start, end, end.Add(time.Hour), "TRY")
+	assert.Zero(t, after.Net)
+}
+
+func TestSubscriptionEditor(t *testing.T) {
+	now := time.Date(2026, time.March, 16, 0, 0, 0, 0, time.UTC)
+	template := tapsilat.SubscriptionCreateRequest{
+		CardID:   "card_1",
+		User:     tapsilat.SubscriptionUser{ID: "user_1", Email: "john@example.com"},
+		Metadata: []tapsilat.OrderMetadata{{Key: "plan", Value: "pro"}, {Key: "change_reason", Value: "stale"}},
+	}
+
+	t.Run("ChangePlanUsesNativeEndpoint", func(t *testing.T) {
+		server, calls := newSubscriptionEditServer(t, 0)
+		defer server.Close()
+		editor := tapsilat.NewSubscriptionEditor(tapsilat.NewCustomAPI(server.URL, "token_sub"))
+		editor.Now = func() time.Time { return now }
+
+		result, err := editor.ChangePlan(context.Background(), "sub_1", tapsilat.SubscriptionPlan{Amount: 250}, template)
+		require.NoError(t, err)
+		assert.False(t, result.Recreated)
+		assert.Equal(t, "sub_1", result.ReferenceID)
+		require.NotNil(t, result.Proration)
+		assert.Equal(t, 77.42, result.Proration.Net)
+
+		recorded := calls()
+		assert.Equal(t, []string{"/subscription", "/subscription/update"}, callPaths(recorded))
+		assert.Equal(t, map[string]any{"reference_id": "sub_1", "amount": 250.0}, recorded[1].Body)
+	})
+
+	t.Run("ChangePlanFallsBackToRecreate", func(t *testing.T) {
+		server, calls := newSubscriptionEditServer(t, http.StatusNotImplemented)
+		defer server.Close()
+		editor := tapsilat.NewSubscriptionEditor(tapsilat.NewCustomAPI(server.URL, "token_sub"))
+		editor.AllowRecreate = true
+		editor.Now = func() time.Time { return now }
+
+		result, err := editor.ChangePlan(context.Background(), "sub_1", tapsilat.SubscriptionPlan{Amount: 250, Title: "Pro"}, template)
+		require.NoError(t, err)
+		assert.True(t, result.Recreated)
+		assert.Equal(t, "sub_2", result.ReferenceID)
+		assert.Equal(t, "sub_1", result.PreviousReferenceID)
+
+		recorded := calls()
+		assert.Equal(t, []string{"/subscription", "/subscription/update", "/subscription/cancel", "/subscription/create"}, callPaths(recorded))
+		created := recorded[3].Body
+		assert.Equal(t, 250.0, created["amount"])
+		assert.Equal(t, "Pro", created["title"])
+		assert.Equal(t, "TRY", created["currency"])
+		assert.Equal(t, 30.0, created["period"])
+		assert.Equal(t, 1.0, created["payment_date"])
+		assert.Equal(t, "ext_1", created["external_reference_id"])
+		assert.Equal(t, "card_1", created["card_id"])
+		assert.Equal(t, []any{
+			map[string]any{"key": "segment", "value": "smb"},
+			map[string]any{"key": "plan", "value": "pro"},
+			map[string]any{"key": "replaces_reference_id", "value": "sub_1"},
+			map[string]any{"key": "original_reference_id", "value": "sub_0"},
+			map[string]any{"key": "change_reason", "value": "plan_change"},
+		}, created["metadata"])
+	})
+
+	t.Run("RecreateSendsIdempotencyKeyWithCreateOnly", func(t *testing.T) {
+		server, calls := newSubscriptionEditServer(t, http.StatusNotImplemented)
+		defer server.Close()
+		editor := tapsilat.NewSubscriptionEditor(tapsilat.NewCustomAPI(server.URL, "token_sub"))
+		editor.AllowRecreate = true
+
+		ctx := tapsilat.ContextWithOptions(context.Background(), tapsilat.WithIdempotencyKey("change-1"))
+		_, err := editor.ChangePlan(ctx, "sub_1", tapsilat.SubscriptionPlan{Amount: 250}, template)
+		require.NoError(t, err)
+		keys := map[string]string{}
+		for _, call := range calls() {
+			keys[call.Path] = call.IdempotencyKey
+		}
+		assert.Equal(t, map[string]string{
+			"/subscription":        "",
+			"/subscription/update": "change-1",
+			"/subscription/cancel": "",
+			"/subscription/create": "change-1",
+		}, keys)
+	})
+
+	t.Run("SwapCardFallsBackToRecreate", func(t *testing.T) {
+		server, calls := newSubscriptionEditServer(t, http.StatusNotImplemented)
+		defer server.Close()
+		editor := tapsilat.NewSubscriptionEditor(tapsilat.NewCustomAPI(server.URL, "token_sub"))
+		editor.AllowRecreate = true
+
+		result, err := editor.SwapCard(context.Background(), "sub_1", "card_9", template)
+		require.NoError(t, err)
+		assert.True(t, result.Recreated)
+		recorded := calls()
+		assert.Equal(t, "card_9", recorded[len(recorded)-1].Body["card_id"])
+		assert.Equal(t, 100.0, recorded[len(recorded)-1].Body["amount"])
+
+		_, err = editor.SwapCard(context.Background(), "sub_1", "", template)
+		require.Error(t, err)
+	})
+
+	t.Run("PauseAndResume", func(t *testing.T) {
+		server, calls := newSubscriptionEditServer(t, 0)
+		defer server.Close()
+		editor := tapsilat.NewSubscriptionEditor(tapsilat.NewCustomAPI(server.URL, "token_sub"))
+
+		paused, err := editor.Pause(context.Background(), "sub_1")
+		require.NoError(t, err)
+		assert.False(t, paused.Canceled)
+		resumed, err := editor.Resume(context.Background(), "sub_1", template)
+		require.NoError(t, err)
+		assert.False(t, resumed.Recreated)
+		assert.Equal(t, []string{"/subscription", "/subscription/pause", "/subscription", "/subscription/resume"}, callPaths(calls()))
+
+		fallbackServer, fallbackCalls := newSubscriptionEditServer(t, http.StatusNotImplemented)
+		defer fallbackServer.Close()
+		editor = tapsilat.NewSubscriptionEditor(tapsilat.NewCustomAPI(fallbackServer.URL, "token_sub"))
+		editor.AllowRecreate = true
+
+		paused, err = editor.Pause(context.Background(), "sub_1")
+		require.NoError(t, err)
+		assert.True(t, paused.Canceled)
+		resumed, err = editor.Resume(context.Background(), "sub_1", template)
+		require.NoError(t, err)
+		assert.True(t, resumed.Recreated)
+		assert.Equal(t, []string{
+			"/subscription", "/subscription/pause", "/subscription/cancel",
+			"/subscription", "/subscription/resume", "/subscription/create",
+		}, callPaths(fallbackCalls()))
+	})
+
+	t.Run("RecreatesOnlyWhenAllowed", func(t *testing.T) {
+		server, calls := newSubscriptionEditServer(t, http.StatusMethodNotAllowed)
+		defer server.Close()
+		editor := tapsilat.NewSubscriptionEditor(tapsilat.NewCustomAPI(server.URL, "token_sub"))
+
+		_, err := editor.ChangePlan(context.Background(), "sub_1", tapsilat.SubscriptionPlan{Amount: 250}, template)
+		require.ErrorIs(t, err, tapsilat.ErrNativeEndpointUnavailable)
+		var apiErr *tapsilat.APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusMethodNotAllowed, apiErr.StatusCode)
+		_, err = editor.Pause(context.Background(), "sub_1")
+		require.ErrorIs(t, err, tapsilat.ErrNativeEndpointUnavailable)
+		assert.Equal(t, []string{"/subscription", "/subscription/update", "/subscription", "/subscription/pause"}, callPaths(calls()))
+	})
+
+	t.Run("NotFoundFromNativeEndpointCancelsNothing", func(t *testing.T) {
+		server, calls := newSubscriptionEditServer(t, http.StatusNotFound)
+		defer server.Close()
+		editor := tapsilat.NewSubscriptionEditor(tapsilat.NewCustomAPI(server.URL, "token_sub"))
+		editor.AllowRecreate = true
+
+		_, err := editor.ChangePlan(context.Background(), "sub_1", tapsilat.SubscriptionPlan{Amount: 250}, template)
+		var apiErr *tapsilat.APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+		assert.NotErrorIs(t, err, tapsilat.ErrNativeEndpointUnavailable)
+		_, err = editor.Pause(context.Background(), "sub_1")
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, []string{"/subscription", "/subscription/update", "/subscription", "/subscription/pause"}, callPaths(calls()))
+	})
+
+	t.Run("ResumeLeavesActiveSubscription", func(t *testing.T) {
+		server, calls := newSubscriptionEditServer(t, http.StatusNotImplemented)
+		defer server.Close()
+		editor := tapsilat.NewSubscriptionEditor(tapsilat.NewCustomAPI(server.URL, "token_sub"))
+
+		result, err := editor.Resume(context.Background(), "sub_1", template)
+		require.NoError(t, err)
+		assert.False(t, result.Recreated)
+		assert.Equal(t, "sub_1", result.ReferenceID)
+		assert.Equal(t, []string{"/subscription", "/subscription/resume"}, callPaths(calls()))
+	})
+
+	t.Run("ResumeRefusesSubscriptionNotPaused", func(t *testing.T) {
+		server, calls := newSubscriptionEditServer(t, http.StatusNotImplemented)
+		defer server.Close()
+		api := tapsilat.NewCustomAPI(server.URL, "token_sub")
+		require.NoError(t, api.CancelSubscription(context.Background(), tapsilat.SubscriptionCancelRequest{ReferenceID: "sub_1"}))
+		editor := tapsilat.NewSubscriptionEditor(api)
+		editor.AllowRecreate = true
+
+		_, err := editor.Resume(context.Background(), "sub_1", template)
+		require.ErrorContains(t, err, "was not paused")
+		assert.NotContains(t, callPaths(calls()), "/subscription/create")
+	})
+
+	t.Run("PauseUnknownSubscriptionCancelsNothing", func(t *testing.T) {
+		server, calls := newSubscriptionEditServer(t, http.StatusNotImplemented)
+		defer server.Close()
+		editor := tapsilat.NewSubscriptionEditor(tapsilat.NewCustomAPI(server.URL, "token_sub"))
+
+		_, err := editor.Pause(context.Background(), "sub_typo")
+		require.Error(t, err)
+		assert.Equal(t, []string{"/subscription"}, callPaths(calls()))
+	})
+
+	t.Run("InvalidTemplateCancelsNothing", func(t *testing.T) {
+		server, calls := newSubscriptionEditServer(t, http.StatusNotImplemented)
+		defer server.Close()
+		editor := tapsilat.NewSubscriptionEditor(tapsilat.NewCustomAPI(server.URL, "token_sub"))
+		editor.AllowRecreate = true
+
+		_, err := editor.ChangePlan(context.Background(), "sub_1", tapsilat.SubscriptionPlan{Amount: 250}, tapsilat.SubscriptionCreateRequest{})
+		var fieldErr *tapsilat.FieldValidationError
+		require.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, []string{"/subscription", "/subscription/update"}, callPaths(calls()))
+	})
+
+	t.Run("InvalidPaymentDayCancelsNothing", func(t *testing.T) {
+		server, calls := newSubscriptionEditServer(t, http.StatusNotImplemented)
+		defer server.Close()
+		editor := tapsilat.NewSubscriptionEditor(tapsilat.NewCustomAPI(server.URL, "token_sub"))
+		editor.AllowRecreate = true
+
+		_, err := editor.ChangePlan(context.Background(), "sub_1", tapsilat.SubscriptionPlan{Period: 7, PaymentDate: 15}, template)
+		var validationErr *tapsilat.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		assert.Contains(t, err.Error(), "payment day is only supported for monthly and yearly subscriptions")
+		assert.Equal(t, []string{"/subscription", "/subscription/update"}, callPaths(calls()))
+	})
+
+	t.Run("PeriodChangeDropsTheOldPaymentDay", func(t *testing.T) {
+		server, calls := newSubscriptionEditServer(t, http.StatusNotImplemented)
+		defer server.Close()
+		editor := tapsilat.NewSubscriptionEditor(tapsilat.NewCustomAPI(server.URL, "token_sub"))
+		editor.AllowRecreate = true
+
+		_, err := editor.ChangePlan(context.Background(), "sub_1", tapsilat.SubscriptionPlan{Period: 7}, template)
+		require.NoError(t, err)
+		recorded := calls()
+		create := recorded[len(recorded)-1]
+		require.Equal(t, "/subscription/create", create.Path)
+		assert.Equal(t, 7.0, create.Body["period"])
+		assert.NotContains(t, create.Body, "payment_date")
+	})
+}
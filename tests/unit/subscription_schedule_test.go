@@ -0,0 +1,151 @@
+package unit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tapsilat "github.com/tapsilat/tapsilat-go"
+)
+
+func chargeDates(projection tapsilat.SubscriptionProjection) []string {
+	dates := make([]string, 0, len(projection.Charges))
+	for _, charge := range projection.Charges {
+		dates = append(dates, charge.At.Format("2006-01-02"))
+	}
+	return dates
+}
+
+func TestSubscriptionPeriod(t *testing.T) {
+	assert.Equal(t, "monthly", tapsilat.SubscriptionPeriodMonthly.String())
+	assert.Equal(t, "yearly", tapsilat.SubscriptionPeriod(366).String())
+	assert.Equal(t, "every 14 days", tapsilat.SubscriptionPeriodDays(14).String())
+	assert.True(t, tapsilat.SubscriptionPeriod(28).IsMonthly())
+	assert.Error(t, tapsilat.SubscriptionPeriod(0).Validate())
+
+	assert.NoError(t, tapsilat.SubscriptionPeriodMonthly.ValidatePaymentDay(0))
+	assert.NoError(t, tapsilat.SubscriptionPeriodMonthly.ValidatePaymentDay(31))
+	assert.Error(t, tapsilat.SubscriptionPeriodMonthly.ValidatePaymentDay(32))
+	assert.Error(t, tapsilat.SubscriptionPeriodDays(7).ValidatePaymentDay(5))
+
+	last := time.Date(2026, time.January, 31, 10, 0, 0, 0, time.UTC)
+	now := time.Date(2026, time.April, 5, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2026, time.April, 30, 10, 0, 0, 0, time.UTC), tapsilat.SubscriptionPeriodMonthly.NextCharge(last, now, 0))
+	assert.Equal(t, time.Date(2026, time.April, 15, 10, 0, 0, 0, time.UTC), tapsilat.SubscriptionPeriodMonthly.NextCharge(last, now, 15))
+	assert.Equal(t, time.Date(2026, time.April, 11, 10, 0, 0, 0, time.UTC), tapsilat.SubscriptionPeriodDays(10).NextCharge(last, now, 0))
+	assert.True(t, tapsilat.SubscriptionPeriod(0).NextCharge(last, now, 0).IsZero())
+}
+
+func TestSubscriptionCycle(t *testing.T) {
+	assert.False(t, tapsilat.SubscriptionCycleUnlimited.Finite())
+	_, ok := tapsilat.SubscriptionCycleUnlimited.Remaining(4)
+	assert.False(t, ok)
+
+	remaining, ok := tapsilat.SubscriptionCycle(12).Remaining(4)
+	assert.True(t, ok)
+	assert.Equal(t, 8, remaining)
+	remaining, _ = tapsilat.SubscriptionCycle(3).Remaining(5)
+	assert.Zero(t, remaining)
+	assert.Equal(t, "12 charges", tapsilat.SubscriptionCycle(12).String())
+	assert.Error(t, tapsilat.SubscriptionCycle(-1).Validate())
+}
+
+func TestSubscriptionPaymentStatus(t *testing.T) {
+	assert.True(t, tapsilat.SubscriptionPaymentStatus(" SUCCESS ").Is(tapsilat.SubscriptionStatusSuccess))
+	assert.False(t, tapsilat.SubscriptionPaymentStatus("pending").Is(tapsilat.SubscriptionStatusFailure))
+}
+
+func TestSubscriptionSchedule(t *testing.T) {
+	start := time.Date(2026, time.January, 31, 0, 0, 0, 0, time.UTC)
+
+	t.Run("FiniteMonthlyClampsToMonthEnd", func(t *testing.T) {
+		schedule := tapsilat.NewSubscriptionSchedule(tapsilat.SubscriptionCreateRequest{
+			Amount:   99.99,
+			Currency: "TRY",
+			Period:   tapsilat.SubscriptionPeriodMonthly,
+			Cycle:    4,
+		}, start)
+
+		projection, err := schedule.Project(start, 10)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"2026-01-31", "2026-02-28", "2026-03-31", "2026-04-30"}, chargeDates(projection))
+		assert.Equal(t, 399.96, projection.Total)
+		assert.False(t, projection.More)
+
+		end, ok := schedule.End()
+		require.True(t, ok)
+		assert.Equal(t, time.Date(2026, time.April, 30, 0, 0, 0, 0, time.UTC), end)
+
+		projection, err = schedule.Project(time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC), 1)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"2026-03-31"}, chargeDates(projection))
+		assert.Equal(t, 2, projection.Charges[0].Index)
+		assert.True(t, projection.More)
+	})
+
+	t.Run("UnlimitedWithPaymentDay", func(t *testing.T) {
+		schedule := tapsilat.SubscriptionSchedule{
+			Start:      start,
+			Period:     tapsilat.SubscriptionPeriodYearly,
+			PaymentDay: 15,
+			Amount:     1200,
+			Currency:   "JPY",
+		}
+		projection, err := schedule.Project(time.Date(2027, time.June, 1, 0, 0, 0, 0, time.UTC), 2)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"2028-01-15", "2029-01-15"}, chargeDates(projection))
+		assert.Equal(t, 2400.0, projection.Total)
+		assert.True(t, projection.More)
+
+		_, ok := schedule.End()
+		assert.False(t, ok)
+	})
+
+	t.Run("BetweenDays", func(t *testing.T) {
+		schedule := tapsilat.SubscriptionSchedule{Start: start, Period: tapsilat.SubscriptionPeriodDays(14), Amount: 10, Currency: "TRY"}
+		projection, err := schedule.Between(time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, time.March, 14, 0, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"2026-02-14", "2026-02-28"}, chargeDates(projection))
+		assert.Equal(t, 20.0, projection.Total)
+		assert.True(t, projection.More)
+	})
+
+	t.Run("RejectsInvalidSchedules", func(t *testing.T) {
+		_, err := tapsilat.SubscriptionSchedule{Start: start, Period: 7, PaymentDay: 3}.Project(start, 1)
+		var validationErr *tapsilat.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+
+		_, err = tapsilat.SubscriptionSchedule{Period: 30}.Project(start, 1)
+		require.Error(t, err)
+	})
+}
+
+func TestCreateSubscriptionValidatesSchedule(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(`{"reference_id":"sub_1"}`))
+	}))
+	defer server.Close()
+	api := tapsilat.NewCustomAPI(server.URL, "token_sub")
+
+	for _, request := range []tapsilat.SubscriptionCreateRequest{
+		{Amount: 10, Period: -1},
+		{Amount: 10, Period: tapsilat.SubscriptionPeriodMonthly, PaymentDate: 32},
+		{Amount: 10, Period: tapsilat.SubscriptionPeriodDays(7), PaymentDate: 1},
+		{Amount: 10, Cycle: -2},
+	} {
+		_, err := api.CreateSubscription(context.Background(), request)
+		var validationErr *tapsilat.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+	}
+	assert.Zero(t, requests)
+
+	_, err := api.CreateSubscription(context.Background(), tapsilat.SubscriptionCreateRequest{Amount: 10, Period: tapsilat.SubscriptionPeriodMonthly, PaymentDate: 31, Cycle: 12})
+	require.NoError(t, err)
+	assert.Equal(t, 1, requests)
+}
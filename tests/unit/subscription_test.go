@@ -0,0 +1,291 @@
+package unit_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tapsilat "github.com/tapsilat/tapsilat-go"
+)
+
+type fakeSubscriptionClient struct {
+	mu        sync.Mutex
+	details   map[string]tapsilat.SubscriptionDetail
+	list      []tapsilat.SubscriptionListItem
+	canceled  []string
+	redirects []string
+	cancelErr error
+}
+
+func (c *fakeSubscriptionClient) GetSubscription(ctx context.Context, payload tapsilat.SubscriptionGetRequest) (tapsilat.SubscriptionDetail, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	detail, ok := c.details[payload.ReferenceID]
+	if !ok {
+		return detail, errors.New("not found")
+	}
+	return detail, nil
+}
+
+func (c *fakeSubscriptionClient) CancelSubscription(ctx context.Context, payload tapsilat.SubscriptionCancelRequest) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cancelErr != nil {
+		return c.cancelErr
+	}
+	c.canceled = append(c.canceled, payload.ReferenceID)
+	detail := c.details[payload.ReferenceID]
+	detail.IsActive = false
+	c.details[payload.ReferenceID] = detail
+	return nil
+}
+
+func (c *fakeSubscriptionClient) RedirectSubscription(ctx context.Context, payload tapsilat.SubscriptionRedirectRequest) (tapsilat.SubscriptionRedirectResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.redirects = append(c.redirects, payload.SubscriptionID)
+	return tapsilat.SubscriptionRedirectResponse{URL: "https://pay.example/update/" + payload.SubscriptionID}, nil
+}
+
+func (c *fakeSubscriptionClient) ListSubscriptions(ctx context.Context, page, perPage int) (tapsilat.PaginatedData, error) {
+	start := min((page-1)*perPage, len(c.list))
+	end := min(start+perPage, len(c.list))
+	rows := make([]any, 0, end-start)
+	for _, item := range c.list[start:end] {
+		rows = append(rows, map[string]any{"reference_id": item.ReferenceID, "is_active": item.IsActive})
+	}
+	return tapsilat.PaginatedData{Page: int64(page), TotalPages: (len(c.list) + perPage - 1) / perPage, Rows: rows}, nil
+}
+
+func eventTypes(events []tapsilat.SubscriptionEvent) []tapsilat.SubscriptionEventType {
+	types := make([]tapsilat.SubscriptionEventType, 0, len(events))
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+func TestSubscriptionManager(t *testing.T) {
+	now := time.Date(2026, time.March, 20, 12, 0, 0, 0, time.UTC)
+
+	t.Run("ComputesNextChargeDate", func(t *testing.T) {
+		client := &fakeSubscriptionClient{details: map[string]tapsilat.SubscriptionDetail{
+			"monthly": {IsActive: true, Period: 30, PaymentDate: 31, Orders: []tapsilat.SubscriptionOrder{
+				{ReferenceID: "o1", PaymentDate: "2026-01-31", Status: "success"},
+				{ReferenceID: "o2", PaymentDate: "2026-02-28", Status: "success"},
+			}},
+			"yearly": {IsActive: true, Period: 365, DueDate: "2025-06-15"},
+			"weekly": {IsActive: true, Period: 7, Orders: []tapsilat.SubscriptionOrder{
+				{ReferenceID: "o1", PaymentDate: "2026-03-16T09:00:00Z", Status: "success"},
+			}},
+		}}
+		manager := tapsilat.NewSubscriptionManager(client)
+		manager.Now = func() time.Time { return now }
+
+		state, err := manager.State(context.Background(), "monthly")
+		require.NoError(t, err)
+		assert.Equal(t, time.Date(2026, time.March, 31, 0, 0, 0, 0, time.UTC), state.NextChargeAt)
+		assert.Equal(t, tapsilat.SubscriptionHealthActive, state.Health)
+
+		state, err = manager.State(context.Background(), "yearly")
+		require.NoError(t, err)
+		assert.Equal(t, time.Date(2026, time.June, 15, 0, 0, 0, 0, time.UTC), state.NextChargeAt)
+
+		state, err = manager.State(context.Background(), "weekly")
+		require.NoError(t, err)
+		assert.Equal(t, time.Date(2026, time.March, 23, 9, 0, 0, 0, time.UTC), state.NextChargeAt)
+	})
+
+	t.Run("DetectsFailedAndOverdueOrders", func(t *testing.T) {
+		client := &fakeSubscriptionClient{details: map[string]tapsilat.SubscriptionDetail{
+			"sub_1": {IsActive: true, Period: 30, Orders: []tapsilat.SubscriptionOrder{
+				{ReferenceID: "o3", PaymentDate: "2026-03-18", Status: "pending"},
+				{ReferenceID: "o1", PaymentDate: "2026-01-18", Status: "success"},
+				{ReferenceID: "o2", PaymentDate: "2026-02-18", Status: "failure"},
+				{ReferenceID: "o4", PaymentDate: "2026-03-20T06:00:00Z", Status: "pending"},
+			}},
+		}}
+		manager := tapsilat.NewSubscriptionManager(client)
+		manager.Now = func() time.Time { return now }
+
+		state, err := manager.State(context.Background(), "sub_1")
+		require.NoError(t, err)
+		assert.Equal(t, tapsilat.SubscriptionHealthPastDue, state.Health)
+		require.Len(t, state.Failed, 1)
+		assert.Equal(t, "o2", state.Failed[0].ReferenceID)
+		require.Len(t, state.Overdue, 1)
+		assert.Equal(t, "o3", state.Overdue[0].ReferenceID)
+		assert.Equal(t, 2, state.ConsecutiveFailures)
+	})
+
+	t.Run("RunsDunningStepsOnce", func(t *testing.T) {
+		client := &fakeSubscriptionClient{details: map[string]tapsilat.SubscriptionDetail{
+			"sub_1": {IsActive: true, Period: 30, ExternalReferenceID: "ext_1", Orders: []tapsilat.SubscriptionOrder{
+				{ReferenceID: "o1", PaymentDate: "2026-01-18", Status: "success"},
+				{ReferenceID: "o2", PaymentDate: "2026-02-18", Status: "failure"},
+			}},
+		}}
+		var notified []string
+		var emitted []tapsilat.SubscriptionEvent
+		manager := tapsilat.NewSubscriptionManager(client)
+		manager.Now = func() time.Time { return now }
+		manager.Notify = func(ctx context.Context, state tapsilat.SubscriptionState, step tapsilat.DunningStep, redirectURL string) error {
+			notified = append(notified, string(step.Action)+":"+redirectURL)
+			return nil
+		}
+		manager.OnEvent = func(event tapsilat.SubscriptionEvent) { emitted = append(emitted, event) }
+		ctx := context.Background()
+
+		_, events, err := manager.Check(ctx, "sub_1")
+		require.NoError(t, err)
+		assert.Equal(t, []tapsilat.SubscriptionEventType{
+			tapsilat.SubscriptionEventRenewed,
+			tapsilat.SubscriptionEventPaymentFailed,
+			tapsilat.SubscriptionEventDunningNotified,
+		}, eventTypes(events))
+		assert.Equal(t, "ext_1", events[1].ExternalReferenceID)
+
+		_, events, err = manager.Check(ctx, "sub_1")
+		require.NoError(t, err)
+		assert.Empty(t, events)
+
+		detail := client.details["sub_1"]
+		detail.Orders = append(detail.Orders, tapsilat.SubscriptionOrder{ReferenceID: "o3", PaymentDate: "2026-03-18", Status: "failure"})
+		client.details["sub_1"] = detail
+		_, events, err = manager.Check(ctx, "sub_1")
+		require.NoError(t, err)
+		assert.Equal(t, []tapsilat.SubscriptionEventType{
+			tapsilat.SubscriptionEventPaymentFailed,
+			tapsilat.SubscriptionEventCardUpdateRequested,
+		}, eventTypes(events))
+		assert.Equal(t, "https://pay.example/update/sub_1", events[1].RedirectURL)
+
+		detail.Orders = append(detail.Orders, tapsilat.SubscriptionOrder{ReferenceID: "o4", PaymentDate: "2026-03-19", Status: "failure"})
+		client.details["sub_1"] = detail
+		state, events, err := manager.Check(ctx, "sub_1")
+		require.NoError(t, err)
+		assert.Equal(t, []tapsilat.SubscriptionEventType{
+			tapsilat.SubscriptionEventPaymentFailed,
+			tapsilat.SubscriptionEventCanceled,
+		}, eventTypes(events))
+		assert.Equal(t, tapsilat.SubscriptionHealthCanceled, state.Health)
+		assert.Equal(t, []string{"sub_1"}, client.canceled)
+		assert.Equal(t, []string{"notify:", "request_card_update:https://pay.example/update/sub_1"}, notified)
+		assert.Len(t, emitted, 7)
+	})
+
+	t.Run("ResumesAtTheCurrentStep", func(t *testing.T) {
+		client := &fakeSubscriptionClient{details: map[string]tapsilat.SubscriptionDetail{
+			"sub_1": {IsActive: true, Period: 30, Orders: []tapsilat.SubscriptionOrder{
+				{ReferenceID: "o1", PaymentDate: "2026-01-18", Status: "failure"},
+				{ReferenceID: "o2", PaymentDate: "2026-02-18", Status: "failure"},
+			}},
+		}}
+		var notified []string
+		manager := tapsilat.NewSubscriptionManager(client)
+		manager.Now = func() time.Time { return now }
+		manager.Notify = func(ctx context.Context, state tapsilat.SubscriptionState, step tapsilat.DunningStep, redirectURL string) error {
+			notified = append(notified, string(step.Action))
+			return nil
+		}
+
+		_, events, err := manager.Check(context.Background(), "sub_1")
+		require.NoError(t, err)
+		assert.Equal(t, []tapsilat.SubscriptionEventType{
+			tapsilat.SubscriptionEventPaymentFailed,
+			tapsilat.SubscriptionEventPaymentFailed,
+			tapsilat.SubscriptionEventCardUpdateRequested,
+		}, eventTypes(events))
+		assert.Equal(t, []string{"request_card_update"}, notified)
+		assert.Empty(t, client.canceled)
+	})
+
+	t.Run("ConcurrentChecksRunEachStepOnce", func(t *testing.T) {
+		client := &fakeSubscriptionClient{details: map[string]tapsilat.SubscriptionDetail{
+			"sub_1": {IsActive: true, Period: 30, Orders: []tapsilat.SubscriptionOrder{
+				{ReferenceID: "o1", PaymentDate: "2026-02-18", Status: "failure"},
+			}},
+		}}
+		var notified atomic.Int32
+		manager := tapsilat.NewSubscriptionManager(client)
+		manager.Now = func() time.Time { return now }
+		manager.Notify = func(ctx context.Context, state tapsilat.SubscriptionState, step tapsilat.DunningStep, redirectURL string) error {
+			notified.Add(1)
+			time.Sleep(time.Millisecond)
+			return nil
+		}
+
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _, err := manager.Check(context.Background(), "sub_1")
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, int32(1), notified.Load())
+	})
+
+	t.Run("RecoveryResetsDunning", func(t *testing.T) {
+		client := &fakeSubscriptionClient{details: map[string]tapsilat.SubscriptionDetail{
+			"sub_1": {IsActive: true, Period: 30, Orders: []tapsilat.SubscriptionOrder{
+				{ReferenceID: "o1", PaymentDate: "2026-02-18", Status: "failure"},
+			}},
+		}}
+		manager := tapsilat.NewSubscriptionManager(client)
+		manager.Now = func() time.Time { return now }
+		manager.Steps = []tapsilat.DunningStep{{AfterFailures: 1, Action: tapsilat.DunningNotify}}
+		ctx := context.Background()
+
+		_, events, err := manager.Check(ctx, "sub_1")
+		require.NoError(t, err)
+		assert.Len(t, events, 2)
+
+		detail := client.details["sub_1"]
+		detail.Orders = append(detail.Orders, tapsilat.SubscriptionOrder{ReferenceID: "o2", PaymentDate: "2026-03-18", Status: "success"})
+		client.details["sub_1"] = detail
+		_, events, err = manager.Check(ctx, "sub_1")
+		require.NoError(t, err)
+		assert.Equal(t, []tapsilat.SubscriptionEventType{tapsilat.SubscriptionEventRecovered}, eventTypes(events))
+
+		detail.Orders = append(detail.Orders, tapsilat.SubscriptionOrder{ReferenceID: "o3", PaymentDate: "2026-03-19", Status: "failure"})
+		client.details["sub_1"] = detail
+		_, events, err = manager.Check(ctx, "sub_1")
+		require.NoError(t, err)
+		assert.Equal(t, []tapsilat.SubscriptionEventType{
+			tapsilat.SubscriptionEventPaymentFailed,
+			tapsilat.SubscriptionEventDunningNotified,
+		}, eventTypes(events))
+	})
+
+	t.Run("CheckAllWalksPagesAndReportsErrors", func(t *testing.T) {
+		client := &fakeSubscriptionClient{
+			details: map[string]tapsilat.SubscriptionDetail{
+				"sub_1": {IsActive: true, Period: 30, Orders: []tapsilat.SubscriptionOrder{{ReferenceID: "o1", PaymentDate: "2026-03-01", Status: "failure"}}},
+				"sub_3": {IsActive: true, Period: 30, Orders: []tapsilat.SubscriptionOrder{{ReferenceID: "o1", PaymentDate: "2026-03-01", Status: "failure"}}},
+			},
+			list: []tapsilat.SubscriptionListItem{
+				{ReferenceID: "sub_1", IsActive: true},
+				{ReferenceID: "sub_2", IsActive: false},
+				{ReferenceID: "sub_3", IsActive: true},
+				{ReferenceID: "sub_missing", IsActive: true},
+			},
+		}
+		manager := tapsilat.NewSubscriptionManager(client)
+		manager.Now = func() time.Time { return now }
+		manager.PerPage = 2
+		manager.Steps = []tapsilat.DunningStep{{AfterFailures: 1, Action: tapsilat.DunningCancel}}
+
+		events, err := manager.CheckAll(context.Background())
+		require.Error(t, err)
+		assert.Len(t, events, 4)
+		assert.Equal(t, []string{"sub_1", "sub_3"}, client.canceled)
+	})
+}
@@ -0,0 +1,180 @@
+package unit_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tapsilat "github.com/tapsilat/tapsilat-go"
+)
+
+func TestParseThreeDSForm(t *testing.T) {
+	t.Run("FromHTML", func(t *testing.T) {
+		form, err := tapsilat.ParseThreeDSForm(tapsilat.CardTokenizeResponse{
+			ThreedformHTML: `<html><body onload="document.forms[0].submit()">
+				<form name='acs' action="https://acs.example/pay?x=1&amp;y=2" method=post>
+					<input type="hidden" name="PaReq" value="abc&quot;def">
+					<input type=hidden name=TermUrl value='https://shop.example/term'>
+					<textarea name="MD">md&lt;1&gt;</textarea>
+					<input type="submit" name="go" value="Go">
+				</form><script>alert(1)</script></body></html>`,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "https://acs.example/pay?x=1&y=2", form.Action)
+		assert.Equal(t, http.MethodPost, form.Method)
+		assert.Equal(t, []tapsilat.ThreeDSField{
+			{Name: "PaReq", Value: `abc"def`},
+			{Name: "TermUrl", Value: "https://shop.example/term"},
+			{Name: "MD", Value: "md<1>"},
+		}, form.Fields)
+	})
+
+	t.Run("FromURL", func(t *testing.T) {
+		form, err := tapsilat.ParseThreeDSForm(tapsilat.CardTokenizeResponse{ThreedformURL: "https://3d.example/form?token=t1"})
+		require.NoError(t, err)
+		assert.Equal(t, "https://3d.example/form", form.Action)
+		assert.Equal(t, http.MethodGet, form.Method)
+		assert.Equal(t, []tapsilat.ThreeDSField{{Name: "token", Value: "t1"}}, form.Fields)
+	})
+
+	t.Run("RejectsUnsafeActions", func(t *testing.T) {
+		_, err := tapsilat.ParseThreeDSForm(tapsilat.CardTokenizeResponse{ThreedformURL: "javascript:alert(1)"})
+		require.Error(t, err)
+		_, err = tapsilat.ParseThreeDSForm(tapsilat.CardTokenizeResponse{ThreedformHTML: `<form action="/relative"></form>`})
+		require.Error(t, err)
+		_, err = tapsilat.ParseThreeDSForm(tapsilat.CardTokenizeResponse{ThreedformHTML: `<p>no form</p>`})
+		require.Error(t, err)
+		_, err = tapsilat.ParseThreeDSForm(tapsilat.CardTokenizeResponse{})
+		require.Error(t, err)
+	})
+}
+
+func TestServeThreeDSPage(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	err := tapsilat.ServeThreeDSPage(recorder, tapsilat.CardTokenizeResponse{
+		ThreedformHTML: `<form action="https://acs.example/pay" method="post"><input name="PaReq" value="&quot;&gt;&lt;script&gt;alert(1)&lt;/script&gt;"></form><script>steal()</script>`,
+	})
+	require.NoError(t, err)
+
+	body := recorder.Body.String()
+	csp := recorder.Header().Get("Content-Security-Policy")
+	nonce := regexp.MustCompile(`'nonce-([^']+)'`).FindStringSubmatch(csp)
+	require.Len(t, nonce, 2)
+	assert.Contains(t, csp, "form-action https://acs.example https:")
+	assert.Contains(t, body, `<script nonce="`+nonce[1]+`">`)
+	assert.Equal(t, 1, strings.Count(body, "<script"))
+	assert.NotContains(t, body, "steal()")
+	assert.Contains(t, body, `value="&#34;&gt;&lt;script&gt;alert(1)&lt;/script&gt;"`)
+	assert.Equal(t, "no-store", recorder.Header().Get("Cache-Control"))
+
+	second := httptest.NewRecorder()
+	require.NoError(t, tapsilat.ServeThreeDSPage(second, tapsilat.CardTokenizeResponse{ThreedformURL: "https://3d.example/form"}))
+	assert.NotEqual(t, csp, second.Header().Get("Content-Security-Policy"))
+}
+
+func TestThreeDSCallback(t *testing.T) {
+	var listCalls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		listCalls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Query().Get("page") {
+		case "1":
+			_, _ = w.Write([]byte(`{"page":1,"total_pages":2,"rows":[{"id":"card_0"}]}`))
+		default:
+			_, _ = w.Write([]byte(`{"page":2,"total_pages":2,"rows":[{"id":"card_1","last_four":"0006","brand":"mastercard"}]}`))
+		}
+	}))
+	defer server.Close()
+
+	api := tapsilat.NewCustomAPI(server.URL, "token_3ds")
+	callback := tapsilat.NewThreeDSCallback(api, nil)
+	callback.Track(tapsilat.CardTokenizeResponse{OrderReferenceID: "ord_1", CardID: "card_1"})
+	callback.Track(tapsilat.CardTokenizeResponse{OrderReferenceID: "ord_2", CardID: "card_2"})
+
+	t.Run("SuccessRedirectFindsSavedCard", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, "/3ds/success?order_reference_id=ord_1", nil)
+		callback.Success().ServeHTTP(recorder, request)
+
+		require.Equal(t, http.StatusOK, recorder.Code)
+		var result tapsilat.ThreeDSResult
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+		assert.Equal(t, tapsilat.ThreeDSSucceeded, result.Status)
+		assert.Equal(t, "card_1", result.CardID)
+		require.NotNil(t, result.Card)
+		assert.Equal(t, "0006", result.Card.LastFour)
+		assert.Equal(t, int32(2), listCalls.Load())
+	})
+
+	t.Run("SuccessRedirectWithoutSavedCardIsUnconfirmed", func(t *testing.T) {
+		result, err := callback.Resolve(context.Background(), true, url.Values{"reference_id": {"ord_2"}})
+		require.NoError(t, err)
+		assert.Equal(t, tapsilat.ThreeDSUnconfirmed, result.Status)
+		assert.Nil(t, result.Card)
+	})
+
+	t.Run("UntrackedReferenceIgnoresCardParameter", func(t *testing.T) {
+		before := listCalls.Load()
+		result, err := callback.Resolve(context.Background(), true, url.Values{"order_reference_id": {"ord_forged"}, "card_id": {"card_1"}})
+		require.NoError(t, err)
+		assert.Equal(t, tapsilat.ThreeDSUnconfirmed, result.Status)
+		assert.Empty(t, result.CardID)
+		assert.Nil(t, result.Card)
+		assert.Equal(t, before, listCalls.Load())
+	})
+
+	t.Run("PendingIsBounded", func(t *testing.T) {
+		now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		bounded := &tapsilat.ThreeDSCallback{Cards: api, TTL: time.Minute, MaxPending: 2, Now: func() time.Time { return now }}
+		bounded.Track(tapsilat.CardTokenizeResponse{OrderReferenceID: "ord_a", CardID: "card_1"})
+		now = now.Add(time.Second)
+		bounded.Track(tapsilat.CardTokenizeResponse{OrderReferenceID: "ord_b", CardID: "card_1"})
+		bounded.Track(tapsilat.CardTokenizeResponse{OrderReferenceID: "ord_c", CardID: "card_1"})
+		assert.Equal(t, 2, bounded.Pending())
+
+		result, err := bounded.Resolve(context.Background(), true, url.Values{"order_reference_id": {"ord_a"}})
+		require.NoError(t, err)
+		assert.Equal(t, tapsilat.ThreeDSUnconfirmed, result.Status, "oldest entry is dropped")
+
+		now = now.Add(time.Minute)
+		result, err = bounded.Resolve(context.Background(), true, url.Values{"order_reference_id": {"ord_b"}})
+		require.NoError(t, err)
+		assert.Equal(t, tapsilat.ThreeDSUnconfirmed, result.Status, "expired entry is not trusted")
+		bounded.Track(tapsilat.CardTokenizeResponse{OrderReferenceID: "ord_d", CardID: "card_1"})
+		assert.Equal(t, 1, bounded.Pending())
+	})
+
+	t.Run("FailureRedirect", func(t *testing.T) {
+		var got tapsilat.ThreeDSResult
+		failure := tapsilat.NewThreeDSCallback(api, func(w http.ResponseWriter, r *http.Request, result tapsilat.ThreeDSResult) {
+			got = result
+			w.WriteHeader(http.StatusNoContent)
+		})
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodPost, "/3ds/failure", strings.NewReader("order_reference_id=ord_3&error_code=51&message=declined"))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		before := listCalls.Load()
+		failure.Failure().ServeHTTP(recorder, request)
+
+		assert.Equal(t, http.StatusNoContent, recorder.Code)
+		assert.Equal(t, tapsilat.ThreeDSFailed, got.Status)
+		assert.Equal(t, "51", got.ErrorCode)
+		assert.Equal(t, "declined", got.Message)
+		assert.Equal(t, before, listCalls.Load())
+	})
+
+	t.Run("MissingOrderReference", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		callback.Success().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/3ds/success", nil))
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+}
@@ -0,0 +1,134 @@
+package unit_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tapsilat "github.com/tapsilat/tapsilat-go"
+)
+
+type userTokenServer struct {
+	mu       sync.Mutex
+	issued   []tapsilat.OrgUserTokenCreateRequest
+	auth     []string
+	rejected map[string]bool
+}
+
+func (s *userTokenServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.URL.Path {
+	case "/organization/user/token":
+		var payload tapsilat.OrgUserTokenCreateRequest
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		s.issued = append(s.issued, payload)
+		time.Sleep(10 * time.Millisecond)
+		_, _ = fmt.Fprintf(w, `{"token":"user-token-%d","user_id":"u_1"}`, len(s.issued))
+	default:
+		auth := r.Header.Get("Authorization")
+		s.auth = append(s.auth, auth)
+		if s.rejected[auth] {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"message":"token expired"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"currencies":[]}`))
+	}
+}
+
+func TestUserTokenManager(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("IssuesOnceForConcurrentCallers", func(t *testing.T) {
+		backend := &userTokenServer{}
+		server := httptest.NewServer(backend)
+		defer server.Close()
+		manager := tapsilat.NewUserTokenManager(tapsilat.NewCustomAPI(server.URL, "org-token"))
+		manager.InvalidateOldTokens = true
+		manager.Expire = 90 * time.Second
+
+		var wg sync.WaitGroup
+		tokens := make([]tapsilat.UserToken, 16)
+		for i := range tokens {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				token, err := manager.Token(ctx, "Jane@Example.com ")
+				assert.NoError(t, err)
+				tokens[i] = token
+			}()
+		}
+		wg.Wait()
+
+		require.Len(t, backend.issued, 1)
+		assert.Equal(t, tapsilat.OrgUserTokenCreateRequest{Email: "Jane@Example.com ", Expire: 2, InvalidateOldTokens: true}, backend.issued[0])
+		for _, token := range tokens {
+			assert.Equal(t, "user-token-1", token.Token.Reveal())
+			assert.Equal(t, "u_1", token.UserID)
+		}
+	})
+
+	t.Run("RefreshesAheadOfExpiry", func(t *testing.T) {
+		backend := &userTokenServer{}
+		server := httptest.NewServer(backend)
+		defer server.Close()
+		now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+		manager := tapsilat.NewUserTokenManager(tapsilat.NewCustomAPI(server.URL, "org-token"))
+		manager.Now = func() time.Time { return now }
+
+		token, err := manager.Token(ctx, "jane@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, now.Add(time.Hour), token.ExpiresAt)
+
+		now = now.Add(54 * time.Minute)
+		token, err = manager.Token(ctx, "jane@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, "user-token-1", token.Token.Reveal())
+
+		now = now.Add(2 * time.Minute)
+		token, err = manager.Token(ctx, "jane@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, "user-token-2", token.Token.Reveal())
+		assert.Len(t, backend.issued, 2)
+	})
+
+	t.Run("UserClientFollowsRefreshAndRevocation", func(t *testing.T) {
+		backend := &userTokenServer{rejected: map[string]bool{"Bearer user-token-1": true}}
+		server := httptest.NewServer(backend)
+		defer server.Close()
+		manager := tapsilat.NewUserTokenManager(tapsilat.NewCustomAPI(server.URL, "org-token"))
+
+		api, err := manager.API(ctx, "jane@example.com")
+		require.NoError(t, err)
+		again, err := manager.API(ctx, "JANE@example.com")
+		require.NoError(t, err)
+		assert.Same(t, api, again)
+
+		_, err = api.GetOrganizationCurrencies(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Bearer user-token-1", "Bearer user-token-2"}, backend.auth)
+		assert.Equal(t, "user-token-2", api.Token.Reveal())
+
+		require.NoError(t, manager.Revoke(ctx, "jane@example.com"))
+		require.Len(t, backend.issued, 3)
+		assert.True(t, backend.issued[2].InvalidateOldTokens)
+		assert.Equal(t, uint32(1), backend.issued[2].Expire)
+
+		_, err = api.GetOrganizationCurrencies(ctx)
+		require.ErrorIs(t, err, tapsilat.ErrUserTokenRevoked)
+		assert.Len(t, backend.auth, 2)
+
+		fresh, err := manager.API(ctx, "jane@example.com")
+		require.NoError(t, err)
+		assert.NotSame(t, api, fresh)
+	})
+}
@@ -131,3 +131,50 @@ func TestValidateGSMNumber(t *testing.T) {
 		assert.Contains(t, err.Error(), "Invalid phone number format")
 	})
 }
+
+func TestValidateIBAN(t *testing.T) {
+	iban, err := tapsilat.ValidateIBAN("tr33 0006 1005 1978 6457 8413 26")
+	require.NoError(t, err)
+	assert.Equal(t, "TR330006100519786457841326", iban)
+
+	_, err = tapsilat.ValidateIBAN("DE89370400440532013000")
+	require.NoError(t, err)
+
+	for _, invalid := range []string{"TR00", "TR340006100519786457841326", "TR33000610051978645784132", "TR330006110519786457841326", "TR33A006100519786457841326"} {
+		_, err = tapsilat.ValidateIBAN(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestValidateTCKN(t *testing.T) {
+	id, err := tapsilat.ValidateTCKN(" 10000000146 ")
+	require.NoError(t, err)
+	assert.Equal(t, "10000000146", id)
+
+	for _, invalid := range []string{"10000000147", "10000000156", "01234567890", "1000000014", "1000000014a"} {
+		_, err = tapsilat.ValidateTCKN(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestValidateVKN(t *testing.T) {
+	for _, valid := range []string{"1234567890", "9876543217", "1111111114"} {
+		_, err := tapsilat.ValidateVKN(valid)
+		require.NoError(t, err, valid)
+	}
+	for _, invalid := range []string{"1234567891", "123456789", "12345678901"} {
+		_, err := tapsilat.ValidateVKN(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestValidateEmail(t *testing.T) {
+	email, err := tapsilat.ValidateEmail(" tenant@example.com ")
+	require.NoError(t, err)
+	assert.Equal(t, "tenant@example.com", email)
+
+	for _, invalid := range []string{"tenant", "tenant@localhost", "Tenant <tenant@example.com>", "a b@example.com"} {
+		_, err = tapsilat.ValidateEmail(invalid)
+		require.Error(t, err, invalid)
+	}
+}
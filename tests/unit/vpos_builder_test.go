@@ -0,0 +1,174 @@
+package unit_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tapsilat "github.com/tapsilat/tapsilat-go"
+)
+
+func vposBuilderServer(t *testing.T, sent *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method + " " + r.URL.Path {
+		case "GET /vpos/acquirers":
+			_, _ = w.Write([]byte(`{"items":[{"id":"acq_1","name":"Akbank","prefix":"akbank"}]}`))
+		case "GET /vpos/card-schemes":
+			_, _ = w.Write([]byte(`{"items":[{"id":"cs_1","name":"Visa"},{"id":"cs_2","name":"Mastercard"}]}`))
+		case "GET /vpos/acquirer-templates":
+			_, _ = w.Write([]byte(`{"items":[{"acquirer_id":"acq_1","name":"Akbank","prefix":"akbank","required_fields":["client_id","store_key","username"],"optional_fields":["password"],"defaults":{"payment_mode":"3d_pay","main":true,"force_three_d":true,"credentials":{"env_mode":"TEST","unknown_default":"x"}}}]}`))
+		case "GET /organization/currencies":
+			_, _ = w.Write([]byte(`{"currencies":[{"id":"9f4050e8-1111-4f25-b4ef-aaaaaaaaaaaa","name":"Turkish Lira","code":"949","currency_unit":"TRY"}]}`))
+		case "POST /vpos", "PATCH /vpos/v_1":
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			*sent = append(*sent, r.Method+" "+string(body))
+			_, _ = w.Write([]byte(`{"code":200,"message":"ok"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestVposBuilder(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("AppliesDefaultsAndCreates", func(t *testing.T) {
+		var sent []string
+		server := vposBuilderServer(t, &sent)
+		defer server.Close()
+		api := tapsilat.NewCustomAPI(server.URL, "token")
+
+		builder, err := api.NewVposBuilder(ctx, "AKBANK")
+		require.NoError(t, err)
+		assert.Equal(t, "acq_1", builder.Request.AcquirerID)
+		assert.Equal(t, "3d_pay", builder.Request.PaymentMode)
+		assert.Equal(t, "TEST", builder.Request.EnvMode)
+		assert.True(t, builder.Request.ForceThreeD)
+		assert.True(t, builder.Request.Main)
+
+		builder.Request.Name = "Akbank POS"
+		builder.Request.CardSchemes = []string{"visa", "Mastercard"}
+		builder.Request.Currencies = []string{"TRY"}
+		builder.SetFields(map[string]string{"client_id": "100", "store_key": "sk", "username": "api", "password": "pw"})
+
+		res, err := builder.Create(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(200), res.Code)
+		require.Len(t, sent, 1)
+
+		var body map[string]any
+		require.NoError(t, json.Unmarshal([]byte(sent[0][len("POST "):]), &body))
+		assert.Equal(t, "acq_1", body["acquirer_id"])
+		assert.Equal(t, "sk", body["store_key"])
+		assert.Equal(t, []any{"cs_1", "cs_2"}, body["card_schemes"])
+		assert.Equal(t, []any{"9f4050e8-1111-4f25-b4ef-aaaaaaaaaaaa"}, body["currencies"])
+	})
+
+	t.Run("CreatesNonMainVposWhenTheTemplateDefaultsToMain", func(t *testing.T) {
+		var sent []string
+		server := vposBuilderServer(t, &sent)
+		defer server.Close()
+		api := tapsilat.NewCustomAPI(server.URL, "token")
+
+		builder, err := api.NewVposBuilder(ctx, "akbank")
+		require.NoError(t, err)
+		builder.Request.Name = "Akbank POS"
+		builder.Request.Currencies = []string{"TRY"}
+		builder.Request.Main = false
+		builder.SetFields(map[string]string{"client_id": "100", "store_key": "sk", "username": "api"})
+
+		_, err = builder.Create(ctx)
+		require.NoError(t, err)
+		require.Len(t, sent, 1)
+		var body map[string]any
+		require.NoError(t, json.Unmarshal([]byte(sent[0][len("POST "):]), &body))
+		assert.Equal(t, false, body["main"])
+		assert.Equal(t, true, body["force_three_d"])
+	})
+
+	t.Run("UpdateSendsOnlyTheFieldsTheCallerSet", func(t *testing.T) {
+		var sent []string
+		server := vposBuilderServer(t, &sent)
+		defer server.Close()
+		api := tapsilat.NewCustomAPI(server.URL, "token")
+
+		builder, err := api.NewVposBuilder(ctx, "akbank")
+		require.NoError(t, err)
+		builder.Request.Name = "Akbank POS"
+		builder.Request.Currencies = []string{"TRY"}
+		builder.Request.ForceThreeD = false
+		builder.SetFields(map[string]string{"client_id": "100", "store_key": "sk", "username": "api"})
+
+		_, err = builder.Update(ctx, "v_1")
+		require.NoError(t, err)
+		require.Len(t, sent, 1)
+		var body map[string]any
+		require.NoError(t, json.Unmarshal([]byte(sent[0][len("PATCH "):]), &body))
+		assert.Equal(t, map[string]any{
+			"name":          "Akbank POS",
+			"currencies":    []any{"9f4050e8-1111-4f25-b4ef-aaaaaaaaaaaa"},
+			"force_three_d": false,
+			"client_id":     "100",
+			"store_key":     "sk",
+			"username":      "api",
+		}, body)
+	})
+
+	t.Run("ReportsMissingUnknownAndInvalidFieldsBeforeSending", func(t *testing.T) {
+		var sent []string
+		server := vposBuilderServer(t, &sent)
+		defer server.Close()
+		api := tapsilat.NewCustomAPI(server.URL, "token")
+
+		builder, err := api.NewVposBuilder(ctx, "acq_1")
+		require.NoError(t, err)
+		builder.Request.Name = "Akbank POS"
+		builder.Request.CardSchemes = []string{"amex"}
+		builder.Request.Currencies = []string{"TRY"}
+		builder.Set("client_id", "100").Set("storekey", "sk").Set("api_key", "k").Set("username", "api")
+
+		_, err = builder.Update(ctx, "v_1")
+		var fieldErr *tapsilat.FieldValidationError
+		require.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, []string{"store_key"}, fieldErr.Fields(tapsilat.FieldMissing))
+		assert.Equal(t, []string{"storekey", "api_key"}, fieldErr.Fields(tapsilat.FieldUnknown))
+		assert.Equal(t, []string{"card_schemes"}, fieldErr.Fields(tapsilat.FieldInvalid))
+		assert.Empty(t, sent)
+
+		builder.Request.ApiKey = ""
+		builder.Request.CardSchemes = []string{"visa"}
+		builder.Set("store_key", "sk")
+		assert.ErrorContains(t, builder.Validate(), "error:storekey: is not a VPOS field")
+	})
+
+	t.Run("ValidateVposOffline", func(t *testing.T) {
+		template := tapsilat.VposAcquirerTemplate{AcquirerID: "acq_1", Name: "Akbank", RequiredFields: []string{"terminal"}}
+		payload := tapsilat.ApplyVposDefaults(template, tapsilat.VposCreateRequest{Name: "POS", Currencies: []string{"TRY"}, Merchant: "m"})
+		assert.Equal(t, "acq_1", payload.AcquirerID)
+		flagged := tapsilat.VposAcquirerTemplate{Defaults: tapsilat.VposAcquirerTemplateDefaults{Main: true}}
+		assert.False(t, tapsilat.ApplyVposDefaults(flagged, tapsilat.VposCreateRequest{}).Main)
+		assert.True(t, tapsilat.NewVposRequest(flagged).Main)
+
+		err := tapsilat.ValidateVpos(template, payload)
+		var fieldErr *tapsilat.FieldValidationError
+		require.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, []tapsilat.FieldIssue{
+			{Field: "terminal", Reason: tapsilat.FieldMissing, Message: "is required by Akbank"},
+			{Field: "merchant", Reason: tapsilat.FieldUnknown, Message: "is not used by Akbank"},
+		}, fieldErr.Issues)
+
+		payload.AcquirerID = "acq_2"
+		payload.Terminal = "t"
+		payload.Merchant = ""
+		err = tapsilat.ValidateVpos(template, payload)
+		require.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, []string{"acquirer_id"}, fieldErr.Fields(tapsilat.FieldInvalid))
+	})
+}
@@ -0,0 +1,156 @@
+package unit_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tapsilat "github.com/tapsilat/tapsilat-go"
+	"github.com/tapsilat/tapsilat-go/vposroute"
+)
+
+const (
+	routeTRY = "9f4050e8-1111-4f25-b4ef-aaaaaaaaaaaa"
+	routeUSD = "9f4050e8-2222-4f25-b4ef-bbbbbbbbbbbb"
+)
+
+type fakeRouteClient struct {
+	vpos   []tapsilat.Vpos
+	scopes map[string][]string
+}
+
+func (c *fakeRouteClient) ListVposWithFilter(ctx context.Context, page, perPage int, filter tapsilat.VposListFilter) (tapsilat.VposListResponse, error) {
+	var items []tapsilat.VposListItem
+	for _, vpos := range c.vpos {
+		if filter.SuborganizationID == "" || strings.Contains(strings.Join(c.scopes[filter.SuborganizationID], ","), vpos.ID) {
+			items = append(items, tapsilat.VposListItem{ID: vpos.ID, Name: vpos.Name})
+		}
+	}
+	start := min((page-1)*perPage, len(items))
+	end := min(start+perPage, len(items))
+	return tapsilat.VposListResponse{Page: int64(page), TotalPages: int64((len(items) + perPage - 1) / perPage), Rows: items[start:end]}, nil
+}
+
+func (c *fakeRouteClient) GetVpos(ctx context.Context, id string) (tapsilat.Vpos, error) {
+	for _, vpos := range c.vpos {
+		if vpos.ID == id {
+			return vpos, nil
+		}
+	}
+	return tapsilat.Vpos{}, &tapsilat.APIError{StatusCode: 404}
+}
+
+func (c *fakeRouteClient) CachedCardSchemes(ctx context.Context) ([]tapsilat.CardScheme, error) {
+	return []tapsilat.CardScheme{{ID: "cs_visa", Name: "Visa"}, {ID: "cs_mc", Name: "MasterCard"}, {ID: "cs_troy", Name: "Troy"}}, nil
+}
+
+func (c *fakeRouteClient) GetOrganizationCurrencies(ctx context.Context) (tapsilat.OrganizationCurrenciesResponse, error) {
+	return tapsilat.OrganizationCurrenciesResponse{Currencies: []tapsilat.OrganizationCurrency{
+		{ID: routeTRY, CurrencyUnit: "TRY", Code: "949"},
+		{ID: routeUSD, CurrencyUnit: "USD", Code: "840"},
+	}}, nil
+}
+
+func TestVposRouting(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	client := &fakeRouteClient{
+		vpos: []tapsilat.Vpos{
+			{ID: "v_akbank", Name: "Akbank", Priority: 5, CardSchemes: []string{"cs_visa", "cs_mc"}, Currencies: []string{routeTRY}},
+			{ID: "v_garanti", Name: "Garanti", Priority: 10, Currencies: []string{routeTRY, routeUSD}},
+			{ID: "v_main", Name: "Main POS", Main: true, Priority: 1, ForceThreeD: true, CardSchemes: []string{"Visa"}, Currencies: []string{routeTRY}},
+			{ID: "v_blocked", Name: "Blocked", Priority: 99, Currencies: []string{routeTRY}, BlockDate: uint64(now.Add(time.Hour).Unix())},
+			{ID: "v_market", Name: "Marketplace", Marketplace: true, Currencies: []string{routeTRY}},
+			{ID: "v_troy", Name: "Troy POS", Priority: 50, Pf: true, CardSchemes: []string{"cs_troy"}, Currencies: []string{routeTRY}},
+		},
+		scopes: map[string][]string{"sub_1": {"v_akbank", "v_market"}},
+	}
+	loader := vposroute.New(client)
+	loader.PerPage = 2
+	snapshot, err := loader.Load(context.Background(), "sub_1")
+	require.NoError(t, err)
+	require.Len(t, snapshot.Vpos, 6)
+	assert.Equal(t, []string{"v_akbank", "v_market"}, snapshot.Suborganizations["sub_1"])
+
+	ids := func(result *vposroute.Result) []string {
+		var eligible []string
+		for _, candidate := range result.Candidates {
+			if candidate.Eligible {
+				eligible = append(eligible, candidate.Vpos.ID)
+			}
+		}
+		return eligible
+	}
+
+	t.Run("RanksMainThenPriority", func(t *testing.T) {
+		result, err := snapshot.Simulate(vposroute.Order{Currency: "try", CardNumber: "4111 1111"}, now)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"v_main", "v_garanti", "v_akbank"}, ids(result))
+		selected, ok := result.Selected()
+		require.True(t, ok)
+		assert.Equal(t, 1, selected.Rank)
+		assert.Equal(t, "v_main", selected.Vpos.ID)
+
+		require.Len(t, result.Candidates, 6)
+		excluded := map[string][]vposroute.Check{}
+		for _, candidate := range result.Candidates[3:] {
+			assert.Zero(t, candidate.Rank)
+			excluded[candidate.Vpos.ID] = candidate.Failed()
+		}
+		assert.Equal(t, []vposroute.Check{{Rule: vposroute.RuleBlocked, Detail: "blocked until 2026-10-19T13:00:00Z"}}, excluded["v_blocked"])
+		assert.Equal(t, []vposroute.Check{{Rule: vposroute.RuleMarketplace, Detail: "marketplace-only VPOS"}}, excluded["v_market"])
+		assert.Equal(t, []vposroute.Check{{Rule: vposroute.RuleCardScheme, Detail: "does not accept visa"}}, excluded["v_troy"])
+
+		var out bytes.Buffer
+		require.NoError(t, result.WriteExplain(&out))
+		assert.Contains(t, out.String(), "#1 Main POS (v_main, main=true, priority=1): blocked: not blocked; currency: accepts TRY; card_scheme: accepts visa; marketplace: not a marketplace VPOS; three_d: forces 3D Secure; pf: not a payment facilitator VPOS\n")
+		assert.Contains(t, out.String(), "excluded Troy POS (v_troy, main=false, priority=50): card_scheme: does not accept visa\n")
+	})
+
+	t.Run("ReportsThreeDAndPfWithoutExcluding", func(t *testing.T) {
+		result, err := snapshot.Simulate(vposroute.Order{Currency: "TRY", CardScheme: "troy"}, now)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"v_troy", "v_garanti"}, ids(result))
+
+		checks := map[string]map[vposroute.Rule]vposroute.Check{}
+		for _, candidate := range result.Candidates {
+			checks[candidate.Vpos.ID] = map[vposroute.Rule]vposroute.Check{}
+			for _, check := range candidate.Checks {
+				checks[candidate.Vpos.ID][check.Rule] = check
+			}
+		}
+		assert.Equal(t, vposroute.Check{Rule: vposroute.RulePf, Passed: true, Detail: "payment facilitator VPOS"}, checks["v_troy"][vposroute.RulePf])
+		assert.Equal(t, vposroute.Check{Rule: vposroute.RuleThreeD, Passed: true, Detail: "does not force 3D Secure"}, checks["v_troy"][vposroute.RuleThreeD])
+		assert.Equal(t, vposroute.Check{Rule: vposroute.RuleThreeD, Passed: true, Detail: "forces 3D Secure"}, checks["v_main"][vposroute.RuleThreeD])
+	})
+
+	t.Run("FiltersByCurrencySchemeAndSuborganization", func(t *testing.T) {
+		result, err := snapshot.Simulate(vposroute.Order{Currency: routeUSD}, now)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"v_garanti"}, ids(result))
+
+		result, err = snapshot.Simulate(vposroute.Order{Currency: "949", CardScheme: "troy"}, now)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"v_troy", "v_garanti"}, ids(result))
+
+		result, err = snapshot.Simulate(vposroute.Order{Currency: "TRY", Marketplace: true, SuborganizationID: "sub_1"}, now)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"v_market"}, ids(result))
+
+		result, err = snapshot.Simulate(vposroute.Order{Currency: "TRY", SuborganizationID: "sub_1"}, now.Add(2*time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"v_akbank"}, ids(result))
+	})
+
+	t.Run("RejectsOrdersItCannotRoute", func(t *testing.T) {
+		_, err := snapshot.Simulate(vposroute.Order{Currency: "EUR"}, now)
+		require.EqualError(t, err, `vposroute: unknown currency "EUR"`)
+		_, err = snapshot.Simulate(vposroute.Order{Currency: "TRY", SuborganizationID: "sub_2"}, now)
+		require.EqualError(t, err, "vposroute: suborganization sub_2 is not in the snapshot")
+		_, err = snapshot.Simulate(vposroute.Order{Currency: "TRY", CardScheme: "discover"}, now)
+		require.EqualError(t, err, `vposroute: unknown card scheme "discover"`)
+	})
+}
@@ -0,0 +1,186 @@
+package unit_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tapsilat "github.com/tapsilat/tapsilat-go"
+)
+
+func newWalletServer(t *testing.T) (*httptest.Server, *[]string) {
+	t.Helper()
+	var mu sync.Mutex
+	var deletes []string
+	cards := []tapsilat.SavedCard{
+		{ID: "card_1", UserID: "u1", Bin: "552608", LastFour: "0006", ExpiryMonth: "12", ExpiryYear: "2030", IsDefault: true},
+		{ID: "card_2", UserID: "u2", Bin: "411111", LastFour: "1111", ExpiryMonth: "01", ExpiryYear: "2031"},
+		{ID: "card_3", UserEmail: "Ada@Example.com", MaskedNumber: "552608******0006", ExpiryMonth: "05", ExpiryYear: "26"},
+		{ID: "card_4", UserID: "u1", Bin: "41111111", LastFour: "1111", ExpiryMonth: "02", ExpiryYear: "2026"},
+		{ID: "card_5", UserID: "u1", Bin: "979203", LastFour: "0796", ExpiryMonth: "11", ExpiryYear: "2029"},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodDelete {
+			id := strings.TrimPrefix(r.URL.Path, "/tokenization/card/")
+			deletes = append(deletes, id)
+			cards = slices.DeleteFunc(cards, func(card tapsilat.SavedCard) bool { return card.ID == id })
+			_, _ = w.Write([]byte(`{"success":true}`))
+			return
+		}
+
+		assert.Equal(t, "/tokenization/card/list", r.URL.Path)
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+		start := min((page-1)*perPage, len(cards))
+		end := min(start+perPage, len(cards))
+		_ = json.NewEncoder(w).Encode(tapsilat.ListSavedCardsResponse{
+			Page:       int64(page),
+			PerPage:    int64(perPage),
+			Total:      int64(len(cards)),
+			TotalPages: int64((len(cards) + perPage - 1) / perPage),
+			Rows:       cards[start:end],
+		})
+	}))
+	return server, &deletes
+}
+
+func TestWallet(t *testing.T) {
+	now := time.Date(2026, time.January, 15, 0, 0, 0, 0, time.UTC)
+	newWallet := func(server *httptest.Server) *tapsilat.Wallet {
+		wallet := tapsilat.NewWallet(tapsilat.NewCustomAPI(server.URL, "token_wallet"))
+		wallet.PerPage = 3
+		wallet.Now = func() time.Time { return now }
+		return wallet
+	}
+	ctx := context.Background()
+
+	t.Run("ListsOwnerCardsAcrossPages", func(t *testing.T) {
+		server, _ := newWalletServer(t)
+		defer server.Close()
+		wallet := newWallet(server)
+
+		cards, err := wallet.Cards(ctx, tapsilat.WalletOwner{UserID: "u1"})
+		require.NoError(t, err)
+		require.Len(t, cards, 3)
+		assert.Equal(t, "card_1", cards[0].ID)
+		assert.True(t, cards[0].Default)
+		assert.False(t, cards[0].Expiring)
+
+		assert.Equal(t, "card_4", cards[1].ID)
+		assert.True(t, cards[1].Expiring)
+		assert.False(t, cards[1].Expired)
+		assert.Equal(t, time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC), cards[1].ExpiresAt)
+
+		byEmail, err := wallet.Cards(ctx, tapsilat.WalletOwner{Email: "ada@example.com"})
+		require.NoError(t, err)
+		require.Len(t, byEmail, 1)
+		assert.Equal(t, "card_3", byEmail[0].ID)
+
+		_, err = wallet.Cards(ctx, tapsilat.WalletOwner{})
+		require.Error(t, err)
+	})
+
+	t.Run("DetectsDuplicates", func(t *testing.T) {
+		server, _ := newWalletServer(t)
+		defer server.Close()
+		wallet := newWallet(server)
+
+		cards, err := wallet.Cards(ctx, tapsilat.WalletOwner{UserID: "u1", Email: "ada@example.com"})
+		require.NoError(t, err)
+		require.Len(t, cards, 4)
+		assert.Equal(t, "card_3", cards[1].ID)
+		assert.Equal(t, "card_1", cards[1].DuplicateOf)
+		assert.Empty(t, cards[2].DuplicateOf)
+
+		duplicate, ok, err := wallet.FindDuplicate(ctx, tapsilat.WalletOwner{UserID: "u1"}, "4111 1111 1111 1111")
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, "card_4", duplicate.ID)
+
+		_, ok, err = wallet.FindDuplicate(ctx, tapsilat.WalletOwner{UserID: "u2"}, "5526080000000006")
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("SetDefault", func(t *testing.T) {
+		server, _ := newWalletServer(t)
+		defer server.Close()
+		wallet := newWallet(server)
+		owner := tapsilat.WalletOwner{UserID: "u1"}
+
+		require.NoError(t, wallet.SetDefault(ctx, owner, "card_5"))
+		card, ok, err := wallet.Default(ctx, owner)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, "card_5", card.ID)
+		assert.False(t, card.IsDefault, "the server flag is not changed")
+
+		err = wallet.SetDefault(ctx, owner, "card_2")
+		var validationErr *tapsilat.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+	})
+
+	t.Run("DeleteDefaultPicksNextCard", func(t *testing.T) {
+		server, deletes := newWalletServer(t)
+		defer server.Close()
+		wallet := newWallet(server)
+		owner := tapsilat.WalletOwner{UserID: "u1"}
+
+		next, ok, err := wallet.Delete(ctx, owner, "card_1")
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, "card_4", next.ID)
+		assert.Equal(t, []string{"card_1"}, *deletes)
+
+		card, ok, err := wallet.Default(ctx, owner)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, "card_4", card.ID)
+
+		_, ok, err = wallet.Delete(ctx, owner, "card_5")
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		_, _, err = wallet.Delete(ctx, owner, "card_2")
+		require.Error(t, err)
+		assert.Equal(t, []string{"card_1", "card_5"}, *deletes)
+	})
+
+	t.Run("ReadsTwoDigitYearsInTheCurrentCentury", func(t *testing.T) {
+		server, _ := newWalletServer(t)
+		defer server.Close()
+		wallet := newWallet(server)
+		wallet.Now = func() time.Time { return time.Date(2105, time.January, 15, 0, 0, 0, 0, time.UTC) }
+
+		cards, err := wallet.Cards(ctx, tapsilat.WalletOwner{Email: "ada@example.com"})
+		require.NoError(t, err)
+		require.Len(t, cards, 1)
+		assert.Equal(t, time.Date(2126, time.June, 1, 0, 0, 0, 0, time.UTC), cards[0].ExpiresAt)
+		assert.False(t, cards[0].Expired)
+	})
+
+	t.Run("FailsWhenCardsCarryNoOwner", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"page":1,"total_pages":1,"rows":[{"id":"card_1","bin":"552608","last_four":"0006"}]}`))
+		}))
+		defer server.Close()
+		wallet := newWallet(server)
+
+		_, err := wallet.Cards(ctx, tapsilat.WalletOwner{UserID: "u1"})
+		require.ErrorIs(t, err, tapsilat.ErrSavedCardOwnerUnknown)
+	})
+}
@@ -0,0 +1,411 @@
+package tapsilat
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ThreeDSField is a form field posted to the 3-D Secure page.
+type ThreeDSField struct {
+	Name  string
+	Value string
+}
+
+// ThreeDSForm is the form that sends the card holder to the 3-D Secure page.
+type ThreeDSForm struct {
+	Action string
+	Method string
+	Fields []ThreeDSField
+}
+
+var (
+	threeDSFormRegex     = regexp.MustCompile(`(?is)<form\b([^>]*)>(.*?)</form\s*>`)
+	threeDSInputRegex    = regexp.MustCompile(`(?is)<input\b([^>]*)>`)
+	threeDSTextareaRegex = regexp.MustCompile(`(?is)<textarea\b([^>]*)>(.*?)</textarea\s*>`)
+	threeDSAttrRegex     = regexp.MustCompile(`([a-zA-Z_:][-a-zA-Z0-9_:.]*)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'=<>` + "`" + `]+))`)
+)
+
+// ParseThreeDSForm builds the 3-D Secure form from a TokenizeCard response.
+// ThreedformHTML is parsed for its first form, keeping only the action,
+// method and field values; ThreedformURL becomes a GET form carrying its
+// query parameters. Only http and https actions are accepted.
+func ParseThreeDSForm(response CardTokenizeResponse) (ThreeDSForm, error) {
+	var form ThreeDSForm
+	switch {
+	case strings.TrimSpace(response.ThreedformHTML) != "":
+		match := threeDSFormRegex.FindStringSubmatch(response.ThreedformHTML)
+		if match == nil {
+			return ThreeDSForm{}, threeDSError("threedform_html does not contain a form")
+		}
+		attrs := parseHTMLAttrs(match[1])
+		form.Action = attrs["action"]
+		form.Method = strings.ToUpper(attrs["method"])
+		for _, input := range threeDSInputRegex.FindAllStringSubmatch(match[2], -1) {
+			attrs := parseHTMLAttrs(input[1])
+			if attrs["name"] == "" || strings.EqualFold(attrs["type"], "submit") {
+				continue
+			}
+			form.Fields = append(form.Fields, ThreeDSField{Name: attrs["name"], Value: attrs["value"]})
+		}
+		for _, textarea := range threeDSTextareaRegex.FindAllStringSubmatch(match[2], -1) {
+			attrs := parseHTMLAttrs(textarea[1])
+			if attrs["name"] == "" {
+				continue
+			}
+			form.Fields = append(form.Fields, ThreeDSField{Name: attrs["name"], Value: html.UnescapeString(textarea[2])})
+		}
+	case strings.TrimSpace(response.ThreedformURL) != "":
+		target, err := url.Parse(strings.TrimSpace(response.ThreedformURL))
+		if err != nil {
+			return ThreeDSForm{}, threeDSError("threedform_url is not a valid URL")
+		}
+		for name, values := range target.Query() {
+			for _, value := range values {
+				form.Fields = append(form.Fields, ThreeDSField{Name: name, Value: value})
+			}
+		}
+		target.RawQuery = ""
+		form.Action = target.String()
+		form.Method = http.MethodGet
+	default:
+		return ThreeDSForm{}, threeDSError("response has neither threedform_url nor threedform_html")
+	}
+
+	if form.Method != http.MethodGet {
+		form.Method = http.MethodPost
+	}
+	action, err := url.Parse(form.Action)
+	if err != nil || (action.Scheme != "https" && action.Scheme != "http") || action.Host == "" {
+		return ThreeDSForm{}, threeDSError(fmt.Sprintf("3-D Secure form action %q must be an absolute http(s) URL", form.Action))
+	}
+	return form, nil
+}
+
+func parseHTMLAttrs(text string) map[string]string {
+	attrs := make(map[string]string)
+	for _, match := range threeDSAttrRegex.FindAllStringSubmatch(text, -1) {
+		name := strings.ToLower(match[1])
+		if _, ok := attrs[name]; ok {
+			continue
+		}
+		attrs[name] = html.UnescapeString(match[2] + match[3] + match[4])
+	}
+	return attrs
+}
+
+var threeDSPageTemplate = template.Must(template.New("threeds").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="referrer" content="no-referrer">
+<title>Redirecting to 3-D Secure</title>
+</head>
+<body>
+<form id="threeds-form" method="{{.Form.Method}}" action="{{.Form.Action}}">
+{{- range .Form.Fields}}
+<input type="hidden" name="{{.Name}}" value="{{.Value}}">
+{{- end}}
+<noscript><button type="submit">Continue</button></noscript>
+</form>
+<script nonce="{{.Nonce}}">document.getElementById("threeds-form").submit();</script>
+</body>
+</html>
+`))
+
+// RenderThreeDSPage renders an HTML page that submits form on load. The only
+// script on the page carries nonce, so the page works under a
+// Content-Security-Policy that allows nothing else.
+func RenderThreeDSPage(form ThreeDSForm, nonce string) ([]byte, error) {
+	var buf bytes.Buffer
+	err := threeDSPageTemplate.Execute(&buf, struct {
+		Form  ThreeDSForm
+		Nonce string
+	}{form, nonce})
+	return buf.Bytes(), err
+}
+
+// ServeThreeDSPage writes the auto-submitting 3-D Secure page for a
+// TokenizeCard response, with a fresh CSP nonce and no-store caching.
+func ServeThreeDSPage(w http.ResponseWriter, response CardTokenizeResponse) error {
+	form, err := ParseThreeDSForm(response)
+	if err != nil {
+		return err
+	}
+	nonce, err := newThreeDSNonce()
+	if err != nil {
+		return err
+	}
+	page, err := RenderThreeDSPage(form, nonce)
+	if err != nil {
+		return err
+	}
+
+	action, _ := url.Parse(form.Action)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", fmt.Sprintf(
+		"default-src 'none'; script-src 'nonce-%s'; form-action %s://%s https:; base-uri 'none'; frame-ancestors 'self'",
+		nonce, action.Scheme, action.Host))
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(page)
+	return err
+}
+
+func newThreeDSNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// ThreeDSStatus is the outcome of a 3-D Secure card tokenization.
+type ThreeDSStatus string
+
+const (
+	// ThreeDSSucceeded means the success URL was hit and the card is saved.
+	ThreeDSSucceeded ThreeDSStatus = "succeeded"
+	// ThreeDSFailed means the failure URL was hit.
+	ThreeDSFailed ThreeDSStatus = "failed"
+	// ThreeDSUnconfirmed means the success URL was hit but the card is not
+	// among the saved cards, or the order reference was not tracked.
+	ThreeDSUnconfirmed ThreeDSStatus = "unconfirmed"
+)
+
+const (
+	// DefaultThreeDSPendingTTL is how long a tracked response waits for its
+	// redirect when ThreeDSCallback.TTL is zero.
+	DefaultThreeDSPendingTTL = 30 * time.Minute
+	// DefaultThreeDSMaxPending caps the tracked responses when
+	// ThreeDSCallback.MaxPending is zero.
+	DefaultThreeDSMaxPending = 10000
+)
+
+// ThreeDSResult is the resolved state of a card after the 3-D Secure redirect.
+type ThreeDSResult struct {
+	Status           ThreeDSStatus `json:"status"`
+	OrderReferenceID string        `json:"order_reference_id"`
+	CardID           string        `json:"card_id,omitempty"`
+	Card             *SavedCard    `json:"card,omitempty"`
+	Message          string        `json:"message,omitempty"`
+	ErrorCode        string        `json:"error_code,omitempty"`
+	Params           url.Values    `json:"-"`
+}
+
+// ThreeDSCallback resolves the redirects to RedirectSuccessUrl and
+// RedirectFailureUrl. Track each TokenizeCard response so the callback knows
+// which card belongs to an order reference, then mount Success and Failure on
+// the redirect URLs.
+//
+// The redirect URLs are public, so only the card ID recorded by Track is
+// trusted: a redirect for an order reference that is not tracked, or whose
+// entry expired, resolves as ThreeDSUnconfirmed. Tracked responses are kept
+// in memory, so run the redirect handlers in the process that tracked them.
+type ThreeDSCallback struct {
+	Cards SavedCardLister
+	// OnResult writes the response for a resolved redirect. When nil the
+	// result is written as JSON.
+	OnResult func(w http.ResponseWriter, r *http.Request, result ThreeDSResult)
+	// TTL is how long a tracked response waits for its redirect. Zero uses
+	// DefaultThreeDSPendingTTL.
+	TTL time.Duration
+	// MaxPending caps the tracked responses; the oldest is dropped when a new
+	// one would exceed it. Zero uses DefaultThreeDSMaxPending.
+	MaxPending int
+	Now        func() time.Time
+
+	mu      sync.Mutex
+	pending map[string]threeDSPending
+}
+
+type threeDSPending struct {
+	cardID    string
+	expiresAt time.Time
+}
+
+// NewThreeDSCallback creates a callback that looks cards up with cards,
+// usually an *API.
+func NewThreeDSCallback(cards SavedCardLister, onResult func(w http.ResponseWriter, r *http.Request, result ThreeDSResult)) *ThreeDSCallback {
+	return &ThreeDSCallback{Cards: cards, OnResult: onResult}
+}
+
+// Track remembers the card ID of a TokenizeCard response until its redirect
+// is resolved or TTL passes.
+func (c *ThreeDSCallback) Track(response CardTokenizeResponse) {
+	if response.OrderReferenceID == "" || response.CardID == "" {
+		return
+	}
+	now := c.now()
+	ttl := c.TTL
+	if ttl <= 0 {
+		ttl = DefaultThreeDSPendingTTL
+	}
+	maxPending := c.MaxPending
+	if maxPending <= 0 {
+		maxPending = DefaultThreeDSMaxPending
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pending == nil {
+		c.pending = make(map[string]threeDSPending)
+	}
+	for reference, entry := range c.pending {
+		if !now.Before(entry.expiresAt) {
+			delete(c.pending, reference)
+		}
+	}
+	if _, ok := c.pending[response.OrderReferenceID]; !ok {
+		for len(c.pending) >= maxPending {
+			c.dropOldestLocked()
+		}
+	}
+	c.pending[response.OrderReferenceID] = threeDSPending{cardID: response.CardID, expiresAt: now.Add(ttl)}
+}
+
+// Pending returns the number of tracked responses waiting for a redirect.
+func (c *ThreeDSCallback) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.pending)
+}
+
+func (c *ThreeDSCallback) dropOldestLocked() {
+	var (
+		oldest    string
+		oldestAt  time.Time
+		haveFirst bool
+	)
+	for reference, entry := range c.pending {
+		if !haveFirst || entry.expiresAt.Before(oldestAt) {
+			oldest, oldestAt, haveFirst = reference, entry.expiresAt, true
+		}
+	}
+	delete(c.pending, oldest)
+}
+
+func (c *ThreeDSCallback) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+// Success returns the handler for RedirectSuccessUrl.
+func (c *ThreeDSCallback) Success() http.Handler {
+	return c.handler(true)
+}
+
+// Failure returns the handler for RedirectFailureUrl.
+func (c *ThreeDSCallback) Failure() http.Handler {
+	return c.handler(false)
+}
+
+func (c *ThreeDSCallback) handler(success bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "invalid redirect parameters", http.StatusBadRequest)
+			return
+		}
+		result, err := c.Resolve(r.Context(), success, r.Form)
+		if err != nil {
+			var validationErr *ValidationError
+			if errors.As(err, &validationErr) {
+				http.Error(w, validationErr.Message, http.StatusBadRequest)
+				return
+			}
+			http.Error(w, "could not resolve card state", http.StatusBadGateway)
+			return
+		}
+
+		if c.OnResult != nil {
+			c.OnResult(w, r, result)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(result)
+	})
+}
+
+// Resolve resolves the card state for redirect parameters. success reports
+// whether the success or the failure URL was hit. The order reference is
+// read from order_reference_id or reference_id, and the card ID only from
+// the response tracked for it; a card_id parameter is ignored.
+func (c *ThreeDSCallback) Resolve(ctx context.Context, success bool, params url.Values) (ThreeDSResult, error) {
+	result := ThreeDSResult{
+		OrderReferenceID: firstParam(params, "order_reference_id", "reference_id"),
+		Message:          params.Get("message"),
+		ErrorCode:        params.Get("error_code"),
+		Params:           params,
+	}
+	if result.OrderReferenceID == "" {
+		return result, threeDSError("order_reference_id is required")
+	}
+
+	c.mu.Lock()
+	if entry, ok := c.pending[result.OrderReferenceID]; ok && c.now().Before(entry.expiresAt) {
+		result.CardID = entry.cardID
+	}
+	c.mu.Unlock()
+
+	if !success {
+		c.forget(result.OrderReferenceID)
+		result.Status = ThreeDSFailed
+		return result, nil
+	}
+
+	result.Status = ThreeDSUnconfirmed
+	if result.CardID == "" {
+		c.forget(result.OrderReferenceID)
+		return result, nil
+	}
+	card, ok, err := FindSavedCard(ctx, c.Cards, result.CardID)
+	if err != nil {
+		// Keep the tracked card so a retried redirect can still resolve it.
+		return result, err
+	}
+	c.forget(result.OrderReferenceID)
+	if ok {
+		result.Status = ThreeDSSucceeded
+		result.Card = &card
+	}
+	return result, nil
+}
+
+func (c *ThreeDSCallback) forget(orderReferenceID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pending, orderReferenceID)
+}
+
+func firstParam(params url.Values, names ...string) string {
+	for _, name := range names {
+		if value := strings.TrimSpace(params.Get(name)); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func threeDSError(message string) error {
+	return &ValidationError{
+		StatusCode: 400,
+		Code:       0,
+		Message:    message,
+	}
+}
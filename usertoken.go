@@ -0,0 +1,278 @@
+package tapsilat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultUserTokenExpire is the lifetime requested for organization user
+	// tokens.
+	DefaultUserTokenExpire = time.Hour
+	// DefaultUserTokenRefreshBefore is how long before expiry a user token is
+	// re-issued.
+	DefaultUserTokenRefreshBefore = 5 * time.Minute
+)
+
+// ErrUserTokenRevoked is returned for users revoked from a UserTokenManager,
+// including by the *API it handed out for them.
+var ErrUserTokenRevoked = errors.New("tapsilat: organization user token revoked")
+
+// UserTokenClient is the subset of *API used to issue organization user
+// tokens.
+type UserTokenClient interface {
+	CreateOrganizationUserToken(ctx context.Context, payload OrgUserTokenCreateRequest) (OrgUserTokenCreateResponse, error)
+}
+
+// UserToken is an issued organization user token.
+type UserToken struct {
+	Email     string    `json:"email"`
+	UserID    string    `json:"user_id,omitempty"`
+	Token     Secret    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// UserTokenManager issues organization user tokens and caches them per
+// email. A token is re-issued RefreshBefore its expiry. Tokens of one email
+// are never issued concurrently: with InvalidateOldTokens, a second issue
+// would invalidate the token the first one just returned.
+type UserTokenManager struct {
+	Client UserTokenClient
+	// EndPoint and HTTPClient are used by the clients returned from API.
+	EndPoint   string
+	HTTPClient *http.Client
+
+	// Expire is the requested token lifetime, rounded up to whole minutes.
+	// Zero uses DefaultUserTokenExpire.
+	Expire time.Duration
+	// RefreshBefore is how long before expiry a token is re-issued. Zero uses
+	// DefaultUserTokenRefreshBefore, capped at half of Expire.
+	RefreshBefore time.Duration
+	// InvalidateOldTokens invalidates the user's earlier tokens on every
+	// issue, including tokens issued outside the manager.
+	InvalidateOldTokens bool
+	Now                 func() time.Time
+
+	mu    sync.Mutex
+	users map[string]*userTokenEntry
+}
+
+type userTokenEntry struct {
+	// issuing is held while a token is issued or revoked for the email.
+	issuing chan struct{}
+	token   UserToken
+	revoked bool
+	api     *API
+}
+
+// NewUserTokenManager creates a manager that issues tokens through api and
+// builds user clients with its endpoint and HTTP client.
+func NewUserTokenManager(api *API) *UserTokenManager {
+	return &UserTokenManager{Client: api, EndPoint: api.EndPoint, HTTPClient: api.client}
+}
+
+// Token returns a valid token for the email, issuing one when none is cached
+// or the cached one is within RefreshBefore of expiry. When re-issuing fails
+// but the cached token has not expired yet, the cached token is returned.
+func (m *UserTokenManager) Token(ctx context.Context, email string) (UserToken, error) {
+	return m.tokenFor(ctx, m.entry(email), email)
+}
+
+func (m *UserTokenManager) tokenFor(ctx context.Context, entry *userTokenEntry, email string) (UserToken, error) {
+	if token, ok := m.fresh(entry); ok {
+		return token, nil
+	}
+
+	select {
+	case entry.issuing <- struct{}{}:
+	case <-ctx.Done():
+		return UserToken{}, ctx.Err()
+	}
+	defer func() { <-entry.issuing }()
+
+	// The token may have been issued while waiting.
+	if token, ok := m.fresh(entry); ok {
+		return token, nil
+	}
+	m.mu.Lock()
+	revoked := entry.revoked
+	m.mu.Unlock()
+	if revoked {
+		return UserToken{}, ErrUserTokenRevoked
+	}
+
+	now := m.now()
+	token, err := m.issue(ctx, email, m.expire(), m.InvalidateOldTokens)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		if entry.token.Token != "" && now.Before(entry.token.ExpiresAt) {
+			return entry.token, nil
+		}
+		return UserToken{}, err
+	}
+	token.ExpiresAt = now.Add(m.expire())
+	entry.token = token
+	if entry.api != nil {
+		entry.api.SetToken(token.Token.Reveal())
+	}
+	return token, nil
+}
+
+// TokenSource returns a source of the user's tokens, for use as
+// API.TokenSource or with ContextWithTokenSource. A token rejected with a
+// 401 is dropped and re-issued. After Revoke, the source fails with
+// ErrUserTokenRevoked.
+func (m *UserTokenManager) TokenSource(email string) RefreshableTokenSource {
+	return &userTokenSource{manager: m, entry: m.entry(email), email: email}
+}
+
+// API returns a client authenticated as the user through TokenSource, so it
+// keeps working across refreshes. After Revoke, its requests fail with
+// ErrUserTokenRevoked.
+func (m *UserTokenManager) API(ctx context.Context, email string) (*API, error) {
+	entry := m.entry(email)
+	token, err := m.tokenFor(ctx, entry, email)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if entry.api == nil {
+		client := m.HTTPClient
+		if client == nil {
+			client = &http.Client{Timeout: 30 * time.Second}
+		}
+		entry.api = NewCustomAPIWithClient(m.EndPoint, token.Token.Reveal(), client)
+		entry.api.TokenSource = &userTokenSource{manager: m, entry: entry, email: email}
+	}
+	return entry.api, nil
+}
+
+// Invalidate drops the cached token of the email so the next use issues a
+// new one. Use it when the API rejects a token that has not expired.
+func (m *UserTokenManager) Invalidate(email string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if entry, ok := m.users[normalizeUserEmail(email)]; ok {
+		entry.token = UserToken{}
+	}
+}
+
+// Revoke invalidates every token of the user on the server by issuing a
+// short-lived replacement with InvalidateOldTokens, which is discarded. The
+// user is then dropped from the manager and the client returned by API stops
+// working; a later Token or API call starts over.
+func (m *UserTokenManager) Revoke(ctx context.Context, email string) error {
+	entry := m.entry(email)
+	select {
+	case entry.issuing <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-entry.issuing }()
+
+	if _, err := m.issue(ctx, email, time.Minute, true); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry.revoked = true
+	entry.token = UserToken{}
+	if m.users[normalizeUserEmail(email)] == entry {
+		delete(m.users, normalizeUserEmail(email))
+	}
+	return nil
+}
+
+func (m *UserTokenManager) issue(ctx context.Context, email string, expire time.Duration, invalidate bool) (UserToken, error) {
+	minutes := (expire + time.Minute - 1) / time.Minute
+	response, err := m.Client.CreateOrganizationUserToken(ctx, OrgUserTokenCreateRequest{
+		Email:               email,
+		Expire:              uint32(minutes),
+		InvalidateOldTokens: invalidate,
+	})
+	if err != nil {
+		return UserToken{}, fmt.Errorf("create token for %s: %w", email, err)
+	}
+	if response.Token == "" {
+		return UserToken{}, fmt.Errorf("create token for %s: response has no token: %s", email, response.Message)
+	}
+	return UserToken{Email: email, UserID: response.UserID, Token: Secret(response.Token)}, nil
+}
+
+func (m *UserTokenManager) entry(email string) *userTokenEntry {
+	key := normalizeUserEmail(email)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.users == nil {
+		m.users = map[string]*userTokenEntry{}
+	}
+	entry, ok := m.users[key]
+	if !ok {
+		entry = &userTokenEntry{issuing: make(chan struct{}, 1)}
+		m.users[key] = entry
+	}
+	return entry
+}
+
+func (m *UserTokenManager) fresh(entry *userTokenEntry) (UserToken, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	token := entry.token
+	if entry.revoked || token.Token == "" {
+		return UserToken{}, false
+	}
+	return token, m.now().Before(token.ExpiresAt.Add(-m.refreshBefore()))
+}
+
+func (m *UserTokenManager) expire() time.Duration {
+	if m.Expire <= 0 {
+		return DefaultUserTokenExpire
+	}
+	return m.Expire
+}
+
+func (m *UserTokenManager) refreshBefore() time.Duration {
+	if m.RefreshBefore > 0 {
+		return m.RefreshBefore
+	}
+	return min(DefaultUserTokenRefreshBefore, m.expire()/2)
+}
+
+func (m *UserTokenManager) now() time.Time {
+	if m.Now != nil {
+		return m.Now()
+	}
+	return time.Now()
+}
+
+func normalizeUserEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+type userTokenSource struct {
+	manager *UserTokenManager
+	entry   *userTokenEntry
+	email   string
+}
+
+func (s *userTokenSource) Token(ctx context.Context) (Secret, error) {
+	token, err := s.manager.tokenFor(ctx, s.entry, s.email)
+	return token.Token, err
+}
+
+func (s *userTokenSource) Refresh(_ context.Context, rejected Secret) error {
+	s.manager.mu.Lock()
+	defer s.manager.mu.Unlock()
+	if s.entry.token.Token == rejected {
+		s.entry.token = UserToken{}
+	}
+	return nil
+}
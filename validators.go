@@ -2,6 +2,7 @@ package tapsilat
 
 import (
 	"fmt"
+	"net/mail"
 	"regexp"
 	"strconv"
 	"strings"
@@ -129,3 +130,193 @@ func ValidateGSMNumber(phone string) (string, error) {
 
 	return cleanPhone, nil
 }
+
+// FieldIssueReason tells whether a field was missing or invalid.
+type FieldIssueReason string
+
+const (
+	FieldMissing FieldIssueReason = "missing"
+	FieldInvalid FieldIssueReason = "invalid"
+	FieldUnknown FieldIssueReason = "unknown"
+)
+
+// FieldIssue is a problem with one field of a request, keyed by its JSON
+// name.
+type FieldIssue struct {
+	Field   string           `json:"field"`
+	Reason  FieldIssueReason `json:"reason"`
+	Message string           `json:"message"`
+}
+
+// FieldValidationError lists every missing or invalid field of a request.
+// errors.As also matches it as a *ValidationError.
+type FieldValidationError struct {
+	Issues []FieldIssue
+}
+
+func (e *FieldValidationError) Error() string {
+	return e.validationError().Error()
+}
+
+func (e *FieldValidationError) Unwrap() error {
+	return e.validationError()
+}
+
+// Fields returns the names of the fields with the given reason.
+func (e *FieldValidationError) Fields(reason FieldIssueReason) []string {
+	var fields []string
+	for _, issue := range e.Issues {
+		if issue.Reason == reason {
+			fields = append(fields, issue.Field)
+		}
+	}
+	return fields
+}
+
+func (e *FieldValidationError) validationError() *ValidationError {
+	messages := make([]string, 0, len(e.Issues))
+	for _, issue := range e.Issues {
+		messages = append(messages, issue.Field+": "+issue.Message)
+	}
+	return &ValidationError{
+		StatusCode: 400,
+		Code:       0,
+		Message:    strings.Join(messages, "; "),
+	}
+}
+
+// ibanLengths holds the IBAN length of countries checked beyond mod-97.
+var ibanLengths = map[string]int{
+	"TR": 26,
+}
+
+// ValidateIBAN validates an IBAN with the mod-97 check and, for Turkish
+// IBANs, the length and the reserved digit. Returns the IBAN upper-cased
+// without spaces.
+func ValidateIBAN(iban string) (string, error) {
+	cleanIBAN := strings.ToUpper(strings.Join(strings.Fields(iban), ""))
+	if len(cleanIBAN) < 15 || len(cleanIBAN) > 34 || !regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[A-Z0-9]+$`).MatchString(cleanIBAN) {
+		return "", &ValidationError{
+			StatusCode: 400,
+			Code:       0,
+			Message:    fmt.Sprintf("Invalid IBAN format: %s", iban),
+		}
+	}
+
+	country := cleanIBAN[:2]
+	if length, ok := ibanLengths[country]; ok && len(cleanIBAN) != length {
+		return "", &ValidationError{
+			StatusCode: 400,
+			Code:       0,
+			Message:    fmt.Sprintf("%s IBAN must be %d characters, got %d", country, length, len(cleanIBAN)),
+		}
+	}
+	if country == "TR" && (!isDigits(cleanIBAN[2:]) || cleanIBAN[9] != '0') {
+		return "", &ValidationError{
+			StatusCode: 400,
+			Code:       0,
+			Message:    fmt.Sprintf("Invalid TR IBAN: %s", iban),
+		}
+	}
+
+	// Move the country code and check digits to the end, map letters to
+	// 10..35 and take the remainder digit by digit.
+	remainder := 0
+	for _, r := range cleanIBAN[4:] + cleanIBAN[:4] {
+		value := int(r - '0')
+		if r >= 'A' && r <= 'Z' {
+			value = int(r-'A') + 10
+			remainder = (remainder*100 + value) % 97
+			continue
+		}
+		remainder = (remainder*10 + value) % 97
+	}
+	if remainder != 1 {
+		return "", &ValidationError{
+			StatusCode: 400,
+			Code:       0,
+			Message:    fmt.Sprintf("IBAN check digits are invalid: %s", iban),
+		}
+	}
+	return cleanIBAN, nil
+}
+
+// ValidateTCKN validates a Turkish identity number (T.C. Kimlik No): eleven
+// digits, not starting with zero, with the two trailing check digits.
+func ValidateTCKN(identityNumber string) (string, error) {
+	id := strings.TrimSpace(identityNumber)
+	if len(id) != 11 || !isDigits(id) || id[0] == '0' {
+		return "", &ValidationError{
+			StatusCode: 400,
+			Code:       0,
+			Message:    fmt.Sprintf("Identity number must be 11 digits not starting with 0: %s", identityNumber),
+		}
+	}
+
+	var odd, even, total int
+	for i := 0; i < 9; i++ {
+		digit := int(id[i] - '0')
+		if i%2 == 0 {
+			odd += digit
+		} else {
+			even += digit
+		}
+	}
+	tenth := ((odd*7-even)%10 + 10) % 10
+	for i := 0; i < 10; i++ {
+		total += int(id[i] - '0')
+	}
+	if int(id[9]-'0') != tenth || int(id[10]-'0') != total%10 {
+		return "", &ValidationError{
+			StatusCode: 400,
+			Code:       0,
+			Message:    fmt.Sprintf("Identity number check digits are invalid: %s", identityNumber),
+		}
+	}
+	return id, nil
+}
+
+// ValidateVKN validates a Turkish tax number (Vergi Kimlik No): ten digits
+// with a trailing check digit.
+func ValidateVKN(taxNumber string) (string, error) {
+	vkn := strings.TrimSpace(taxNumber)
+	if len(vkn) != 10 || !isDigits(vkn) {
+		return "", &ValidationError{
+			StatusCode: 400,
+			Code:       0,
+			Message:    fmt.Sprintf("Tax number must be 10 digits: %s", taxNumber),
+		}
+	}
+
+	sum := 0
+	for i := 0; i < 9; i++ {
+		shifted := (int(vkn[i]-'0') + 9 - i) % 10
+		value := (shifted << (9 - i)) % 9
+		if shifted != 0 && value == 0 {
+			value = 9
+		}
+		sum += value
+	}
+	if (10-sum%10)%10 != int(vkn[9]-'0') {
+		return "", &ValidationError{
+			StatusCode: 400,
+			Code:       0,
+			Message:    fmt.Sprintf("Tax number check digit is invalid: %s", taxNumber),
+		}
+	}
+	return vkn, nil
+}
+
+// ValidateEmail validates an email address. Returns it trimmed.
+func ValidateEmail(email string) (string, error) {
+	cleanEmail := strings.TrimSpace(email)
+	address, err := mail.ParseAddress(cleanEmail)
+	if err != nil || address.Address != cleanEmail || !strings.Contains(cleanEmail[strings.LastIndex(cleanEmail, "@"):], ".") {
+		return "", &ValidationError{
+			StatusCode: 400,
+			Code:       0,
+			Message:    fmt.Sprintf("Invalid email address: %s", email),
+		}
+	}
+	return cleanEmail, nil
+}
@@ -0,0 +1,303 @@
+package tapsilat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+)
+
+// vposCredentialFields are the VposCreateRequest fields that hold acquirer
+// credentials. Acquirer templates say which of them an acquirer uses.
+var vposCredentialFields = []string{
+	"merchant", "merchant_code", "merchant_key", "auth_key", "terminal", "company", "username",
+	"password", "store_key", "api_key", "api_secret", "guid", "pid", "client_id", "client_code",
+}
+
+var vposCommonFields = []string{"name", "acquirer_id", "currencies"}
+
+// vposStringField returns the string field of payload with the given JSON
+// name.
+func vposStringField(payload *VposCreateRequest, name string) (*string, bool) {
+	fields := map[string]*string{
+		"name":          &payload.Name,
+		"bank_name":     &payload.BankName,
+		"env_mode":      &payload.EnvMode,
+		"merchant":      &payload.Merchant,
+		"merchant_code": &payload.MerchantCode,
+		"merchant_key":  (*string)(&payload.MerchantKey),
+		"auth_key":      (*string)(&payload.AuthKey),
+		"terminal":      &payload.Terminal,
+		"company":       &payload.Company,
+		"username":      &payload.Username,
+		"password":      (*string)(&payload.Password),
+		"store_key":     (*string)(&payload.StoreKey),
+		"api_key":       (*string)(&payload.ApiKey),
+		"api_secret":    (*string)(&payload.ApiSecret),
+		"guid":          &payload.GUID,
+		"pid":           &payload.PID,
+		"client_id":     &payload.ClientID,
+		"client_code":   &payload.ClientCode,
+		"type":          &payload.Type,
+		"payment_mode":  &payload.PaymentMode,
+		"prefix":        &payload.Prefix,
+		"acquirer_id":   &payload.AcquirerID,
+	}
+	field, ok := fields[name]
+	return field, ok
+}
+
+func vposFieldValue(payload VposCreateRequest, name string) string {
+	switch name {
+	case "card_schemes":
+		return strings.Join(payload.CardSchemes, ",")
+	case "currencies":
+		return strings.Join(payload.Currencies, ",")
+	}
+	if field, ok := vposStringField(&payload, name); ok {
+		return *field
+	}
+	return ""
+}
+
+// ApplyVposDefaults fills in the template defaults for the fields payload
+// leaves empty: the payment mode and credential defaults such as env_mode.
+// AcquirerID is set to the template's acquirer. The Main, Marketplace and
+// ForceThreeD flags are left as they are, since false cannot be told apart
+// from unset; start from NewVposRequest to get the template's flags.
+func ApplyVposDefaults(template VposAcquirerTemplate, payload VposCreateRequest) VposCreateRequest {
+	if payload.AcquirerID == "" {
+		payload.AcquirerID = template.AcquirerID
+	}
+	if payload.PaymentMode == "" {
+		payload.PaymentMode = template.Defaults.PaymentMode
+	}
+	for name, value := range template.Defaults.Credentials {
+		// Defaults for fields this SDK does not know are left to the API.
+		if field, ok := vposStringField(&payload, name); ok && *field == "" {
+			*field = value
+		}
+	}
+	return payload
+}
+
+// NewVposRequest returns a new VPOS configuration holding the template
+// defaults, including its Main, Marketplace and ForceThreeD flags. Set or
+// clear fields on the result afterwards.
+func NewVposRequest(template VposAcquirerTemplate) VposCreateRequest {
+	return ApplyVposDefaults(template, VposCreateRequest{
+		Main:        template.Defaults.Main,
+		Marketplace: template.Defaults.Marketplace,
+		ForceThreeD: template.Defaults.ForceThreeD,
+	})
+}
+
+// ValidateVpos checks a VPOS configuration against its acquirer template
+// without calling the API. It reports the template's required fields and
+// Name, AcquirerID and Currencies as missing, an AcquirerID of another
+// acquirer as invalid, and credentials the template neither requires nor
+// lists as optional as unknown. It returns nil or a *FieldValidationError.
+// Card schemes and currencies are only resolved by (*VposBuilder).Build.
+func ValidateVpos(template VposAcquirerTemplate, payload VposCreateRequest) error {
+	var issues []FieldIssue
+	for _, field := range vposCommonFields {
+		if slices.Contains(template.RequiredFields, field) {
+			continue
+		}
+		if strings.TrimSpace(vposFieldValue(payload, field)) == "" {
+			issues = append(issues, FieldIssue{Field: field, Reason: FieldMissing, Message: "is required"})
+		}
+	}
+	for _, field := range template.RequiredFields {
+		if strings.TrimSpace(vposFieldValue(payload, field)) == "" {
+			issues = append(issues, FieldIssue{Field: field, Reason: FieldMissing, Message: "is required by " + templateName(template)})
+		}
+	}
+
+	if payload.AcquirerID != "" && template.AcquirerID != "" && payload.AcquirerID != template.AcquirerID {
+		issues = append(issues, FieldIssue{Field: "acquirer_id", Reason: FieldInvalid, Message: "does not match the template acquirer " + template.AcquirerID})
+	}
+
+	// Templates without field lists do not say which credentials are unused.
+	if len(template.RequiredFields) > 0 || len(template.OptionalFields) > 0 {
+		for _, field := range vposCredentialFields {
+			if vposFieldValue(payload, field) == "" ||
+				slices.Contains(template.RequiredFields, field) ||
+				slices.Contains(template.OptionalFields, field) {
+				continue
+			}
+			issues = append(issues, FieldIssue{Field: field, Reason: FieldUnknown, Message: "is not used by " + templateName(template)})
+		}
+	}
+
+	if len(issues) > 0 {
+		return &FieldValidationError{Issues: issues}
+	}
+	return nil
+}
+
+func templateName(template VposAcquirerTemplate) string {
+	if template.Name != "" {
+		return template.Name
+	}
+	return "acquirer " + template.AcquirerID
+}
+
+// VposBuilder assembles a VposCreateRequest for one acquirer from its
+// template, so that missing or misspelled credentials are reported before
+// CreateVpos or PatchVpos is sent. Request starts out with the template
+// defaults and can be edited directly, including turning off flags the
+// template turns on; Set and SetFields take fields by JSON name, as they
+// appear in configuration files.
+type VposBuilder struct {
+	Template VposAcquirerTemplate
+	Request  VposCreateRequest
+
+	api      *API
+	defaults VposCreateRequest
+	unknown  []string
+}
+
+// NewVposBuilder loads the template for an acquirer ID, name or prefix with
+// GetVposAcquirerTemplate and starts a request from its defaults.
+func (t *API) NewVposBuilder(ctx context.Context, acquirerRef string) (*VposBuilder, error) {
+	template, err := t.GetVposAcquirerTemplate(ctx, acquirerRef)
+	if err != nil {
+		return nil, err
+	}
+	defaults := NewVposRequest(template)
+	return &VposBuilder{
+		Template: template,
+		Request:  defaults,
+		api:      t,
+		defaults: defaults,
+	}, nil
+}
+
+// Set sets a string field by its JSON name. Names VposCreateRequest does not
+// have are reported as unknown fields by Validate and Build.
+func (b *VposBuilder) Set(name, value string) *VposBuilder {
+	name = strings.ToLower(strings.TrimSpace(name))
+	field, ok := vposStringField(&b.Request, name)
+	if !ok {
+		if !slices.Contains(b.unknown, name) {
+			b.unknown = append(b.unknown, name)
+		}
+		return b
+	}
+	*field = strings.TrimSpace(value)
+	return b
+}
+
+// SetFields calls Set for each entry of fields, in name order.
+func (b *VposBuilder) SetFields(fields map[string]string) *VposBuilder {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		b.Set(name, fields[name])
+	}
+	return b
+}
+
+// Validate checks the request with ValidateVpos and adds the unknown field
+// names passed to Set. It does not call the API.
+func (b *VposBuilder) Validate() error {
+	var issues []FieldIssue
+	for _, name := range b.unknown {
+		issues = append(issues, FieldIssue{Field: name, Reason: FieldUnknown, Message: "is not a VPOS field"})
+	}
+	var fieldErr *FieldValidationError
+	if err := ValidateVpos(b.Template, b.Request); errors.As(err, &fieldErr) {
+		issues = append(issues, fieldErr.Issues...)
+	}
+	if len(issues) > 0 {
+		return &FieldValidationError{Issues: issues}
+	}
+	return nil
+}
+
+// Build validates the request and resolves its card schemes and currencies
+// from IDs, names or codes. Unresolved references are reported as invalid
+// fields alongside the Validate issues, in one *FieldValidationError.
+func (b *VposBuilder) Build(ctx context.Context) (VposCreateRequest, error) {
+	payload := b.Request
+	var fieldErr *FieldValidationError
+	if err := b.Validate(); err != nil && !errors.As(err, &fieldErr) {
+		return payload, err
+	}
+	if fieldErr == nil {
+		fieldErr = &FieldValidationError{}
+	}
+
+	resolve := func(field string, refs []string, resolveIDs func(context.Context, []string) ([]string, error)) ([]string, error) {
+		if len(refs) == 0 {
+			return refs, nil
+		}
+		ids, err := resolveIDs(ctx, refs)
+		var validationErr *ValidationError
+		if errors.As(err, &validationErr) {
+			fieldErr.Issues = append(fieldErr.Issues, FieldIssue{Field: field, Reason: FieldInvalid, Message: validationErr.Message})
+			return refs, nil
+		}
+		return ids, err
+	}
+	var err error
+	if payload.CardSchemes, err = resolve("card_schemes", payload.CardSchemes, b.api.ResolveCardSchemeIDs); err != nil {
+		return payload, err
+	}
+	if payload.Currencies, err = resolve("currencies", payload.Currencies, b.api.normalizeCurrencyIDs); err != nil {
+		return payload, err
+	}
+
+	if len(fieldErr.Issues) > 0 {
+		return payload, fieldErr
+	}
+	return payload, nil
+}
+
+// Create builds the request and creates the VPOS. Nothing is sent when the
+// request does not build.
+func (b *VposBuilder) Create(ctx context.Context) (VposMutationResponse, error) {
+	payload, err := b.Build(ctx)
+	if err != nil {
+		return VposMutationResponse{}, err
+	}
+	return b.api.CreateVpos(ctx, payload)
+}
+
+// Update builds the request and sends the fields the caller set to
+// PatchVpos for the VPOS with the given ID. The whole configuration is
+// validated, but fields still holding their template default are not sent,
+// so the stored VPOS keeps its own flags, payment mode and credentials.
+// Nothing is sent when no field was set.
+func (b *VposBuilder) Update(ctx context.Context, id string) (VposMutationResponse, error) {
+	payload, err := b.Build(ctx)
+	if err != nil {
+		return VposMutationResponse{}, err
+	}
+	before, err := jsonObject(b.defaults)
+	if err != nil {
+		return VposMutationResponse{}, err
+	}
+	after, err := jsonObject(payload)
+	if err != nil {
+		return VposMutationResponse{}, err
+	}
+	changes := diffJSONObjects(before, after)
+	if len(changes) == 0 {
+		return VposMutationResponse{}, nil
+	}
+	body, err := json.Marshal(changes)
+	if err != nil {
+		return VposMutationResponse{}, err
+	}
+	var patch VposPatch
+	if err := json.Unmarshal(body, &patch); err != nil {
+		return VposMutationResponse{}, err
+	}
+	return b.api.PatchVpos(ctx, id, patch)
+}
@@ -0,0 +1,116 @@
+package tapsilat
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// CachedVposAcquirers returns the VPOS acquirers from the reference cache.
+func (t *API) CachedVposAcquirers(ctx context.Context) ([]VposAcquirer, error) {
+	response, err := t.references().acquirers.Get(ctx)
+	return response.Items, err
+}
+
+// CachedCardSchemes returns the card schemes from the reference cache.
+func (t *API) CachedCardSchemes(ctx context.Context) ([]CardScheme, error) {
+	response, err := t.references().cardSchemes.Get(ctx)
+	return response.Items, err
+}
+
+// CachedVposAcquirerTemplates returns the acquirer templates from the reference cache.
+func (t *API) CachedVposAcquirerTemplates(ctx context.Context) ([]VposAcquirerTemplate, error) {
+	response, err := t.references().acquirerTemplates.Get(ctx)
+	return response.Items, err
+}
+
+// ResolveAcquirerID resolves an acquirer ID, name or prefix to the acquirer ID.
+func (t *API) ResolveAcquirerID(ctx context.Context, ref string) (string, error) {
+	trimmedRef := strings.TrimSpace(ref)
+	if trimmedRef == "" {
+		return "", &ValidationError{
+			StatusCode: 400,
+			Code:       0,
+			Message:    "acquirer_id is required",
+		}
+	}
+
+	acquirers, err := t.CachedVposAcquirers(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, acquirer := range acquirers {
+		if acquirer.ID == trimmedRef ||
+			strings.EqualFold(acquirer.Name, trimmedRef) ||
+			(acquirer.Prefix != "" && strings.EqualFold(acquirer.Prefix, trimmedRef)) {
+			return acquirer.ID, nil
+		}
+	}
+
+	return "", &ValidationError{
+		StatusCode: 400,
+		Code:       0,
+		Message:    fmt.Sprintf("unknown acquirer reference %q; use an acquirer ID, name or prefix", ref),
+	}
+}
+
+// ResolveCardSchemeIDs resolves card scheme IDs or names to card scheme IDs,
+// dropping duplicates.
+func (t *API) ResolveCardSchemeIDs(ctx context.Context, refs []string) ([]string, error) {
+	schemes, err := t.CachedCardSchemes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := make([]string, 0, len(refs))
+	seen := make(map[string]struct{}, len(refs))
+	for _, ref := range refs {
+		trimmedRef := strings.TrimSpace(ref)
+		id := ""
+		for _, scheme := range schemes {
+			if scheme.ID == trimmedRef || strings.EqualFold(scheme.Name, trimmedRef) {
+				id = scheme.ID
+				break
+			}
+		}
+		if id == "" {
+			return nil, &ValidationError{
+				StatusCode: 400,
+				Code:       0,
+				Message:    fmt.Sprintf("unknown card scheme reference %q; use a card scheme ID or name", ref),
+			}
+		}
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		resolved = append(resolved, id)
+	}
+
+	return resolved, nil
+}
+
+// GetVposAcquirerTemplate returns the cached template for an acquirer ID,
+// name or prefix.
+func (t *API) GetVposAcquirerTemplate(ctx context.Context, acquirerRef string) (VposAcquirerTemplate, error) {
+	acquirerID, err := t.ResolveAcquirerID(ctx, acquirerRef)
+	if err != nil {
+		return VposAcquirerTemplate{}, err
+	}
+
+	templates, err := t.CachedVposAcquirerTemplates(ctx)
+	if err != nil {
+		return VposAcquirerTemplate{}, err
+	}
+	for _, template := range templates {
+		if template.AcquirerID == acquirerID {
+			return template, nil
+		}
+	}
+
+	return VposAcquirerTemplate{}, &ValidationError{
+		StatusCode: 400,
+		Code:       0,
+		Message:    fmt.Sprintf("no VPOS template found for acquirer %q", acquirerRef),
+	}
+}
@@ -0,0 +1,307 @@
+package vposroute
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tapsilat/tapsilat-go"
+)
+
+// Order describes the payment to route.
+type Order struct {
+	// Currency is a currency ID or code such as "TRY".
+	Currency string
+	// CardNumber is the card BIN or full number; only its leading digits are
+	// used. CardScheme, a card scheme ID or name, is used when it is empty.
+	CardNumber string
+	CardScheme string
+	// Marketplace orders are only routed to marketplace VPOS, and other
+	// orders only to the others.
+	Marketplace bool
+	// SuborganizationID restricts routing to the VPOS listed for the
+	// suborganization. It must have been loaded into the snapshot.
+	SuborganizationID string
+}
+
+// Rule names a routing check.
+type Rule string
+
+const (
+	RuleBlocked         Rule = "blocked"
+	RuleSuborganization Rule = "suborganization"
+	RuleCurrency        Rule = "currency"
+	RuleCardScheme      Rule = "card_scheme"
+	RuleMarketplace     Rule = "marketplace"
+	// RuleThreeD and RulePf report the VPOS ForceThreeD and Pf flags. They
+	// always pass: the platform does not document how either flag affects
+	// routing, so the simulator shows them without excluding on them.
+	RuleThreeD Rule = "three_d"
+	RulePf     Rule = "pf"
+)
+
+// Check is the outcome of one rule for one VPOS.
+type Check struct {
+	Rule   Rule   `json:"rule"`
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail"`
+}
+
+// Candidate is a VPOS with the checks that include or exclude it. Rank is its
+// 1-based position among the eligible VPOS, or 0 when it is excluded.
+type Candidate struct {
+	Vpos     tapsilat.Vpos `json:"vpos"`
+	Eligible bool          `json:"eligible"`
+	Rank     int           `json:"rank,omitempty"`
+	Checks   []Check       `json:"checks"`
+}
+
+// Failed returns the checks that excluded the candidate.
+func (c Candidate) Failed() []Check {
+	var failed []Check
+	for _, check := range c.Checks {
+		if !check.Passed {
+			failed = append(failed, check)
+		}
+	}
+	return failed
+}
+
+// Result lists every VPOS of the snapshot: the eligible ones in routing
+// order, followed by the excluded ones.
+type Result struct {
+	Order      Order       `json:"order"`
+	Candidates []Candidate `json:"candidates"`
+}
+
+// Selected returns the VPOS the order is expected to use.
+func (r *Result) Selected() (Candidate, bool) {
+	if len(r.Candidates) == 0 || !r.Candidates[0].Eligible {
+		return Candidate{}, false
+	}
+	return r.Candidates[0], true
+}
+
+// Simulate routes an order against the snapshot without calling the API.
+// A VPOS is excluded when it is blocked at now, is not listed for the
+// order's suborganization, does not accept the currency or card scheme, or
+// differs from the order in its Marketplace flag. A VPOS without card
+// schemes accepts every scheme, and the scheme check is skipped when the
+// order has no card. The ForceThreeD and Pf flags are reported on every
+// candidate but exclude none. Eligible VPOS are ranked Main first, then by
+// higher Priority, then by name.
+//
+// This mirrors the documented routing inputs; the platform may apply rules
+// of its own, such as acquirer availability, on top.
+func (s *Snapshot) Simulate(order Order, now time.Time) (*Result, error) {
+	currency, ok := s.currency(order.Currency)
+	if !ok {
+		return nil, fmt.Errorf("vposroute: unknown currency %q", order.Currency)
+	}
+	var scope []string
+	if order.SuborganizationID != "" {
+		if scope, ok = s.Suborganizations[order.SuborganizationID]; !ok {
+			return nil, fmt.Errorf("vposroute: suborganization %s is not in the snapshot", order.SuborganizationID)
+		}
+	}
+	card, err := s.card(order)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Order: order, Candidates: make([]Candidate, 0, len(s.Vpos))}
+	for _, vpos := range s.Vpos {
+		candidate := Candidate{Vpos: vpos, Eligible: true}
+		check := func(rule Rule, passed bool, detail string) {
+			candidate.Checks = append(candidate.Checks, Check{Rule: rule, Passed: passed, Detail: detail})
+			candidate.Eligible = candidate.Eligible && passed
+		}
+
+		if until, blocked := blockedUntil(vpos, now); blocked {
+			check(RuleBlocked, false, "blocked until "+until.UTC().Format(time.RFC3339))
+		} else {
+			check(RuleBlocked, true, "not blocked")
+		}
+
+		if order.SuborganizationID != "" {
+			if slices.Contains(scope, vpos.ID) {
+				check(RuleSuborganization, true, "listed for suborganization "+order.SuborganizationID)
+			} else {
+				check(RuleSuborganization, false, "not listed for suborganization "+order.SuborganizationID)
+			}
+		}
+
+		if slices.Contains(vpos.Currencies, currency.ID) {
+			check(RuleCurrency, true, "accepts "+currencyName(currency))
+		} else {
+			check(RuleCurrency, false, "does not accept "+currencyName(currency))
+		}
+
+		switch {
+		case card.brand == tapsilat.CardBrandUnknown && card.schemeID == "":
+			check(RuleCardScheme, true, "no card given")
+		case len(vpos.CardSchemes) == 0:
+			check(RuleCardScheme, true, "accepts every card scheme")
+		case s.acceptsCard(vpos, card):
+			check(RuleCardScheme, true, "accepts "+card.name)
+		default:
+			check(RuleCardScheme, false, "does not accept "+card.name)
+		}
+
+		switch {
+		case order.Marketplace && vpos.Marketplace:
+			check(RuleMarketplace, true, "marketplace VPOS")
+		case order.Marketplace:
+			check(RuleMarketplace, false, "not a marketplace VPOS")
+		case vpos.Marketplace:
+			check(RuleMarketplace, false, "marketplace-only VPOS")
+		default:
+			check(RuleMarketplace, true, "not a marketplace VPOS")
+		}
+
+		if vpos.ForceThreeD {
+			check(RuleThreeD, true, "forces 3D Secure")
+		} else {
+			check(RuleThreeD, true, "does not force 3D Secure")
+		}
+		if vpos.Pf {
+			check(RulePf, true, "payment facilitator VPOS")
+		} else {
+			check(RulePf, true, "not a payment facilitator VPOS")
+		}
+
+		result.Candidates = append(result.Candidates, candidate)
+	}
+
+	slices.SortStableFunc(result.Candidates, func(a, b Candidate) int {
+		if a.Eligible != b.Eligible {
+			if a.Eligible {
+				return -1
+			}
+			return 1
+		}
+		if !a.Eligible {
+			return 0
+		}
+		if a.Vpos.Main != b.Vpos.Main {
+			if a.Vpos.Main {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Or(
+			cmp.Compare(b.Vpos.Priority, a.Vpos.Priority),
+			cmp.Compare(a.Vpos.Name, b.Vpos.Name),
+			cmp.Compare(a.Vpos.ID, b.Vpos.ID),
+		)
+	})
+	for i := range result.Candidates {
+		if result.Candidates[i].Eligible {
+			result.Candidates[i].Rank = i + 1
+		}
+	}
+	return result, nil
+}
+
+// WriteExplain writes one line per candidate with its rank or exclusion and
+// the checks behind it.
+func (r *Result) WriteExplain(w io.Writer) error {
+	for _, candidate := range r.Candidates {
+		status := fmt.Sprintf("#%d", candidate.Rank)
+		checks := candidate.Checks
+		if !candidate.Eligible {
+			status = "excluded"
+			checks = candidate.Failed()
+		}
+		details := make([]string, 0, len(checks))
+		for _, check := range checks {
+			details = append(details, fmt.Sprintf("%s: %s", check.Rule, check.Detail))
+		}
+		line := fmt.Sprintf("%s %s (%s, main=%t, priority=%d): %s",
+			status, candidate.Vpos.Name, candidate.Vpos.ID, candidate.Vpos.Main, candidate.Vpos.Priority, strings.Join(details, "; "))
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blockedUntil reports whether the VPOS is blocked at now. BlockDate is a
+// Unix time in seconds; values of 1e12 and above are taken as milliseconds.
+func blockedUntil(vpos tapsilat.Vpos, now time.Time) (time.Time, bool) {
+	if vpos.BlockDate == 0 {
+		return time.Time{}, false
+	}
+	until := time.Unix(int64(vpos.BlockDate), 0)
+	if vpos.BlockDate >= 1e12 {
+		until = time.UnixMilli(int64(vpos.BlockDate))
+	}
+	return until, now.Before(until)
+}
+
+func (s *Snapshot) currency(ref string) (tapsilat.OrganizationCurrency, bool) {
+	ref = strings.TrimSpace(ref)
+	for _, currency := range s.Currencies {
+		if currency.ID == ref || strings.EqualFold(currency.CurrencyUnit, ref) || (currency.Code != "" && currency.Code == ref) {
+			return currency, true
+		}
+	}
+	return tapsilat.OrganizationCurrency{}, false
+}
+
+func currencyName(currency tapsilat.OrganizationCurrency) string {
+	if currency.CurrencyUnit != "" {
+		return currency.CurrencyUnit
+	}
+	return currency.ID
+}
+
+type orderCard struct {
+	brand    tapsilat.CardBrand
+	schemeID string
+	name     string
+}
+
+func (s *Snapshot) card(order Order) (orderCard, error) {
+	if number := tapsilat.NormalizeCardNumber(strings.TrimSpace(order.CardNumber)); number != "" {
+		brand := tapsilat.DetectCardBrand(number)
+		if brand == tapsilat.CardBrandUnknown {
+			return orderCard{}, fmt.Errorf("vposroute: unknown card brand for BIN %.6s", number)
+		}
+		return orderCard{brand: brand, name: string(brand)}, nil
+	}
+	ref := strings.TrimSpace(order.CardScheme)
+	if ref == "" {
+		return orderCard{}, nil
+	}
+	for _, scheme := range s.CardSchemes {
+		if scheme.ID == ref || strings.EqualFold(scheme.Name, ref) {
+			return orderCard{schemeID: scheme.ID, name: scheme.Name}, nil
+		}
+	}
+	return orderCard{}, fmt.Errorf("vposroute: unknown card scheme %q", order.CardScheme)
+}
+
+// acceptsCard matches the VPOS card schemes, given as IDs or names, against
+// the order's scheme ID or card brand.
+func (s *Snapshot) acceptsCard(vpos tapsilat.Vpos, card orderCard) bool {
+	for _, ref := range vpos.CardSchemes {
+		name := ref
+		for _, scheme := range s.CardSchemes {
+			if scheme.ID == ref {
+				name = scheme.Name
+				break
+			}
+		}
+		if card.schemeID != "" && (ref == card.schemeID || strings.EqualFold(name, card.name)) {
+			return true
+		}
+		if card.brand != tapsilat.CardBrandUnknown && card.brand.MatchesScheme(name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+// Package vposroute predicts which virtual POS an order will be routed to,
+// from a snapshot of the organization's VPOS configuration.
+package vposroute
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tapsilat/tapsilat-go"
+	"github.com/tapsilat/tapsilat-go/internal/parallel"
+)
+
+// Client is the subset of *tapsilat.API used to load a snapshot.
+type Client interface {
+	ListVposWithFilter(ctx context.Context, page, perPage int, filter tapsilat.VposListFilter) (tapsilat.VposListResponse, error)
+	GetVpos(ctx context.Context, id string) (tapsilat.Vpos, error)
+	CachedCardSchemes(ctx context.Context) ([]tapsilat.CardScheme, error)
+	GetOrganizationCurrencies(ctx context.Context) (tapsilat.OrganizationCurrenciesResponse, error)
+}
+
+// Snapshot is the VPOS configuration an order is routed against. It can be
+// loaded with a Loader or built by hand, and is not changed by Simulate.
+type Snapshot struct {
+	Vpos        []tapsilat.Vpos                 `json:"vpos"`
+	CardSchemes []tapsilat.CardScheme           `json:"card_schemes"`
+	Currencies  []tapsilat.OrganizationCurrency `json:"currencies"`
+	// Suborganizations maps a suborganization ID to the IDs of the VPOS
+	// listed for it by ListVposWithFilter.
+	Suborganizations map[string][]string `json:"suborganizations,omitempty"`
+	LoadedAt         time.Time           `json:"loaded_at"`
+}
+
+// Loader loads snapshots from the API.
+type Loader struct {
+	Client Client
+
+	// Workers bounds the number of concurrent API calls.
+	Workers int
+	// PerPage is the page size used for ListVposWithFilter.
+	PerPage int
+}
+
+// New creates a Loader with default settings.
+func New(client Client) *Loader {
+	return &Loader{Client: client, Workers: 4, PerPage: 100}
+}
+
+// Load lists every VPOS, loads its details with GetVpos, and records which
+// VPOS are listed for each of the given suborganizations. Orders for other
+// suborganizations cannot be simulated.
+func (l *Loader) Load(ctx context.Context, suborganizationIDs ...string) (*Snapshot, error) {
+	snapshot := &Snapshot{Suborganizations: map[string][]string{}, LoadedAt: time.Now()}
+
+	schemes, err := l.Client.CachedCardSchemes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("vposroute: load card schemes: %w", err)
+	}
+	snapshot.CardSchemes = schemes
+	currencies, err := l.Client.GetOrganizationCurrencies(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("vposroute: load currencies: %w", err)
+	}
+	snapshot.Currencies = currencies.Currencies
+
+	items, err := l.list(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	snapshot.Vpos = make([]tapsilat.Vpos, len(items))
+	err = parallel.Each(ctx, l.workers(), len(items), func(ctx context.Context, i int) error {
+		vpos, err := l.Client.GetVpos(ctx, items[i].ID)
+		if err != nil {
+			return fmt.Errorf("vposroute: get vpos %s: %w", items[i].ID, err)
+		}
+		snapshot.Vpos[i] = vpos
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := make([][]string, len(suborganizationIDs))
+	err = parallel.Each(ctx, l.workers(), len(suborganizationIDs), func(ctx context.Context, i int) error {
+		items, err := l.list(ctx, suborganizationIDs[i])
+		if err != nil {
+			return err
+		}
+		scopes[i] = make([]string, 0, len(items))
+		for _, item := range items {
+			scopes[i] = append(scopes[i], item.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, id := range suborganizationIDs {
+		snapshot.Suborganizations[id] = scopes[i]
+	}
+	return snapshot, nil
+}
+
+func (l *Loader) list(ctx context.Context, suborganizationID string) ([]tapsilat.VposListItem, error) {
+	filter := tapsilat.VposListFilter{SuborganizationID: suborganizationID}
+	var items []tapsilat.VposListItem
+	for page := 1; ; page++ {
+		res, err := l.Client.ListVposWithFilter(ctx, page, l.perPage(), filter)
+		if err != nil {
+			if suborganizationID != "" {
+				return nil, fmt.Errorf("vposroute: list vpos for suborganization %s page %d: %w", suborganizationID, page, err)
+			}
+			return nil, fmt.Errorf("vposroute: list vpos page %d: %w", page, err)
+		}
+		items = append(items, res.Rows...)
+		if int64(page) >= res.TotalPages || len(res.Rows) == 0 {
+			return items, nil
+		}
+	}
+}
+
+func (l *Loader) workers() int {
+	if l.Workers <= 0 {
+		return 1
+	}
+	return l.Workers
+}
+
+func (l *Loader) perPage() int {
+	if l.PerPage <= 0 {
+		return 100
+	}
+	return l.PerPage
+}
@@ -0,0 +1,369 @@
+package tapsilat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultExpiringWithin is how close to its expiry a card is marked as
+// expiring when Wallet.ExpiringWithin is zero.
+const DefaultExpiringWithin = 60 * 24 * time.Hour
+
+// WalletClient is the part of *API used by Wallet.
+type WalletClient interface {
+	SavedCardLister
+	DeleteSavedCard(ctx context.Context, id string) (DeleteSavedCardResponse, error)
+}
+
+// ErrSavedCardOwnerUnknown is returned by the Wallet methods when
+// ListSavedCards returns cards but none of them carries a user_id or
+// user_email, so the cards of one customer cannot be told apart.
+var ErrSavedCardOwnerUnknown = errors.New("tapsilat: saved cards carry no user_id or user_email, wallet owners cannot be matched")
+
+// WalletOwner identifies the customer a wallet belongs to. Cards match when
+// their user ID equals UserID or their email equals Email, ignoring case.
+type WalletOwner struct {
+	UserID string
+	Email  string
+}
+
+func (o WalletOwner) key() string {
+	if o.UserID != "" {
+		return "id:" + o.UserID
+	}
+	return "email:" + strings.ToLower(strings.TrimSpace(o.Email))
+}
+
+func (o WalletOwner) owns(card SavedCard) bool {
+	if o.UserID != "" && card.UserID == o.UserID {
+		return true
+	}
+	return o.Email != "" && strings.EqualFold(strings.TrimSpace(card.UserEmail), strings.TrimSpace(o.Email))
+}
+
+// DefaultCardStore keeps the default card chosen for a wallet owner after
+// tokenization. The API only sets is_default when a card is saved and has no
+// call to change it, so a default chosen later exists only in this store and
+// is never sent to the server.
+type DefaultCardStore interface {
+	DefaultCard(ctx context.Context, owner string) (string, bool, error)
+	SetDefaultCard(ctx context.Context, owner, cardID string) error
+}
+
+// MemoryDefaultCardStore is an in-memory DefaultCardStore.
+type MemoryDefaultCardStore struct {
+	mu       sync.Mutex
+	defaults map[string]string
+}
+
+// DefaultCard returns the default card ID stored for owner.
+func (s *MemoryDefaultCardStore) DefaultCard(ctx context.Context, owner string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cardID, ok := s.defaults[owner]
+	return cardID, ok, nil
+}
+
+// SetDefaultCard stores cardID as the default card of owner. An empty cardID
+// clears it.
+func (s *MemoryDefaultCardStore) SetDefaultCard(ctx context.Context, owner, cardID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cardID == "" {
+		delete(s.defaults, owner)
+		return nil
+	}
+	if s.defaults == nil {
+		s.defaults = make(map[string]string)
+	}
+	s.defaults[owner] = cardID
+	return nil
+}
+
+// WalletCard is a saved card with its wallet state.
+type WalletCard struct {
+	SavedCard
+	// Default reports whether this is the owner's default card, taking the
+	// DefaultCardStore choice over the API is_default flag.
+	Default bool `json:"default"`
+	// ExpiresAt is the first instant the card is no longer valid, zero when
+	// the expiry is unknown.
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+	Expired   bool      `json:"expired"`
+	Expiring  bool      `json:"expiring"`
+	// DuplicateOf is the ID of an earlier card of the owner with the same BIN
+	// and last four digits.
+	DuplicateOf string `json:"duplicate_of,omitempty"`
+}
+
+// Wallet manages the saved cards of a single customer on top of
+// ListSavedCards, which returns the cards of the whole organization.
+type Wallet struct {
+	Client   WalletClient
+	Defaults DefaultCardStore
+	PerPage  int
+	// ExpiringWithin marks cards expiring within this duration. Zero uses
+	// DefaultExpiringWithin.
+	ExpiringWithin time.Duration
+	Now            func() time.Time
+
+	defaultsOnce sync.Once
+}
+
+// NewWallet creates a wallet backed by client, usually an *API, keeping
+// default card choices in memory. They are lost when the process exits; use
+// a persistent DefaultCardStore to keep them.
+func NewWallet(client WalletClient) *Wallet {
+	return &Wallet{
+		Client:   client,
+		Defaults: &MemoryDefaultCardStore{},
+	}
+}
+
+// Cards returns the saved cards of owner, walking every ListSavedCards page.
+func (w *Wallet) Cards(ctx context.Context, owner WalletOwner) ([]WalletCard, error) {
+	if owner.UserID == "" && strings.TrimSpace(owner.Email) == "" {
+		return nil, walletError("user_id or user_email is required")
+	}
+
+	var cards []WalletCard
+	listed, withOwner := 0, 0
+	err := WalkSavedCards(ctx, w.Client, w.PerPage, func(card SavedCard) error {
+		listed++
+		if card.UserID != "" || strings.TrimSpace(card.UserEmail) != "" {
+			withOwner++
+		}
+		if owner.owns(card) {
+			cards = append(cards, w.walletCard(card))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// The owner fields are not documented for the card list; without them
+	// every wallet would look empty.
+	if listed > 0 && withOwner == 0 {
+		return nil, ErrSavedCardOwnerUnknown
+	}
+
+	seen := make(map[string]string, len(cards))
+	for i := range cards {
+		fingerprint := savedCardFingerprint(cards[i].SavedCard)
+		if fingerprint == "" {
+			continue
+		}
+		if first, ok := seen[fingerprint]; ok {
+			cards[i].DuplicateOf = first
+			continue
+		}
+		seen[fingerprint] = cards[i].ID
+	}
+
+	defaultID, err := w.defaultCardID(ctx, owner, cards)
+	if err != nil {
+		return nil, err
+	}
+	for i := range cards {
+		cards[i].Default = cards[i].ID == defaultID
+	}
+	return cards, nil
+}
+
+// Default returns the default card of owner. The boolean is false when the
+// owner has no cards.
+func (w *Wallet) Default(ctx context.Context, owner WalletOwner) (WalletCard, bool, error) {
+	cards, err := w.Cards(ctx, owner)
+	if err != nil {
+		return WalletCard{}, false, err
+	}
+	for _, card := range cards {
+		if card.Default {
+			return card, true, nil
+		}
+	}
+	return WalletCard{}, false, nil
+}
+
+// FindDuplicate returns the saved card of owner with the same BIN and last
+// four digits as cardNumber, so a card can be checked before tokenizing it
+// again.
+func (w *Wallet) FindDuplicate(ctx context.Context, owner WalletOwner, cardNumber string) (WalletCard, bool, error) {
+	number := NormalizeCardNumber(cardNumber)
+	if len(number) < 10 || !isDigits(number) {
+		return WalletCard{}, false, walletError("card number must have at least 10 digits")
+	}
+	fingerprint := number[:6] + number[len(number)-4:]
+
+	cards, err := w.Cards(ctx, owner)
+	if err != nil {
+		return WalletCard{}, false, err
+	}
+	for _, card := range cards {
+		if savedCardFingerprint(card.SavedCard) == fingerprint {
+			return card, true, nil
+		}
+	}
+	return WalletCard{}, false, nil
+}
+
+// SetDefault makes cardID the default card of owner in Defaults. The
+// is_default flag on the server is left unchanged.
+func (w *Wallet) SetDefault(ctx context.Context, owner WalletOwner, cardID string) error {
+	cards, err := w.Cards(ctx, owner)
+	if err != nil {
+		return err
+	}
+	if _, ok := findWalletCard(cards, cardID); !ok {
+		return walletError(fmt.Sprintf("card %s not found in wallet", cardID))
+	}
+	return w.defaults().SetDefaultCard(ctx, owner.key(), cardID)
+}
+
+// Delete deletes a card of owner. When it was the default card, the next
+// unexpired card, or failing that the next card, becomes the default in
+// Defaults, and is returned with true. As with SetDefault, the new default
+// is not sent to the server.
+func (w *Wallet) Delete(ctx context.Context, owner WalletOwner, cardID string) (WalletCard, bool, error) {
+	cards, err := w.Cards(ctx, owner)
+	if err != nil {
+		return WalletCard{}, false, err
+	}
+	card, ok := findWalletCard(cards, cardID)
+	if !ok {
+		return WalletCard{}, false, walletError(fmt.Sprintf("card %s not found in wallet", cardID))
+	}
+
+	if _, err := w.Client.DeleteSavedCard(ctx, cardID); err != nil {
+		return WalletCard{}, false, err
+	}
+	if !card.Default {
+		return WalletCard{}, false, nil
+	}
+
+	var next *WalletCard
+	for i := range cards {
+		if cards[i].ID == cardID {
+			continue
+		}
+		if next == nil || (next.Expired && !cards[i].Expired) {
+			next = &cards[i]
+		}
+	}
+	if next == nil {
+		return WalletCard{}, false, w.defaults().SetDefaultCard(ctx, owner.key(), "")
+	}
+	if err := w.defaults().SetDefaultCard(ctx, owner.key(), next.ID); err != nil {
+		return WalletCard{}, false, err
+	}
+	next.Default = true
+	return *next, true, nil
+}
+
+func (w *Wallet) defaults() DefaultCardStore {
+	w.defaultsOnce.Do(func() {
+		if w.Defaults == nil {
+			w.Defaults = &MemoryDefaultCardStore{}
+		}
+	})
+	return w.Defaults
+}
+
+func (w *Wallet) defaultCardID(ctx context.Context, owner WalletOwner, cards []WalletCard) (string, error) {
+	stored, ok, err := w.defaults().DefaultCard(ctx, owner.key())
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		if _, found := findWalletCard(cards, stored); found {
+			return stored, nil
+		}
+	}
+	for _, card := range cards {
+		if card.IsDefault {
+			return card.ID, nil
+		}
+	}
+	return "", nil
+}
+
+func (w *Wallet) walletCard(card SavedCard) WalletCard {
+	result := WalletCard{SavedCard: card}
+	now := time.Now()
+	if w.Now != nil {
+		now = w.Now()
+	}
+	expiresAt, ok := savedCardExpiry(card, now)
+	if !ok {
+		return result
+	}
+
+	within := w.ExpiringWithin
+	if within == 0 {
+		within = DefaultExpiringWithin
+	}
+	result.ExpiresAt = expiresAt
+	result.Expired = !now.Before(expiresAt)
+	result.Expiring = !result.Expired && expiresAt.Sub(now) <= within
+	return result
+}
+
+// savedCardExpiry returns the start of the month after the card expiry,
+// reading two digit years as ValidateCardExpiry does.
+func savedCardExpiry(card SavedCard, now time.Time) (time.Time, bool) {
+	month, err := strconv.Atoi(strings.TrimSpace(card.ExpiryMonth))
+	if err != nil || month < 1 || month > 12 {
+		return time.Time{}, false
+	}
+	year, ok := expiryYear(strings.TrimSpace(card.ExpiryYear), now)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Date(year, time.Month(month)+1, 1, 0, 0, 0, 0, time.UTC), true
+}
+
+// savedCardFingerprint returns the six digit BIN and last four digits of a
+// saved card, falling back to the masked number.
+func savedCardFingerprint(card SavedCard) string {
+	masked := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, card.MaskedNumber)
+
+	bin := card.Bin
+	if len(bin) < 6 && len(masked) >= 6 && isDigits(masked[:6]) {
+		bin = masked[:6]
+	}
+	lastFour := card.LastFour
+	if len(lastFour) != 4 && len(masked) >= 4 && isDigits(masked[len(masked)-4:]) {
+		lastFour = masked[len(masked)-4:]
+	}
+	if len(bin) < 6 || len(lastFour) != 4 {
+		return ""
+	}
+	return bin[:6] + lastFour
+}
+
+func findWalletCard(cards []WalletCard, cardID string) (WalletCard, bool) {
+	for _, card := range cards {
+		if card.ID == cardID {
+			return card, true
+		}
+	}
+	return WalletCard{}, false
+}
+
+func walletError(message string) error {
+	return &ValidationError{
+		StatusCode: 400,
+		Code:       0,
+		Message:    message,
+	}
+}